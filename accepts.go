@@ -0,0 +1,141 @@
+package tobingo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptHeaders is the parsed form of the content negotiation headers of a request
+type acceptHeaders struct {
+	media       []acceptRange // Accept
+	encodings   []qToken      // Accept-Encoding
+	hasEncoding bool          // Whether Accept-Encoding was sent, an absent header accepts any coding
+	languages   []qToken      // Accept-Language, nil when absent
+}
+
+// qToken is one entry of a token list with quality values such as "gzip;q=0.8"
+type qToken struct {
+	value string  // Lower-cased token, may be "*"
+	q     float64 // Quality between 0 and 1
+}
+
+// parseQTokens parses a comma separated list of tokens with optional q parameters
+// Entries without a token are skipped; an invalid q counts as 0 like in parseAccept
+func parseQTokens(header string) []qToken {
+	var tokens []qToken
+	for part := range strings.SplitSeq(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		token := qToken{value: value, q: 1}
+		for param := range strings.SplitSeq(params, ";") {
+			key, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				q, err := strconv.ParseFloat(v, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				token.q = q
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// accepted returns the negotiation headers of r, parsed once per request when r came
+// through a router
+func accepted(r *http.Request) *acceptHeaders {
+	parse := func() *acceptHeaders {
+		encoding, hasEncoding := r.Header["Accept-Encoding"]
+		return &acceptHeaders{
+			media:       parseAccept(r.Header.Get("Accept")),
+			encodings:   parseQTokens(strings.Join(encoding, ",")),
+			hasEncoding: hasEncoding,
+			languages:   parseQTokens(r.Header.Get("Accept-Language")),
+		}
+	}
+
+	state := stateFrom(r)
+	if state == nil {
+		return parse()
+	}
+	state.acceptOnce.Do(func() {
+		state.accept = parse()
+	})
+	return state.accept
+}
+
+// Accepts returns the offered media type the client prefers according to the Accept header,
+// or "" when none is acceptable; a missing header accepts the first offer
+// Example: if tobingo.Accepts(r, "application/json", "text/html") == "text/html" { ... }
+func Accepts(r *http.Request, offers ...string) string {
+	return bestMediaType(accepted(r).media, offers)
+}
+
+// AcceptsEncoding reports whether the client accepts the content coding, e.g. "gzip"
+// "identity" is acceptable unless refused with "identity;q=0", or with "*;q=0" when identity
+// isn't listed; other codings need an entry or "*" with a non-zero quality
+// A request without Accept-Encoding accepts every coding
+func AcceptsEncoding(r *http.Request, encoding string) bool {
+	h := accepted(r)
+	if !h.hasEncoding {
+		return true
+	}
+	encoding = strings.ToLower(encoding)
+
+	wildcard := -1.0
+	for _, token := range h.encodings {
+		switch token.value {
+		case encoding:
+			return token.q > 0
+		case "*":
+			wildcard = token.q
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard > 0
+	}
+	return encoding == "identity"
+}
+
+// AcceptsLanguage returns the offered language tag the client prefers according to
+// Accept-Language, or "" when none is acceptable; a missing header accepts the first offer
+// Ranges match tags and their subtags, so "en" covers "en-GB"; the longest matching range
+// decides an offer's quality and ties go to the offer listed first
+// Example: tobingo.AcceptsLanguage(r, "en", "de", "fr-CA") with "fr;q=0.9, de;q=0.5" returns "fr-CA"
+func AcceptsLanguage(r *http.Request, offers ...string) string {
+	h := accepted(r)
+	if len(h.languages) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		tag := strings.ToLower(offer)
+
+		// The longest range covering the tag decides, so "en-US;q=0" overrides "en"
+		matched, q := -1, 0.0
+		for _, token := range h.languages {
+			covers := token.value == "*" || token.value == tag || strings.HasPrefix(tag, token.value+"-")
+			length := len(token.value)
+			if token.value == "*" {
+				length = 0
+			}
+			if covers && length > matched {
+				matched, q = length, token.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
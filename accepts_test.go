@@ -0,0 +1,110 @@
+package tobingo
+
+import (
+	"net/http"
+	"testing"
+)
+
+// withAccept runs fn on a routed request carrying the header, if any
+func withAccept(t *testing.T, name, value string, fn func(r *http.Request)) {
+	t.Helper()
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) { fn(r) })
+	var opts []TestOption
+	if name != "" {
+		opts = append(opts, WithTestHeader(name, value))
+	}
+	rt.Test("GET", "/", nil, opts...)
+}
+
+func TestAccepts(t *testing.T) {
+	offers := []string{"application/json", "text/html"}
+	for header, want := range map[string]string{
+		"text/html;q=0.8, application/json;q=0.9": "application/json",
+		"application/json;q=0.5, text/html":       "text/html",
+		"text/*":                                  "text/html",
+		"*/*":                                     "application/json",
+		"image/png":                               "",
+		"text/html;q=0":                           "",
+		"*/*;q=0.1, text/html;q=0":                "application/json",
+		"text/html;q=abc, application/json;q=0.1": "application/json", // An invalid q counts as 0
+		",,;q=1, nonsense, text/html":             "text/html",        // Malformed entries are skipped
+		"*/html":                                  "",
+	} {
+		withAccept(t, "Accept", header, func(r *http.Request) {
+			if got := Accepts(r, offers...); got != want {
+				t.Errorf("Accept %q = %q, want %q", header, got, want)
+			}
+		})
+	}
+	withAccept(t, "", "", func(r *http.Request) {
+		if got := Accepts(r, offers...); got != "application/json" {
+			t.Errorf("no Accept = %q, want the first offer", got)
+		}
+	})
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	for header, want := range map[string]map[string]bool{
+		"gzip, br;q=0.5":    {"gzip": true, "br": true, "zstd": false, "identity": true},
+		"GZIP;q=0":          {"gzip": false, "identity": true},
+		"identity;q=0, *":   {"identity": false, "gzip": true},
+		"*;q=0":             {"identity": false, "gzip": false},
+		"*;q=0, identity":   {"identity": true, "gzip": false},
+		"br;q=oops":         {"br": false, "identity": true},
+		"":                  {"gzip": false, "identity": true},
+		"deflate;q=0.1;x=y": {"deflate": true},
+	} {
+		withAccept(t, "Accept-Encoding", header, func(r *http.Request) {
+			for encoding, ok := range want {
+				if got := AcceptsEncoding(r, encoding); got != ok {
+					t.Errorf("Accept-Encoding %q: %s = %v, want %v", header, encoding, got, ok)
+				}
+			}
+		})
+	}
+	withAccept(t, "", "", func(r *http.Request) {
+		if !AcceptsEncoding(r, "gzip") || !AcceptsEncoding(r, "identity") {
+			t.Error("a request without Accept-Encoding should accept every coding")
+		}
+	})
+}
+
+func TestAcceptsLanguage(t *testing.T) {
+	offers := []string{"en", "de", "fr-CA"}
+	for header, want := range map[string]string{
+		"fr;q=0.9, de;q=0.5":     "fr-CA",
+		"de, en;q=0.9":           "de",
+		"en-GB":                  "",
+		"*":                      "en",
+		"*;q=0.5, fr-CA;q=0":     "en",
+		"fr;q=0.8, fr-ca;q=0":    "",
+		"ja":                     "",
+		"de;q=0.5, en;q=0.5":     "en", // Ties go to the offer listed first
+		"en;q=bad, de;q=0.1, ,;": "de",
+	} {
+		withAccept(t, "Accept-Language", header, func(r *http.Request) {
+			if got := AcceptsLanguage(r, offers...); got != want {
+				t.Errorf("Accept-Language %q = %q, want %q", header, got, want)
+			}
+		})
+	}
+	withAccept(t, "", "", func(r *http.Request) {
+		if got := AcceptsLanguage(r, offers...); got != "en" {
+			t.Errorf("no Accept-Language = %q, want the first offer", got)
+		}
+	})
+}
+
+func TestAcceptHeadersParsedOnce(t *testing.T) {
+	withAccept(t, "Accept", "text/html", func(r *http.Request) {
+		if Accepts(r, "text/html") == "" {
+			t.Fatal("first read failed")
+		}
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set("Accept-Encoding", "br")
+		if Accepts(r, "text/html") == "" || !AcceptsEncoding(r, "gzip") {
+			t.Error("headers were parsed again")
+		}
+	})
+}
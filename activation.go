@@ -0,0 +1,88 @@
+package tobingo
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+// (SD_LISTEN_FDS_START); it is a variable so tests can point it at other descriptors
+var listenFDsStart = 3
+
+// ActivationListeners returns the listeners passed to this process by systemd socket
+// activation, following the LISTEN_PID, LISTEN_FDS, and LISTEN_FDNAMES protocol
+// Returns nil without error when the process was not socket-activated
+// The environment variables are cleared so child processes don't claim the sockets
+func ActivationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		// Variables missing or meant for another process
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	// Names are optional and only used to label the files
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for i := range count {
+		fd := listenFDsStart + i
+
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener duplicates the descriptor, so the original can be closed right away
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("tobingo: socket activation fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// SetActivationFallback sets the address StartFromActivation binds when the process
+// was not started through systemd socket activation; defaults to ":http"
+func (rt *Rastauter) SetActivationFallback(addr string) {
+	rt.mu.Lock()
+	rt.activationFallback = addr
+	rt.mu.Unlock()
+}
+
+// StartFromActivation serves this router on every socket inherited from systemd socket
+// activation, or on the fallback address set by SetActivationFallback otherwise
+// Shutdown stops all servers; StartFromActivation then returns http.ErrServerClosed
+func (rt *Rastauter) StartFromActivation() error {
+	listeners, err := ActivationListeners()
+	if err != nil {
+		return err
+	}
+
+	if len(listeners) == 0 {
+		rt.mu.Lock()
+		addr := rt.activationFallback
+		rt.mu.Unlock()
+
+		return rt.StartServer(addr)
+	}
+
+	return rt.serveListeners(listeners)
+}
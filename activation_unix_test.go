@@ -0,0 +1,150 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tobingo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// activate makes the given descriptors look inherited from systemd: they must be
+// consecutive, and listenFDsStart and the LISTEN_ variables are pointed at them
+// ActivationListeners takes ownership of the descriptors and closes them
+func activate(t *testing.T, names string, fds ...int) {
+	t.Helper()
+	for i, fd := range fds[1:] {
+		if fd != fds[i]+1 {
+			for _, fd := range fds {
+				syscall.Close(fd)
+			}
+			t.Skip("duplicated descriptors are not consecutive")
+		}
+	}
+	old := listenFDsStart
+	listenFDsStart = fds[0]
+	t.Cleanup(func() { listenFDsStart = old })
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", strconv.Itoa(len(fds)))
+	t.Setenv("LISTEN_FDNAMES", names)
+}
+
+// inheritable returns loopback listeners and raw duplicates of their descriptors, duplicated
+// one after another so they are usually consecutive; raw descriptors have no finalizer that
+// could close a reused number after ActivationListeners closed them
+func inheritable(t *testing.T, n int) ([]net.Listener, []int) {
+	t.Helper()
+	listeners := make([]net.Listener, n)
+	for i := range listeners {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		listeners[i] = l
+	}
+	files := make([]*os.File, n)
+	for i, l := range listeners {
+		f, err := l.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	fds := make([]int, n)
+	for i, f := range files {
+		fd, err := syscall.Dup(int(f.Fd()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fds[i] = fd
+	}
+	return listeners, fds
+}
+
+func TestActivationListeners(t *testing.T) {
+	inherited, fds := inheritable(t, 2)
+	activate(t, "web:admin", fds...)
+
+	listeners, err := ActivationListeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("got %d listeners, want 2", len(listeners))
+	}
+	for i, want := range inherited {
+		if listeners[i].Addr().String() != want.Addr().String() {
+			t.Errorf("listener %d on %s, want %s", i, listeners[i].Addr(), want.Addr())
+		}
+		listeners[i].Close()
+	}
+	if os.Getenv("LISTEN_FDS") != "" || os.Getenv("LISTEN_PID") != "" {
+		t.Error("activation variables were not cleared")
+	}
+}
+
+func TestActivationListenersIgnoresOtherProcesses(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+	if listeners, err := ActivationListeners(); listeners != nil || err != nil {
+		t.Errorf("got %v, %v for another process's sockets", listeners, err)
+	}
+}
+
+func TestStartFromActivation(t *testing.T) {
+	inherited, fds := inheritable(t, 1)
+	activate(t, "", fds...)
+	l := inherited[0]
+
+	rt := NewRastaRouterInitializer()
+	rt.GET("/ping", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("pong")) })
+	listening := make(chan struct{})
+	rt.OnListen(func(net.Addr) { close(listening) })
+	errCh := make(chan error, 1)
+	go func() { errCh <- rt.StartFromActivation() }()
+	<-listening
+
+	// The inherited socket, not a new one, answers
+	res, err := http.Get("http://" + l.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("body = %q", body)
+	}
+	l.Close()
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("StartFromActivation = %v, want ErrServerClosed", err)
+	}
+}
+
+func TestStartFromActivationFallback(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	rt := NewRastaRouterInitializer()
+	rt.SetActivationFallback("127.0.0.1:0")
+	addrs := make(chan net.Addr, 1)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+	errCh := make(chan error, 1)
+	go func() { errCh <- rt.StartFromActivation() }()
+
+	if addr := <-addrs; addr.(*net.TCPAddr).Port == 0 {
+		t.Errorf("fallback bound %s", addr)
+	}
+	rt.Shutdown(context.Background())
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("StartFromActivation = %v, want ErrServerClosed", err)
+	}
+}
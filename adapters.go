@@ -0,0 +1,79 @@
+package tobingo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// H adapts a function computing a response to an http.HandlerFunc
+// On success the status, 200 when zero, is written with body encoded as JSON, or without a body when body is
+// nil or the status is 204; an error goes to the router's error handler with the status of
+// an HTTPError in its chain, 413 for an oversized body, 422 for a failed validation, and 500
+// for any other error
+// Example: rt.GET("/users/:id", tobingo.H(func(r *http.Request) (int, any, error) { return http.StatusOK, users.Find(tobingo.GetParam(r, "id")), nil }))
+func H(fn func(r *http.Request) (int, any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, body, err := fn(r)
+		if err != nil {
+			handleError(w, r, errorStatus(err, http.StatusInternalServerError), err)
+			return
+		}
+		writeResult(w, r, cmp.Or(status, http.StatusOK), body)
+	}
+}
+
+// writeResult writes a successful adapter result, JSON-encoded unless there's no body
+func writeResult(w http.ResponseWriter, r *http.Request, status int, body any) {
+	if body == nil || !bodyAllowed(status) {
+		writeStatus(w, status)
+		return
+	}
+	if err := JSON(w, status, body); err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// JSONHandler adapts a typed function to an http.HandlerFunc: the JSON body is bound into In
+// with BindJSON, the function gets the request context and a copy of the path parameters,
+// and Out is written as JSON with status 200
+// Binding failures answer 415 for a non-JSON Content-Type, 413 for an oversized body, 422
+// for a failed validation, and 400 otherwise; errors from fn go to the error handler like with H
+// An In of struct{} skips body decoding, for GETs and other bodiless requests
+// Example: rt.GET("/users/:id", tobingo.JSONHandler(func(ctx context.Context, _ struct{}, p map[string]string) (User, error) { return users.Get(ctx, p["id"]) }))
+func JSONHandler[In, Out any](fn func(ctx context.Context, in In, params map[string]string) (Out, error)) http.HandlerFunc {
+	t := reflect.TypeFor[In]()
+	skipBody := t.Kind() == reflect.Struct && t.NumField() == 0
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in In
+		if !skipBody {
+			if err := BindJSON(r, &in); err != nil {
+				handleError(w, r, bindStatus(err), err)
+				return
+			}
+		}
+
+		out, err := fn(r.Context(), in, Params(r))
+		if err != nil {
+			handleError(w, r, errorStatus(err, http.StatusInternalServerError), err)
+			return
+		}
+		writeResult(w, r, http.StatusOK, out)
+	}
+}
+
+// bindStatus returns the status answering a body binding error
+func bindStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrValidation):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
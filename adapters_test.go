@@ -0,0 +1,127 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// itemOut is the body the adapter tests answer with
+type itemOut struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestH(t *testing.T) {
+	var handled []error
+	rt := NewRastaRouterInitializer()
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		handled = append(handled, err)
+		DefaultErrorHandler(w, r, status, err)
+	})
+	rt.GET("/items/:id", H(func(r *http.Request) (int, any, error) {
+		return http.StatusCreated, itemOut{ID: GetParam(r, "id"), Name: "lamp"}, nil
+	}))
+	rt.GET("/default", H(func(r *http.Request) (int, any, error) { return 0, []int{1, 2}, nil }))
+	rt.GET("/empty", H(func(r *http.Request) (int, any, error) { return http.StatusNoContent, nil, nil }))
+	rt.GET("/nobody", H(func(r *http.Request) (int, any, error) { return http.StatusAccepted, nil, nil }))
+	rt.GET("/bodyless", H(func(r *http.Request) (int, any, error) { return http.StatusNoContent, itemOut{ID: "x"}, nil }))
+	rt.GET("/plain", H(func(r *http.Request) (int, any, error) {
+		return http.StatusOK, nil, errors.New("db: connection refused")
+	}))
+	rt.GET("/http", H(func(r *http.Request) (int, any, error) {
+		return http.StatusOK, nil, fmt.Errorf("loading: %w", &HTTPError{Status: http.StatusConflict, Message: "item is locked"})
+	}))
+	rt.GET("/unencodable", H(func(r *http.Request) (int, any, error) { return http.StatusOK, func() {}, nil }))
+
+	for target, want := range map[string]struct {
+		status      int
+		body, ctype string
+	}{
+		"/items/7":     {http.StatusCreated, `{"id":"7","name":"lamp"}` + "\n", "application/json; charset=utf-8"},
+		"/default":     {http.StatusOK, "[1,2]\n", "application/json; charset=utf-8"},
+		"/empty":       {http.StatusNoContent, "", ""},
+		"/nobody":      {http.StatusAccepted, "", ""},
+		"/bodyless":    {http.StatusNoContent, "", ""},
+		"/plain":       {http.StatusInternalServerError, "Internal Server Error\n", "text/plain; charset=utf-8"},
+		"/http":        {http.StatusConflict, "item is locked\n", "text/plain; charset=utf-8"},
+		"/unencodable": {http.StatusInternalServerError, "Internal Server Error\n", "text/plain; charset=utf-8"},
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != want.status || res.BodyString() != want.body || res.Header("Content-Type") != want.ctype {
+			t.Errorf("%s = %d %q %q, want %d %q %q", target, res.StatusCode(), res.BodyString(), res.Header("Content-Type"), want.status, want.body, want.ctype)
+		}
+	}
+	if len(handled) != 3 {
+		t.Errorf("error handler ran %d times, want 3", len(handled))
+	}
+}
+
+// renameIn is the body of the JSONHandler tests
+type renameIn struct {
+	Name string `json:"name"`
+}
+
+func TestJSONHandler(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.addRoute(http.MethodPost, "/items/:id", JSONHandler(func(ctx context.Context, in renameIn, p map[string]string) (itemOut, error) {
+		if in.Name == "taken" {
+			return itemOut{}, &HTTPError{Status: http.StatusConflict, Message: "name is taken"}
+		}
+		if in.Name == "broken" {
+			return itemOut{}, errors.New("storage failed")
+		}
+		return itemOut{ID: p["id"], Name: in.Name}, nil
+	}))
+	var sawCtx bool
+	rt.GET("/items/:id", JSONHandler(func(ctx context.Context, _ struct{}, p map[string]string) (itemOut, error) {
+		sawCtx = ctx != nil && ctx.Err() == nil
+		return itemOut{ID: p["id"]}, nil
+	}))
+	jsonType := WithTestHeader("Content-Type", "application/json")
+
+	for name, tc := range map[string]struct {
+		body   string
+		opts   []TestOption
+		status int
+		want   string
+	}{
+		"valid":          {`{"name":"lamp"}`, []TestOption{jsonType}, http.StatusOK, `{"id":"9","name":"lamp"}` + "\n"},
+		"malformed":      {`{"name":`, []TestOption{jsonType}, http.StatusBadRequest, ""},
+		"wrong type":     {`{"name":42}`, []TestOption{jsonType}, http.StatusBadRequest, ""},
+		"empty":          {``, []TestOption{jsonType}, http.StatusBadRequest, ""},
+		"not JSON":       {`name=lamp`, []TestOption{WithTestHeader("Content-Type", "application/x-www-form-urlencoded")}, http.StatusUnsupportedMediaType, ""},
+		"status error":   {`{"name":"taken"}`, []TestOption{jsonType}, http.StatusConflict, "name is taken\n"},
+		"internal error": {`{"name":"broken"}`, []TestOption{jsonType}, http.StatusInternalServerError, "Internal Server Error\n"},
+	} {
+		res := rt.Test("POST", "/items/9", strings.NewReader(tc.body), tc.opts...)
+		if res.StatusCode() != tc.status || (tc.want != "" && res.BodyString() != tc.want) {
+			t.Errorf("%s = %d %q, want %d %q", name, res.StatusCode(), res.BodyString(), tc.status, tc.want)
+		}
+	}
+
+	// struct{} input skips decoding, so a GET without a body or Content-Type works
+	res := rt.Test("GET", "/items/3", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != `{"id":"3","name":""}`+"\n" || !sawCtx {
+		t.Errorf("GET = %d %q, context seen %v", res.StatusCode(), res.BodyString(), sawCtx)
+	}
+}
+
+func TestJSONHandlerParamsAreACopy(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/items/:id", JSONHandler(func(ctx context.Context, _ struct{}, p map[string]string) (string, error) {
+		p["id"] = "changed"
+		return "", nil
+	})).Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			if id := GetParam(r, "id"); id != "5" {
+				t.Errorf("param after the handler = %q", id)
+			}
+		})
+	})
+	rt.Test("GET", "/items/5", nil)
+}
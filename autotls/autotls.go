@@ -0,0 +1,128 @@
+// Package autotls serves a tobingo router over HTTPS using certificates obtained
+// automatically from Let's Encrypt (or any ACME directory) via golang.org/x/crypto/acme/autocert
+// It lives in its own module so the core router stays free of third-party dependencies
+package autotls
+
+import (
+	"crypto/tls"
+	"net"
+	"slices"
+
+	"github.com/ShourovRoy/tobingo"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// LetsEncryptStagingURL is the ACME directory of the Let's Encrypt staging environment
+// Use it with WithDirectoryURL while testing to avoid production rate limits
+const LetsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+// config holds the settings collected from Option values
+type config struct {
+	directoryURL string // ACME directory URL, empty means Let's Encrypt production
+	email        string // Contact email registered with the ACME account
+	httpsAddr    string // Address of the TLS listener
+	httpAddr     string // Address of the HTTP-01 challenge and redirect listener
+}
+
+// Option configures the automatic TLS setup
+type Option func(*config)
+
+// WithDirectoryURL sets the ACME directory URL, e.g. LetsEncryptStagingURL
+func WithDirectoryURL(url string) Option {
+	return func(c *config) {
+		c.directoryURL = url
+	}
+}
+
+// WithEmail sets the contact email used when registering the ACME account
+func WithEmail(email string) Option {
+	return func(c *config) {
+		c.email = email
+	}
+}
+
+// WithAddrs overrides the HTTPS and HTTP listener addresses (default ":443" and ":80")
+func WithAddrs(httpsAddr, httpAddr string) Option {
+	return func(c *config) {
+		c.httpsAddr = httpsAddr
+		c.httpAddr = httpAddr
+	}
+}
+
+// newConfig applies the options on top of the defaults
+func newConfig(opts []Option) *config {
+	c := &config{
+		httpsAddr: ":443",
+		httpAddr:  ":80",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewManager creates an autocert.Manager that only issues certificates for the given domains
+// and caches them in cacheDir so restarts don't request new certificates
+func NewManager(domains []string, cacheDir string, opts ...Option) *autocert.Manager {
+	c := newConfig(opts)
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      c.email,
+	}
+
+	// Only override the ACME client when a non-default directory was requested
+	if c.directoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: c.directoryURL}
+	}
+	return m
+}
+
+// Start serves rt over HTTPS on :443 with certificates managed for the given domains,
+// and serves ACME HTTP-01 challenges on :80 while redirecting all other HTTP traffic to https
+// Both listeners are bound before serving so address errors are returned immediately, and
+// both servers get the router's ConfigureServer options and are reported to OnListen
+// Calling rt.Shutdown stops both servers; Start then returns http.ErrServerClosed
+func Start(rt *tobingo.Rastauter, domains []string, cacheDir string, opts ...Option) error {
+	c := newConfig(opts)
+	m := NewManager(domains, cacheDir, opts...)
+
+	// Hand certificate selection to the manager, keeping the rest of the TLS settings such as
+	// the minimum version and client authentication; HTTP/2 is still offered by net/http
+	srv := rt.Server()
+	tlsConfig := srv.TLSConfig.Clone()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.GetCertificate = m.GetCertificate
+	if !slices.Contains(tlsConfig.NextProtos, acme.ALPNProto) {
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+	}
+	srv.TLSConfig = tlsConfig
+
+	// Redirect everything but the challenges like StartRedirectServer
+	redirect, err := rt.HTTPSRedirectHandler("", tobingo.RedirectOptions{})
+	if err != nil {
+		return err
+	}
+
+	tlsListener, err := net.Listen("tcp", c.httpsAddr)
+	if err != nil {
+		return err
+	}
+
+	httpListener, err := net.Listen("tcp", c.httpAddr)
+	if err != nil {
+		tlsListener.Close()
+		return err
+	}
+
+	// The challenge server is built with the router's options and drained by its Shutdown
+	go rt.ServeCompanion(httpListener, m.HTTPHandler(redirect))
+
+	// Certificates come from the TLS config, so no certificate files are passed
+	return rt.ServeTLS(tlsListener, "", "")
+}
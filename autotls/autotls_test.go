@@ -0,0 +1,141 @@
+package autotls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ShourovRoy/tobingo"
+	"golang.org/x/crypto/acme"
+)
+
+func TestNewManager(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager([]string{"example.com", "www.example.com"}, dir, WithEmail("ops@example.com"), WithDirectoryURL(LetsEncryptStagingURL))
+
+	for host, allowed := range map[string]bool{"example.com": true, "www.example.com": true, "evil.com": false} {
+		if err := m.HostPolicy(context.Background(), host); (err == nil) != allowed {
+			t.Errorf("HostPolicy(%s) = %v, want allowed %v", host, err, allowed)
+		}
+	}
+	if m.Email != "ops@example.com" {
+		t.Errorf("Email = %q", m.Email)
+	}
+	if m.Client == nil || m.Client.DirectoryURL != LetsEncryptStagingURL {
+		t.Error("directory URL not applied")
+	}
+	if m.Cache == nil {
+		t.Error("no certificate cache")
+	}
+	if NewManager([]string{"example.com"}, dir).Client != nil {
+		t.Error("default manager overrides the ACME client")
+	}
+}
+
+func TestStart(t *testing.T) {
+	rt := tobingo.NewRastaRouterInitializer()
+	var mu sync.Mutex
+	var built []*http.Server
+	rt.ConfigureServer(func(srv *http.Server) {
+		mu.Lock()
+		built = append(built, srv)
+		mu.Unlock()
+	})
+	addrs := make(chan net.Addr, 2)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Start(rt, []string{"example.com"}, t.TempDir(), WithAddrs("127.0.0.1:0", "127.0.0.1:0"))
+	}()
+	listening := []net.Addr{<-addrs, <-addrs}
+
+	// One of the listeners is the challenge server, redirecting everything else to https
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	var redirects int
+	for _, addr := range listening {
+		res, err := client.Get("http://" + addr.String() + "/users/42?tab=posts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusMovedPermanently {
+			redirects++
+			if got := res.Header.Get("Location"); got != "https://127.0.0.1/users/42?tab=posts" {
+				t.Errorf("Location = %q", got)
+			}
+		}
+	}
+	if redirects != 1 {
+		t.Errorf("%d listeners redirected, want the challenge server's", redirects)
+	}
+	mu.Lock()
+	if len(built) != 2 {
+		t.Errorf("options applied to %d servers, want the TLS and challenge servers", len(built))
+	}
+	mu.Unlock()
+
+	if rt.Server().TLSConfig == nil || rt.Server().TLSConfig.GetCertificate == nil {
+		t.Error("TLS config does not take certificates from the manager")
+	}
+	if protos := rt.Server().TLSConfig.NextProtos; !slices.Contains(protos, acme.ALPNProto) {
+		t.Errorf("NextProtos = %q, want the TLS-ALPN-01 protocol", protos)
+	}
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("Start = %v, want ErrServerClosed", err)
+	}
+	for _, addr := range listening {
+		if conn, err := net.Dial("tcp", addr.String()); err == nil {
+			conn.Close()
+			t.Errorf("%s still accepts connections after Shutdown", addr)
+		}
+	}
+}
+
+func TestStartBindError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	rt := tobingo.NewRastaRouterInitializer()
+	if err := Start(rt, []string{"example.com"}, t.TempDir(), WithAddrs("127.0.0.1:0", l.Addr().String())); err == nil {
+		t.Error("Start succeeded with the HTTP address in use")
+	}
+}
+
+func TestStartKeepsTLSSettings(t *testing.T) {
+	pool := x509.NewCertPool()
+	rt := tobingo.NewRastaRouterInitializer()
+	rt.ConfigureServer(tobingo.WithClientCAs(pool), tobingo.WithClientAuth(tls.RequireAndVerifyClientCert), func(srv *http.Server) {
+		srv.TLSConfig.MinVersion = tls.VersionTLS13
+		srv.TLSConfig.NextProtos = []string{"http/1.1"}
+	})
+	addrs := make(chan net.Addr, 2)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Start(rt, []string{"example.com"}, t.TempDir(), WithAddrs("127.0.0.1:0", "127.0.0.1:0"))
+	}()
+	<-addrs
+
+	cfg := rt.Server().TLSConfig
+	if cfg.GetCertificate == nil || cfg.MinVersion != tls.VersionTLS13 || cfg.ClientCAs != pool || cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("MinVersion %x, ClientAuth %v, ClientCAs kept %v, want the configured settings", cfg.MinVersion, cfg.ClientAuth, cfg.ClientCAs == pool)
+	}
+	if want := []string{"http/1.1", acme.ALPNProto}; !slices.Equal(cfg.NextProtos, want) {
+		t.Errorf("NextProtos = %q, want %q", cfg.NextProtos, want)
+	}
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-errCh
+}
@@ -0,0 +1,13 @@
+module github.com/ShourovRoy/tobingo/autotls
+
+go 1.24.5
+
+require github.com/ShourovRoy/tobingo v0.0.0
+
+require (
+	golang.org/x/crypto v0.48.0
+	golang.org/x/net v0.49.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+)
+
+replace github.com/ShourovRoy/tobingo => ../
@@ -0,0 +1,154 @@
+package tobingo
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// SetBasePath mounts the router at prefix, such as "/myapp", for gateways that forward
+// requests to it either with the prefix or with the prefix stripped
+// Paths starting with the prefix lose it before middleware and routes see them; a path
+// without it is served as is only when a trusted proxy (see SetTrustedProxies) reports the
+// stripped prefix in X-Forwarded-Prefix, and answers 404 otherwise
+// Redirects sent by the router, Redirect, RedirectToRoute, and URLFor put the external
+// prefix back, preferring a trusted X-Forwarded-Prefix; URL keeps returning router paths
+// "" or "/" mounts the router at the root again; it panics on a prefix not starting with "/"
+// Example: rt.SetBasePath("/myapp")
+func (rt *Rastauter) SetBasePath(prefix string) {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		rt.basePath.Store(nil)
+		return
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "?#") {
+		panic(fmt.Sprintf("tobingo: SetBasePath %q: prefix must be a path starting with /", prefix))
+	}
+	rt.basePath.Store(&prefix)
+}
+
+// BasePath returns the prefix set with SetBasePath, "" when the router is mounted at the root
+func (rt *Rastauter) BasePath() string {
+	if prefix := rt.basePath.Load(); prefix != nil {
+		return *prefix
+	}
+	return ""
+}
+
+// ExternalPrefix returns the prefix the client sees in front of the router's paths: the
+// X-Forwarded-Prefix of a trusted proxy, else the base path when the request carried it
+func ExternalPrefix(r *http.Request) string {
+	if state := stateFrom(r); state != nil {
+		return state.prefix
+	}
+	return ""
+}
+
+// ExternalPath returns the router path p as the client must request it, with ExternalPrefix
+// in front, for links in pages, SSE events, and API responses; other values are returned as is
+// Example: tobingo.ExternalPath(r, "/static/app.js") returns "/myapp/static/app.js" behind the gateway
+func ExternalPath(r *http.Request, p string) string {
+	prefix := ExternalPrefix(r)
+	if prefix == "" || !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	return prefix + p
+}
+
+// URLFor builds the path of the named route of the router serving r like URL, as the client
+// must request it
+// Example: tobingo.URLFor(r, "user", "id", "42") returns "/myapp/users/42" behind the gateway
+func URLFor(r *http.Request, name string, params ...string) (string, error) {
+	rt := routerFrom(r)
+	if rt == nil {
+		return "", fmt.Errorf("%w: %q: request not served by a router", ErrRouteNotFound, name)
+	}
+	p, err := rt.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	return ExternalPath(r, p), nil
+}
+
+// stripBasePath removes the base path from the path of r, recording the external prefix
+// in state and marking paths that aren't addressed to the router
+// r must be a copy owned by the router, its URL is replaced rather than modified
+func (rt *Rastauter) stripBasePath(r *http.Request, state *requestState) {
+	forwarded := rt.forwardedPrefix(r)
+	base := rt.basePath.Load()
+	if base == nil {
+		state.prefix = forwarded
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, *base)
+	if !ok || (rest != "" && rest[0] != '/') {
+		// Gateways stripping the prefix say so, anything else is outside the mount
+		state.prefix = forwarded
+		state.outside = forwarded == ""
+		return
+	}
+	state.prefix = cmp.Or(forwarded, *base)
+
+	u := *r.URL
+	u.Path = cmp.Or(rest, "/")
+	u.RawPath = ""
+	if raw, ok := strings.CutPrefix(r.URL.RawPath, *base); ok {
+		u.RawPath = cmp.Or(raw, "/")
+	}
+	r.URL = &u
+}
+
+// forwardedPrefix returns the X-Forwarded-Prefix of r when the peer is a trusted proxy,
+// "" without one or when it isn't a plain path, so clients can't point redirects elsewhere
+func (rt *Rastauter) forwardedPrefix(r *http.Request) string {
+	value := r.Header.Get("X-Forwarded-Prefix")
+	if value == "" {
+		return ""
+	}
+	if peer, ok := parseHostAddr(r.RemoteAddr); !ok || !isTrusted(rt.trusted(), peer) {
+		return ""
+	}
+
+	// Proxies in a chain may each append theirs, the first is the one the client sees
+	value, _, _ = strings.Cut(value, ",")
+	value = strings.TrimRight(strings.TrimSpace(value), "/")
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") {
+		return ""
+	}
+	if strings.ContainsFunc(value, func(c rune) bool { return c < ' ' || c == 0x7f || strings.ContainsRune(`?#\`, c) }) {
+		return ""
+	}
+	return path.Clean(value)
+}
+
+// externalLocation returns a redirect location as the client must follow it: rooted paths
+// get the external prefix, and relative ones are first resolved against the request path,
+// which http.Redirect would otherwise do without the prefix; absolute URLs are kept
+func externalLocation(r *http.Request, location string) string {
+	if ExternalPrefix(r) == "" || strings.HasPrefix(location, "//") {
+		return location
+	}
+	if u, err := url.Parse(location); err != nil || u.Scheme != "" {
+		return location
+	}
+
+	if !strings.HasPrefix(location, "/") {
+		// Resolve like http.Redirect, keeping a trailing slash path.Clean drops
+		p, query, hasQuery := strings.Cut(location, "?")
+		dir, _ := path.Split(r.URL.Path)
+		trailing := strings.HasSuffix(p, "/") || (p == "" && strings.HasSuffix(dir, "/"))
+		p = path.Clean(dir + p)
+		if trailing && !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+		location = p
+		if hasQuery {
+			location += "?" + query
+		}
+	}
+	return ExternalPath(r, location)
+}
@@ -0,0 +1,229 @@
+package tobingo
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mountedRouter returns a router mounted at /myapp trusting 192.0.2.0/24, the address
+// httptest gives requests, with a named route, a relative redirect, and redirect routes
+func mountedRouter(t *testing.T) *Rastauter {
+	t.Helper()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.SetBasePath("/myapp/")
+	rt.TrailingSlash(TrailingSlashRedirect)
+	if err := rt.SetTrustedProxies("192.0.2.0/24"); err != nil {
+		t.Fatal(err)
+	}
+
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		link, err := URLFor(r, "user", "id", "42")
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("X-Link", link)
+		w.Header().Set("X-Path", r.URL.Path)
+		_, _ = w.Write([]byte(Params(r)["id"]))
+	}).Named("user")
+	rt.GET("/docs/", func(w http.ResponseWriter, r *http.Request) {})
+	rt.GET("/account/logout", func(w http.ResponseWriter, r *http.Request) {
+		Redirect(w, r, http.StatusSeeOther, "../login")
+	})
+	rt.GET("/away", func(w http.ResponseWriter, r *http.Request) {
+		Redirect(w, r, http.StatusFound, "https://example.com/elsewhere")
+	})
+	rt.Redirect("GET", "/old/users/:id", "/users/:id", http.StatusMovedPermanently)
+	return rt
+}
+
+// mountedRequest serves target on rt from remote with an optional X-Forwarded-Prefix
+func mountedRequest(rt *Rastauter, target, remote, forwarded string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remote
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-Prefix", forwarded)
+	}
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	return rec
+}
+
+const (
+	trustedPeer   = "192.0.2.10:4000"
+	untrustedPeer = "198.51.100.7:4000"
+)
+
+func TestBasePathUnstripped(t *testing.T) {
+	rt := mountedRouter(t)
+	if got := rt.BasePath(); got != "/myapp" {
+		t.Fatalf("BasePath = %q", got)
+	}
+
+	// The gateway forwards the full path, from an untrusted peer, or a trusted one saying nothing
+	for _, remote := range []string{untrustedPeer, trustedPeer} {
+		rec := mountedRequest(rt, "/myapp/users/7", remote, "")
+		if rec.Code != http.StatusOK || rec.Body.String() != "7" {
+			t.Fatalf("%s: status %d body %q", remote, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("X-Path"); got != "/users/7" {
+			t.Errorf("%s: handler path = %q, want the prefix stripped", remote, got)
+		}
+		if got := rec.Header().Get("X-Link"); got != "/myapp/users/42" {
+			t.Errorf("%s: URLFor = %q", remote, got)
+		}
+	}
+
+	locations := []struct {
+		target, want string
+	}{
+		{"/myapp/docs", "/myapp/docs/"},          // Trailing slash redirect
+		{"/myapp/old/users/7", "/myapp/users/7"}, // Redirect route
+		{"/myapp/old/users/7?tab=a", "/myapp/users/7?tab=a"},
+		{"/myapp/account/logout", "/myapp/login"},        // Relative location
+		{"/myapp/away", "https://example.com/elsewhere"}, // Absolute URL is kept
+	}
+	for _, tc := range locations {
+		rec := mountedRequest(rt, tc.target, untrustedPeer, "")
+		if rec.Code < 300 || rec.Code > 399 {
+			t.Fatalf("%s: status %d, want a redirect", tc.target, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tc.want {
+			t.Errorf("%s: Location = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+
+	// Paths outside the mount, including ones only sharing its first letters
+	for _, target := range []string{"/users/7", "/myappx/users/7", "/other"} {
+		if rec := mountedRequest(rt, target, untrustedPeer, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestBasePathStripped(t *testing.T) {
+	rt := mountedRouter(t)
+
+	rec := mountedRequest(rt, "/users/7", trustedPeer, "/myapp")
+	if rec.Code != http.StatusOK || rec.Body.String() != "7" {
+		t.Fatalf("status %d body %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Path"); got != "/users/7" {
+		t.Errorf("handler path = %q", got)
+	}
+	if got := rec.Header().Get("X-Link"); got != "/myapp/users/42" {
+		t.Errorf("URLFor = %q", got)
+	}
+
+	locations := []struct {
+		target, want string
+	}{
+		{"/docs", "/myapp/docs/"},
+		{"/old/users/7", "/myapp/users/7"},
+		{"/account/logout", "/myapp/login"},
+		{"/away", "https://example.com/elsewhere"},
+	}
+	for _, tc := range locations {
+		rec := mountedRequest(rt, tc.target, trustedPeer, "/myapp")
+		if got := rec.Header().Get("Location"); got != tc.want {
+			t.Errorf("%s: Location = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+
+	// A gateway using another external prefix than the base path, possibly behind another proxy
+	rec = mountedRequest(rt, "/docs", trustedPeer, " /edge/app/ , /myapp")
+	if got := rec.Header().Get("Location"); got != "/edge/app/docs/" {
+		t.Errorf("Location = %q, want the first forwarded prefix", got)
+	}
+	rec = mountedRequest(rt, "/myapp/docs", trustedPeer, "/edge")
+	if got := rec.Header().Get("Location"); got != "/edge/docs/" {
+		t.Errorf("unstripped with a forwarded prefix: Location = %q", got)
+	}
+
+	// Clients can't claim the prefix was stripped
+	if rec := mountedRequest(rt, "/users/7", untrustedPeer, "/myapp"); rec.Code != http.StatusNotFound {
+		t.Errorf("untrusted peer: status %d, want 404", rec.Code)
+	}
+	rec = mountedRequest(rt, "/myapp/docs", untrustedPeer, "/evil")
+	if got := rec.Header().Get("Location"); got != "/myapp/docs/" {
+		t.Errorf("untrusted prefix: Location = %q", got)
+	}
+}
+
+func TestBasePathRejectsForwardedPrefixes(t *testing.T) {
+	rt := mountedRouter(t)
+	for _, value := range []string{"//evil.example", "http://evil.example", "myapp", "/a?b", "/a#b", `/a\b`, "/a\x7fb"} {
+		// Ignored, so the stripped path is outside the mount
+		if rec := mountedRequest(rt, "/users/7", trustedPeer, value); rec.Code != http.StatusNotFound {
+			t.Errorf("%q: stripped request status %d, want 404", value, rec.Code)
+		}
+		rec := mountedRequest(rt, "/myapp/docs", trustedPeer, value)
+		if got := rec.Header().Get("Location"); got != "/myapp/docs/" {
+			t.Errorf("%q: Location = %q, want the base path", value, got)
+		}
+	}
+}
+
+func TestBasePathRootAndPanics(t *testing.T) {
+	rt := mountedRouter(t)
+	rt.SetBasePath("")
+	if got := rt.BasePath(); got != "" {
+		t.Fatalf("BasePath after reset = %q", got)
+	}
+	if rec := mountedRequest(rt, "/users/7", untrustedPeer, ""); rec.Code != http.StatusOK {
+		t.Errorf("status %d after reset", rec.Code)
+	}
+	if rec := mountedRequest(rt, "/myapp/users/7", untrustedPeer, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("prefixed path status %d after reset, want 404", rec.Code)
+	}
+
+	// Without a base path a trusted gateway's prefix still goes into locations
+	rec := mountedRequest(rt, "/docs", trustedPeer, "/myapp")
+	if got := rec.Header().Get("Location"); got != "/myapp/docs/" {
+		t.Errorf("Location = %q", got)
+	}
+	rec = mountedRequest(rt, "/docs", untrustedPeer, "/myapp")
+	if got := rec.Header().Get("Location"); got != "/docs/" {
+		t.Errorf("untrusted: Location = %q", got)
+	}
+
+	for _, prefix := range []string{"myapp", "/my?app", "/my#app"} {
+		if msg := panicMessage(func() { rt.SetBasePath(prefix) }); !strings.Contains(msg, "SetBasePath") {
+			t.Errorf("SetBasePath(%q) panic = %q", prefix, msg)
+		}
+	}
+}
+
+func TestExternalPath(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users", nil)
+	if got := ExternalPath(req, "/static/app.js"); got != "/static/app.js" {
+		t.Errorf("outside a router: %q", got)
+	}
+	if _, err := URLFor(req, "user", "id", "1"); err == nil {
+		t.Error("URLFor outside a router succeeded")
+	}
+
+	rt := NewRastaRouterInitializer()
+	rt.SetBasePath("/myapp")
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range []string{"/static/app.js", "//cdn.example/app.js", "app.js", "https://cdn.example/app.js"} {
+			w.Header().Add("X-Path", ExternalPath(r, p))
+		}
+		w.Header().Set("X-Prefix", ExternalPrefix(r))
+	})
+	// The mount root is the router root, with or without its slash
+	if rec := mountedRequest(rt, "/myapp", untrustedPeer, ""); rec.Code != http.StatusOK {
+		t.Errorf("/myapp: status %d", rec.Code)
+	}
+	rec := mountedRequest(rt, "/myapp/", untrustedPeer, "")
+	want := []string{"/myapp/static/app.js", "//cdn.example/app.js", "app.js", "https://cdn.example/app.js"}
+	if got := rec.Header().Values("X-Path"); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("ExternalPath = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("X-Prefix"); got != "/myapp" {
+		t.Errorf("ExternalPrefix = %q", got)
+	}
+}
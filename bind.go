@@ -0,0 +1,382 @@
+package tobingo
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a single struct field that could not be bound
+type FieldError struct {
+	Field string // Go name of the struct field, empty for keys the struct doesn't declare
+	Key   string // Name the field is bound from, taken from its struct tag
+	Err   error  // Cause, wrapping the source's missing or invalid sentinel
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	// Unknown keys have no field to name
+	if e.Field == "" {
+		return e.Key + ": " + e.Err.Error()
+	}
+	return e.Field + " (" + e.Key + "): " + e.Err.Error()
+}
+
+// Unwrap returns the cause so errors.Is can find the sentinel errors
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError aggregates every field that failed while binding a request into a struct
+type BindError struct {
+	Source string        // What was being bound, e.g. "path parameters"
+	Fields []*FieldError // One entry per failing field in declaration order
+}
+
+// Error lists every failing field in a single message
+func (e *BindError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Error()
+	}
+	return "tobingo: binding " + e.Source + ": " + strings.Join(parts, "; ")
+}
+
+// Unwrap exposes the field errors so errors.Is and errors.As see through the aggregate
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// BindParams populates the struct pointed to by dst from path parameters using the `param` tag
+// Supported field types are string, every int and uint width, bool, float32/64, time.Time
+// (RFC3339, or another layout via `param:"day,layout=2006-01-02"`), and any type implementing
+// encoding.TextUnmarshaler; pointers to these make the parameter optional, all other
+// tagged fields are required
+// Every missing or unconvertible field is reported together in a *BindError whose entries
+// wrap ErrParamMissing or ErrParamInvalid
+// Example: var in struct{ Org string `param:"org"`; ID int64 `param:"id"` }; err := tobingo.BindParams(r, &in)
+func BindParams(r *http.Request, dst any) error {
+	return validateBound(r, dst, bindParams(r, dst))
+}
+
+// bindParams implements BindParams without running validation, so Bind can validate once at the end
+func bindParams(r *http.Request, dst any) error {
+	params := routeParams(r)
+	return paramBinder.bind(dst, func(key string) ([]string, bool) {
+		value, ok := params[key]
+		if !ok {
+			return nil, false
+		}
+		return []string{value}, true
+	})
+}
+
+// paramBinder binds path parameters; every non-pointer field is required
+var paramBinder = binder{
+	tag:               "param",
+	source:            "path parameters",
+	missing:           ErrParamMissing,
+	invalid:           ErrParamInvalid,
+	requiredByDefault: true,
+}
+
+// binder populates struct fields from string values found through a struct tag
+type binder struct {
+	tag               string // Struct tag naming the key of each field
+	source            string // Description used in BindError messages
+	missing           error  // Sentinel wrapped by errors for required fields without a value
+	invalid           error  // Sentinel wrapped by errors for values that fail to convert
+	requiredByDefault bool   // Whether non-pointer fields are required without a "required" tag option
+	splitCommas       bool   // Split each value on commas when filling slices
+	onOff             bool   // Accept "on" and "off" as booleans, which HTML checkboxes submit
+}
+
+// fieldTag is the parsed form of a binding struct tag such as `query:"page,required"`
+type fieldTag struct {
+	key      string // Name of the value in the source
+	required bool   // Missing values are an error
+	layout   string // time.Time layout, RFC3339 when empty
+	onOff    bool   // Accept "on" and "off" as booleans, set by the binder rather than the tag
+}
+
+// parseFieldTag splits a tag into its key and comma separated options
+func parseFieldTag(tag string) fieldTag {
+	key, rest, _ := strings.Cut(tag, ",")
+	ft := fieldTag{key: key}
+	for opt := range strings.SplitSeq(rest, ",") {
+		switch {
+		case opt == "required":
+			ft.required = true
+		case strings.HasPrefix(opt, "layout="):
+			ft.layout = strings.TrimPrefix(opt, "layout=")
+		}
+	}
+	return ft
+}
+
+// bind fills the tagged fields of the struct pointed to by dst from lookup, collecting
+// every field failure into a single *BindError
+func (b binder) bind(dst any, lookup func(key string) ([]string, bool)) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("tobingo: binding %s requires a non-nil pointer to a struct, got %T", b.source, dst)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var bindErr BindError
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(b.tag)
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		ft := parseFieldTag(tag)
+		if ft.key == "" {
+			ft.key = field.Name
+		}
+		ft.onOff = b.onOff
+
+		fail := func(err error) {
+			bindErr.Fields = append(bindErr.Fields, &FieldError{Field: field.Name, Key: ft.key, Err: err})
+		}
+
+		// A single empty value counts as absent, like an empty path segment
+		values, present := lookup(ft.key)
+		if present && (len(values) == 0 || (len(values) == 1 && values[0] == "")) {
+			present = false
+		}
+
+		// A `default:"..."` tag stands in for an absent value
+		if !present {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				values, present = []string{def}, true
+			}
+		}
+
+		if !present {
+			if ft.required || (b.requiredByDefault && field.Type.Kind() != reflect.Pointer) {
+				fail(b.missing)
+			}
+			continue
+		}
+
+		if b.splitCommas && fieldIsSlice(field.Type) {
+			values = splitValues(values)
+		}
+
+		if err := setField(rv.Field(i), values, ft); err != nil {
+			fail(fmt.Errorf("%w: %w", b.invalid, err))
+		}
+	}
+
+	if len(bindErr.Fields) > 0 {
+		bindErr.Source = b.source
+		return &bindErr
+	}
+	return nil
+}
+
+// keys returns the source keys declared by the tagged fields of the struct pointed to by dst
+func (b binder) keys(dst any) map[string]bool {
+	keys := make(map[string]bool)
+	rt := reflect.TypeOf(dst)
+	if rt == nil || rt.Kind() != reflect.Pointer || rt.Elem().Kind() != reflect.Struct {
+		return keys
+	}
+	rt = rt.Elem()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(b.tag)
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		ft := parseFieldTag(tag)
+		if ft.key == "" {
+			ft.key = field.Name
+		}
+		keys[ft.key] = true
+	}
+	return keys
+}
+
+// textUnmarshalerType is used to detect fields that parse themselves
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// timeType is handled before TextUnmarshaler so the layout tag option applies
+var timeType = reflect.TypeFor[time.Time]()
+
+// setField converts values into the field, allocating pointers and filling slices as needed
+func setField(fv reflect.Value, values []string, ft fieldTag) error {
+	// Optional fields are set through a freshly allocated value
+	if fv.Kind() == reflect.Pointer {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), values, ft); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	// Slices take every value, other kinds the first one; []byte stays a scalar string
+	if fieldIsSlice(fv.Type()) {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setScalar(slice.Index(i), value, ft); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setScalar(fv, values[0], ft)
+}
+
+// fieldIsSlice reports whether a field of type t collects several values
+func fieldIsSlice(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !isScalarType(t)
+}
+
+// splitValues splits every value on commas, so "a,b" and repeated keys fill a slice alike
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		split = append(split, strings.Split(value, ",")...)
+	}
+	return split
+}
+
+// isScalarType reports whether t converts from a single string by itself, e.g. via TextUnmarshaler
+func isScalarType(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setScalar converts a single string into fv
+func setScalar(fv reflect.Value, value string, ft fieldTag) error {
+	if fv.Type() == timeType {
+		layout := ft.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Bool:
+		b, ok := parseBool(value, paramConfig{onOff: ft.onOff})
+		if !ok {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		fv.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		// Only []byte reaches here, taking the raw value
+		fv.SetBytes([]byte(value))
+	default:
+		return errors.New("unsupported field type " + fv.Type().String())
+	}
+	return nil
+}
+
+// Bind fills one struct from the whole request: query parameters via `query` tags, the body
+// according to its Content-Type (JSON, urlencoded or multipart form fields via `form` tags),
+// and path parameters via `param` tags
+// Sources are applied in that order, so when a field is tagged for several of them the path
+// wins over the body, which wins over the query
+// Requests without a body skip body binding; a body of any other type fails with
+// ErrUnsupportedMediaType, which handlers typically map to 415
+// Errors from the sources are joined, so errors.Is and errors.As see each of them; once all
+// succeed the struct is validated once, see SetValidator
+// Example: var in struct{ ID int64 `param:"id"`; Name string `json:"name"`; Dry bool `query:"dry"` }; err := tobingo.Bind(r, &in)
+func Bind(r *http.Request, dst any, opts ...BindOption) error {
+	queryErr := bindQuery(r, dst, opts...)
+
+	bodyErr := bindBody(r, dst, opts)
+	if errors.Is(bodyErr, ErrUnsupportedMediaType) {
+		return bodyErr
+	}
+
+	paramErr := bindParams(r, dst)
+	return validateBound(r, dst, errors.Join(queryErr, bodyErr, paramErr))
+}
+
+// bindBody binds the body of r into dst according to its Content-Type
+func bindBody(r *http.Request, dst any, opts []BindOption) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+			return nil
+		}
+		return fmt.Errorf("%w: missing Content-Type", ErrUnsupportedMediaType)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+
+	switch {
+	case isJSONMediaType(mediaType):
+		return bindJSON(r, dst, opts...)
+	case mediaType == "application/x-www-form-urlencoded":
+		return bindForm(r, dst, opts...)
+	case mediaType == "multipart/form-data":
+		cfg := newBindConfig(r, opts)
+		limit := cfg.maxBodySize
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+		if err := parseMultipart(r, limit); err != nil {
+			return err
+		}
+		return formBinder.bind(dst, func(key string) ([]string, bool) {
+			values, ok := r.MultipartForm.Value[key]
+			return values, ok
+		})
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+}
@@ -0,0 +1,171 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindParamsKinds(t *testing.T) {
+	var in struct {
+		Org   string     `param:"org"`
+		I     int        `param:"i"`
+		I8    int8       `param:"i8"`
+		I16   int16      `param:"i16"`
+		I32   int32      `param:"i32"`
+		I64   int64      `param:"i64"`
+		U     uint       `param:"u"`
+		U8    uint8      `param:"u8"`
+		U64   uint64     `param:"u64"`
+		B     bool       `param:"b"`
+		F32   float32    `param:"f32"`
+		F64   float64    `param:"f64"`
+		At    time.Time  `param:"at"`
+		Day   time.Time  `param:"day,layout=2006-01-02"`
+		Addr  netip.Addr `param:"addr"`
+		Page  *int       `param:"page"`
+		Skip  string
+		other string
+	}
+	pattern := "/:org/:i/:i8/:i16/:i32/:i64/:u/:u8/:u64/:b/:f32/:f64/:at/:day/:addr"
+	path := "/acme/-1/-8/16/32/64/1/255/18446744073709551615/true/1.5/2.25/2024-06-01T10:00:00Z/2024-06-02/192.0.2.1"
+	withParams(t, pattern, path, func(r *http.Request) {
+		if err := BindParams(r, &in); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	at, _ := time.Parse(time.RFC3339, "2024-06-01T10:00:00Z")
+	if in.Org != "acme" || in.I != -1 || in.I8 != -8 || in.I16 != 16 || in.I32 != 32 || in.I64 != 64 ||
+		in.U != 1 || in.U8 != 255 || in.U64 != 1<<64-1 || !in.B || in.F32 != 1.5 || in.F64 != 2.25 {
+		t.Errorf("scalars bound as %+v", in)
+	}
+	if !in.At.Equal(at) || in.Day.Format(time.DateOnly) != "2024-06-02" {
+		t.Errorf("times bound as %v and %v", in.At, in.Day)
+	}
+	if in.Addr != netip.MustParseAddr("192.0.2.1") {
+		t.Errorf("TextUnmarshaler bound as %v", in.Addr)
+	}
+	if in.Page != nil {
+		t.Errorf("absent optional parameter bound as %d", *in.Page)
+	}
+}
+
+func TestBindParamsOptionalPointer(t *testing.T) {
+	var in struct {
+		Page *int `param:"page"`
+	}
+	withParams(t, "/list/:page", "/list/3", func(r *http.Request) {
+		if err := BindParams(r, &in); err != nil || in.Page == nil || *in.Page != 3 {
+			t.Errorf("BindParams = %v, page %v", err, in.Page)
+		}
+	})
+}
+
+func TestBindParamsAggregatesErrors(t *testing.T) {
+	var in struct {
+		ID    int64 `param:"id"`
+		Small uint8 `param:"small"`
+		Org   string
+		Team  string `param:"team"`
+	}
+	withParams(t, "/items/:id/:small", "/items/abc/300", func(r *http.Request) {
+		err := BindParams(r, &in)
+		var be *BindError
+		if !errors.As(err, &be) || len(be.Fields) != 3 {
+			t.Fatalf("BindParams = %v, want a *BindError with 3 fields", err)
+		}
+		if !errors.Is(err, ErrParamInvalid) || !errors.Is(err, ErrParamMissing) {
+			t.Error("aggregate does not match both sentinels")
+		}
+		for _, want := range []string{"binding path parameters", "ID (id)", "Small (small)", "Team (team)"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%q does not mention %q", err, want)
+			}
+		}
+	})
+}
+
+type updateUser struct {
+	ID     int64  `param:"id" json:"-"`
+	Name   string `json:"name" form:"name" query:"name"`
+	Dry    bool   `query:"dry"`
+	Shadow int64  `param:"id" json:"shadow" query:"shadow"`
+}
+
+// bindRoute binds every POST to /users/:id into a fresh updateUser
+func bindRoute(t *testing.T) (*Rastauter, *updateUser, *error) {
+	t.Helper()
+	in, bindErr := new(updateUser), new(error)
+	rt := NewRastaRouterInitializer()
+	rt.addRoute("POST", "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		*in = updateUser{}
+		*bindErr = Bind(r, in)
+	})
+	return rt, in, bindErr
+}
+
+func TestBindSources(t *testing.T) {
+	rt, in, bindErr := bindRoute(t)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "multi")
+	mw.Close()
+
+	for name, tc := range map[string]struct {
+		target, body, contentType string
+		want                      updateUser
+	}{
+		"path only":  {"/users/7", "", "", updateUser{ID: 7, Shadow: 7}},
+		"query only": {"/users/7?name=q&dry=true", "", "", updateUser{ID: 7, Name: "q", Dry: true, Shadow: 7}},
+		"json":       {"/users/7", `{"name":"j"}`, "application/json", updateUser{ID: 7, Name: "j", Shadow: 7}},
+		"form":       {"/users/7", "name=f", "application/x-www-form-urlencoded", updateUser{ID: 7, Name: "f", Shadow: 7}},
+		"multipart":  {"/users/7", body.String(), mw.FormDataContentType(), updateUser{ID: 7, Name: "multi", Shadow: 7}},
+	} {
+		var opts []TestOption
+		if tc.contentType != "" {
+			opts = append(opts, WithTestHeader("Content-Type", tc.contentType))
+		}
+		rt.Test("POST", tc.target, strings.NewReader(tc.body), opts...)
+		if *bindErr != nil || *in != tc.want {
+			t.Errorf("%s: bound %+v, %v; want %+v", name, *in, *bindErr, tc.want)
+		}
+	}
+}
+
+func TestBindPrecedence(t *testing.T) {
+	rt, in, bindErr := bindRoute(t)
+	rt.Test("POST", "/users/7?name=query&shadow=1&dry=1", strings.NewReader(`{"name":"body","shadow":2}`), WithTestHeader("Content-Type", "application/json"))
+	if *bindErr != nil {
+		t.Fatal(*bindErr)
+	}
+	// Path over body over query
+	if want := (updateUser{ID: 7, Name: "body", Dry: true, Shadow: 7}); *in != want {
+		t.Errorf("bound %+v, want %+v", *in, want)
+	}
+}
+
+func TestBindUnsupportedMediaType(t *testing.T) {
+	rt, _, bindErr := bindRoute(t)
+	rt.Test("POST", "/users/7", strings.NewReader("<user/>"), WithTestHeader("Content-Type", "application/xml"))
+	if !errors.Is(*bindErr, ErrUnsupportedMediaType) {
+		t.Errorf("xml err = %v", *bindErr)
+	}
+	rt.Test("POST", "/users/7", strings.NewReader("name=x"))
+	if !errors.Is(*bindErr, ErrUnsupportedMediaType) {
+		t.Errorf("untyped body err = %v", *bindErr)
+	}
+}
+
+func TestBindJoinsErrors(t *testing.T) {
+	rt, _, bindErr := bindRoute(t)
+	rt.Test("POST", "/users/x?dry=maybe", strings.NewReader(`{"name":5}`), WithTestHeader("Content-Type", "application/json"))
+	if !errors.Is(*bindErr, ErrQueryInvalid) || !errors.Is(*bindErr, ErrInvalidBody) || !errors.Is(*bindErr, ErrParamInvalid) {
+		t.Errorf("err = %v, want query, body, and param errors", *bindErr)
+	}
+}
@@ -0,0 +1,99 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// Sentinel errors wrapped by the FieldError entries of BindForm
+var (
+	ErrFieldMissing = errors.New("tobingo: missing form field")
+	ErrFieldInvalid = errors.New("tobingo: invalid form field")
+	ErrFieldUnknown = errors.New("tobingo: unknown form field")
+)
+
+// formBinder binds form values; fields are optional unless tagged "required"
+var formBinder = binder{
+	tag:     "form",
+	source:  "form fields",
+	missing: ErrFieldMissing,
+	invalid: ErrFieldInvalid,
+	onOff:   true,
+}
+
+// BindForm parses an x-www-form-urlencoded body, or the query string for methods without a
+// body, and populates the struct
+// pointed to by dst using the `form` tag, e.g. `form:"email,required"`
+// Field types are as for BindParams plus slices, which collect repeated keys; time.Time
+// takes a layout option such as `form:"born,layout=2006-01-02"`, and bools also take the
+// "on" a checked checkbox submits
+// The body is limited like BindJSON; unknown keys are ignored unless DisallowUnknownFields is passed
+// Every failing field is reported together in a *BindError whose entries wrap ErrFieldMissing,
+// ErrFieldInvalid, or ErrFieldUnknown
+func BindForm(r *http.Request, dst any, opts ...BindOption) error {
+	return validateBound(r, dst, bindForm(r, dst, opts...))
+}
+
+// bindForm implements BindForm without running validation, so Bind can validate once at the end
+func bindForm(r *http.Request, dst any, opts ...BindOption) error {
+	cfg := newBindConfig(r, opts)
+	if err := parseForm(r, cfg); err != nil {
+		return err
+	}
+
+	// Bodies carry the form for POST, PUT, and PATCH; other methods submit it in the query
+	form := r.PostForm
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		form = r.Form
+	}
+
+	err := formBinder.bind(dst, func(key string) ([]string, bool) {
+		values, ok := form[key]
+		return values, ok
+	})
+	if !cfg.disallowUnknown {
+		return err
+	}
+
+	// Report unknown keys alongside any binding failures, in a stable order
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		if err != nil {
+			return err
+		}
+		bindErr = &BindError{Source: formBinder.source}
+	}
+	known := formBinder.keys(dst)
+	for _, key := range slices.Sorted(maps.Keys(form)) {
+		if !known[key] {
+			bindErr.Fields = append(bindErr.Fields, &FieldError{Key: key, Err: ErrFieldUnknown})
+		}
+	}
+	if len(bindErr.Fields) == 0 {
+		return nil
+	}
+	return bindErr
+}
+
+// parseForm limits the body and parses the form, translating an oversized body into ErrBodyTooLarge
+func parseForm(r *http.Request, cfg bindConfig) error {
+	if r.Form != nil {
+		return nil
+	}
+	if cfg.maxBodySize > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+	}
+	if err := r.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+		}
+		return fmt.Errorf("%w: %w", ErrInvalidBody, err)
+	}
+	return nil
+}
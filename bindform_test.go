@@ -0,0 +1,143 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formRequest builds a request with the urlencoded body for methods that carry one
+func formRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// upperName decodes text into its upper case form
+type upperName string
+
+func (n *upperName) UnmarshalText(b []byte) error {
+	if strings.ContainsAny(string(b), "0123456789") {
+		return errors.New("names have no digits")
+	}
+	*n = upperName(strings.ToUpper(string(b)))
+	return nil
+}
+
+type signupForm struct {
+	Email string    `form:"email,required"`
+	Age   int       `form:"age"`
+	Terms bool      `form:"terms"`
+	Born  time.Time `form:"born,layout=2006-01-02"`
+	Tags  []string  `form:"tag"`
+	Score []float64 `form:"score"`
+	Nick  upperName `form:"nick"`
+}
+
+func TestBindForm(t *testing.T) {
+	var in signupForm
+	r := formRequest("POST", "/signup?email=ignored@example.com", "email=a%40example.com&age=30&terms=on&born=1990-05-01&tag=go&tag=web&score=1.5&score=2&nick=ann&extra=1")
+	if err := BindForm(r, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Email != "a@example.com" || in.Age != 30 || !in.Terms || in.Nick != "ANN" {
+		t.Errorf("bound %+v", in)
+	}
+	if !in.Born.Equal(time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Born = %v", in.Born)
+	}
+	if !slices.Equal(in.Tags, []string{"go", "web"}) || !slices.Equal(in.Score, []float64{1.5, 2}) {
+		t.Errorf("slices = %v %v", in.Tags, in.Score)
+	}
+}
+
+func TestBindFormCheckbox(t *testing.T) {
+	for body, want := range map[string]bool{"terms=on": true, "terms=off": false, "terms=true": true, "": false} {
+		var in struct {
+			Terms bool `form:"terms"`
+		}
+		if err := BindForm(formRequest("POST", "/signup", body), &in); err != nil || in.Terms != want {
+			t.Errorf("%q bound %v, %v; want %v", body, in.Terms, err, want)
+		}
+	}
+
+	// Path and query parameters keep rejecting the checkbox spelling
+	withParams(t, "/:flag", "/on", func(r *http.Request) {
+		if _, err := GetParamBool(r, "flag"); !errors.Is(err, ErrParamInvalid) {
+			t.Errorf("GetParamBool(on) = %v", err)
+		}
+	})
+}
+
+func TestBindFormQueryForGET(t *testing.T) {
+	var in signupForm
+	if err := BindForm(httptest.NewRequest("GET", "/search?email=x&tag=a&tag=b", nil), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Email != "x" || !slices.Equal(in.Tags, []string{"a", "b"}) {
+		t.Errorf("bound %+v", in)
+	}
+}
+
+func TestBindFormErrors(t *testing.T) {
+	var in signupForm
+	err := BindForm(formRequest("POST", "/signup", "age=old&terms=maybe&born=01/05/1990&score=1&score=x&nick=4nn"), &in)
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("err = %v, want a *BindError", err)
+	}
+	want := map[string]error{
+		"email": ErrFieldMissing,
+		"age":   ErrFieldInvalid,
+		"terms": ErrFieldInvalid,
+		"born":  ErrFieldInvalid,
+		"score": ErrFieldInvalid,
+		"nick":  ErrFieldInvalid,
+	}
+	if len(bindErr.Fields) != len(want) {
+		t.Errorf("got %d field errors, want %d: %v", len(bindErr.Fields), len(want), err)
+	}
+	for _, f := range bindErr.Fields {
+		if !errors.Is(f, want[f.Key]) {
+			t.Errorf("%s: %v, want %v", f.Key, f.Err, want[f.Key])
+		}
+	}
+}
+
+func TestBindFormStrict(t *testing.T) {
+	var in signupForm
+	err := BindForm(formRequest("POST", "/signup", "email=a&zeta=1&admin=true&age=x"), &in, DisallowUnknownFields())
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("err = %v, want a *BindError", err)
+	}
+	var keys []string
+	for _, f := range bindErr.Fields {
+		keys = append(keys, f.Key)
+	}
+	// Binding failures first, then unknown keys sorted
+	if !slices.Equal(keys, []string{"age", "admin", "zeta"}) {
+		t.Errorf("keys = %v", keys)
+	}
+	if !errors.Is(bindErr.Fields[1], ErrFieldUnknown) || !strings.Contains(err.Error(), "admin: "+ErrFieldUnknown.Error()) {
+		t.Errorf("unknown key error = %v", bindErr.Fields[1])
+	}
+
+	if err := BindForm(formRequest("POST", "/signup", "email=a&tag=x"), &in, DisallowUnknownFields()); err != nil {
+		t.Errorf("known keys rejected: %v", err)
+	}
+}
+
+func TestBindFormLimit(t *testing.T) {
+	var in signupForm
+	body := "email=" + strings.Repeat("a", 100)
+	if err := BindForm(formRequest("POST", "/signup", body), &in, WithMaxBodySize(50)); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("err = %v, want ErrBodyTooLarge", err)
+	}
+}
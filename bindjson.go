@@ -0,0 +1,213 @@
+package tobingo
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the request body limit of the body binding helpers unless the router
+// or the call sets another one
+const DefaultMaxBodySize = 1 << 20
+
+// Sentinel errors returned by the body binding helpers
+// Handlers can map them with errors.Is, e.g. ErrUnsupportedMediaType to 415,
+// ErrBodyTooLarge to 413, and the others to 400
+var (
+	ErrUnsupportedMediaType = errors.New("tobingo: unsupported content type")
+	ErrBodyTooLarge         = errors.New("tobingo: request body too large")
+	ErrEmptyBody            = errors.New("tobingo: empty request body")
+	ErrInvalidBody          = errors.New("tobingo: invalid request body")
+)
+
+// BodyError describes a malformed request body, matching ErrInvalidBody and its cause
+// with errors.Is and errors.As
+type BodyError struct {
+	Field  string // Dotted path of the offending field when known, e.g. "address.zip"
+	Offset int64  // Byte offset in the body where decoding failed
+	Err    error  // Underlying decoder error
+}
+
+// Error implements the error interface
+func (e *BodyError) Error() string {
+	msg := ErrInvalidBody.Error()
+	if e.Field != "" {
+		msg += ": field " + e.Field
+	}
+	return fmt.Sprintf("%s at offset %d: %v", msg, e.Offset, e.Err)
+}
+
+// Unwrap exposes both the sentinel and the decoder error
+func (e *BodyError) Unwrap() []error {
+	return []error{ErrInvalidBody, e.Err}
+}
+
+// BindOption adjusts how the body binding helpers read a request
+type BindOption func(*bindConfig)
+
+// bindConfig collects the settings of BindOption values
+type bindConfig struct {
+	maxBodySize         int64 // Body limit, the router default when zero
+	disallowUnknown     bool  // Reject fields or keys the destination doesn't declare
+	allowMissingType    bool  // Accept requests without a Content-Type header
+	maxBodySizeExplicit bool  // Whether WithMaxBodySize was given
+	splitCommas         bool  // Split query values on commas when filling slices
+}
+
+// newBindConfig resolves the options against the defaults of the router serving r
+func newBindConfig(r *http.Request, opts []BindOption) bindConfig {
+	var cfg bindConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !cfg.maxBodySizeExplicit {
+		cfg.maxBodySize = DefaultMaxBodySize
+		if state := stateFrom(r); state != nil && state.route != nil && state.route.maxBodySet {
+			// The route's own limit, where zero means none
+			cfg.maxBodySize = cmp.Or(state.route.maxBody, -1)
+		} else if rt := routerFrom(r); rt != nil {
+			if n := rt.maxBodySize.Load(); n != 0 {
+				cfg.maxBodySize = n
+			}
+		}
+	}
+	return cfg
+}
+
+// WithMaxBodySize limits the body to n bytes for this call; n <= 0 removes the limit
+func WithMaxBodySize(n int64) BindOption {
+	return func(cfg *bindConfig) {
+		cfg.maxBodySize = n
+		cfg.maxBodySizeExplicit = true
+	}
+}
+
+// DisallowUnknownFields rejects bodies with fields or keys that the destination doesn't declare
+func DisallowUnknownFields() BindOption {
+	return func(cfg *bindConfig) {
+		cfg.disallowUnknown = true
+	}
+}
+
+// AllowMissingContentType accepts requests that send no Content-Type header at all
+func AllowMissingContentType() BindOption {
+	return func(cfg *bindConfig) {
+		cfg.allowMissingType = true
+	}
+}
+
+// SplitCommaValues makes BindQuery fill slices from comma separated values such as
+// "?ids=1,2,3" in addition to repeated keys
+func SplitCommaValues() BindOption {
+	return func(cfg *bindConfig) {
+		cfg.splitCommas = true
+	}
+}
+
+// SetMaxBodySize sets the default body limit of the binding helpers for requests served
+// by this router; n <= 0 removes the limit
+func (rt *Rastauter) SetMaxBodySize(n int64) {
+	// Zero means "use DefaultMaxBodySize" internally, so store no limit as -1
+	if n <= 0 {
+		n = -1
+	}
+	rt.maxBodySize.Store(n)
+}
+
+// limitBody caps r.Body at the configured size so oversized bodies fail while reading
+func (cfg bindConfig) limitBody(r *http.Request) io.Reader {
+	if cfg.maxBodySize <= 0 {
+		return r.Body
+	}
+	return http.MaxBytesReader(nil, r.Body, cfg.maxBodySize)
+}
+
+// checkContentType verifies the request's media type with accept, honoring AllowMissingContentType
+func (cfg bindConfig) checkContentType(r *http.Request, accept func(mediaType string) bool) error {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		if cfg.allowMissingType {
+			return nil
+		}
+		return fmt.Errorf("%w: missing Content-Type", ErrUnsupportedMediaType)
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || !accept(mediaType) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, header)
+	}
+	return nil
+}
+
+// isJSONMediaType accepts application/json and structured syntax types such as application/problem+json
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// BindJSON decodes the JSON request body into dst
+// The body is limited to DefaultMaxBodySize unless SetMaxBodySize or WithMaxBodySize says
+// otherwise, the Content-Type must be JSON, and exactly one JSON value may be sent
+// Errors match ErrUnsupportedMediaType, ErrBodyTooLarge, ErrEmptyBody, or ErrInvalidBody;
+// the latter come as *BodyError naming the field and offset where decoding failed
+// Example: var in CreateUser; if err := tobingo.BindJSON(r, &in, tobingo.DisallowUnknownFields()); err != nil { ... }
+func BindJSON(r *http.Request, dst any, opts ...BindOption) error {
+	return validateBound(r, dst, bindJSON(r, dst, opts...))
+}
+
+// bindJSON implements BindJSON without running validation, so Bind can validate once at the end
+func bindJSON(r *http.Request, dst any, opts ...BindOption) error {
+	cfg := newBindConfig(r, opts)
+	if err := cfg.checkContentType(r, isJSONMediaType); err != nil {
+		return err
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return ErrEmptyBody
+	}
+
+	dec := json.NewDecoder(cfg.limitBody(r))
+	if cfg.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		return jsonBodyError(err, dec)
+	}
+
+	// Anything but whitespace after the first value is an error, like "{}{}" or "{} x"
+	if err := dec.Decode(&json.RawMessage{}); err != io.EOF {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+		}
+		return &BodyError{Offset: dec.InputOffset(), Err: errors.New("unexpected data after the JSON value")}
+	}
+	return nil
+}
+
+// jsonBodyError translates a decoder error into the binding errors
+func jsonBodyError(err error, dec *json.Decoder) error {
+	var (
+		maxErr    *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+	case err == io.EOF:
+		return ErrEmptyBody
+	case errors.As(err, &syntaxErr):
+		return &BodyError{Offset: syntaxErr.Offset, Err: err}
+	case errors.As(err, &typeErr):
+		return &BodyError{Field: typeErr.Field, Offset: typeErr.Offset, Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &BodyError{Field: field, Offset: dec.InputOffset(), Err: err}
+	}
+	return &BodyError{Offset: dec.InputOffset(), Err: err}
+}
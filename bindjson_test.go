@@ -0,0 +1,128 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// jsonRequest builds a POST with the given body and Content-Type, none when empty
+func jsonRequest(body, contentType string) *http.Request {
+	r := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	if body == "" {
+		r.Body = http.NoBody
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+type createUser struct {
+	Name    string `json:"name"`
+	Age     int    `json:"age"`
+	Address struct {
+		Zip string `json:"zip"`
+	} `json:"address"`
+}
+
+func TestBindJSON(t *testing.T) {
+	for _, ct := range []string{"application/json", "application/json; charset=utf-8", "application/merge-patch+json"} {
+		var in createUser
+		if err := BindJSON(jsonRequest(`{"name":"ann","age":30,"address":{"zip":"123"}} `+"\n", ct), &in); err != nil {
+			t.Errorf("%s: %v", ct, err)
+		}
+		if in.Name != "ann" || in.Age != 30 || in.Address.Zip != "123" {
+			t.Errorf("%s: bound %+v", ct, in)
+		}
+	}
+}
+
+func TestBindJSONErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body, contentType string
+		opts              []BindOption
+		want              error
+		field             string
+	}{
+		"unknown field":     {`{"name":"a","admin":true}`, "application/json", []BindOption{DisallowUnknownFields()}, ErrInvalidBody, "admin"},
+		"wrong type":        {`{"address":{"zip":5}}`, "application/json", nil, ErrInvalidBody, "address.zip"},
+		"syntax":            {`{"name" 1}`, "application/json", nil, ErrInvalidBody, ""},
+		"truncated":         {`{"name":`, "application/json", nil, ErrInvalidBody, ""},
+		"trailing value":    {`{}{}`, "application/json", nil, ErrInvalidBody, ""},
+		"trailing garbage":  {`{} x`, "application/json", nil, ErrInvalidBody, ""},
+		"wrong type header": {`{}`, "text/plain", nil, ErrUnsupportedMediaType, ""},
+		"missing header":    {`{}`, "", nil, ErrUnsupportedMediaType, ""},
+		"empty body":        {"", "application/json", nil, ErrEmptyBody, ""},
+		"whitespace body":   {"  \n", "application/json", nil, ErrEmptyBody, ""},
+		"oversized":         {`{"name":"` + strings.Repeat("a", 100) + `"}`, "application/json", []BindOption{WithMaxBodySize(50)}, ErrBodyTooLarge, ""},
+		"oversized trailer": {`{}` + strings.Repeat(" ", 60) + "x", "application/json", []BindOption{WithMaxBodySize(50)}, ErrBodyTooLarge, ""},
+	} {
+		var in createUser
+		err := BindJSON(jsonRequest(tc.body, tc.contentType), &in, tc.opts...)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+			continue
+		}
+		var bodyErr *BodyError
+		if errors.As(err, &bodyErr) != (tc.want == ErrInvalidBody) {
+			t.Errorf("%s: %v is not a *BodyError", name, err)
+		} else if bodyErr != nil && bodyErr.Field != tc.field {
+			t.Errorf("%s: field %q at %d, want %q", name, bodyErr.Field, bodyErr.Offset, tc.field)
+		}
+	}
+}
+
+func TestBindJSONAllowMissingContentType(t *testing.T) {
+	var in createUser
+	if err := BindJSON(jsonRequest(`{"name":"ann"}`, ""), &in, AllowMissingContentType()); err != nil || in.Name != "ann" {
+		t.Errorf("bound %+v, %v", in, err)
+	}
+	if err := BindJSON(jsonRequest(`{}`, "text/plain"), &in, AllowMissingContentType()); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("a wrong Content-Type was accepted: %v", err)
+	}
+}
+
+func TestBindJSONRouterLimit(t *testing.T) {
+	var routerErr, callErr error
+	rt := NewRastaRouterInitializer()
+	rt.SetMaxBodySize(10)
+	rt.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+		var in createUser
+		routerErr = BindJSON(r, &in)
+	})
+	rt.GET("/imports", func(w http.ResponseWriter, r *http.Request) {
+		var in createUser
+		callErr = BindJSON(r, &in, WithMaxBodySize(100))
+	})
+
+	body := `{"name":"ann"}`
+	rt.Test("GET", "/users", strings.NewReader(body), WithTestHeader("Content-Type", "application/json"))
+	if !errors.Is(routerErr, ErrBodyTooLarge) {
+		t.Errorf("router limit: %v", routerErr)
+	}
+	rt.Test("GET", "/imports", strings.NewReader(body), WithTestHeader("Content-Type", "application/json"))
+	if callErr != nil {
+		t.Errorf("the call's limit did not win: %v", callErr)
+	}
+
+	rt.SetMaxBodySize(0)
+	rt.Test("GET", "/users", strings.NewReader(body), WithTestHeader("Content-Type", "application/json"))
+	if routerErr != nil {
+		t.Errorf("no limit: %v", routerErr)
+	}
+}
+
+func TestBindJSONOffsets(t *testing.T) {
+	var in createUser
+	var bodyErr *BodyError
+	err := BindJSON(jsonRequest(`{"name":"ann","age":"old"}`, "application/json"), &in)
+	if !errors.As(err, &bodyErr) || bodyErr.Field != "age" || bodyErr.Offset != 25 {
+		t.Errorf("err = %v", err)
+	}
+	if !strings.Contains(err.Error(), "field age at offset 25") {
+		t.Errorf("message %q lacks the field and offset", err)
+	}
+}
@@ -0,0 +1,63 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BufferBody reads the request body, up to max bytes, and replaces r.Body with a reader over
+// the same bytes so later reads such as Bind or a proxy see the full body again
+// The bytes are cached for the request, so calling BufferBody again or RawBody returns them
+// without touching the network; r.Body is rewound on every call
+// Returns ErrBodyTooLarge when the body exceeds max; an absent body yields an empty slice
+// Example: raw, err := tobingo.BufferBody(r, 1<<20); verifySignature(raw); err = tobingo.BindJSON(r, &event)
+func BufferBody(r *http.Request, max int64) ([]byte, error) {
+	state := stateFrom(r)
+	if state != nil && state.bodyBuffered {
+		rewindBody(r, state.body)
+		return state.body, nil
+	}
+
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, max))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+			}
+			return nil, err
+		}
+		body = b
+	}
+	if body == nil {
+		body = []byte{}
+	}
+
+	if state != nil {
+		state.body, state.bodyBuffered = body, true
+	}
+	rewindBody(r, body)
+	return body, nil
+}
+
+// RawBody returns the bytes cached by BufferBody, and false if the body wasn't buffered
+func RawBody(r *http.Request) ([]byte, bool) {
+	state := stateFrom(r)
+	if state == nil || !state.bodyBuffered {
+		return nil, false
+	}
+	return state.body, true
+}
+
+// rewindBody points r.Body and r.GetBody at fresh readers over body
+func rewindBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+}
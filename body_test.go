@@ -0,0 +1,109 @@
+package tobingo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// sign returns the hex HMAC-SHA256 of body, as webhook senders compute it
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	io.WriteString(mac, body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestBufferBodyVerifyThenBind(t *testing.T) {
+	const payload = `{"event":"paid","amount":42}`
+	var event struct {
+		Event  string `json:"event"`
+		Amount int    `json:"amount"`
+	}
+	rt := NewRastaRouterInitializer()
+	rt.GET("/hook", func(w http.ResponseWriter, r *http.Request) {
+		raw, err := BufferBody(r, 1<<10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sign(string(raw)) != r.Header.Get("X-Signature") {
+			t.Error("signature mismatch")
+		}
+		if err := BindJSON(r, &event); err != nil {
+			t.Error(err)
+		}
+		// The body can be replayed again, e.g. to a proxy
+		if body, _ := r.GetBody(); body != nil {
+			if b, _ := io.ReadAll(body); string(b) != payload {
+				t.Errorf("GetBody = %q", b)
+			}
+		}
+		if r.ContentLength != int64(len(payload)) {
+			t.Errorf("ContentLength = %d", r.ContentLength)
+		}
+	})
+
+	rt.Test("GET", "/hook", strings.NewReader(payload),
+		WithTestHeader("Content-Type", "application/json"), WithTestHeader("X-Signature", sign(payload)))
+	if event.Event != "paid" || event.Amount != 42 {
+		t.Errorf("bound %+v", event)
+	}
+}
+
+func TestBufferBodyTwice(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/hook", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := RawBody(r); ok {
+			t.Error("RawBody before buffering reported a body")
+		}
+		first, _ := BufferBody(r, 1<<10)
+		io.ReadAll(r.Body)
+
+		// Served from the cache even though the request body was swapped out
+		r.Body = io.NopCloser(strings.NewReader("other"))
+		second, err := BufferBody(r, 1<<10)
+		if err != nil || string(second) != "data" || &first[0] != &second[0] {
+			t.Errorf("second call = %q, %v", second, err)
+		}
+		if b, _ := io.ReadAll(r.Body); string(b) != "data" {
+			t.Errorf("r.Body not rewound: %q", b)
+		}
+		if raw, ok := RawBody(r); !ok || string(raw) != "data" {
+			t.Errorf("RawBody = %q, %v", raw, ok)
+		}
+	})
+	rt.Test("GET", "/hook", strings.NewReader("data"))
+}
+
+func TestBufferBodyTooLarge(t *testing.T) {
+	var bufErr error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/hook", func(w http.ResponseWriter, r *http.Request) {
+		_, bufErr = BufferBody(r, 4)
+		if _, ok := RawBody(r); ok {
+			t.Error("a failed buffering was cached")
+		}
+	})
+	rt.Test("GET", "/hook", strings.NewReader("too long"))
+	if !errors.Is(bufErr, ErrBodyTooLarge) {
+		t.Errorf("err = %v, want ErrBodyTooLarge", bufErr)
+	}
+}
+
+func TestBufferBodyEmpty(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/hook", func(w http.ResponseWriter, r *http.Request) {
+		raw, err := BufferBody(r, 4)
+		if err != nil || raw == nil || len(raw) != 0 {
+			t.Errorf("BufferBody = %q, %v; want an empty slice", raw, err)
+		}
+		if raw, ok := RawBody(r); !ok || len(raw) != 0 {
+			t.Errorf("RawBody = %q, %v", raw, ok)
+		}
+	})
+	rt.Test("GET", "/hook", nil)
+}
@@ -0,0 +1,103 @@
+package tobingo
+
+import (
+	"bufio"
+	"cmp"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// BodylessWriter wraps a ResponseWriter for responses that must not have a body, such as the
+// answer to a HEAD request, discarding body bytes while counting them
+// The status is held back until Finish or Flush, so when the handler wrote a body without
+// setting Content-Length, Finish can report the length a GET would have returned
+// The router installs one for every HEAD request; use it directly with other handlers
+// Example: bw := tobingo.NewBodylessWriter(w); getHandler.ServeHTTP(bw, r); bw.Finish()
+type BodylessWriter struct {
+	http.ResponseWriter
+	status  int   // Status given to WriteHeader, or the implicit 200 of Write
+	size    int64 // Body bytes discarded
+	flushed bool  // Whether Flush sent the status before the body was complete
+	sent    bool  // Whether the status was forwarded
+}
+
+// NewBodylessWriter returns a BodylessWriter discarding the body written to w
+func NewBodylessWriter(w http.ResponseWriter) *BodylessWriter {
+	return &BodylessWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the status, forwarding informational 1xx statuses straight away
+func (w *BodylessWriter) WriteHeader(code int) {
+	if code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// Write discards b, counts it, and reports success so handlers carry on as for a GET
+// Like net/http, the first write sniffs a Content-Type when the handler set none
+func (w *BodylessWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if h := w.ResponseWriter.Header(); !w.sent && w.size == 0 && len(b) > 0 {
+		if _, ok := h["Content-Type"]; !ok && h.Get("Content-Encoding") == "" {
+			h.Set("Content-Type", http.DetectContentType(b))
+		}
+	}
+	w.size += int64(len(b))
+	return len(b), nil
+}
+
+// Discarded returns the number of body bytes discarded so far
+func (w *BodylessWriter) Discarded() int64 {
+	return w.size
+}
+
+// Flush sends the status without a Content-Length, since the body isn't complete yet
+func (w *BodylessWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError flushes like Flush and reports http.ErrNotSupported when the underlying
+// writer can't flush
+func (w *BodylessWriter) FlushError() error {
+	if !w.sent {
+		w.flushed = true
+		w.send()
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over to the caller, keeping http.Hijacker available
+func (w *BodylessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Finish forwards the status, with a Content-Length of the discarded body when the handler
+// set none and nothing was flushed; call it once the handler has returned
+func (w *BodylessWriter) Finish() {
+	if !w.sent {
+		w.send()
+	}
+}
+
+// send forwards the held back status
+func (w *BodylessWriter) send() {
+	w.sent = true
+	status := cmp.Or(w.status, http.StatusOK)
+	h := w.ResponseWriter.Header()
+	if !w.flushed && w.size > 0 && bodyAllowed(status) && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.FormatInt(w.size, 10))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (w *BodylessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
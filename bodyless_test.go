@@ -0,0 +1,167 @@
+package tobingo
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHeadAgainstGetHandler(t *testing.T) {
+	var written int64
+	rt := NewRastaRouterInitializer()
+	rt.GET("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Page", "1")
+		io.WriteString(w, "<html>hello</html>")
+		written = Recorder(r).BytesWritten()
+	})
+	rt.GET("/sized", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "hello")
+	})
+	rt.GET("/own", writeRoute("get body"))
+	rt.addRoute("HEAD", "/own", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Head", "own")
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	res, err := http.Head(srv.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.ContentLength != 18 || res.Header.Get("X-Page") != "1" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("HEAD /page: %d, Content-Length %d, headers %v", res.StatusCode, res.ContentLength, res.Header)
+	}
+	if written != 18 {
+		t.Errorf("the handler's recorder counted %d bytes, want 18", written)
+	}
+
+	res, err = http.Head(srv.URL + "/sized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || res.ContentLength != 5 {
+		t.Errorf("HEAD /sized: %d, Content-Length %d", res.StatusCode, res.ContentLength)
+	}
+
+	// A HEAD route of its own wins over the GET route
+	rec := rt.Test("HEAD", "/own", nil)
+	if rec.Header("X-Head") != "own" || rec.BodyString() != "" {
+		t.Errorf("HEAD /own: headers %v, body %q", rec.Recorder.Header(), rec.BodyString())
+	}
+	if res := rt.Test("HEAD", "/page", nil); res.BodyString() != "" || res.Header("Content-Length") != "18" {
+		t.Errorf("recorded HEAD /page: body %q, Content-Length %q", res.BodyString(), res.Header("Content-Length"))
+	}
+	// Other methods don't fall back to GET
+	if res := rt.Test("POST", "/page", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("POST /page = %d", res.StatusCode())
+	}
+}
+
+func TestHeadFlushKeepsStreaming(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "part one")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush = %v", err)
+		}
+		io.WriteString(w, "part two")
+	})
+
+	res := rt.Test("HEAD", "/stream", nil)
+	if !res.Recorder.Flushed || res.BodyString() != "" || res.Header("Content-Length") != "" || res.StatusCode() != http.StatusOK {
+		t.Errorf("flushed %v, body %q, Content-Length %q, status %d", res.Recorder.Flushed, res.BodyString(), res.Header("Content-Length"), res.StatusCode())
+	}
+}
+
+func TestBodyAfterBodylessStatus(t *testing.T) {
+	logs := newRecordHandler()
+	var results [][2]any
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(logs))
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		rt.addRoute("GET", "/"+strconv.Itoa(status), func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(status)
+			n, err := io.WriteString(w, "oops")
+			results = append(results, [2]any{n, err})
+			io.WriteString(w, "again")
+		})
+	}
+
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		results = nil
+		before := len(logs.all())
+		res := rt.Test("GET", "/"+strconv.Itoa(status), nil)
+		if res.StatusCode() != status || res.BodyString() != "" || res.Header("ETag") != `"v1"` {
+			t.Errorf("%d: status %d, body %q, ETag %q", status, res.StatusCode(), res.BodyString(), res.Header("ETag"))
+		}
+		if len(results) != 1 || results[0] != [2]any{4, nil} {
+			t.Errorf("%d: Write returned %v, want 4 bytes and no error", status, results)
+		}
+
+		var warnings []logRecord
+		for _, rec := range logs.all()[before:] {
+			if rec.level == slog.LevelWarn {
+				warnings = append(warnings, rec)
+			}
+		}
+		if len(warnings) != 1 || warnings[0].attrs["status"] != strconv.Itoa(status) {
+			t.Errorf("%d: warnings %+v, want one", status, warnings)
+		}
+	}
+
+	// A GET handler answering HEAD is expected, so discarding its body isn't warned about
+	rt.GET("/page", writeRoute("body"))
+	before := len(logs.all())
+	rt.Test("HEAD", "/page", nil)
+	if got := logs.all()[before:]; len(got) != 0 {
+		t.Errorf("HEAD logged %+v", got)
+	}
+}
+
+func TestBodylessWriter(t *testing.T) {
+	// Informational statuses go straight through
+	rec := httptest.NewRecorder()
+	NewBodylessWriter(rec).WriteHeader(http.StatusEarlyHints)
+	if rec.Code != http.StatusEarlyHints {
+		t.Errorf("1xx held back: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	bw := NewBodylessWriter(rec)
+	bw.Header().Set("X-Kept", "yes")
+	bw.WriteHeader(http.StatusCreated)
+	bw.WriteHeader(http.StatusTeapot) // Later statuses are ignored like net/http
+	bw.Write([]byte("12345"))
+	bw.Write([]byte("678"))
+	if bw.Discarded() != 8 || rec.Body.Len() != 0 {
+		t.Errorf("discarded %d, passed %q", bw.Discarded(), rec.Body.String())
+	}
+	bw.Finish()
+	bw.Finish()
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusCreated || res.Header.Get("Content-Length") != "8" || res.Header.Get("X-Kept") != "yes" {
+		t.Errorf("after Finish: %d, headers %v", res.StatusCode, res.Header)
+	}
+
+	// Nothing written gives a 200 without a Content-Length
+	rec = httptest.NewRecorder()
+	NewBodylessWriter(rec).Finish()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("empty: %d, Content-Length %q", rec.Code, rec.Header().Get("Content-Length"))
+	}
+
+	// A writer that can't flush reports it
+	if err := NewBodylessWriter(&plainWriter{httptest.NewRecorder()}).FlushError(); err == nil {
+		t.Error("FlushError on a writer without Flush returned nil")
+	}
+}
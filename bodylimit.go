@@ -0,0 +1,53 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBodySize limits the request body of this route to n bytes, overriding the router-wide
+// SetMaxBodySize for the binding helpers as well; zero means no limit
+// Reads past the limit fail with *http.MaxBytesError, and a handler that returns without
+// answering after hitting it, e.g. because it only returned the error of io.ReadAll, gets
+// a 413 through the error handler
+// Example: rt.GET("/search", search).MaxBodySize(64 << 10)
+func (route *Route) MaxBodySize(n int64) *Route {
+	route.maxBody = max(n, 0)
+	route.maxBodySet = true
+	return route
+}
+
+// limitedBody wraps the body of a route with MaxBodySize, remembering whether the limit was hit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read reads from the limited body and records a MaxBytesError
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// serveLimited runs h with the request body capped at n bytes, answering 413 if h hit the
+// limit and returned without sending a status
+func serveLimited(w http.ResponseWriter, r *http.Request, n int64, h http.HandlerFunc) {
+	if r.Body == nil || r.Body == http.NoBody {
+		h(w, r)
+		return
+	}
+
+	body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, n)}
+	r.Body = body
+	h(w, r)
+
+	if rw := trackedWriter(w); body.exceeded && rw != nil && rw.status == 0 {
+		handleError(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, n))
+	}
+}
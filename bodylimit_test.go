@@ -0,0 +1,97 @@
+package tobingo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySizeOverridesRouter(t *testing.T) {
+	errs := map[string]error{}
+	bind := func(w http.ResponseWriter, r *http.Request) {
+		var in createUser
+		if errs[r.URL.Path] = BindJSON(r, &in); errs[r.URL.Path] == nil {
+			w.Write([]byte(in.Name))
+		}
+	}
+	rt := NewRastaRouterInitializer()
+	rt.SetMaxBodySize(10)
+	rt.GET("/default", bind)
+	rt.GET("/upload", bind).MaxBodySize(100)
+	rt.GET("/tiny", bind).MaxBodySize(5)
+	rt.GET("/unlimited", bind).MaxBodySize(0)
+
+	body := `{"name":"` + strings.Repeat("a", 20) + `"}`
+	for path, ok := range map[string]bool{"/default": false, "/upload": true, "/tiny": false, "/unlimited": true} {
+		res := rt.Test("GET", path, strings.NewReader(body), WithTestHeader("Content-Type", "application/json"))
+		if ok && (errs[path] != nil || res.BodyString() != strings.Repeat("a", 20)) {
+			t.Errorf("%s: %v, body %q", path, errs[path], res.BodyString())
+		}
+		if !ok && !errors.Is(errs[path], ErrBodyTooLarge) {
+			t.Errorf("%s: %v, want ErrBodyTooLarge", path, errs[path])
+		}
+	}
+
+	// A body under the route's limit still binds
+	if res := rt.Test("GET", "/tiny", strings.NewReader(`{}`), WithTestHeader("Content-Type", "application/json")); errs["/tiny"] != nil || res.StatusCode() != http.StatusOK {
+		t.Errorf("/tiny with a small body: %d, %v", res.StatusCode(), errs["/tiny"])
+	}
+}
+
+func TestMaxBodySizeReadAll(t *testing.T) {
+	var readErr error
+	var statuses []int
+	rt := NewRastaRouterInitializer()
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		statuses = append(statuses, status)
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("error handler got %v, want ErrBodyTooLarge", err)
+		}
+		DefaultErrorHandler(w, r, status, err)
+	})
+	rt.addRoute("POST", "/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}).MaxBodySize(16)
+	rt.addRoute("POST", "/answered", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, "too big, try again", http.StatusBadRequest)
+		}
+	}).MaxBodySize(16)
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	// A reader of unknown length makes the client send the body chunked, so the limit is only
+	// found while reading
+	post := func(path string, size int) *http.Response {
+		t.Helper()
+		res, err := http.Post(srv.URL+path, "application/octet-stream", io.MultiReader(strings.NewReader(strings.Repeat("x", size))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		io.ReadAll(res.Body)
+		return res
+	}
+
+	if res := post("/upload", 1000); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized chunked body = %d, want 413", res.StatusCode)
+	}
+	var maxErr *http.MaxBytesError
+	if !errors.As(readErr, &maxErr) || maxErr.Limit != 16 {
+		t.Errorf("handler read error %v, want a *http.MaxBytesError of 16", readErr)
+	}
+	if res := post("/upload", 16); res.StatusCode != http.StatusOK || readErr != nil {
+		t.Errorf("body at the limit = %d, %v", res.StatusCode, readErr)
+	}
+
+	// A handler that answered itself keeps its response
+	if res := post("/answered", 1000); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("handler's own answer = %d, want 400", res.StatusCode)
+	}
+	if len(statuses) != 1 || statuses[0] != http.StatusRequestEntityTooLarge {
+		t.Errorf("error handler statuses %v, want one 413", statuses)
+	}
+}
@@ -0,0 +1,266 @@
+package tobingo
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+// Circuit breaker states
+const (
+	CircuitClosed   CircuitState = iota // Requests pass through while failures are counted
+	CircuitOpen                         // Requests are answered with 503 until the cool-down ends
+	CircuitHalfOpen                     // A limited number of probes decide whether to close again
+)
+
+// String returns the state's name, as used in logs and metrics labels
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "CircuitState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// CircuitBreakerOptions configures CircuitBreaker
+// Zero values fall back to the defaults noted on each field
+type CircuitBreakerOptions struct {
+	FailureThreshold int                         // Consecutive failures that open the circuit, 5 by default
+	FailureRatio     float64                     // Failure ratio over Window that opens it too, off when 0
+	MinRequests      int                         // Requests in Window before FailureRatio applies, 10 by default
+	Window           time.Duration               // Rolling window of the ratio, 10 seconds by default
+	CoolDown         time.Duration               // Time the circuit stays open, 30 seconds by default
+	HalfOpenProbes   int                         // Successful probes needed to close again, 1 by default
+	IsFailure        func(status int) bool       // Classifies responses, 5xx by default
+	OnStateChange    func(from, to CircuitState) // Called on every transition, for metrics
+	Clock            func() time.Time            // Time source, time.Now by default
+}
+
+// circuitBuckets is the number of slices the rolling window is divided into
+const circuitBuckets = 10
+
+// circuitBucket counts the outcomes of one slice of the rolling window
+type circuitBucket struct {
+	start     time.Time // Start of the slice the counts belong to
+	successes int
+	failures  int
+}
+
+// circuitBreaker is the shared state behind one CircuitBreaker middleware
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+	span time.Duration // Length of one bucket
+
+	mu          sync.Mutex
+	state       CircuitState
+	openedAt    time.Time // When the circuit last opened
+	consecutive int       // Failures in a row while closed
+	buckets     [circuitBuckets]circuitBucket
+	probes      int // Probes in flight while half-open
+	probed      int // Successful probes while half-open
+}
+
+// CircuitBreaker returns middleware that stops calling a failing handler for a while
+// Failures are responses IsFailure accepts, 5xx by default, which includes errors returned by
+// error-returning handlers and panics; FailureThreshold of them in a row, or a FailureRatio
+// over the rolling Window, open the circuit and requests are answered with 503 and
+// Retry-After through the error handler until CoolDown passes
+// Then up to HalfOpenProbes requests at a time probe the handler: as many successes close the
+// circuit, while any failure opens it for another cool-down
+// Each call returns an independent breaker; use it on routes or groups with Route.Use and
+// Group.Use so one failing backend doesn't trip the whole router
+// Example: api.Use(tobingo.CircuitBreaker(tobingo.CircuitBreakerOptions{FailureRatio: 0.5}))
+func CircuitBreaker(opts ...CircuitBreakerOptions) Middleware {
+	cb := newCircuitBreaker(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			probe, ok := cb.allow(w, r)
+			if !ok {
+				return
+			}
+
+			// A panic counts as a failure before it travels on to the recovery handler
+			completed := false
+			defer func() {
+				if !completed {
+					cb.record(probe, false)
+				}
+			}()
+			next.ServeHTTP(w, r)
+			completed = true
+
+			cb.record(probe, !cb.opts.IsFailure(responseStatus(w, r)))
+		})
+	}
+}
+
+// newCircuitBreaker applies the defaults to the first options
+func newCircuitBreaker(opts ...CircuitBreakerOptions) *circuitBreaker {
+	var o CircuitBreakerOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 5
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 10
+	}
+	if o.Window <= 0 {
+		o.Window = 10 * time.Second
+	}
+	if o.CoolDown <= 0 {
+		o.CoolDown = 30 * time.Second
+	}
+	if o.HalfOpenProbes <= 0 {
+		o.HalfOpenProbes = 1
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = func(status int) bool { return status >= http.StatusInternalServerError }
+	}
+	if o.Clock == nil {
+		o.Clock = time.Now
+	}
+	return &circuitBreaker{opts: o, span: max(o.Window/circuitBuckets, 1)}
+}
+
+// responseStatus returns the status sent for r, 200 when the handler wrote nothing
+func responseStatus(w http.ResponseWriter, r *http.Request) int {
+	var status int
+	if rw := trackedWriter(w); rw != nil {
+		status = rw.Status()
+	} else if rec := Recorder(r); rec != nil {
+		status = rec.Status()
+	}
+	if status == 0 {
+		return http.StatusOK
+	}
+	return status
+}
+
+// allow reports whether r may reach the handler and whether it does so as a half-open probe;
+// otherwise the 503 response was written
+func (cb *circuitBreaker) allow(w http.ResponseWriter, r *http.Request) (probe, ok bool) {
+	now := cb.opts.Clock()
+
+	cb.mu.Lock()
+	from := cb.state
+	if cb.state == CircuitOpen && !now.Before(cb.openedAt.Add(cb.opts.CoolDown)) {
+		cb.state, cb.probes, cb.probed = CircuitHalfOpen, 0, 0
+	}
+	to := cb.state
+
+	var wait time.Duration
+	switch cb.state {
+	case CircuitClosed:
+		ok = true
+	case CircuitHalfOpen:
+		if cb.probes < cb.opts.HalfOpenProbes {
+			cb.probes++
+			probe, ok = true, true
+		}
+	case CircuitOpen:
+		wait = cb.openedAt.Add(cb.opts.CoolDown).Sub(now)
+	}
+	cb.mu.Unlock()
+
+	cb.changed(from, to)
+	if ok {
+		return probe, true
+	}
+
+	// Probes are short, so a client turned away while half-open may try again soon
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+	handleError(w, r, http.StatusServiceUnavailable, &HTTPError{Status: http.StatusServiceUnavailable})
+	return false, false
+}
+
+// record counts the outcome of a request allow let through, moving the circuit as needed
+func (cb *circuitBreaker) record(probe, success bool) {
+	now := cb.opts.Clock()
+
+	cb.mu.Lock()
+	from := cb.state
+	switch {
+	case probe:
+		// Only probes admitted in this half-open period decide the outcome
+		if cb.state != CircuitHalfOpen {
+			break
+		}
+		cb.probes--
+		if !success {
+			cb.open(now)
+		} else if cb.probed++; cb.probed >= cb.opts.HalfOpenProbes {
+			cb.state = CircuitClosed
+			cb.consecutive = 0
+			cb.buckets = [circuitBuckets]circuitBucket{}
+		}
+	case cb.state == CircuitClosed:
+		b := cb.bucket(now)
+		if success {
+			b.successes++
+			cb.consecutive = 0
+			break
+		}
+		b.failures++
+		cb.consecutive++
+		if cb.consecutive >= cb.opts.FailureThreshold || cb.ratioExceeded(now) {
+			cb.open(now)
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.changed(from, to)
+}
+
+// open moves the circuit to the open state; the caller must hold cb.mu
+func (cb *circuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+	cb.probes, cb.probed = 0, 0
+}
+
+// bucket returns the bucket for now, recycling it if it last held an older slice; the
+// caller must hold cb.mu
+func (cb *circuitBreaker) bucket(now time.Time) *circuitBucket {
+	start := now.Truncate(cb.span)
+	b := &cb.buckets[(start.UnixNano()/int64(cb.span))%circuitBuckets]
+	if !b.start.Equal(start) {
+		*b = circuitBucket{start: start}
+	}
+	return b
+}
+
+// ratioExceeded reports whether the failures within the window reach FailureRatio; the
+// caller must hold cb.mu
+func (cb *circuitBreaker) ratioExceeded(now time.Time) bool {
+	if cb.opts.FailureRatio <= 0 {
+		return false
+	}
+	var total, failures int
+	oldest := now.Truncate(cb.span).Add(-cb.opts.Window + cb.span)
+	for _, b := range cb.buckets {
+		if !b.start.Before(oldest) && !b.start.After(now) {
+			total += b.successes + b.failures
+			failures += b.failures
+		}
+	}
+	return total >= cb.opts.MinRequests && float64(failures) >= cb.opts.FailureRatio*float64(total)
+}
+
+// changed reports a transition to OnStateChange, outside the lock so the callback may be slow
+func (cb *circuitBreaker) changed(from, to CircuitState) {
+	if from != to && cb.opts.OnStateChange != nil {
+		cb.opts.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,242 @@
+package tobingo
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// breakerRouter serves /flaky through a breaker on a fake clock, failing while *fail is set
+func breakerRouter(opts CircuitBreakerOptions, fail *bool, now *time.Time) (*Rastauter, *[]string) {
+	var transitions []string
+	opts.Clock = func() time.Time { return *now }
+	opts.OnStateChange = func(from, to CircuitState) {
+		transitions = append(transitions, from.String()+">"+to.String())
+	}
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/flaky", H(func(r *http.Request) (int, any, error) {
+		if *fail {
+			return 0, nil, errors.New("backend down")
+		}
+		return http.StatusOK, "ok", nil
+	})).Use(CircuitBreaker(opts))
+	return rt, &transitions
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	fail, now := true, time.Unix(1000, 0)
+	rt, transitions := breakerRouter(CircuitBreakerOptions{FailureThreshold: 3, CoolDown: 10 * time.Second}, &fail, &now)
+
+	for i := range 3 {
+		if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusInternalServerError {
+			t.Fatalf("failure %d reached the handler as %d", i, res.StatusCode())
+		}
+	}
+
+	// Open: the handler isn't called, even once it recovers
+	fail = false
+	now = now.Add(4 * time.Second)
+	res := rt.Test("GET", "/flaky", nil)
+	if res.StatusCode() != http.StatusServiceUnavailable || res.Header("Retry-After") != "6" {
+		t.Fatalf("open: %d, Retry-After %q", res.StatusCode(), res.Header("Retry-After"))
+	}
+
+	// Half-open after the cool-down, and a successful probe closes it
+	now = now.Add(6 * time.Second)
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusOK {
+		t.Fatalf("probe: %d", res.StatusCode())
+	}
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusOK {
+		t.Fatalf("closed again: %d", res.StatusCode())
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>closed"}
+	if len(*transitions) != len(want) || (*transitions)[0] != want[0] || (*transitions)[1] != want[1] || (*transitions)[2] != want[2] {
+		t.Errorf("transitions %v, want %v", *transitions, want)
+	}
+
+	// Closing forgot the earlier failures
+	fail = true
+	for range 2 {
+		rt.Test("GET", "/flaky", nil)
+	}
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("third failure after closing: %d", res.StatusCode())
+	}
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("after three more failures: %d", res.StatusCode())
+	}
+}
+
+func TestCircuitBreakerProbeFailureReopens(t *testing.T) {
+	fail, now := true, time.Unix(1000, 0)
+	rt, transitions := breakerRouter(CircuitBreakerOptions{FailureThreshold: 1, CoolDown: 10 * time.Second, HalfOpenProbes: 2}, &fail, &now)
+
+	rt.Test("GET", "/flaky", nil)
+	now = now.Add(10 * time.Second)
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Fatalf("probe: %d", res.StatusCode())
+	}
+
+	// The failed probe started a new cool-down
+	now = now.Add(9 * time.Second)
+	fail = false
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusServiceUnavailable || res.Header("Retry-After") != "1" {
+		t.Fatalf("reopened: %d, Retry-After %q", res.StatusCode(), res.Header("Retry-After"))
+	}
+
+	// Two successful probes are needed to close
+	now = now.Add(time.Second)
+	for i := range 2 {
+		if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusOK {
+			t.Fatalf("probe %d: %d", i, res.StatusCode())
+		}
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if len(*transitions) != len(want) {
+		t.Fatalf("transitions %v, want %v", *transitions, want)
+	}
+	for i := range want {
+		if (*transitions)[i] != want[i] {
+			t.Errorf("transition %d = %s, want %s", i, (*transitions)[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerLimitsProbes(t *testing.T) {
+	now := time.Unix(1000, 0)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	fail := true
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		started <- struct{}{}
+		<-release
+	}).Use(CircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1, Clock: func() time.Time { return now }}))
+
+	rt.Test("GET", "/slow", nil)
+	fail = false
+	now = now.Add(30 * time.Second)
+
+	// One probe is in flight, so another request is turned away
+	done := make(chan int)
+	go func() { done <- rt.Test("GET", "/slow", nil).StatusCode() }()
+	<-started
+	if res := rt.Test("GET", "/slow", nil); res.StatusCode() != http.StatusServiceUnavailable || res.Header("Retry-After") != "1" {
+		t.Errorf("second request while probing: %d, Retry-After %q", res.StatusCode(), res.Header("Retry-After"))
+	}
+	close(release)
+	if status := <-done; status != http.StatusOK {
+		t.Errorf("probe = %d", status)
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	fail, now := false, time.Unix(1000, 0)
+	rt, _ := breakerRouter(CircuitBreakerOptions{FailureThreshold: 100, FailureRatio: 0.5, MinRequests: 4, Window: 10 * time.Second}, &fail, &now)
+
+	// Alternating outcomes never reach the consecutive threshold, but reach the ratio
+	for i := range 3 {
+		fail = i%2 == 1
+		rt.Test("GET", "/flaky", nil)
+		now = now.Add(time.Second)
+	}
+	fail = false
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusOK {
+		t.Fatalf("below MinRequests: %d", res.StatusCode())
+	}
+	fail = true
+	for i, want := range []int{500, 500, 503} {
+		// 2 of 5 failed stays closed, the third failure makes half and opens it
+		if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != want {
+			t.Errorf("request %d after MinRequests: %d, want %d", i, res.StatusCode(), want)
+		}
+	}
+
+	// Failures that left the window don't count
+	fail, now = true, time.Unix(5000, 0)
+	rt, _ = breakerRouter(CircuitBreakerOptions{FailureThreshold: 100, FailureRatio: 0.5, MinRequests: 4, Window: 10 * time.Second}, &fail, &now)
+	for range 3 {
+		rt.Test("GET", "/flaky", nil)
+	}
+	now = now.Add(20 * time.Second)
+	fail = false
+	for range 3 {
+		rt.Test("GET", "/flaky", nil)
+	}
+	fail = true
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("old failures counted: %d", res.StatusCode())
+	}
+	if res := rt.Test("GET", "/flaky", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("2 of 5 within the window opened it: %d", res.StatusCode())
+	}
+}
+
+func TestCircuitBreakerCountsPanicsAndCustomFailures(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") }).
+		Use(CircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2}))
+	rt.GET("/missing", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }).
+		Use(CircuitBreaker(CircuitBreakerOptions{FailureThreshold: 1, IsFailure: func(status int) bool { return status == http.StatusNotFound }}))
+	rt.GET("/fine", okHandler).Use(CircuitBreaker())
+
+	for _, want := range []int{500, 500, 503} {
+		if res := rt.Test("GET", "/panic", nil); res.StatusCode() != want {
+			t.Errorf("panicking route: %d, want %d", res.StatusCode(), want)
+		}
+	}
+	for _, want := range []int{404, 503} {
+		if res := rt.Test("GET", "/missing", nil); res.StatusCode() != want {
+			t.Errorf("custom failure: %d, want %d", res.StatusCode(), want)
+		}
+	}
+	// Each route's breaker is its own
+	if res := rt.Test("GET", "/fine", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("other route: %d", res.StatusCode())
+	}
+}
+
+func TestGroupAndRouteUse(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name+":"+GetParam(r, "id"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	rt := NewRastaRouterInitializer()
+	rt.Use(tag("router"))
+	api := rt.Group("/api").Use(tag("api"))
+	v1 := api.Group("/v1").Use(tag("v1"))
+	v1.GET("/items/:id", okHandler).Use(tag("route"))
+	rt.GET("/other", okHandler)
+
+	rt.Test("GET", "/api/v1/items/7", nil)
+	want := "router: api:7 v1:7 route:7"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("order %q, want %q", got, want)
+	}
+
+	order = nil
+	rt.Test("GET", "/other", nil)
+	if got := strings.Join(order, " "); got != "router:" {
+		t.Errorf("outside the group: %q", got)
+	}
+
+	if msg := panicMessage(func() { api.Use(nil) }); msg == "" {
+		t.Error("Group.Use(nil) did not panic")
+	}
+}
@@ -0,0 +1,341 @@
+package tobingo
+
+import (
+	"container/list"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOptions configures Cache
+// Zero values fall back to the defaults noted on each field
+type CacheOptions struct {
+	Query   []string                   // Query parameters in the key, the whole query when nil
+	Vary    []string                   // Request headers in the key, like a Vary response header
+	Key     func(*http.Request) string // Extra key part, such as a tenant from the session
+	MaxBody int64                      // Largest body stored, 1 MB by default; larger ones aren't cached
+	Clock   func() time.Time           // Time source, time.Now by default
+
+	// MaxEntries caps the responses stored, 1000 by default; the least recently used one is
+	// dropped for a new one, so varying the query can't grow memory without bound
+	MaxEntries int
+}
+
+// hopHeaders are the connection-level headers a stored response is replayed without
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// responseCache is the state behind one Cache middleware
+type responseCache struct {
+	ttl  time.Duration
+	opts CacheOptions
+
+	router atomic.Pointer[Rastauter] // Router the cache last joined with attach, to skip the lock
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	recent    *list.List // Keys of entries, most recently used first
+	calls     map[string]*cacheCall
+	lastSweep time.Time
+}
+
+// cacheEntry is a stored response
+type cacheEntry struct {
+	pattern string        // Pattern of the route it was stored for, for CacheInvalidate
+	elem    *list.Element // Position in recent, nil until stored
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// cacheCall is a miss being handled, which concurrent misses for the same key wait for
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry // Set before done closes when the response was stored
+}
+
+// Cache returns middleware storing successful GET responses for ttl and serving repeats from
+// memory without calling the handler, with X-Cache set to HIT or MISS and Age on hits
+// Responses are keyed by the route pattern, the path, the query, and the Vary headers;
+// concurrent misses for one key wait for the first instead of all running the handler
+// Only 2xx responses other than 206 are stored, and not those with Set-Cookie or
+// Cache-Control no-store or private; requests with Cache-Control no-cache skip the cache
+// Hits answer 304 when If-None-Match lists the stored ETag; at most MaxEntries responses
+// are kept, the least recently used going first
+// Use it on routes or groups with Route.Use and Group.Use so the route pattern is known
+// Example: rt.GET("/reports/:id", report).Use(tobingo.Cache(30*time.Second, tobingo.CacheOptions{Vary: []string{"Accept-Language"}}))
+func Cache(ttl time.Duration, opts ...CacheOptions) Middleware {
+	if ttl <= 0 {
+		panic("tobingo: Cache needs a positive ttl")
+	}
+	c := &responseCache{ttl: ttl, entries: make(map[string]*cacheEntry), recent: list.New(), calls: make(map[string]*cacheCall)}
+	if len(opts) > 0 {
+		c.opts = opts[0]
+	}
+	if c.opts.MaxBody <= 0 {
+		c.opts.MaxBody = 1 << 20
+	}
+	if c.opts.Clock == nil {
+		c.opts.Clock = time.Now
+	}
+	if c.opts.MaxEntries <= 0 {
+		c.opts.MaxEntries = 1000
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rt := routerFrom(r); rt != nil {
+				c.attach(rt)
+			}
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || hasToken(r.Header, "Cache-Control", "no-cache") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			c.serve(w, r, next)
+		})
+	}
+}
+
+// CacheInvalidate drops the responses stored for the route pattern, such as "/reports/:id",
+// from every Cache middleware that served requests of this router, returning how many were
+// dropped; the caches of other routers are left alone
+// Example: rt.CacheInvalidate("/reports/:id")
+func (rt *Rastauter) CacheInvalidate(pattern string) int {
+	rt.cachesMu.Lock()
+	all := slices.Clone(rt.caches)
+	rt.cachesMu.Unlock()
+
+	dropped := 0
+	for _, c := range all {
+		c.mu.Lock()
+		for key, e := range c.entries {
+			if e.pattern == pattern {
+				c.remove(key, e)
+				dropped++
+			}
+		}
+		c.mu.Unlock()
+	}
+	return dropped
+}
+
+// attach registers c with rt for CacheInvalidate, once per router, so a cache lives and dies
+// with the routers using it rather than in a process-wide list
+func (c *responseCache) attach(rt *Rastauter) {
+	if c.router.Load() == rt {
+		return
+	}
+	rt.cachesMu.Lock()
+	if !slices.Contains(rt.caches, c) {
+		rt.caches = append(rt.caches, c)
+	}
+	rt.cachesMu.Unlock()
+	c.router.Store(rt)
+}
+
+// serve answers a GET or HEAD request from the cache or, on a miss, through next
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	pattern := ""
+	if state := stateFrom(r); state != nil && state.route != nil {
+		pattern = state.route.Path
+	}
+	key := c.key(r, pattern)
+
+	// Serve a fresh entry, or wait for the miss already being handled for the key
+	c.mu.Lock()
+	if e := c.fresh(key); e != nil {
+		c.mu.Unlock()
+		c.replay(w, r, e)
+		return
+	}
+	if call := c.calls[key]; call != nil {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-r.Context().Done():
+			return
+		}
+		if call.entry != nil {
+			c.replay(w, r, call.entry)
+			return
+		}
+
+		// The first response wasn't cacheable, so this request needs its own
+		w.Header().Set("X-Cache", "MISS")
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// HEAD answers carry no body to store, so they never lead a miss
+	if r.Method == http.MethodHead {
+		c.mu.Unlock()
+		w.Header().Set("X-Cache", "MISS")
+		next.ServeHTTP(w, r)
+		return
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	// Let the waiters go even when the handler panics
+	capture := &captureWriter{ResponseWriter: w, max: c.opts.MaxBody}
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		if call.entry != nil {
+			c.sweep(call.entry.stored)
+			c.store(key, call.entry)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	w.Header().Set("X-Cache", "MISS")
+	next.ServeHTTP(exposeFeatures(capture, writerFeatures(w)), r)
+
+	capture.finish()
+	if !cacheable(capture) {
+		return
+	}
+	capture.header.Del("X-Cache")
+	for _, name := range hopHeaders {
+		capture.header.Del(name)
+	}
+	now := c.opts.Clock()
+	call.entry = &cacheEntry{
+		pattern: pattern,
+		status:  capture.status,
+		header:  capture.header,
+		body:    slices.Clone(capture.body.Bytes()),
+		stored:  now,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// key builds the cache key of r from the route pattern, path, query, and Vary headers
+func (c *responseCache) key(r *http.Request, pattern string) string {
+	var sb strings.Builder
+	sb.WriteString(pattern)
+	sb.WriteByte(0)
+	sb.WriteString(r.URL.Path)
+	sb.WriteByte(0)
+	if c.opts.Query == nil {
+		sb.WriteString(r.URL.Query().Encode())
+	} else {
+		query := r.URL.Query()
+		selected := make(url.Values, len(c.opts.Query))
+		for _, name := range c.opts.Query {
+			if values, ok := query[name]; ok {
+				selected[name] = values
+			}
+		}
+		sb.WriteString(selected.Encode())
+	}
+	for _, name := range c.opts.Vary {
+		sb.WriteByte(0)
+		sb.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	if c.opts.Key != nil {
+		sb.WriteByte(0)
+		sb.WriteString(c.opts.Key(r))
+	}
+	return sb.String()
+}
+
+// fresh returns the unexpired entry for key, nil when there is none; the caller must hold c.mu
+func (c *responseCache) fresh(key string) *cacheEntry {
+	e := c.entries[key]
+	if e == nil {
+		return nil
+	}
+	if !c.opts.Clock().Before(e.expires) {
+		c.remove(key, e)
+		return nil
+	}
+	c.recent.MoveToFront(e.elem)
+	return e
+}
+
+// store adds e under key as the most recently used entry, dropping the least recently used
+// ones beyond MaxEntries; the caller must hold c.mu
+func (c *responseCache) store(key string, e *cacheEntry) {
+	if old := c.entries[key]; old != nil {
+		c.remove(key, old)
+	}
+	e.elem = c.recent.PushFront(key)
+	c.entries[key] = e
+	for c.recent.Len() > c.opts.MaxEntries {
+		oldest := c.recent.Back().Value.(string)
+		c.remove(oldest, c.entries[oldest])
+	}
+}
+
+// remove drops the entry e stored under key; the caller must hold c.mu
+func (c *responseCache) remove(key string, e *cacheEntry) {
+	c.recent.Remove(e.elem)
+	delete(c.entries, key)
+}
+
+// replay writes a stored response, or 304 when it carries an ETag If-None-Match lists
+func (c *responseCache) replay(w http.ResponseWriter, r *http.Request, e *cacheEntry) {
+	h := w.Header()
+	for name, values := range e.header {
+		h[name] = slices.Clone(values)
+	}
+	h.Set("X-Cache", "HIT")
+	h.Set("Age", strconv.Itoa(int(c.opts.Clock().Sub(e.stored)/time.Second)))
+	if etag, inm := e.header.Get("ETag"), r.Header.Get("If-None-Match"); etag != "" && inm != "" && etagMatches(inm, etag) {
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// sweep drops expired entries at most once per ttl; the caller must hold c.mu
+func (c *responseCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			c.remove(key, e)
+		}
+	}
+}
+
+// cacheable reports whether a captured response may be stored and shared
+func cacheable(capture *captureWriter) bool {
+	if capture.overflow || capture.status < 200 || capture.status >= 300 || capture.status == http.StatusPartialContent {
+		return false
+	}
+	if _, ok := capture.header["Set-Cookie"]; ok {
+		return false
+	}
+	return !hasToken(capture.header, "Cache-Control", "no-store") && !hasToken(capture.header, "Cache-Control", "private")
+}
+
+// hasToken reports whether the comma-separated header lists token, ignoring case and
+// directive arguments
+func hasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			part, _, _ = strings.Cut(part, "=")
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
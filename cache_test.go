@@ -0,0 +1,245 @@
+package tobingo
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cacheRouter serves a counting handler at /items/:id behind Cache
+func cacheRouter(ttl time.Duration, opts CacheOptions, h http.HandlerFunc) (*Rastauter, *atomic.Int32) {
+	var calls atomic.Int32
+	rt := NewRastaRouterInitializer()
+	rt.GET("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if h != nil {
+			h(w, r)
+			return
+		}
+		w.Write([]byte("call " + strconv.Itoa(int(n))))
+	}).Use(Cache(ttl, opts))
+	return rt, &calls
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	now := time.Unix(1000, 0)
+	rt, calls := cacheRouter(time.Minute, CacheOptions{Clock: func() time.Time { return now }}, nil)
+
+	first := rt.Test("GET", "/items/1", nil)
+	if first.Header("X-Cache") != "MISS" || first.BodyString() != "call 1" {
+		t.Fatalf("first = %q %q, want a MISS from the handler", first.Header("X-Cache"), first.BodyString())
+	}
+	now = now.Add(5 * time.Second)
+	second := rt.Test("GET", "/items/1", nil)
+	if second.Header("X-Cache") != "HIT" || second.BodyString() != "call 1" || second.Header("Age") != "5" {
+		t.Fatalf("second = %q %q Age %q, want a 5 second old HIT", second.Header("X-Cache"), second.BodyString(), second.Header("Age"))
+	}
+	if rt.Test("GET", "/items/1?page=2", nil).BodyString() != "call 2" {
+		t.Error("a different query was served from the cache")
+	}
+
+	now = now.Add(time.Minute)
+	if rt.Test("GET", "/items/1", nil).Header("X-Cache") != "MISS" {
+		t.Error("an expired entry was served")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3", calls.Load())
+	}
+}
+
+func TestCacheSkipsUncacheableResponses(t *testing.T) {
+	for name, h := range map[string]http.HandlerFunc{
+		"no-store":   func(w http.ResponseWriter, r *http.Request) { w.Header().Set("Cache-Control", "no-store") },
+		"private":    func(w http.ResponseWriter, r *http.Request) { w.Header().Set("Cache-Control", "private, max-age=60") },
+		"set-cookie": func(w http.ResponseWriter, r *http.Request) { w.Header().Set("Set-Cookie", "a=b") },
+		"error":      func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+	} {
+		rt, calls := cacheRouter(time.Minute, CacheOptions{}, h)
+		rt.Test("GET", "/items/1", nil)
+		rt.Test("GET", "/items/1", nil)
+		if calls.Load() != 2 {
+			t.Errorf("%s: handler ran %d times, want 2", name, calls.Load())
+		}
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	rt, calls := cacheRouter(time.Minute, CacheOptions{}, nil)
+	rt.Test("GET", "/items/1", nil)
+	rt.Test("GET", "/items/1", nil, WithTestHeader("Cache-Control", "no-cache"))
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2 with Cache-Control no-cache", calls.Load())
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	rt, calls := cacheRouter(time.Minute, CacheOptions{Vary: []string{"Accept-Language"}}, nil)
+	rt.Test("GET", "/items/1", nil, WithTestHeader("Accept-Language", "en"))
+	rt.Test("GET", "/items/1", nil, WithTestHeader("Accept-Language", "fr"))
+	rt.Test("GET", "/items/1", nil, WithTestHeader("Accept-Language", "en"))
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	var calls atomic.Int32
+	rt.GET("/invalidate/:id", func(w http.ResponseWriter, r *http.Request) { calls.Add(1) }).Use(Cache(time.Minute))
+
+	rt.Test("GET", "/invalidate/1", nil)
+	rt.Test("GET", "/invalidate/2", nil)
+	if n := rt.CacheInvalidate("/invalidate/:id"); n != 2 {
+		t.Errorf("CacheInvalidate dropped %d, want 2", n)
+	}
+	rt.Test("GET", "/invalidate/1", nil)
+	if calls.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3", calls.Load())
+	}
+}
+
+func TestCacheInvalidatePerRouter(t *testing.T) {
+	a, aCalls := cacheRouter(time.Minute, CacheOptions{}, nil)
+	b, bCalls := cacheRouter(time.Minute, CacheOptions{}, nil)
+	for range 3 {
+		a.Test("GET", "/items/1", nil)
+		b.Test("GET", "/items/1", nil)
+	}
+	if len(a.caches) != 1 || len(b.caches) != 1 {
+		t.Fatalf("routers hold %d and %d caches, want one each", len(a.caches), len(b.caches))
+	}
+
+	if n := a.CacheInvalidate("/items/:id"); n != 1 {
+		t.Errorf("CacheInvalidate dropped %d, want router a's entry only", n)
+	}
+	a.Test("GET", "/items/1", nil)
+	if res := b.Test("GET", "/items/1", nil); res.Header("X-Cache") != "HIT" {
+		t.Errorf("router b X-Cache = %q, want its entry kept", res.Header("X-Cache"))
+	}
+	if aCalls.Load() != 2 || bCalls.Load() != 1 {
+		t.Errorf("handlers ran %d and %d times, want 2 and 1", aCalls.Load(), bCalls.Load())
+	}
+}
+
+func TestCacheMaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	rt, calls := cacheRouter(time.Minute, CacheOptions{MaxEntries: 2}, nil)
+	rt.Test("GET", "/items/1", nil)
+	rt.Test("GET", "/items/2", nil)
+	rt.Test("GET", "/items/1", nil) // 1 is now the most recently used
+	rt.Test("GET", "/items/3", nil) // Evicts 2
+
+	if rt.Test("GET", "/items/1", nil).Header("X-Cache") != "HIT" {
+		t.Error("recently used entry was evicted")
+	}
+	if rt.Test("GET", "/items/2", nil).Header("X-Cache") != "MISS" {
+		t.Error("least recently used entry was kept")
+	}
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+
+	for i := range 100 {
+		rt.Test("GET", "/items/x?v="+strconv.Itoa(i), nil)
+	}
+	rt.cachesMu.Lock()
+	c := rt.caches[0]
+	rt.cachesMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) != 2 || c.recent.Len() != 2 {
+		t.Errorf("cache holds %d entries (%d in order), want 2", len(c.entries), c.recent.Len())
+	}
+}
+
+func TestCacheHitAnswersNotModified(t *testing.T) {
+	rt, _ := cacheRouter(time.Minute, CacheOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("body"))
+	})
+	rt.Test("GET", "/items/1", nil)
+
+	res := rt.Test("GET", "/items/1", nil, WithTestHeader("If-None-Match", `W/"v1"`))
+	if res.StatusCode() != http.StatusNotModified || res.BodyString() != "" {
+		t.Fatalf("got %d %q, want an empty 304", res.StatusCode(), res.BodyString())
+	}
+	if res.Header("ETag") != `"v1"` || res.Header("X-Cache") != "HIT" {
+		t.Errorf("304 headers ETag %q X-Cache %q", res.Header("ETag"), res.Header("X-Cache"))
+	}
+	if res := rt.Test("GET", "/items/1", nil, WithTestHeader("If-None-Match", `"v0"`)); res.StatusCode() != http.StatusOK {
+		t.Errorf("stale tag got %d, want 200", res.StatusCode())
+	}
+}
+
+func TestCacheConcurrentMissesRunHandlerOnce(t *testing.T) {
+	release := make(chan struct{})
+	rt, calls := cacheRouter(time.Minute, CacheOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("shared"))
+	})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bodies[i] = rt.Test("GET", "/items/1", nil).BodyString()
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+	for i, body := range bodies {
+		if body != "shared" {
+			t.Errorf("request %d got %q", i, body)
+		}
+	}
+}
+
+func TestCacheKeyOptionsAndStoredHeaders(t *testing.T) {
+	rt, calls := cacheRouter(time.Minute, CacheOptions{
+		Query: []string{"page"},
+		Key:   func(r *http.Request) string { return r.Header.Get("X-Tenant") },
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+		w.Header().Set("X-Id", GetParam(r, "id"))
+		w.Write([]byte("page " + r.URL.Query().Get("page")))
+	})
+
+	rt.Test("GET", "/items/1?page=2&utm=a", nil)
+	hit := rt.Test("GET", "/items/1?utm=b&page=2", nil)
+	if hit.Header("X-Cache") != "HIT" || hit.BodyString() != "page 2" || hit.Header("X-Id") != "1" {
+		t.Errorf("unselected query changed: %q %q, X-Id %q", hit.Header("X-Cache"), hit.BodyString(), hit.Header("X-Id"))
+	}
+	if hit.Header("Connection") != "" {
+		t.Errorf("hop-by-hop header replayed: Connection %q", hit.Header("Connection"))
+	}
+	rt.Test("GET", "/items/1?page=3", nil)
+	rt.Test("GET", "/items/1?page=2", nil, WithTestHeader("X-Tenant", "acme"))
+	if calls.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3", calls.Load())
+	}
+}
+
+func TestCacheSkipsOversizedBodies(t *testing.T) {
+	rt, calls := cacheRouter(time.Minute, CacheOptions{MaxBody: 4}, nil)
+	for range 2 {
+		if res := rt.Test("GET", "/items/1", nil); res.Header("X-Cache") != "MISS" || res.BodyString() == "" {
+			t.Errorf("oversized: %q %q", res.Header("X-Cache"), res.BodyString())
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+	if msg := panicMessage(func() { Cache(0) }); msg == "" {
+		t.Error("zero ttl did not panic")
+	}
+}
@@ -0,0 +1,94 @@
+package tobingo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+)
+
+// captureWriter passes the response on while keeping a copy of it, for middleware that
+// stores responses to replay them such as Idempotency and Cache
+type captureWriter struct {
+	http.ResponseWriter
+	max      int64       // Largest body kept
+	status   int         // Status sent, 0 until then
+	header   http.Header // Headers as they were sent
+	body     bytes.Buffer
+	overflow bool // Whether the body outgrew max, leaving body empty
+}
+
+// WriteHeader records the status and headers, then sends them
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write keeps a copy of p while it fits, then sends it
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if int64(w.body.Len()+len(p)) > w.max {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data to the client when the underlying writer supports it
+func (w *captureWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError flushes like Flush and reports http.ErrNotSupported when the underlying
+// writer can't flush
+func (w *captureWriter) FlushError() error {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over, leaving nothing to keep: the response is marked as a
+// body too large to store so it's never replayed
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.overflow = true
+	}
+	return conn, brw, err
+}
+
+// Push initiates an HTTP/2 server push when the underlying writer supports it
+func (w *captureWriter) Push(target string, opts *http.PushOptions) error {
+	return pushTo(w.ResponseWriter, target, opts)
+}
+
+// ReadFrom copies src through Write, since every byte has to be kept
+func (w *captureWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish completes the copy once the handler has returned, as a bare 200 when it wrote
+// nothing, leaving out the Date header so replays don't carry a stale one
+func (w *captureWriter) finish() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.header.Del("Date")
+}
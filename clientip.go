@@ -0,0 +1,126 @@
+package tobingo
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// SetTrustedProxies sets the proxies allowed to report the client address in forwarding
+// headers, as CIDR prefixes ("10.0.0.0/8") or single addresses ("192.0.2.1")
+// ClientIP ignores X-Forwarded-For and X-Real-IP unless the direct peer is trusted
+// Calling it again replaces the list; no proxies are trusted by default
+func (rt *Rastauter) SetTrustedProxies(proxies ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return fmt.Errorf("tobingo: trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	rt.trustedProxies.Store(&prefixes)
+	return nil
+}
+
+// trusted returns the proxies set with SetTrustedProxies, nil when there are none
+func (rt *Rastauter) trusted() []netip.Prefix {
+	if p := rt.trustedProxies.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// ClientIP returns the address of the client that sent the request
+// Without trusted proxies this is the host part of RemoteAddr; when the peer is a trusted
+// proxy, X-Forwarded-For is walked from the right and the first untrusted hop is returned,
+// so entries prepended by the client can't spoof the result; X-Real-IP is used without XFF
+// Returns the zero Addr and false when RemoteAddr can't be parsed
+// Example: behind a trusted 10.0.0.1, "X-Forwarded-For: 6.6.6.6, 203.0.113.7" returns 203.0.113.7
+func ClientIP(r *http.Request) (netip.Addr, bool) {
+	peer, ok := parseHostAddr(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+
+	var trusted []netip.Prefix
+	if rt := routerFrom(r); rt != nil {
+		trusted = rt.trusted()
+	}
+	if !isTrusted(trusted, peer) {
+		return peer, true
+	}
+
+	// Every header line is one list, and each proxy appends the peer it saw
+	var hops []string
+	for _, line := range r.Header.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(line, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) == 0 {
+		if addr, ok := parseHostAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+			return addr, true
+		}
+		return peer, true
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(hops[i])
+		if !ok {
+			// Garbage can't be attributed to anyone, so stop at the last hop we trust
+			return client, true
+		}
+		client = addr
+		if !isTrusted(trusted, addr) {
+			break
+		}
+	}
+	return client, true
+}
+
+// parseHostAddr parses an address with or without a port, including bracketed IPv6
+// IPv4-mapped IPv6 addresses are unmapped so they match IPv4 prefixes
+func parseHostAddr(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// parsePrefix parses a CIDR prefix or a single address as a full-length prefix
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isTrusted reports whether addr falls inside one of the trusted prefixes
+func isTrusted(trusted []netip.Prefix, addr netip.Addr) bool {
+	// Prefixes never contain zoned addresses, so compare without the zone
+	addr = addr.WithZone("")
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
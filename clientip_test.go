@@ -0,0 +1,95 @@
+package tobingo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ipRouter answers every request with its ClientIP, or "invalid" when there is none
+func ipRouter() *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := ClientIP(r)
+		if !ok || !addr.IsValid() {
+			io.WriteString(w, "invalid")
+			return
+		}
+		io.WriteString(w, addr.String())
+	})
+	return rt
+}
+
+// clientIP serves a request from remoteAddr with the given headers and returns what ClientIP saw
+func clientIP(rt *Rastauter, remoteAddr string, header http.Header) string {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = remoteAddr
+	r.Header = header
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	return rec.Body.String()
+}
+
+func TestClientIPPeer(t *testing.T) {
+	rt := ipRouter()
+	for remote, want := range map[string]string{
+		"192.0.2.1:1234":             "192.0.2.1",
+		"192.0.2.1":                  "192.0.2.1",
+		"[2001:db8::1]:443":          "2001:db8::1",
+		"[fe80::1%eth0]:443":         "fe80::1%eth0",
+		"[::ffff:198.51.100.7]:80":   "198.51.100.7",
+		"[2001:db8::2]":              "2001:db8::2",
+		"2001:db8::3":                "2001:db8::3",
+		"not an address":             "invalid",
+		"":                           "invalid",
+		"192.0.2.1:1234:extra":       "invalid",
+		"[2001:db8::1]:443 trailing": "invalid",
+	} {
+		// Forwarding headers from an untrusted peer are ignored
+		if got := clientIP(rt, remote, http.Header{"X-Forwarded-For": {"6.6.6.6"}, "X-Real-Ip": {"6.6.6.6"}}); got != want {
+			t.Errorf("%q = %s, want %s", remote, got, want)
+		}
+	}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	rt := ipRouter()
+	if err := rt.SetTrustedProxies("10.0.0.0/8", "2001:db8:ffff::1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		remote string
+		header http.Header
+		want   string
+	}{
+		"load balancer":      {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		"spoofed prefix":     {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"6.6.6.6, 203.0.113.7"}}, "203.0.113.7"},
+		"proxy chain":        {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"6.6.6.6, 203.0.113.7, 10.1.2.3"}}, "203.0.113.7"},
+		"split header lines": {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"6.6.6.6", "203.0.113.7, 10.1.2.3"}}, "203.0.113.7"},
+		"all trusted":        {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"10.9.9.9, 10.1.2.3"}}, "10.9.9.9"},
+		"garbage hop":        {"10.0.0.1:80", http.Header{"X-Forwarded-For": {"203.0.113.7, junk, 10.1.2.3"}}, "10.1.2.3"},
+		"ipv6 proxy":         {"[2001:db8:ffff::1]:443", http.Header{"X-Forwarded-For": {"[2001:db8::7]:5000"}}, "2001:db8::7"},
+		"real ip":            {"10.0.0.1:80", http.Header{"X-Real-Ip": {"203.0.113.9"}}, "203.0.113.9"},
+		"bad real ip":        {"10.0.0.1:80", http.Header{"X-Real-Ip": {"nope"}}, "10.0.0.1"},
+		"no headers":         {"10.0.0.1:80", http.Header{}, "10.0.0.1"},
+		"untrusted client":   {"198.51.100.1:80", http.Header{"X-Forwarded-For": {"1.2.3.4"}, "X-Real-Ip": {"1.2.3.4"}}, "198.51.100.1"},
+	} {
+		if got := clientIP(rt, tc.remote, tc.header); got != tc.want {
+			t.Errorf("%s = %s, want %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestSetTrustedProxiesInvalid(t *testing.T) {
+	rt := ipRouter()
+	rt.SetTrustedProxies("10.0.0.0/8")
+	if err := rt.SetTrustedProxies("10.0.0.0/8", "not-a-cidr"); err == nil {
+		t.Fatal("invalid proxy accepted")
+	}
+	// The earlier list stays in place
+	if got := clientIP(rt, "10.0.0.1:80", http.Header{"X-Forwarded-For": {"203.0.113.7"}}); got != "203.0.113.7" {
+		t.Errorf("ClientIP = %s after a rejected update", got)
+	}
+}
@@ -0,0 +1,149 @@
+package tobingo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors returned by the signed cookie helpers
+var (
+	ErrCookieSignature = errors.New("tobingo: cookie signature is invalid")
+	ErrNoCookieKey     = errors.New("tobingo: no cookie signing key configured, see SetCookieKey")
+)
+
+// CookieOptions configures cookies written by SetCookie
+// The zero value gives a session cookie for the whole site that scripts can't read,
+// sent on same-site requests and top-level navigations only
+type CookieOptions struct {
+	Path            string        // Path scope, "/" when empty
+	Domain          string        // Domain scope, the request host only when empty
+	MaxAge          time.Duration // Lifetime; zero makes a session cookie
+	SameSite        http.SameSite // SameSite mode, http.SameSiteLaxMode when zero; None implies Secure
+	Secure          bool          // Force Secure on plain HTTP requests, e.g. behind a TLS terminating proxy
+	AllowJavaScript bool          // Leave out HttpOnly so document.cookie can read the value
+}
+
+// SetCookie adds a Set-Cookie header with safe defaults: HttpOnly, SameSite=Lax, and Secure
+// whenever the request arrived over TLS
+// A positive MaxAge sets both Max-Age and Expires so older clients honor the lifetime
+func SetCookie(w http.ResponseWriter, r *http.Request, name, value string, opts CookieOptions) {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		SameSite: opts.SameSite,
+		Secure:   opts.Secure || r.TLS != nil,
+		HttpOnly: !opts.AllowJavaScript,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	// Browsers reject SameSite=None cookies that aren't Secure
+	if c.SameSite == http.SameSiteNoneMode {
+		c.Secure = true
+	}
+	if opts.MaxAge > 0 {
+		c.MaxAge = int(opts.MaxAge / time.Second)
+		c.Expires = time.Now().Add(opts.MaxAge).UTC()
+	}
+	http.SetCookie(w, c)
+}
+
+// Cookie returns the value of the named request cookie and whether it was sent
+func Cookie(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// DeleteCookie tells the client to drop the named cookie; path must match the one it was set with
+func DeleteCookie(w http.ResponseWriter, name, path string) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:    name,
+		Value:   "",
+		Path:    path,
+		MaxAge:  -1,
+		Expires: time.Unix(0, 0),
+	})
+}
+
+// SetCookieKey sets the HMAC key used by SetSignedCookie and SignedCookie on this router
+// Use at least 32 random bytes; changing the key invalidates every signed cookie
+func (rt *Rastauter) SetCookieKey(key []byte) {
+	key = append([]byte(nil), key...)
+	rt.cookieKey.Store(&key)
+}
+
+// SetSignedCookie sets a cookie like SetCookie whose value carries an HMAC-SHA256 signature,
+// so SignedCookie detects any modification by the client; the value itself is not encrypted
+// Returns ErrNoCookieKey when the router serving r has no key
+func SetSignedCookie(w http.ResponseWriter, r *http.Request, name, value string, opts CookieOptions) error {
+	key := cookieKey(r)
+	if key == nil {
+		return ErrNoCookieKey
+	}
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(value))
+	SetCookie(w, r, name, encoded+"."+signCookie(key, name, value), opts)
+	return nil
+}
+
+// SignedCookie returns the value of a cookie set with SetSignedCookie after checking its signature
+// Returns http.ErrNoCookie when it wasn't sent, ErrCookieSignature when it was tampered with,
+// and ErrNoCookieKey when the router has no key
+func SignedCookie(r *http.Request, name string) (string, error) {
+	key := cookieKey(r)
+	if key == nil {
+		return "", ErrNoCookieKey
+	}
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return "", ErrCookieSignature
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCookieSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(signCookie(key, name, string(value)))) {
+		return "", ErrCookieSignature
+	}
+	return string(value), nil
+}
+
+// signCookie computes the signature over the name and value, so a valid value can't be
+// moved to a cookie with another name
+func signCookie(key []byte, name, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookieKey returns the signing key of the router serving r, or nil
+func cookieKey(r *http.Request) []byte {
+	rt := routerFrom(r)
+	if rt == nil {
+		return nil
+	}
+	if key := rt.cookieKey.Load(); key != nil {
+		return *key
+	}
+	return nil
+}
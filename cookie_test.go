@@ -0,0 +1,153 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setCookies serves req with a handler that calls set and returns the cookies it wrote
+func setCookies(rt *Rastauter, req *http.Request, set http.HandlerFunc) []*http.Cookie {
+	rt.GET("/", set)
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	return rec.Result().Cookies()
+}
+
+func TestSetCookieDefaults(t *testing.T) {
+	cookies := setCookies(NewRastaRouterInitializer(), httptest.NewRequest("GET", "http://example.com/", nil), func(w http.ResponseWriter, r *http.Request) {
+		SetCookie(w, r, "session", "abc", CookieOptions{})
+	})
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	c := cookies[0]
+	if c.Value != "abc" || c.Path != "/" || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Secure || c.MaxAge != 0 || !c.Expires.IsZero() {
+		t.Errorf("cookie = %+v", c)
+	}
+}
+
+func TestSetCookieOptions(t *testing.T) {
+	before := time.Now()
+	cookies := setCookies(NewRastaRouterInitializer(), httptest.NewRequest("GET", "https://example.com/", nil), func(w http.ResponseWriter, r *http.Request) {
+		SetCookie(w, r, "tls", "1", CookieOptions{})
+		SetCookie(w, r, "pref", "dark", CookieOptions{Path: "/app", MaxAge: time.Hour, AllowJavaScript: true, SameSite: http.SameSiteStrictMode})
+	})
+	if len(cookies) != 2 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	if !cookies[0].Secure {
+		t.Error("cookie set over TLS is not Secure")
+	}
+	c := cookies[1]
+	if c.Path != "/app" || c.HttpOnly || c.SameSite != http.SameSiteStrictMode || c.MaxAge != 3600 {
+		t.Errorf("cookie = %+v", c)
+	}
+	if d := c.Expires.Sub(before); d < 59*time.Minute || d > 61*time.Minute {
+		t.Errorf("Expires is %v ahead, want an hour", d)
+	}
+
+	// SameSite=None forces Secure even on plain HTTP
+	cookies = setCookies(NewRastaRouterInitializer(), httptest.NewRequest("GET", "http://example.com/", nil), func(w http.ResponseWriter, r *http.Request) {
+		SetCookie(w, r, "embed", "1", CookieOptions{SameSite: http.SameSiteNoneMode})
+	})
+	if !cookies[0].Secure {
+		t.Error("SameSite=None cookie is not Secure")
+	}
+}
+
+func TestDeleteCookie(t *testing.T) {
+	rec := httptest.NewRecorder()
+	DeleteCookie(rec, "session", "")
+	header := rec.Header().Get("Set-Cookie")
+	for _, want := range []string{"session=;", "Path=/;", "Max-Age=0", "Expires=Thu, 01 Jan 1970 00:00:00 GMT"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Set-Cookie %q lacks %q", header, want)
+		}
+	}
+}
+
+func TestCookie(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "a", Value: "1"})
+	if v, ok := Cookie(r, "a"); v != "1" || !ok {
+		t.Errorf("Cookie(a) = %q, %v", v, ok)
+	}
+	if v, ok := Cookie(r, "b"); v != "" || ok {
+		t.Errorf("Cookie(b) = %q, %v", v, ok)
+	}
+}
+
+func TestSignedCookie(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetCookieKey([]byte("0123456789abcdef0123456789abcdef"))
+	var got string
+	var gotErr error
+	rt.GET("/set", func(w http.ResponseWriter, r *http.Request) {
+		if err := SetSignedCookie(w, r, "user", "ann; role=admin", CookieOptions{}); err != nil {
+			t.Error(err)
+		}
+	})
+	rt.GET("/get", func(w http.ResponseWriter, r *http.Request) {
+		got, gotErr = SignedCookie(r, "user")
+	})
+	rt.GET("/get-admin", func(w http.ResponseWriter, r *http.Request) {
+		got, gotErr = SignedCookie(r, "admin")
+	})
+
+	cookies := rt.Test("GET", "/set", nil).Recorder.Result().Cookies()
+	if len(cookies) != 1 || strings.Contains(cookies[0].Value, "ann") {
+		t.Fatalf("cookies = %v, want one with an encoded value", cookies)
+	}
+	signed := cookies[0].Value
+
+	get := func(header string) {
+		got, gotErr = "", nil
+		rt.Test("GET", "/get", nil, WithTestHeader("Cookie", header))
+	}
+	if get("user=" + signed); got != "ann; role=admin" || gotErr != nil {
+		t.Errorf("valid = %q, %v", got, gotErr)
+	}
+
+	encoded, sig, _ := strings.Cut(signed, ".")
+	for name, header := range map[string]string{
+		"tampered value": "user=" + encoded + "x." + sig,
+		"tampered sig":   "user=" + encoded + "." + strings.Repeat("A", len(sig)),
+		"unsigned":       "user=" + encoded,
+	} {
+		if get(header); !errors.Is(gotErr, ErrCookieSignature) || got != "" {
+			t.Errorf("%s = %q, %v; want ErrCookieSignature", name, got, gotErr)
+		}
+	}
+	// The signature covers the name, so the value can't be replayed under another cookie
+	rt.Test("GET", "/get-admin", nil, WithTestHeader("Cookie", "admin="+signed))
+	if !errors.Is(gotErr, ErrCookieSignature) {
+		t.Errorf("renamed = %v, want ErrCookieSignature", gotErr)
+	}
+
+	if get(""); !errors.Is(gotErr, http.ErrNoCookie) {
+		t.Errorf("absent = %v, want http.ErrNoCookie", gotErr)
+	}
+
+	// Another key rejects the cookie
+	rt.SetCookieKey([]byte("another key of thirty-two bytes!"))
+	if get("user=" + signed); !errors.Is(gotErr, ErrCookieSignature) {
+		t.Errorf("rotated key = %v", gotErr)
+	}
+}
+
+func TestSignedCookieWithoutKey(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	var setErr, getErr error
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		setErr = SetSignedCookie(w, r, "user", "ann", CookieOptions{})
+		_, getErr = SignedCookie(r, "user")
+	})
+	res := rt.Test("GET", "/", nil)
+	if !errors.Is(setErr, ErrNoCookieKey) || !errors.Is(getErr, ErrNoCookieKey) || res.Header("Set-Cookie") != "" {
+		t.Errorf("errors = %v, %v; Set-Cookie %q", setErr, getErr, res.Header("Set-Cookie"))
+	}
+}
@@ -0,0 +1,174 @@
+package tobingo
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions is a cross-origin resource sharing policy for the CORS middleware or a route
+// The zero value allows no cross-origin requests
+type CORSOptions struct {
+	AllowedOrigins   []string      // Origins such as "https://app.example.com", or "*" for any
+	AllowedMethods   []string      // Methods allowed in preflights, GET, HEAD, and POST by default
+	AllowedHeaders   []string      // Request headers allowed beyond the safelisted ones, "*" for any
+	ExposedHeaders   []string      // Response headers scripts may read
+	AllowCredentials bool          // Allow cookies and auth; "*" origins are then answered with the request's origin
+	MaxAge           time.Duration // How long browsers may cache a preflight, not sent when zero
+}
+
+// corsPolicy is a CORSOptions prepared for matching requests
+type corsPolicy struct {
+	opts        CORSOptions
+	anyOrigin   bool
+	anyHeader   bool
+	methods     string
+	headers     map[string]bool
+	exposed     string
+	maxAge      string
+	credentials bool
+}
+
+// newCORSPolicy normalizes opts once so requests don't repeat the work
+func newCORSPolicy(opts CORSOptions) *corsPolicy {
+	p := &corsPolicy{
+		opts:        opts,
+		anyOrigin:   slices.Contains(opts.AllowedOrigins, "*"),
+		anyHeader:   slices.Contains(opts.AllowedHeaders, "*"),
+		headers:     make(map[string]bool),
+		exposed:     strings.Join(opts.ExposedHeaders, ", "),
+		credentials: opts.AllowCredentials,
+	}
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	p.opts.AllowedMethods = methods
+	p.methods = strings.Join(methods, ", ")
+	for _, h := range opts.AllowedHeaders {
+		p.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// CORS returns middleware applying the policy to cross-origin requests and answering their
+// preflight OPTIONS requests with 204
+// Routes with their own policy set with Route.CORS use it instead, for preflights too, which
+// are resolved against the route the announced method would match
+// Example: rt.Use(tobingo.CORS(tobingo.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}))
+func CORS(opts CORSOptions) Middleware {
+	global := newCORSPolicy(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requested := r.Header.Get("Access-Control-Request-Method")
+			preflight := r.Method == http.MethodOptions && requested != ""
+			method := r.Method
+			if preflight {
+				method = requested
+			}
+
+			policy := global
+			if rt := routerFrom(r); rt != nil {
+				if route, _, _ := rt.match(method, r.URL.Path); route != nil && route.cors != nil {
+					policy = route.cors
+				}
+			}
+
+			if preflight {
+				policy.preflight(w, r, origin, requested)
+				return
+			}
+			policy.allowOrigin(w.Header(), origin)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORS gives the route its own policy, replacing the one of the CORS middleware, which must
+// be in use for the policy to apply
+// Example: rt.GET("/widget.js", widget).CORS(tobingo.CORSOptions{AllowedOrigins: []string{"*"}})
+func (route *Route) CORS(opts CORSOptions) *Route {
+	route.cors = newCORSPolicy(opts)
+	return route
+}
+
+// allowOrigin sets the response headers of an allowed origin and reports whether it is allowed
+func (p *corsPolicy) allowOrigin(h http.Header, origin string) bool {
+	// The answer depends on the origin unless every origin gets "*"
+	if !p.anyOrigin || p.credentials {
+		h.Add("Vary", "Origin")
+	}
+	if !p.anyOrigin && !slices.Contains(p.opts.AllowedOrigins, origin) {
+		return false
+	}
+
+	if p.anyOrigin && !p.credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.exposed != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposed)
+	}
+	return true
+}
+
+// preflight answers a preflight request with 204, with the CORS headers only when the
+// origin, method, and headers are all allowed
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, origin, method string) {
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	defer w.WriteHeader(http.StatusNoContent)
+
+	if !slices.Contains(p.opts.AllowedMethods, method) && !corsSafelistedMethod(method) {
+		h.Add("Vary", "Origin")
+		return
+	}
+	var requested []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				requested = append(requested, name)
+			}
+		}
+	}
+	for _, name := range requested {
+		if !p.anyHeader && !p.headers[http.CanonicalHeaderKey(name)] {
+			h.Add("Vary", "Origin")
+			return
+		}
+	}
+
+	// Credentials, exposed headers, and the origin follow the rules of actual requests
+	if !p.allowOrigin(h, origin) {
+		return
+	}
+	h.Del("Access-Control-Expose-Headers")
+	h.Set("Access-Control-Allow-Methods", p.methods)
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+}
+
+// corsSafelistedMethod reports whether method needs no explicit permission, per the Fetch standard
+func corsSafelistedMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPost
+}
@@ -0,0 +1,133 @@
+package tobingo
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// corsRouter uses a restrictive global policy with a public and a partner route overriding it
+func corsRouter() *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.Use(CORS(CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		ExposedHeaders: []string{"X-Request-Id"},
+	}))
+	rt.GET("/api/users", writeRoute("users"))
+	rt.GET("/widget.js", writeRoute("widget")).CORS(CORSOptions{AllowedOrigins: []string{"*"}})
+	rt.addRoute("PUT", "/partner/orders/:id", writeRoute("updated")).CORS(CORSOptions{
+		AllowedOrigins:   []string{"https://partner.example.net"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"X-Partner-Token", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	rt.GET("/partner/orders/:id", writeRoute("order"))
+	return rt
+}
+
+func TestCORSGlobalPolicy(t *testing.T) {
+	rt := corsRouter()
+
+	res := rt.Test("GET", "/api/users", nil, WithTestHeader("Origin", "https://app.example.com"))
+	if res.Header("Access-Control-Allow-Origin") != "https://app.example.com" || res.Header("Access-Control-Expose-Headers") != "X-Request-Id" || res.Header("Vary") != "Origin" {
+		t.Errorf("allowed origin: headers %v", res.Recorder.Header())
+	}
+	if res.Header("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials allowed without AllowCredentials")
+	}
+
+	res = rt.Test("GET", "/api/users", nil, WithTestHeader("Origin", "https://evil.example"))
+	if res.Header("Access-Control-Allow-Origin") != "" || res.BodyString() != "users" || res.Header("Vary") != "Origin" {
+		t.Errorf("other origin: %q, headers %v", res.BodyString(), res.Recorder.Header())
+	}
+
+	// Same-origin requests pass untouched
+	if res := rt.Test("GET", "/api/users", nil); len(res.Recorder.Header().Values("Vary")) != 0 || res.Header("Access-Control-Allow-Origin") != "" {
+		t.Errorf("no Origin: headers %v", res.Recorder.Header())
+	}
+}
+
+func TestCORSRouteOverrides(t *testing.T) {
+	rt := corsRouter()
+
+	// The wildcard route answers "*" to any origin and doesn't vary by it
+	for _, origin := range []string{"https://evil.example", "https://app.example.com"} {
+		res := rt.Test("GET", "/widget.js", nil, WithTestHeader("Origin", origin))
+		if res.Header("Access-Control-Allow-Origin") != "*" || res.Header("Vary") != "" || res.Header("Access-Control-Expose-Headers") != "" {
+			t.Errorf("widget from %s: headers %v", origin, res.Recorder.Header())
+		}
+	}
+
+	// The partner route allows credentials from its origin only, replacing the global list
+	res := rt.Test("PUT", "/partner/orders/7", nil, WithTestHeader("Origin", "https://partner.example.net"))
+	if res.Header("Access-Control-Allow-Origin") != "https://partner.example.net" || res.Header("Access-Control-Allow-Credentials") != "true" || res.Header("Vary") != "Origin" {
+		t.Errorf("partner: headers %v", res.Recorder.Header())
+	}
+	res = rt.Test("PUT", "/partner/orders/7", nil, WithTestHeader("Origin", "https://app.example.com"))
+	if res.Header("Access-Control-Allow-Origin") != "" || res.Header("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("globally allowed origin on the partner route: headers %v", res.Recorder.Header())
+	}
+
+	// GET on the same pattern is another route and keeps the global policy
+	res = rt.Test("GET", "/partner/orders/7", nil, WithTestHeader("Origin", "https://app.example.com"))
+	if res.Header("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("GET route: headers %v", res.Recorder.Header())
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rt := corsRouter()
+	preflight := func(target, origin, method, headers string) *TestResponse {
+		opts := []TestOption{WithTestHeader("Origin", origin), WithTestHeader("Access-Control-Request-Method", method)}
+		if headers != "" {
+			opts = append(opts, WithTestHeader("Access-Control-Request-Headers", headers))
+		}
+		return rt.Test("OPTIONS", target, nil, opts...)
+	}
+
+	// Resolved against the PUT route, whose policy allows the method, headers, and credentials
+	res := preflight("/partner/orders/7", "https://partner.example.net", "PUT", "x-partner-token, content-type")
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://partner.example.net",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "x-partner-token, content-type",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := res.Header(name); got != want {
+			t.Errorf("partner preflight: %s = %q, want %q", name, got, want)
+		}
+	}
+	if res.StatusCode() != http.StatusNoContent || res.BodyString() != "" {
+		t.Errorf("partner preflight: %d %q", res.StatusCode(), res.BodyString())
+	}
+	if vary := res.Recorder.Header().Values("Vary"); !slices.Contains(vary, "Origin") || !slices.Contains(vary, "Access-Control-Request-Method") {
+		t.Errorf("partner preflight: Vary %v", vary)
+	}
+
+	for name, res := range map[string]*TestResponse{
+		"header not allowed":    preflight("/partner/orders/7", "https://partner.example.net", "PUT", "X-Other"),
+		"origin not allowed":    preflight("/partner/orders/7", "https://app.example.com", "PUT", ""),
+		"method not allowed":    preflight("/partner/orders/7", "https://partner.example.net", "DELETE", ""),
+		"global policy for GET": preflight("/partner/orders/7", "https://partner.example.net", "GET", ""),
+		"global method":         preflight("/api/users", "https://app.example.com", "PUT", ""),
+		"global header":         preflight("/api/users", "https://app.example.com", "GET", "X-Partner-Token"),
+	} {
+		if res.StatusCode() != http.StatusNoContent || res.Header("Access-Control-Allow-Origin") != "" || res.Header("Access-Control-Allow-Methods") != "" {
+			t.Errorf("%s: %d, headers %v", name, res.StatusCode(), res.Recorder.Header())
+		}
+	}
+
+	// The global policy allows its own origin for safelisted methods, without exposing headers
+	res = preflight("/api/users", "https://app.example.com", "POST", "")
+	if res.Header("Access-Control-Allow-Methods") != "GET, HEAD, POST" || res.Header("Access-Control-Allow-Origin") != "https://app.example.com" || res.Header("Access-Control-Expose-Headers") != "" || res.Header("Access-Control-Max-Age") != "" {
+		t.Errorf("global preflight: headers %v", res.Recorder.Header())
+	}
+
+	// An OPTIONS request without Access-Control-Request-Method isn't a preflight
+	if res := rt.Test("OPTIONS", "/api/users", nil, WithTestHeader("Origin", "https://app.example.com")); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("plain OPTIONS = %d, want 404", res.StatusCode())
+	}
+}
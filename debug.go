@@ -0,0 +1,63 @@
+package tobingo
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// errDebugRemote is reported to the error handler for remote requests to the debug endpoints
+var errDebugRemote = errors.New("tobingo: debug endpoints only answer loopback clients")
+
+// DebugOptions configures the endpoints registered by EnableDebugEndpoints
+type DebugOptions struct {
+	AllowRemote bool       // Answer non-loopback clients too, e.g. when Middleware authenticates them
+	Middleware  Middleware // Wraps every debug endpoint, e.g. basic auth; nil for none
+}
+
+// EnableDebugEndpoints registers the net/http/pprof profiles at prefix + "/debug/pprof/" and
+// the expvar variables at prefix + "/debug/vars"
+// Only loopback clients, as reported by ClientIP, are answered unless AllowRemote is set;
+// profiles expose internals, so keep them off public listeners or behind Middleware
+// Example: rt.EnableDebugEndpoints("/internal", tobingo.DebugOptions{Middleware: basicAuth})
+func (rt *Rastauter) EnableDebugEndpoints(prefix string, opts DebugOptions) {
+	base := strings.TrimSuffix(prefix, "/") + "/debug/pprof"
+
+	register := func(method, path string, h http.HandlerFunc) {
+		var handler http.Handler = h
+		if opts.Middleware != nil {
+			handler = opts.Middleware(handler)
+		}
+		rt.addRoute(method, path, func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := ClientIP(r); !opts.AllowRemote && (!ok || !ip.IsLoopback()) {
+				handleError(w, r, http.StatusForbidden, errDebugRemote)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	// The index links to profiles relatively, so it needs the trailing slash
+	register(http.MethodGet, base, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			// One leading slash, as "//debug/pprof" matches too but "//debug" names a host
+			http.Redirect(w, r, externalLocation(r, "/"+strings.TrimLeft(r.URL.Path, "/")+"/"), http.StatusMovedPermanently)
+			return
+		}
+		pprof.Index(w, r)
+	})
+	register(http.MethodGet, base+"/cmdline", pprof.Cmdline)
+	register(http.MethodGet, base+"/profile", pprof.Profile)
+	register(http.MethodGet, base+"/symbol", pprof.Symbol)
+	register(http.MethodPost, base+"/symbol", pprof.Symbol)
+	register(http.MethodGet, base+"/trace", pprof.Trace)
+
+	// pprof.Index only finds named profiles under /debug/pprof/ itself, so serve them directly
+	register(http.MethodGet, base+"/:profile", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(GetParam(r, "profile")).ServeHTTP(w, r)
+	})
+
+	register(http.MethodGet, strings.TrimSuffix(prefix, "/")+"/debug/vars", expvar.Handler().ServeHTTP)
+}
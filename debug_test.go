@@ -0,0 +1,117 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// debugRequest serves a GET of target from remoteAddr
+func debugRequest(rt *Rastauter, target, remoteAddr string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", target, nil)
+	r.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.EnableDebugEndpoints("/internal", DebugOptions{})
+
+	rec := debugRequest(rt, "/internal/debug/pprof/", "127.0.0.1:5000")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "href='goroutine?debug=1'") {
+		t.Fatalf("index = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = debugRequest(rt, "/internal/debug/pprof/goroutine?debug=1", "[::1]:5000")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "goroutine profile:") {
+		t.Errorf("goroutine = %d %.40q", rec.Code, rec.Body.String())
+	}
+
+	rec = debugRequest(rt, "/internal/debug/pprof/cmdline", "127.0.0.1:5000")
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("cmdline = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = debugRequest(rt, "/internal/debug/vars", "127.0.0.1:5000")
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil || vars["memstats"] == nil {
+		t.Errorf("vars = %d, %v", rec.Code, err)
+	}
+
+	rec = debugRequest(rt, "/internal/debug/pprof", "127.0.0.1:5000")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/internal/debug/pprof/" {
+		t.Errorf("index without a slash = %d Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec := debugRequest(rt, "/internal/debug/pprof/nosuchprofile", "127.0.0.1:5000"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown profile = %d, want 404", rec.Code)
+	}
+}
+
+func TestDebugEndpointsLoopbackOnly(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.EnableDebugEndpoints("", DebugOptions{})
+	for _, remote := range []string{"203.0.113.7:5000", "[2001:db8::1]:5000", "10.0.0.1:80", ""} {
+		for _, target := range []string{"/debug/pprof/", "/debug/pprof/goroutine", "/debug/vars"} {
+			if rec := debugRequest(rt, target, remote); rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "goroutine") {
+				t.Errorf("%s from %q = %d", target, remote, rec.Code)
+			}
+		}
+	}
+
+	// A proxy on loopback can't vouch for a remote client unless it is trusted
+	rt.SetTrustedProxies("127.0.0.1")
+	r := httptest.NewRequest("GET", "/debug/vars", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("forwarded remote client = %d, want 403", rec.Code)
+	}
+}
+
+func TestDebugEndpointsMiddleware(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.EnableDebugEndpoints("/ops", DebugOptions{AllowRemote: true, Middleware: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "ops" || pass != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="debug"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}})
+
+	if rec := debugRequest(rt, "/ops/debug/pprof/", "203.0.113.7:5000"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without credentials = %d, want 401", rec.Code)
+	}
+	r := httptest.NewRequest("GET", "/ops/debug/pprof/heap?debug=1", nil)
+	r.RemoteAddr = "203.0.113.7:5000"
+	r.SetBasicAuth("ops", "secret")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	if body, _ := io.ReadAll(rec.Body); rec.Code != http.StatusOK || !strings.Contains(string(body), "heap profile:") {
+		t.Errorf("with credentials = %d %.40q", rec.Code, body)
+	}
+}
+
+func TestDebugEndpointsBehindBasePath(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetBasePath("/myapp")
+	rt.EnableDebugEndpoints("", DebugOptions{})
+
+	rec := debugRequest(rt, "/myapp/debug/pprof", "127.0.0.1:5000")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/myapp/debug/pprof/" {
+		t.Errorf("redirect = %d Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := debugRequest(rt, "/myapp/debug/pprof/", "127.0.0.1:5000"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "href='heap?debug=1'") {
+		t.Errorf("index = %d", rec.Code)
+	}
+}
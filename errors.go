@@ -0,0 +1,115 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error that carries the status to answer with and, optionally, a message
+// meant for the client; adapters such as H render it through the router's error handler
+// Example: return 0, nil, &tobingo.HTTPError{Status: http.StatusNotFound, Message: "no such user"}
+type HTTPError struct {
+	Status  int    // Status code to answer with, 500 when zero
+	Message string // Client-facing message, the status text when empty
+	Err     error  // Underlying cause for logs, never shown to clients
+}
+
+// Error returns the message, the cause, or the status text, whichever is set first
+func (e *HTTPError) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	}
+	return http.StatusText(e.status())
+}
+
+// Unwrap returns the underlying cause for errors.Is and errors.As
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// status returns the status code, defaulting to 500
+func (e *HTTPError) status() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// errorStatus returns the status of the HTTPError in err's chain, 413 for an oversized
+// request body, 422 for a failed validation, or fallback otherwise
+func errorStatus(err error, fallback int) int {
+	var (
+		he     *HTTPError
+		maxErr *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &he):
+		return he.status()
+	case errors.As(err, &maxErr), errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrValidation):
+		return http.StatusUnprocessableEntity
+	}
+	return fallback
+}
+
+// ErrorHandlerFunc renders the response for an error the router or a helper can't recover from
+// status is the code chosen for err; err is for logging and must not be shown to clients as is
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// DefaultErrorHandler answers with the status text only, so internal error messages such as
+// OS paths never reach the client; the Message of an HTTPError is shown instead when set, and
+// so is the message of a *ValidationError, which is written for the client
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var (
+		he *HTTPError
+		ve *ValidationError
+	)
+	switch {
+	case errors.As(err, &he) && he.Message != "":
+		http.Error(w, he.Message, status)
+		return
+	case errors.As(err, &ve):
+		http.Error(w, ve.Error(), status)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// ErrorHandler sets how errors are rendered, e.g. as JSON problem details for an API
+// It is used for failures such as filesystem errors behind static mounts; DefaultErrorHandler
+// is used when none is set, and groups and routes may set their own
+func (rt *Rastauter) ErrorHandler(h ErrorHandlerFunc) {
+	rt.errorHandler.Store(&h)
+}
+
+// recordError remembers the first error a response was rendered for, for OnErrorResponse
+func recordError(r *http.Request, err error) {
+	if state := stateFrom(r); state != nil {
+		state.mu.Lock()
+		if state.err == nil {
+			state.err = err
+		}
+		state.mu.Unlock()
+	}
+}
+
+// handleError renders err with the error handler of the route matched for r, resolved
+// through its groups to the router serving r
+func handleError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	recordError(r, err)
+	h := DefaultErrorHandler
+	if rt := routerFrom(r); rt != nil {
+		var route *Route
+		if state := stateFrom(r); state != nil {
+			route = state.route
+		}
+		if eh := rt.errorHandlerFor(route); eh != nil {
+			h = eh
+		}
+	}
+	h(w, r, status, err)
+}
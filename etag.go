@@ -0,0 +1,57 @@
+package tobingo
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// JSONETags makes the JSON helper tag 200 responses to GET and HEAD with a strong ETag
+// hashed from the encoded body, answering 304 without the body when If-None-Match matches
+// A JSONOpts passed to a call decides for that call instead; it may be changed while serving
+func (rt *Rastauter) JSONETags(enabled bool) {
+	rt.jsonETags.Store(enabled)
+}
+
+// contentETag returns a strong entity tag for body, quoted
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value lists etag, comparing weakly as
+// RFC 9110 requires for it, so W/"x" matches "x"; "*" matches any tag
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified tags a 200 response to a GET or HEAD request with the entity tag of body and
+// reports whether the client's copy is current, in which case 304 was written instead
+func notModified(w http.ResponseWriter, r *http.Request, status int, body []byte) bool {
+	if status != http.StatusOK || r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	h := w.Header()
+	etag := h.Get("ETag")
+	if etag == "" {
+		etag = contentETag(body)
+		h.Set("ETag", etag)
+	}
+	if inm := r.Header.Get("If-None-Match"); inm == "" || !etagMatches(inm, etag) {
+		return false
+	}
+
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	writeStatus(w, http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,149 @@
+package tobingo
+
+import (
+	"net/http"
+	"testing"
+)
+
+// etagRouter serves JSON for every method under the router default, with per-call variants
+func etagRouter() *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.JSONETags(true)
+	user := func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]string{"name": r.URL.Query().Get("name")})
+	}
+	rt.GET("/user", user)
+	rt.addRoute("POST", "/user", user)
+	rt.addRoute("PUT", "/user", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusCreated, map[string]string{"name": "ann"})
+	})
+	rt.GET("/created", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusCreated, map[string]string{"name": "ann"})
+	})
+	rt.GET("/untagged", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]string{"name": "ann"}, JSONOpts{})
+	})
+	rt.GET("/versioned", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v7"`)
+		JSON(w, http.StatusOK, map[string]string{"name": "ann"})
+	})
+	return rt
+}
+
+func TestJSONETag(t *testing.T) {
+	rt := etagRouter()
+
+	first := rt.Test("GET", "/user?name=ann", nil)
+	etag := first.Header("ETag")
+	if first.StatusCode() != http.StatusOK || len(etag) < 3 || etag[0] != '"' || first.BodyString() != `{"name":"ann"}`+"\n" {
+		t.Fatalf("first request: %d, ETag %q, body %q", first.StatusCode(), etag, first.BodyString())
+	}
+	if again := rt.Test("GET", "/user?name=ann", nil).Header("ETag"); again != etag {
+		t.Errorf("same body tagged %q then %q", etag, again)
+	}
+	if other := rt.Test("GET", "/user?name=bob", nil).Header("ETag"); other == etag {
+		t.Error("different bodies share a tag")
+	}
+
+	for name, ifNoneMatch := range map[string]string{
+		"exact":    etag,
+		"weak":     "W/" + etag,
+		"in list":  `"stale", ` + etag,
+		"wildcard": "*",
+	} {
+		res := rt.Test("GET", "/user?name=ann", nil, WithTestHeader("If-None-Match", ifNoneMatch))
+		if res.StatusCode() != http.StatusNotModified || res.BodyString() != "" || res.Header("ETag") != etag {
+			t.Errorf("%s: %d, body %q, ETag %q", name, res.StatusCode(), res.BodyString(), res.Header("ETag"))
+		}
+		if res.Header("Content-Type") != "" || res.Header("Content-Length") != "" {
+			t.Errorf("%s: 304 kept Content-Type %q, Content-Length %q", name, res.Header("Content-Type"), res.Header("Content-Length"))
+		}
+	}
+
+	// A stale tag gets the body
+	res := rt.Test("GET", "/user?name=ann", nil, WithTestHeader("If-None-Match", `"stale", W/"older"`))
+	if res.StatusCode() != http.StatusOK || res.BodyString() == "" {
+		t.Errorf("stale tag: %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	// HEAD is conditional like GET
+	if res := rt.Test("HEAD", "/user?name=ann", nil, WithTestHeader("If-None-Match", etag)); res.StatusCode() != http.StatusNotModified {
+		t.Errorf("HEAD = %d, want 304", res.StatusCode())
+	}
+}
+
+func TestJSONETagExemptions(t *testing.T) {
+	rt := etagRouter()
+	etag := rt.Test("GET", "/user?name=ann", nil).Header("ETag")
+
+	for name, tc := range map[string]struct {
+		method, target string
+		status         int
+	}{
+		"POST":         {"POST", "/user?name=ann", http.StatusOK},
+		"PUT 201":      {"PUT", "/user", http.StatusCreated},
+		"GET 201":      {"GET", "/created", http.StatusCreated},
+		"per-call off": {"GET", "/untagged", http.StatusOK},
+	} {
+		res := rt.Test(tc.method, tc.target, nil, WithTestHeader("If-None-Match", "*"))
+		if res.StatusCode() != tc.status || res.Header("ETag") != "" || res.BodyString() == "" {
+			t.Errorf("%s: %d, ETag %q, body %q", name, res.StatusCode(), res.Header("ETag"), res.BodyString())
+		}
+	}
+	if res := rt.Test("POST", "/user?name=ann", nil, WithTestHeader("If-None-Match", etag)); res.StatusCode() != http.StatusOK {
+		t.Errorf("POST with a matching tag = %d", res.StatusCode())
+	}
+
+	// A tag set by the handler is used as it is
+	res := rt.Test("GET", "/versioned", nil, WithTestHeader("If-None-Match", `W/"v7"`))
+	if res.StatusCode() != http.StatusNotModified || res.Header("ETag") != `"v7"` {
+		t.Errorf("handler's tag: %d, ETag %q", res.StatusCode(), res.Header("ETag"))
+	}
+}
+
+func TestJSONETagPerCall(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/default", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, []int{1, 2})
+	})
+	rt.GET("/tagged", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, []int{1, 2}, JSONOpts{ETag: true})
+	})
+
+	if res := rt.Test("GET", "/default", nil); res.Header("ETag") != "" {
+		t.Errorf("tagged without JSONETags: %q", res.Header("ETag"))
+	}
+	etag := rt.Test("GET", "/tagged", nil).Header("ETag")
+	if etag == "" {
+		t.Fatal("JSONOpts.ETag did not tag the response")
+	}
+	if res := rt.Test("GET", "/tagged", nil, WithTestHeader("If-None-Match", etag)); res.StatusCode() != http.StatusNotModified {
+		t.Errorf("per-call 304 = %d", res.StatusCode())
+	}
+
+	rt.JSONETags(true)
+	if res := rt.Test("GET", "/default", nil); res.Header("ETag") != etag {
+		t.Errorf("after JSONETags(true): ETag %q, want %q", res.Header("ETag"), etag)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	for _, tc := range []struct {
+		header, etag string
+		want         bool
+	}{
+		{`"a"`, `"a"`, true},
+		{`W/"a"`, `"a"`, true},
+		{`"a"`, `W/"a"`, true},
+		{` "x" , "a" `, `"a"`, true},
+		{`*`, `"a"`, true},
+		{`"b"`, `"a"`, false},
+		{`"a`, `"a"`, false},
+		{`a`, `"a"`, false},
+		{``, `"a"`, false},
+	} {
+		if got := etagMatches(tc.header, tc.etag); got != tc.want {
+			t.Errorf("etagMatches(%q, %q) = %v", tc.header, tc.etag, got)
+		}
+	}
+}
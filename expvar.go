@@ -0,0 +1,52 @@
+package tobingo
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+)
+
+// routerStats holds the counters published by PublishExpvars
+type routerStats struct {
+	requests    expvar.Int    // Requests served
+	inFlight    expvar.Int    // Requests being served
+	statusClass [5]expvar.Int // Responses by status class, 1xx to 5xx
+	notFound    expvar.Int    // 404 responses
+	panics      expvar.Int    // Panics recovered by the router
+}
+
+// PublishExpvars publishes request counters under name in expvar, shown by /debug/vars:
+// requests, in_flight, responses_1xx through responses_5xx, not_found, and panics;
+// counting starts with the call and costs nothing before it
+// expvar names are process-wide, so publishing a name twice panics like expvar.Publish
+// Example: rt.PublishExpvars("tobingo")
+func (rt *Rastauter) PublishExpvars(name string) {
+	stats := new(routerStats)
+	m := expvar.NewMap(name)
+	m.Set("requests", &stats.requests)
+	m.Set("in_flight", &stats.inFlight)
+	for i := range stats.statusClass {
+		m.Set("responses_"+strconv.Itoa(i+1)+"xx", &stats.statusClass[i])
+	}
+	m.Set("not_found", &stats.notFound)
+	m.Set("panics", &stats.panics)
+	rt.stats.Store(stats)
+}
+
+// begin counts a request the router starts serving
+func (s *routerStats) begin() {
+	s.requests.Add(1)
+	s.inFlight.Add(1)
+}
+
+// end counts a finished request by the status it was answered with
+// Panics are counted by recoverPanic, which knows about http.ErrAbortHandler
+func (s *routerStats) end(status int) {
+	s.inFlight.Add(-1)
+	if class := status/100 - 1; class >= 0 && class < len(s.statusClass) {
+		s.statusClass[class].Add(1)
+	}
+	if status == http.StatusNotFound {
+		s.notFound.Add(1)
+	}
+}
@@ -0,0 +1,87 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestPublishExpvars(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	rt.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	rt.PublishExpvars("tobingo_test")
+
+	rt.Test("GET", "/ok", nil)
+	rt.Test("GET", "/ok", nil)
+	rt.Test("GET", "/missing", nil)
+	rt.Test("GET", "/panic", nil)
+
+	var got map[string]int
+	if err := json.Unmarshal([]byte(expvar.Get("tobingo_test").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"requests": 4, "in_flight": 0, "not_found": 1, "panics": 1,
+		"responses_1xx": 0, "responses_2xx": 2, "responses_3xx": 0, "responses_4xx": 1, "responses_5xx": 1,
+	}
+	if len(got) != len(want) {
+		t.Errorf("published %v, want the keys of %v", got, want)
+	}
+	for key, n := range want {
+		if got[key] != n {
+			t.Errorf("%s = %d, want %d", key, got[key], n)
+		}
+	}
+}
+
+// expvarCount reads one counter of the map published under name
+func expvarCount(t *testing.T, name, key string) int {
+	t.Helper()
+	var got map[string]int
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got[key]
+}
+
+func TestPublishExpvarsInFlightAndClasses(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	var during int
+	rt.GET("/busy", func(w http.ResponseWriter, r *http.Request) {
+		during = expvarCount(t, "tobingo_test_flight", "in_flight")
+	})
+	rt.GET("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/busy", http.StatusFound)
+	})
+	rt.GET("/teapot", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+
+	// Requests before publishing aren't counted
+	rt.Test("GET", "/teapot", nil)
+	rt.PublishExpvars("tobingo_test_flight")
+
+	rt.Test("GET", "/busy", nil)
+	rt.Test("GET", "/moved", nil)
+	rt.Test("GET", "/teapot", nil)
+	if during != 1 {
+		t.Errorf("in_flight while serving = %d, want 1", during)
+	}
+	for key, want := range map[string]int{"requests": 3, "in_flight": 0, "responses_2xx": 1, "responses_3xx": 1, "responses_4xx": 1, "not_found": 0} {
+		if got := expvarCount(t, "tobingo_test_flight", key); got != want {
+			t.Errorf("%s = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestPublishExpvarsTwicePanics(t *testing.T) {
+	NewRastaRouterInitializer().PublishExpvars("tobingo_test_twice")
+	defer func() {
+		if recover() == nil {
+			t.Error("publishing a name twice didn't panic")
+		}
+	}()
+	NewRastaRouterInitializer().PublishExpvars("tobingo_test_twice")
+}
@@ -0,0 +1,197 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File serves the file at path with http.ServeContent, so Range requests, conditional
+// requests, and Content-Type detection from the extension work as with http.ServeFile
+// It is meant for handlers that resolve the path themselves, e.g. after authorization:
+// paths with ".." elements are rejected with 400, and directories answer 404 instead of
+// being listed or redirected
+// Example: tobingo.File(w, r, filepath.Join(uploadsDir, doc.StoredName))
+func File(w http.ResponseWriter, r *http.Request, path string) {
+	if hasDotDot(filepath.ToSlash(path)) {
+		fileError(w, r, fs.ErrInvalid)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fileError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	serveFile(w, r, f, filepath.Base(path))
+}
+
+// FileFS serves the named file of fsys like File, for embedded or otherwise virtual assets
+// name must be a valid fs.FS path, which rules out ".." elements and leading slashes
+// Example: tobingo.FileFS(w, r, assets, "static/logo.svg")
+func FileFS(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	if !fs.ValidPath(name) {
+		fileError(w, r, fs.ErrInvalid)
+		return
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		fileError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	serveFile(w, r, f, name)
+}
+
+// serveFile serves an opened file, answering 404 for directories
+func serveFile(w http.ResponseWriter, r *http.Request, f fs.File, name string) {
+	info, err := f.Stat()
+	if err != nil {
+		fileError(w, r, err)
+		return
+	}
+	if info.IsDir() {
+		fileError(w, r, fs.ErrNotExist)
+		return
+	}
+	serveContent(w, r, f, name, modTime(info))
+}
+
+// serveContent serves an opened regular file with http.ServeContent
+// A zero modtime leaves out Last-Modified
+func serveContent(w http.ResponseWriter, r *http.Request, f fs.File, name string, modtime time.Time) {
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// ServeContent needs to seek for Range requests and type sniffing
+		b, err := io.ReadAll(f)
+		if err != nil {
+			fileError(w, r, err)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	http.ServeContent(w, r, filepath.Base(name), modtime, content)
+}
+
+// modTime returns the modification time, or the zero time for files that don't record one
+// such as embed.FS entries, which makes ServeContent skip Last-Modified
+func modTime(info fs.FileInfo) time.Time {
+	t := info.ModTime()
+	if t.Unix() <= 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+// fileError maps a filesystem error to a response without leaking the underlying message
+// A Content-Disposition set by Attachment is dropped so the error page isn't downloaded
+func fileError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Del("Content-Disposition")
+	switch {
+	case errors.Is(err, fs.ErrInvalid):
+		http.Error(w, "invalid file path", http.StatusBadRequest)
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		handleError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// hasDotDot reports whether any element of the slash separated path p is ".."
+func hasDotDot(p string) bool {
+	for elem := range strings.SplitSeq(p, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// Attachment serves the file at path like File with a Content-Disposition header that makes
+// browsers download it as downloadName, which may contain any UTF-8 characters
+// Example: tobingo.Attachment(w, r, reportPath, "Отчёт 2024.pdf")
+func Attachment(w http.ResponseWriter, r *http.Request, path, downloadName string) {
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", downloadName))
+	File(w, r, path)
+}
+
+// AttachmentReader sends the contents of rd as a download named downloadName
+// When rd can seek it is served with http.ServeContent, so Range and conditional requests
+// work; otherwise it is streamed like BlobReader with size as Content-Length (-1 for chunked)
+func AttachmentReader(w http.ResponseWriter, r *http.Request, contentType, downloadName string, rd io.Reader, size int64) error {
+	h := w.Header()
+	h.Set("Content-Disposition", contentDisposition("attachment", downloadName))
+
+	if seeker, ok := rd.(io.ReadSeeker); ok {
+		h.Set("Content-Type", contentType)
+		http.ServeContent(w, r, downloadName, time.Time{}, seeker)
+		return nil
+	}
+	return BlobReader(w, http.StatusOK, contentType, rd, size)
+}
+
+// contentDisposition formats a Content-Disposition header carrying filename both as a quoted
+// ASCII fallback and, when it has other characters, as an RFC 5987 filename* parameter
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(c)
+		case c < 0x20 || c == 0x7f:
+			fallback.WriteByte('_')
+		case c > 0x7e:
+			ascii = false
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+
+	header := disposition + `; filename="` + fallback.String() + `"`
+	if !ascii {
+		header += "; filename*=UTF-8''" + rfc5987Escape(filename)
+	}
+	return header
+}
+
+// rfc5987Escape percent-encodes every byte of s outside the RFC 5987 attr-char set
+func rfc5987Escape(s string) string {
+	const upperhex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(upperhex[c>>4])
+		b.WriteByte(upperhex[c&15])
+	}
+	return b.String()
+}
+
+// isAttrChar reports whether c may appear unencoded in an RFC 5987 value
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
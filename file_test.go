@@ -0,0 +1,170 @@
+package tobingo
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fileRouter serves the files of dir named by the catch-all with File
+func fileRouter(t *testing.T) (*Rastauter, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	modtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(filepath.Join(dir, "notes.txt"), modtime, modtime)
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+
+	rt := NewRastaRouterInitializer()
+	rt.GET("/files/*name", func(w http.ResponseWriter, r *http.Request) {
+		File(w, r, dir+"/"+GetParam(r, "name"))
+	})
+	return rt, modtime
+}
+
+func TestFile(t *testing.T) {
+	rt, modtime := fileRouter(t)
+
+	res := rt.Test("GET", "/files/notes.txt", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "0123456789" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if ct := res.Header("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if lm := res.Header("Last-Modified"); lm != modtime.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+}
+
+func TestFileRange(t *testing.T) {
+	rt, _ := fileRouter(t)
+	res := rt.Test("GET", "/files/notes.txt", nil, WithTestHeader("Range", "bytes=2-4"))
+	if res.StatusCode() != http.StatusPartialContent || res.BodyString() != "234" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if cr := res.Header("Content-Range"); cr != "bytes 2-4/10" {
+		t.Errorf("Content-Range = %q", cr)
+	}
+}
+
+func TestFileConditional(t *testing.T) {
+	rt, modtime := fileRouter(t)
+	res := rt.Test("GET", "/files/notes.txt", nil, WithTestHeader("If-Modified-Since", modtime.Format(http.TimeFormat)))
+	if res.StatusCode() != http.StatusNotModified || res.BodyString() != "" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	rt, _ := fileRouter(t)
+	for path, want := range map[string]int{
+		"/files/sub":          http.StatusNotFound,
+		"/files/sub/":         http.StatusNotFound,
+		"/files/missing.txt":  http.StatusNotFound,
+		"/files/../notes.txt": http.StatusBadRequest,
+	} {
+		res := rt.Test("GET", path, nil)
+		if res.StatusCode() != want || res.Header("Location") != "" {
+			t.Errorf("GET %s = %d, Location %q; want %d", path, res.StatusCode(), res.Header("Location"), want)
+		}
+	}
+}
+
+func TestFileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/logo.svg": {Data: []byte("<svg/>")},
+		"static/css":      {Mode: os.ModeDir},
+	}
+	rt := NewRastaRouterInitializer()
+	rt.GET("/assets/*name", func(w http.ResponseWriter, r *http.Request) {
+		FileFS(w, r, fsys, GetParam(r, "name"))
+	})
+
+	res := rt.Test("GET", "/assets/static/logo.svg", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "<svg/>" || res.Header("Content-Type") != "image/svg+xml" {
+		t.Errorf("got %d %q %q", res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+	}
+	// MapFS entries without a modtime don't claim one
+	if lm := res.Header("Last-Modified"); lm != "" {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+	for path, want := range map[string]int{
+		"/assets/static/css":  http.StatusNotFound,
+		"/assets/static/nope": http.StatusNotFound,
+		"/assets/../secret":   http.StatusBadRequest,
+	} {
+		if code := rt.Test("GET", path, nil).StatusCode(); code != want {
+			t.Errorf("GET %s = %d, want %d", path, code, want)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	for name, want := range map[string]string{
+		"report.pdf":     `attachment; filename="report.pdf"`,
+		"q1 report.pdf":  `attachment; filename="q1 report.pdf"`,
+		"Отчёт.pdf":      `attachment; filename="_____.pdf"; filename*=UTF-8''%D0%9E%D1%82%D1%87%D1%91%D1%82.pdf`,
+		`say "hi".txt`:   `attachment; filename="say \"hi\".txt"`,
+		"a\\b\r\n.txt":   `attachment; filename="a\\b__.txt"`,
+		"naïve café.txt": `attachment; filename="na_ve caf_.txt"; filename*=UTF-8''na%C3%AFve%20caf%C3%A9.txt`,
+	} {
+		if got := contentDisposition("attachment", name); got != want {
+			t.Errorf("%q:\n got %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "r.csv"), []byte("a,b"), 0o644)
+	rt := NewRastaRouterInitializer()
+	rt.GET("/file", func(w http.ResponseWriter, r *http.Request) {
+		Attachment(w, r, filepath.Join(dir, "r.csv"), "Отчёт 2024.csv")
+	})
+	rt.GET("/missing", func(w http.ResponseWriter, r *http.Request) {
+		Attachment(w, r, filepath.Join(dir, "nope.csv"), "nope.csv")
+	})
+
+	res := rt.Test("GET", "/file", nil)
+	if res.BodyString() != "a,b" || !strings.Contains(res.Header("Content-Disposition"), "filename*=UTF-8''%D0%9E") {
+		t.Errorf("got %q, Content-Disposition %q", res.BodyString(), res.Header("Content-Disposition"))
+	}
+
+	// The error page isn't offered as a download
+	res = rt.Test("GET", "/missing", nil)
+	if res.StatusCode() != http.StatusNotFound || res.Header("Content-Disposition") != "" {
+		t.Errorf("missing = %d, Content-Disposition %q", res.StatusCode(), res.Header("Content-Disposition"))
+	}
+}
+
+func TestAttachmentReader(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/seeker", func(w http.ResponseWriter, r *http.Request) {
+		AttachmentReader(w, r, "text/csv", "export.csv", strings.NewReader("0123456789"), 10)
+	})
+	rt.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		AttachmentReader(w, r, "text/csv", "export.csv", io.MultiReader(strings.NewReader("0123456789")), -1)
+	})
+
+	res := rt.Test("GET", "/seeker", nil, WithTestHeader("Range", "bytes=0-3"))
+	if res.StatusCode() != http.StatusPartialContent || res.BodyString() != "0123" || res.Header("Content-Type") != "text/csv" {
+		t.Errorf("seeker = %d %q %q", res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+	}
+
+	// Without a seeker the whole body is sent and Range ignored
+	res = rt.Test("GET", "/stream", nil, WithTestHeader("Range", "bytes=0-3"))
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "0123456789" {
+		t.Errorf("stream = %d %q", res.StatusCode(), res.BodyString())
+	}
+	if cd := res.Header("Content-Disposition"); cd != `attachment; filename="export.csv"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+}
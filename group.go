@@ -0,0 +1,186 @@
+package tobingo
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Group registers routes under a common path prefix, optionally with its own error handler
+// Groups are created with Rastauter.Group and nest with Group.Group
+type Group struct {
+	rt           *Rastauter
+	prefix       string                           // Full prefix including those of enclosing groups, e.g. "/api/v1"
+	parent       *Group                           // Enclosing group, nil at the top level
+	errorHandler atomic.Pointer[ErrorHandlerFunc] // Set with ErrorHandler
+	limiter      *rateLimiter                     // Set with RateLimit, nil for none
+	middleware   []Middleware                     // Added with Use, applied to routes registered afterwards
+}
+
+// Group returns a group registering routes under prefix, such as "/api"
+// Example: api := rt.Group("/api"); api.GET("/users/:id", getUser)
+func (rt *Rastauter) Group(prefix string) *Group {
+	return rt.newGroup(nil, prefix)
+}
+
+// Group returns a group nested in g, registering routes under g's prefix followed by prefix
+func (g *Group) Group(prefix string) *Group {
+	return g.rt.newGroup(g, g.prefix+prefix)
+}
+
+// newGroup records a group so unmatched requests under its prefix find its error handler
+func (rt *Rastauter) newGroup(parent *Group, prefix string) *Group {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("%v: group prefix %q must start with /", ErrInvalidPattern, prefix))
+	}
+
+	g := &Group{rt: rt, prefix: prefix, parent: parent}
+	rt.mu.Lock()
+	var groups []*Group
+	if old := rt.groups.Load(); old != nil {
+		groups = slices.Clone(*old)
+	}
+	groups = append(groups, g)
+	rt.groups.Store(&groups)
+	rt.mu.Unlock()
+	return g
+}
+
+// GET registers a GET route under the group's prefix, panicking like Rastauter.GET
+func (g *Group) GET(path string, handler http.HandlerFunc) *Route {
+	return g.Handle(http.MethodGet, path, handler)
+}
+
+// Handle registers a route for any method under the group's prefix, panicking like
+// Rastauter.GET when it can't be added
+func (g *Group) Handle(method, path string, handler http.HandlerFunc) *Route {
+	route := g.rt.addRoute(method, g.path(path), handler)
+	route.group = g
+	if mw := g.inheritedMiddleware(); len(mw) > 0 {
+		route.Middleware = mw
+		route.buildChain()
+	}
+	return route
+}
+
+// path joins the group's prefix and path, "/" standing for the prefix itself
+func (g *Group) path(path string) string {
+	if path == "" || path == "/" {
+		return cmp.Or(g.prefix, "/")
+	}
+	return g.prefix + path
+}
+
+// ErrorHandler sets how errors of the group's routes and nested groups are rendered,
+// overriding the router's, e.g. JSON problem details for an API group and an error page
+// for the HTML one; unmatched requests under the prefix are answered with it too, as 404
+// Example: rt.Group("/api").ErrorHandler(problemJSON)
+func (g *Group) ErrorHandler(h ErrorHandlerFunc) *Group {
+	g.errorHandler.Store(&h)
+	return g
+}
+
+// ErrorHandler sets how errors of this route are rendered, overriding its group's and the
+// router's; like metadata it must be set before serving
+func (route *Route) ErrorHandler(h ErrorHandlerFunc) *Route {
+	route.errorHandler = h
+	return route
+}
+
+// errorHandlerFor resolves the error handler of route innermost first, through its groups
+// to the router's; nil means DefaultErrorHandler
+func (rt *Rastauter) errorHandlerFor(route *Route) ErrorHandlerFunc {
+	if route != nil {
+		if route.errorHandler != nil {
+			return route.errorHandler
+		}
+		if h := route.group.inheritedErrorHandler(); h != nil {
+			return h
+		}
+	}
+	if h := rt.errorHandler.Load(); h != nil {
+		return *h
+	}
+	return nil
+}
+
+// inheritedErrorHandler returns the first error handler set on g or an enclosing group, nil
+// if none
+func (g *Group) inheritedErrorHandler() ErrorHandlerFunc {
+	for ; g != nil; g = g.parent {
+		if h := g.errorHandler.Load(); h != nil && *h != nil {
+			return *h
+		}
+	}
+	return nil
+}
+
+// groupFor returns the innermost group whose prefix covers path, nil if none does
+func (rt *Rastauter) groupFor(path string) *Group {
+	groups := rt.groups.Load()
+	if groups == nil {
+		return nil
+	}
+	var best *Group
+	for _, g := range *groups {
+		if g.prefix == "" || path != g.prefix && !strings.HasPrefix(path, g.prefix+"/") {
+			continue
+		}
+		if best == nil || len(g.prefix) > len(best.prefix) {
+			best = g
+		}
+	}
+	return best
+}
+
+// Use adds middleware to the routes registered through the group and its nested groups
+// afterwards; it runs after route matching, inside the router's middleware, so it sees the
+// path parameters
+// Example: api := rt.Group("/api"); api.Use(tobingo.CircuitBreaker(tobingo.CircuitBreakerOptions{}))
+func (g *Group) Use(mw ...Middleware) *Group {
+	for i, m := range mw {
+		if m == nil {
+			panic(fmt.Sprintf("tobingo: Group.Use: middleware %d is nil", i))
+		}
+	}
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+// Use adds middleware to this route alone, inside that of its groups; like Group.Use it runs
+// after route matching and must be added before serving
+// Example: rt.GET("/reports", reports).Use(tobingo.CircuitBreaker(tobingo.CircuitBreakerOptions{}))
+func (route *Route) Use(mw ...Middleware) *Route {
+	for i, m := range mw {
+		if m == nil {
+			panic(fmt.Sprintf("tobingo: Route.Use: middleware %d is nil", i))
+		}
+	}
+	route.Middleware = append(route.Middleware, mw...)
+	route.buildChain()
+	return route
+}
+
+// buildChain wraps the route's handler in its middleware, the first added outermost
+func (route *Route) buildChain() {
+	var h http.Handler = route.Handler
+	for i := len(route.Middleware) - 1; i >= 0; i-- {
+		if h = route.Middleware[i](h); h == nil {
+			panic(fmt.Sprintf("tobingo: route %s %q: middleware %d returned a nil handler", route.Method, route.Path, i))
+		}
+	}
+	route.chain = h
+}
+
+// inheritedMiddleware returns the middleware of g and its enclosing groups, outermost first
+func (g *Group) inheritedMiddleware() []Middleware {
+	var mw []Middleware
+	for ; g != nil; g = g.parent {
+		mw = append(slices.Clone(g.middleware), mw...)
+	}
+	return mw
+}
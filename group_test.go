@@ -0,0 +1,141 @@
+package tobingo
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// jsonErrors renders errors as a JSON object
+func jsonErrors(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"status":` + strconv.Itoa(status) + `}`))
+}
+
+// htmlErrors renders errors as an HTML page
+func htmlErrors(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	w.Write([]byte("<h1>" + http.StatusText(status) + "</h1>"))
+}
+
+// failing answers with a 500 through the error handler
+func failing(w http.ResponseWriter, r *http.Request) {
+	handleError(w, r, http.StatusInternalServerError, errors.New("boom"))
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	api := rt.Group("/api").ErrorHandler(jsonErrors)
+	api.GET("/fail", failing)
+	api.Group("/v1").GET("/fail", failing)
+	api.GET("/own", failing).ErrorHandler(htmlErrors)
+	rt.Group("/pages").ErrorHandler(htmlErrors).GET("/fail", failing)
+	rt.GET("/fail", failing)
+
+	for path, want := range map[string]string{
+		"/api/fail":    `{"status":500}`,
+		"/api/v1/fail": `{"status":500}`,
+		"/api/own":     "<h1>Internal Server Error</h1>",
+		"/pages/fail":  "<h1>Internal Server Error</h1>",
+		"/fail":        "Internal Server Error\n",
+		"/api/missing": `{"status":404}`,
+		"/pages/nope":  "<h1>Not Found</h1>",
+	} {
+		if got := rt.Test("GET", path, nil).BodyString(); got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+
+	rt.ErrorHandler(jsonErrors)
+	if got := rt.Test("GET", "/fail", nil).BodyString(); got != `{"status":500}` {
+		t.Errorf("router error handler: got %q", got)
+	}
+}
+
+func TestGroupErrorHandlersForPanicsAndErrors(t *testing.T) {
+	var seen []error
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	api := rt.Group("/api").ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		seen = append(seen, err)
+		jsonErrors(w, r, status, err)
+	})
+	admin := api.Group("/admin").ErrorHandler(htmlErrors)
+	api.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	api.GET("/teapot", H(func(r *http.Request) (int, any, error) {
+		return 0, nil, &HTTPError{Status: http.StatusTeapot}
+	}))
+	admin.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	rt.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	rt.GET("/apiary", failing) // Shares the prefix as text only
+
+	for path, want := range map[string]struct {
+		status int
+		body   string
+	}{
+		"/api/panic":       {http.StatusInternalServerError, `{"status":500}`},
+		"/api/teapot":      {http.StatusTeapot, `{"status":418}`},
+		"/api/admin/panic": {http.StatusInternalServerError, "<h1>Internal Server Error</h1>"},
+		"/api/admin/gone":  {http.StatusNotFound, "<h1>Not Found</h1>"},
+		"/api":             {http.StatusNotFound, `{"status":404}`},
+		"/panic":           {http.StatusInternalServerError, "Internal Server Error\n"},
+		"/apiary":          {http.StatusInternalServerError, "Internal Server Error\n"},
+		"/apix":            {http.StatusNotFound, "404 page not found\n"},
+	} {
+		res := rt.Test("GET", path, nil)
+		if res.StatusCode() != want.status || res.BodyString() != want.body {
+			t.Errorf("GET %s = %d %q, want %d %q", path, res.StatusCode(), res.BodyString(), want.status, want.body)
+		}
+	}
+
+	// The group's handler got the converted panic and the handler's error
+	teapots := 0
+	for _, err := range seen {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusTeapot {
+			teapots++
+		}
+	}
+	if len(seen) != 3 || teapots != 1 {
+		t.Errorf("group handler saw %v", seen)
+	}
+
+	// A router NotFound handler still answers outside the groups
+	rt.NotFound(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGone) })
+	if res := rt.Test("GET", "/apix", nil); res.StatusCode() != http.StatusGone {
+		t.Errorf("outside the groups = %d, want the router's NotFound", res.StatusCode())
+	}
+	if res := rt.Test("GET", "/api/missing", nil); res.StatusCode() != http.StatusNotFound || res.BodyString() != `{"status":404}` {
+		t.Errorf("inside the group = %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestErrorHandlersChangeWhileServing(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	api := rt.Group("/api")
+	api.GET("/fail", failing)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				rt.Test("GET", "/api/fail", nil)
+				rt.Test("GET", "/api/missing", nil)
+			}
+		}()
+	}
+	for range 50 {
+		api.ErrorHandler(jsonErrors)
+		rt.ErrorHandler(htmlErrors)
+		rt.NotFound(nil)
+		rt.Group("/other")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,149 @@
+// Package h3 serves a tobingo router over HTTP/3 using github.com/quic-go/quic-go
+// It lives in its own module so the core router stays free of third-party dependencies
+package h3
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ShourovRoy/tobingo"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// StartServer serves rt over HTTP/3 on the UDP address addr, such as ":443"
+// certFile and keyFile are paths to the PEM encoded certificate and private key
+// Calling rt.Shutdown drains the server with the others; StartServer then returns
+// http.ErrServerClosed
+// Example: go h3.StartServer(rt, ":443", "cert.pem", "key.pem")
+func StartServer(rt *tobingo.Rastauter, addr, certFile, keyFile string) error {
+	srv, conn, err := listen(rt, addr, certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return serve(rt, srv, conn)
+}
+
+// StartServers serves rt over HTTPS on the TCP address addr and over HTTP/3 on the UDP
+// port of the same number, advertising HTTP/3 to TCP clients with an Alt-Svc header so
+// browsers upgrade on their next request
+// Both listeners are bound before serving so address errors are returned immediately
+// Calling rt.Shutdown stops both servers; StartServers then returns http.ErrServerClosed
+// Example: log.Fatal(h3.StartServers(rt, ":443", "cert.pem", "key.pem"))
+func StartServers(rt *tobingo.Rastauter, addr, certFile, keyFile string) error {
+	srv, conn, err := listen(rt, addr, certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	// A ":0" address picks the port for UDP, and TCP must share it for Alt-Svc to hold
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	tcp, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(srv.Port)))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	// Advertise the UDP port on every TCP response while the servers run, on the TLS server
+	// only rather than as a ConfigureServer option other servers would inherit
+	defer advertise(rt.Server(), srv)()
+
+	udpErr := make(chan error, 1)
+	go func() {
+		udpErr <- serve(rt, srv, conn)
+	}()
+	tcpErr := rt.ServeTLS(tcp, certFile, keyFile)
+
+	// A TCP listener failing on its own takes the UDP one down with it
+	if !errors.Is(tcpErr, http.ErrServerClosed) {
+		srv.Close()
+	}
+	return errors.Join(tcpErr, ignoreClosed(<-udpErr))
+}
+
+// AltSvc wraps next so that its responses advertise srv with an Alt-Svc header, for TCP
+// servers set up separately from StartServers
+// A next already wrapped by AltSvc is unwrapped first, so srv replaces the server it advertised
+// Example: hs := rt.Server(); hs.Handler = h3.AltSvc(srv, hs.Handler)
+func AltSvc(srv *http3.Server, next http.Handler) http.Handler {
+	if a, ok := next.(*altSvcHandler); ok {
+		next = a.next
+	}
+	a := &altSvcHandler{next: next}
+	a.srv.Store(srv)
+	return a
+}
+
+// altSvcHandler is the handler returned by AltSvc
+type altSvcHandler struct {
+	srv  atomic.Pointer[http3.Server] // HTTP/3 server advertised, none when nil
+	next http.Handler                 // Handler serving the request, never an altSvcHandler itself
+}
+
+// ServeHTTP sets the Alt-Svc header and serves the request with next
+func (h *altSvcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if srv := h.srv.Load(); srv != nil {
+		srv.SetQUICHeaders(w.Header())
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// advertise makes the responses of hs advertise srv until stop is called; the handler of hs
+// is wrapped once, later calls swap the server advertised so a restart doesn't stack wrappers
+// and connections still open on hs don't race a handler change
+func advertise(hs *http.Server, srv *http3.Server) (stop func()) {
+	a, ok := hs.Handler.(*altSvcHandler)
+	if !ok {
+		a = &altSvcHandler{next: hs.Handler}
+		hs.Handler = a
+	}
+	a.srv.Store(srv)
+	return func() { a.srv.CompareAndSwap(srv, nil) }
+}
+
+// listen loads the certificate and binds the UDP socket of an HTTP/3 server for rt
+func listen(rt *tobingo.Rastauter, addr, certFile, keyFile string) (*http3.Server, net.PacketConn, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv := &http3.Server{
+		Handler:   rt,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		Port:      conn.LocalAddr().(*net.UDPAddr).Port,
+	}
+	return srv, conn, nil
+}
+
+// serve registers srv with rt's shutdown and lifecycle hooks, then serves on conn until
+// Shutdown, reporting the end of a graceful shutdown as http.ErrServerClosed like net/http
+func serve(rt *tobingo.Rastauter, srv *http3.Server, conn net.PacketConn) error {
+	rt.TrackCompanion(srv)
+	rt.NotifyListen(conn.LocalAddr())
+
+	err := srv.Serve(conn)
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, quic.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+		return http.ErrServerClosed
+	}
+	return err
+}
+
+// ignoreClosed drops http.ErrServerClosed, which StartServers reports once for both servers
+func ignoreClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+package h3
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShourovRoy/tobingo"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and returns the file paths
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+// startServers runs StartServers on a random port, returning the addresses reported to
+// OnListen and the channel StartServers' result arrives on
+func startServers(t *testing.T, rt *tobingo.Rastauter) (tcp, udp net.Addr, done chan error) {
+	t.Helper()
+	certFile, keyFile := writeCert(t)
+
+	addrs := make(chan net.Addr, 2)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+	done = make(chan error, 1)
+	go func() { done <- StartServers(rt, "127.0.0.1:0", certFile, keyFile) }()
+
+	for range 2 {
+		select {
+		case addr := <-addrs:
+			if addr.Network() == "udp" {
+				udp = addr
+			} else {
+				tcp = addr
+			}
+		case err := <-done:
+			t.Fatalf("StartServers: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("listeners not reported")
+		}
+	}
+	return tcp, udp, done
+}
+
+func TestStartServersServesBothProtocols(t *testing.T) {
+	rt := tobingo.NewRastaRouterInitializer()
+	rt.GET("/proto", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "user "+tobingo.Params(r)["id"])
+	})
+	tcp, udp, done := startServers(t, rt)
+
+	if tcp.(*net.TCPAddr).Port != udp.(*net.UDPAddr).Port {
+		t.Fatalf("TCP port %v and UDP port %v differ", tcp, udp)
+	}
+
+	// TCP responses advertise the UDP port
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: insecure}}
+	resp, err := client.Get("https://" + tcp.String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if altSvc := resp.Header.Get("Alt-Svc"); !strings.Contains(altSvc, "h3=") {
+		t.Errorf("Alt-Svc = %q, want an h3 entry", altSvc)
+	}
+
+	h3Transport := &http3.Transport{TLSClientConfig: insecure}
+	defer h3Transport.Close()
+	resp, err = (&http.Client{Transport: h3Transport}).Get("https://" + udp.String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/3.0" {
+		t.Errorf("proto = %q, want HTTP/3.0", body)
+	}
+	resp, err = (&http.Client{Transport: h3Transport}).Get("https://" + udp.String() + "/users/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "user 42" {
+		t.Errorf("param route over HTTP/3 = %q, want %q", body, "user 42")
+	}
+
+	// One Shutdown stops both
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Fatalf("StartServers returned %v, want http.ErrServerClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartServers didn't return after Shutdown")
+	}
+
+	// The advertisement ends with the servers
+	if a, ok := rt.Server().Handler.(*altSvcHandler); !ok || a.srv.Load() != nil {
+		t.Errorf("TLS server handler %T still advertises HTTP/3", rt.Server().Handler)
+	}
+}
+
+// quicServer serves an HTTP/3 server on a random port until the test ends, returning once
+// it advertises itself
+func quicServer(t *testing.T) *http3.Server {
+	t.Helper()
+	certFile, keyFile := writeCert(t)
+	srv, conn, err := listen(tobingo.NewRastaRouterInitializer(), "127.0.0.1:0", certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(conn)
+	t.Cleanup(func() { srv.Close() })
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.SetQUICHeaders(http.Header{}) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("HTTP/3 server not serving")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return srv
+}
+
+// altSvcHeader returns the Alt-Svc header h sends
+func altSvcHeader(h http.Handler) string {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	return strings.Join(rec.Header().Values("Alt-Svc"), ", ")
+}
+
+func TestAltSvcReplacesEarlierServer(t *testing.T) {
+	first, second := quicServer(t), quicServer(t)
+	firstPort, secondPort := ":"+strconv.Itoa(first.Port), ":"+strconv.Itoa(second.Port)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	h := AltSvc(second, AltSvc(first, next))
+	if got := altSvcHeader(h); !strings.Contains(got, secondPort) || strings.Contains(got, firstPort) {
+		t.Errorf("Alt-Svc = %q, want only the second server", got)
+	}
+	if a := h.(*altSvcHandler); a.next == nil || reflect.ValueOf(a.next).Pointer() != reflect.ValueOf(next).Pointer() {
+		t.Error("AltSvc stacked on an earlier wrapper")
+	}
+}
+
+func TestAdvertiseWrapsOnce(t *testing.T) {
+	rt := tobingo.NewRastaRouterInitializer()
+	hs := rt.Server()
+	first, second := quicServer(t), quicServer(t)
+	firstPort, secondPort := ":"+strconv.Itoa(first.Port), ":"+strconv.Itoa(second.Port)
+
+	stopFirst := advertise(hs, first)
+	wrapped := hs.Handler
+	if got := altSvcHeader(wrapped); !strings.Contains(got, firstPort) {
+		t.Errorf("Alt-Svc = %q, want the first server", got)
+	}
+
+	// A restart swaps the server advertised, and the first run ending late leaves it alone
+	stopSecond := advertise(hs, second)
+	if hs.Handler != wrapped || hs.Handler.(*altSvcHandler).next != http.Handler(rt) {
+		t.Fatalf("handler %T rewrapped", hs.Handler)
+	}
+	stopFirst()
+	if got := altSvcHeader(hs.Handler); !strings.Contains(got, secondPort) || strings.Contains(got, firstPort) {
+		t.Errorf("Alt-Svc = %q, want only the second server", got)
+	}
+	stopSecond()
+	if got := altSvcHeader(hs.Handler); got != "" {
+		t.Errorf("Alt-Svc = %q once stopped, want none", got)
+	}
+}
+
+func TestStartServerBadCertificate(t *testing.T) {
+	rt := tobingo.NewRastaRouterInitializer()
+	if err := StartServer(rt, "127.0.0.1:0", "missing.pem", "missing.key"); err == nil {
+		t.Fatal("StartServer succeeded without a certificate")
+	}
+}
@@ -0,0 +1,157 @@
+package tobingo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthOptions configures the endpoints registered by EnableHealthEndpoints
+// Zero values fall back to the defaults noted on each field
+type HealthOptions struct {
+	LivenessPath  string        // Liveness route, "/healthz" by default
+	ReadinessPath string        // Readiness route, "/readyz" by default
+	CheckTimeout  time.Duration // Deadline for all readiness checks of one request, 5 seconds by default
+}
+
+// readinessCheck is a named check registered with AddReadinessCheck
+type readinessCheck struct {
+	name string                          // Name reported in the readiness response
+	fn   func(ctx context.Context) error // Returns nil when the dependency is ready
+}
+
+// checkResult is the JSON representation of a single readiness check
+type checkResult struct {
+	Status string `json:"status"`          // "ok" or "fail"
+	Error  string `json:"error,omitempty"` // Error message of a failing check
+}
+
+// healthResponse is the JSON body returned by the health endpoints
+type healthResponse struct {
+	Status string                 `json:"status"`           // "ok", "unavailable", or "shutting_down"
+	Checks map[string]checkResult `json:"checks,omitempty"` // Per-check results for readiness
+}
+
+// AddReadinessCheck registers a check consulted by the readiness endpoint
+// The endpoint returns 503 if any check returns an error, reporting every check by name
+// Checks run concurrently and share the CheckTimeout deadline passed in ctx
+func (rt *Rastauter) AddReadinessCheck(name string, fn func(ctx context.Context) error) {
+	rt.healthMu.Lock()
+	rt.readinessChecks = append(rt.readinessChecks, readinessCheck{name: name, fn: fn})
+	rt.healthMu.Unlock()
+}
+
+// EnableHealthEndpoints registers a liveness route that always answers 200 while the process
+// runs and a readiness route that aggregates the checks added with AddReadinessCheck
+// Readiness answers 503 as soon as Shutdown begins so load balancers stop sending traffic
+// while in-flight requests drain
+func (rt *Rastauter) EnableHealthEndpoints(opts HealthOptions) {
+	if opts.LivenessPath == "" {
+		opts.LivenessPath = "/healthz"
+	}
+	if opts.ReadinessPath == "" {
+		opts.ReadinessPath = "/readyz"
+	}
+	if opts.CheckTimeout <= 0 {
+		opts.CheckTimeout = 5 * time.Second
+	}
+
+	// Probes arrive every few seconds, so Logger leaves them out unless told otherwise
+	paths := []string{opts.LivenessPath, opts.ReadinessPath}
+	if old := rt.healthPaths.Load(); old != nil {
+		paths = append(paths, *old...)
+	}
+	rt.healthPaths.Store(&paths)
+
+	rt.GET(opts.LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+	})
+
+	rt.GET(opts.ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+		// Report not ready during drain without bothering the checks
+		if rt.shuttingDown.Load() {
+			writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "shutting_down"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), opts.CheckTimeout)
+		defer cancel()
+
+		results := rt.runReadinessChecks(ctx)
+
+		// Any failing check makes the whole service unavailable
+		resp := healthResponse{Status: "ok", Checks: results}
+		status := http.StatusOK
+		for _, result := range results {
+			if result.Status != "ok" {
+				resp.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+				break
+			}
+		}
+		writeHealth(w, status, resp)
+	})
+}
+
+// runReadinessChecks runs every registered check concurrently and collects their results,
+// reporting checks still running when ctx is done as failed so a check ignoring ctx can't
+// hold the endpoint
+func (rt *Rastauter) runReadinessChecks(ctx context.Context) map[string]checkResult {
+	rt.healthMu.RLock()
+	checks := rt.readinessChecks
+	rt.healthMu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]checkResult, len(checks))
+	finished := false
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := checkResult{Status: "ok"}
+			if err := check.fn(ctx); err != nil {
+				result = checkResult{Status: "fail", Error: err.Error()}
+			}
+
+			mu.Lock()
+			if !finished {
+				results[check.name] = result
+			}
+			mu.Unlock()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	// Late checks can't change the answer once it is being written
+	mu.Lock()
+	defer mu.Unlock()
+	finished = true
+	for _, check := range checks {
+		if _, ok := results[check.name]; !ok {
+			results[check.name] = checkResult{Status: "fail", Error: "check did not finish: " + ctx.Err().Error()}
+		}
+	}
+	return results
+}
+
+// writeHealth writes a health response as JSON with the given status code
+func writeHealth(w http.ResponseWriter, status int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,87 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLivenessEndpoint(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.EnableHealthEndpoints(HealthOptions{})
+
+	res := rt.Test("GET", "/healthz", nil)
+	if res.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d, want 200", res.StatusCode())
+	}
+	if res.Header("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", res.Header("Cache-Control"))
+	}
+}
+
+func TestReadinessAggregatesChecks(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.AddReadinessCheck("db", func(ctx context.Context) error { return nil })
+	rt.AddReadinessCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+	rt.EnableHealthEndpoints(HealthOptions{ReadinessPath: "/ready"})
+
+	res := rt.Test("GET", "/ready", nil)
+	if res.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", res.StatusCode())
+	}
+	var body healthResponse
+	if err := res.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "unavailable" || body.Checks["db"].Status != "ok" || body.Checks["cache"].Error != "connection refused" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	release := make(chan struct{})
+	defer close(release)
+	rt.AddReadinessCheck("stuck", func(ctx context.Context) error {
+		<-release // Ignores ctx
+		return nil
+	})
+	rt.AddReadinessCheck("fine", func(ctx context.Context) error { return nil })
+	rt.EnableHealthEndpoints(HealthOptions{CheckTimeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	res := rt.Test("GET", "/readyz", nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("readiness took %v with a 20ms CheckTimeout", elapsed)
+	}
+	if res.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", res.StatusCode())
+	}
+	var body healthResponse
+	if err := res.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+	if stuck := body.Checks["stuck"]; stuck.Status != "fail" || !strings.Contains(stuck.Error, "deadline exceeded") {
+		t.Errorf("stuck check = %+v, want a timeout failure", stuck)
+	}
+	if body.Checks["fine"].Status != "ok" {
+		t.Errorf("fine check = %+v", body.Checks["fine"])
+	}
+}
+
+func TestReadinessDuringShutdown(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.EnableHealthEndpoints(HealthOptions{})
+	rt.shuttingDown.Store(true)
+
+	res := rt.Test("GET", "/readyz", nil)
+	if res.StatusCode() != http.StatusServiceUnavailable || !strings.Contains(res.BodyString(), "shutting_down") {
+		t.Fatalf("got %d %q, want 503 shutting_down", res.StatusCode(), res.BodyString())
+	}
+	if res := rt.Test("GET", "/healthz", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("liveness status = %d during shutdown, want 200", res.StatusCode())
+	}
+}
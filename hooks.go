@@ -0,0 +1,196 @@
+package tobingo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// routerHooks holds the subscribers of the router events, replaced as a whole on change
+type routerHooks struct {
+	match    []func(r *http.Request, pattern string) // Called when a route matched, before its handler
+	notFound []func(r *http.Request)                 // Called when no route matched
+	panic    []func(r *http.Request, recovered any)  // Called when a handler panic was recovered
+
+	errorResponse []errorResponseHook // Called once an error response was sent
+}
+
+// ErrorResponseInfo describes an error response for OnErrorResponse subscribers
+type ErrorResponseInfo struct {
+	Status    int    // Status sent
+	Method    string // Request method
+	Pattern   string // Pattern of the matched route, "" when none matched
+	Path      string // Request path
+	Err       error  // Error the response was rendered for, such as an *HTTPError or *PanicError, when known
+	Recovered any    // Value of a recovered handler panic, nil without one
+	RequestID string // X-Request-ID of the response, or of the request when the response has none
+}
+
+// ErrorResponseOptions configures an OnErrorResponse subscriber
+// Zero values fall back to the defaults noted on each field
+type ErrorResponseOptions struct {
+	MinStatus int // Lowest status reported, 500 by default; 400 includes client errors
+}
+
+// errorResponseHook is an OnErrorResponse subscriber with its threshold
+type errorResponseHook struct {
+	fn        func(ErrorResponseInfo)
+	minStatus int
+}
+
+// PanicError is the error a recovered handler panic is reported as to the error handler
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack of the panicking goroutine
+}
+
+// Error describes the panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("tobingo: panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// OnMatch subscribes fn to matched requests; it runs before the route's handler with the
+// registered pattern, e.g. "/users/:id", and the request carrying the path parameters
+// Subscribers run in order and a panicking one is logged without affecting the request
+func (rt *Rastauter) OnMatch(fn func(r *http.Request, pattern string)) {
+	rt.updateHooks(func(h *routerHooks) { h.match = append(h.match, fn) })
+}
+
+// OnNotFound subscribes fn to requests that no route matched, before the NotFound handler runs
+func (rt *Rastauter) OnNotFound(fn func(r *http.Request)) {
+	rt.updateHooks(func(h *routerHooks) { h.notFound = append(h.notFound, fn) })
+}
+
+// OnPanic subscribes fn to handler panics, called with the value passed to panic once the
+// router has recovered it and before the error handler renders the 500
+func (rt *Rastauter) OnPanic(fn func(r *http.Request, recovered any)) {
+	rt.updateHooks(func(h *routerHooks) { h.panic = append(h.panic, fn) })
+}
+
+// OnErrorResponse subscribes fn to responses with a status of at least MinStatus, 500 by
+// default, whoever produced them: handlers, the NotFound handler, recovered panics, timeouts,
+// or rejected bodies, once per request after the handler returned, for error reporting
+// Example: rt.OnErrorResponse(func(info tobingo.ErrorResponseInfo) { sentry.CaptureException(info.Err) })
+func (rt *Rastauter) OnErrorResponse(fn func(info ErrorResponseInfo), opts ...ErrorResponseOptions) {
+	hook := errorResponseHook{fn: fn, minStatus: http.StatusInternalServerError}
+	if len(opts) > 0 && opts[0].MinStatus > 0 {
+		hook.minStatus = opts[0].MinStatus
+	}
+	rt.updateHooks(func(h *routerHooks) { h.errorResponse = append(h.errorResponse, hook) })
+}
+
+// updateHooks applies change to a copy of the hooks and publishes it
+// Copy on write lets ServeHTTP read the hooks without locking
+func (rt *Rastauter) updateHooks(change func(*routerHooks)) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	var hooks routerHooks
+	if current := rt.hooks.Load(); current != nil {
+		hooks = routerHooks{
+			match:    append([]func(*http.Request, string){}, current.match...),
+			notFound: append([]func(*http.Request){}, current.notFound...),
+			panic:    append([]func(*http.Request, any){}, current.panic...),
+
+			errorResponse: append([]errorResponseHook{}, current.errorResponse...),
+		}
+	}
+	change(&hooks)
+	rt.hooks.Store(&hooks)
+}
+
+// fireMatch runs the OnMatch subscribers
+func (rt *Rastauter) fireMatch(r *http.Request, pattern string) {
+	if hooks := rt.hooks.Load(); hooks != nil {
+		for _, fn := range hooks.match {
+			runHook(r, "OnMatch", func() { fn(r, pattern) })
+		}
+	}
+}
+
+// fireNotFound runs the OnNotFound subscribers
+func (rt *Rastauter) fireNotFound(r *http.Request) {
+	if hooks := rt.hooks.Load(); hooks != nil {
+		for _, fn := range hooks.notFound {
+			runHook(r, "OnNotFound", func() { fn(r) })
+		}
+	}
+}
+
+// fireErrorResponse runs the OnErrorResponse subscribers whose threshold status reaches
+func (rt *Rastauter) fireErrorResponse(w http.ResponseWriter, r *http.Request, status int) {
+	hooks := rt.hooks.Load()
+	if hooks == nil || len(hooks.errorResponse) == 0 {
+		return
+	}
+
+	info := ErrorResponseInfo{
+		Status:    status,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		RequestID: cmp.Or(w.Header().Get("X-Request-ID"), r.Header.Get("X-Request-ID")),
+	}
+	if state := stateFrom(r); state != nil {
+		state.mu.Lock()
+		info.Err, info.Recovered = state.err, state.recovered
+		state.mu.Unlock()
+		if state.route != nil {
+			info.Pattern = state.route.Path
+		}
+	}
+	for _, hook := range hooks.errorResponse {
+		if status >= hook.minStatus {
+			runHook(r, "OnErrorResponse", func() { hook.fn(info) })
+		}
+	}
+}
+
+// runHook calls a subscriber, logging rather than propagating its panic
+func runHook(r *http.Request, event string, call func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			loggerFor(r).Error("tobingo: hook panicked", "event", event, "panic", recovered)
+		}
+	}()
+	call()
+}
+
+// recoverPanic handles a panic that escaped the middleware chain: it is logged, reported to
+// the OnPanic subscribers, and answered with a 500 by the error handler
+// Reports whether the connection must be aborted instead, which is the case for
+// http.ErrAbortHandler and for panics after the response was committed
+func (rt *Rastauter) recoverPanic(w http.ResponseWriter, r *http.Request, recovered any) bool {
+	if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+		return true
+	}
+
+	perr := &PanicError{Value: recovered, Stack: debug.Stack()}
+	if state := stateFrom(r); state != nil {
+		state.recovered = recovered
+	}
+	recordError(r, perr)
+	if stats := rt.stats.Load(); stats != nil {
+		stats.panics.Add(1)
+	}
+	rt.logger().ErrorContext(r.Context(), "tobingo: panic serving request", "method", r.Method, "path", r.URL.Path, "panic", recovered, "stack", string(perr.Stack))
+
+	if hooks := rt.hooks.Load(); hooks != nil {
+		for _, fn := range hooks.panic {
+			runHook(r, "OnPanic", func() { fn(r, recovered) })
+		}
+	}
+
+	if rw := trackedWriter(w); rw != nil && rw.status != 0 {
+		return true // Too late for an error page, cut the response short
+	}
+	handleError(w, r, http.StatusInternalServerError, perr)
+	return false
+}
@@ -0,0 +1,173 @@
+package tobingo
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.OnMatch(func(r *http.Request, pattern string) {
+		events = append(events, "match "+pattern+" id="+GetParam(r, "id"))
+	})
+	rt.OnMatch(func(r *http.Request, pattern string) {
+		events = append(events, "second match")
+	})
+	rt.OnNotFound(func(r *http.Request) {
+		events = append(events, "not found "+r.URL.Path)
+	})
+	rt.OnPanic(func(r *http.Request, recovered any) {
+		events = append(events, "panic "+recovered.(string))
+	})
+	rt.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		events = append(events, "NotFound handler")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		events = append(events, "handler")
+	})
+	rt.GET("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	})
+
+	for target, want := range map[string]string{
+		"/users/42": "match /users/:id id=42, second match, handler",
+		"/missing":  "not found /missing, NotFound handler",
+		"/boom":     "match /boom id=, second match, panic kaboom",
+	} {
+		events = nil
+		rt.Test("GET", target, nil)
+		if got := strings.Join(events, ", "); got != want {
+			t.Errorf("%s: events %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestHookPanicsAreIsolated(t *testing.T) {
+	var logs strings.Builder
+	var reached []string
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	rt.OnMatch(func(r *http.Request, pattern string) { panic("bad hook") })
+	rt.OnMatch(func(r *http.Request, pattern string) { reached = append(reached, "next hook") })
+	rt.OnNotFound(func(r *http.Request) { panic("bad hook") })
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, "handler")
+		w.Write([]byte("ok"))
+	})
+
+	if res := rt.Test("GET", "/", nil); res.StatusCode() != http.StatusOK || res.BodyString() != "ok" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if strings.Join(reached, ", ") != "next hook, handler" {
+		t.Errorf("reached %q", reached)
+	}
+	if res := rt.Test("GET", "/missing", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("miss = %d, want 404", res.StatusCode())
+	}
+	if n := strings.Count(logs.String(), "hook panicked"); n != 2 {
+		t.Errorf("logged %d hook panics, want 2:\n%s", n, logs.String())
+	}
+}
+
+func TestOnErrorResponse(t *testing.T) {
+	var infos []ErrorResponseInfo
+	var clientErrors int
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.OnErrorResponse(func(info ErrorResponseInfo) { infos = append(infos, info) })
+	rt.OnErrorResponse(func(info ErrorResponseInfo) { clientErrors++ }, ErrorResponseOptions{MinStatus: 400})
+	rt.GET("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-7")
+		panic(errors.New("db down"))
+	})
+	rt.GET("/ok", func(w http.ResponseWriter, r *http.Request) {})
+
+	rt.Test("GET", "/ok", nil)
+	rt.Test("GET", "/missing", nil)
+	rt.Test("GET", "/items/3", nil)
+
+	if clientErrors != 2 || len(infos) != 1 {
+		t.Fatalf("client errors %d, server errors %d", clientErrors, len(infos))
+	}
+	info := infos[0]
+	var perr *PanicError
+	if info.Status != http.StatusInternalServerError || info.Pattern != "/items/:id" || info.Path != "/items/3" || info.RequestID != "req-7" || !errors.As(info.Err, &perr) {
+		t.Errorf("info = %+v", info)
+	}
+	if err, _ := info.Recovered.(error); err == nil || err.Error() != "db down" {
+		t.Errorf("Recovered = %v", info.Recovered)
+	}
+}
+
+func TestOnErrorResponseSources(t *testing.T) {
+	var infos []ErrorResponseInfo
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.OnErrorResponse(func(info ErrorResponseInfo) { infos = append(infos, info) }, ErrorResponseOptions{MinStatus: 400})
+	rt.GET("/orders/:id", H(func(r *http.Request) (int, any, error) {
+		return 0, nil, errors.New("db down")
+	}))
+	rt.GET("/busy", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+	rt.GET("/slow", func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() }).Timeout(10 * time.Millisecond)
+	rt.GET("/upload", func(w http.ResponseWriter, r *http.Request) { io.ReadAll(r.Body) }).MaxBodySize(4)
+	rt.GET("/fine", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+
+	for _, tc := range []struct {
+		target  string
+		body    string
+		status  int
+		pattern string
+		err     error
+	}{
+		{"/orders/9", "", http.StatusInternalServerError, "/orders/:id", nil},
+		{"/busy", "", http.StatusServiceUnavailable, "/busy", nil},
+		{"/nowhere", "", http.StatusNotFound, "", nil},
+		{"/slow", "", http.StatusGatewayTimeout, "/slow", http.ErrHandlerTimeout},
+		{"/upload", "far too long", http.StatusRequestEntityTooLarge, "/upload", ErrBodyTooLarge},
+		{"/fine", "", 0, "", nil},
+	} {
+		infos = nil
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		rt.Test("GET", tc.target, body, WithTestHeader("X-Request-ID", "req-"+tc.target))
+		if tc.status == 0 {
+			if len(infos) != 0 {
+				t.Errorf("%s reported %+v", tc.target, infos)
+			}
+			continue
+		}
+		if len(infos) != 1 {
+			t.Errorf("%s reported %d times, want once", tc.target, len(infos))
+			continue
+		}
+		info := infos[0]
+		if info.Status != tc.status || info.Pattern != tc.pattern || info.Path != tc.target || info.Method != "GET" || info.RequestID != "req-"+tc.target || info.Recovered != nil {
+			t.Errorf("%s: %+v", tc.target, info)
+		}
+		if tc.err != nil && !errors.Is(info.Err, tc.err) {
+			t.Errorf("%s: Err %v, want %v", tc.target, info.Err, tc.err)
+		}
+	}
+
+	// The handler's error and the 404 reach the subscriber as rendered
+	infos = nil
+	rt.Test("GET", "/orders/9", nil)
+	rt.Test("GET", "/nowhere", nil)
+	var httpErr *HTTPError
+	if len(infos) != 2 || infos[0].Err == nil || infos[0].Err.Error() != "db down" || !errors.As(infos[1].Err, &httpErr) || httpErr.Status != http.StatusNotFound {
+		t.Errorf("errors reported: %+v", infos)
+	}
+	if infos[1].RequestID != "" {
+		t.Errorf("request ID without one: %q", infos[1].RequestID)
+	}
+}
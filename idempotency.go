@@ -0,0 +1,242 @@
+package tobingo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is what an IdempotencyStore keeps per key: the hash of the first
+// request's body and, once it has completed, its response
+type IdempotencyRecord struct {
+	BodyHash string      // Hex SHA-256 of the request body
+	Done     bool        // False while the first request is still being handled
+	Status   int         // Status of the stored response
+	Header   http.Header // Headers of the stored response
+	Body     []byte      // Body of the stored response
+}
+
+// IdempotencyStore keeps the records of the Idempotency middleware; implementations backed
+// by Redis or a database let replicas share them and must be safe for concurrent use
+// Keys are hex digests of the idempotency key, request, and client identity
+type IdempotencyStore interface {
+	// Reserve stores rec for key unless the key has a record, which it returns instead,
+	// atomically so only one of several concurrent first requests gets nil, like SET NX
+	Reserve(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error)
+
+	// Complete replaces the reserved record with the finished one
+	Complete(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error
+
+	// Release drops the reserved record of a request whose response isn't replayed, so that
+	// a retry runs the handler again
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore for a single process
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+// memoryIdempotencyEntry is a record with its expiry
+type memoryIdempotencyEntry struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty in-memory store, expired records being dropped
+// as new ones arrive
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]memoryIdempotencyEntry)}
+}
+
+// Reserve implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		existing := e.rec
+		return &existing, nil
+	}
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Complete implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: time.Now().Add(ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.records, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// sweep drops expired records at most once a minute; the caller must hold s.mu
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// IdempotencyOptions configures Idempotency
+// Zero values fall back to the defaults noted on each field
+type IdempotencyOptions struct {
+	Methods        []string                   // Methods made idempotent, POST and PATCH by default
+	Header         string                     // Request header carrying the key, "Idempotency-Key" by default
+	TTL            time.Duration              // How long responses are replayed, 24 hours by default
+	MaxBody        int64                      // Largest response stored, 1 MB by default; larger ones aren't replayed
+	MaxRequestBody int64                      // Largest request body hashed, 1 MB by default; larger ones get 413
+	Identity       func(*http.Request) string // Client identity in the key, the Authorization header by default
+}
+
+// Idempotency returns middleware replaying the stored response of the first request with a
+// given Idempotency-Key instead of running the handler again, for the configured methods
+// Records are keyed by the key, method, path, and client identity, so clients can't see each
+// other's responses; a repeat whose body differs from the first request's gets 409, as does
+// one arriving while the first is still running, with Retry-After
+// Responses with status 5xx, panics, and responses over MaxBody aren't stored, so they may
+// be retried; requests without the header pass straight through
+// Example: payments.Use(tobingo.Idempotency(tobingo.NewMemoryIdempotencyStore(), tobingo.IdempotencyOptions{}))
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOptions) Middleware {
+	if store == nil {
+		panic("tobingo: Idempotency needs a store")
+	}
+	var o IdempotencyOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if len(o.Methods) == 0 {
+		o.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if o.Header == "" {
+		o.Header = "Idempotency-Key"
+	}
+	if o.TTL <= 0 {
+		o.TTL = 24 * time.Hour
+	}
+	if o.MaxBody <= 0 {
+		o.MaxBody = 1 << 20
+	}
+	if o.MaxRequestBody <= 0 {
+		o.MaxRequestBody = 1 << 20
+	}
+	if o.Identity == nil {
+		o.Identity = func(r *http.Request) string { return r.Header.Get("Authorization") }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(o.Header)
+			if idemKey == "" || !slices.Contains(o.Methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := BufferBody(r, o.MaxRequestBody)
+			if err != nil {
+				handleError(w, r, errorStatus(err, http.StatusBadRequest), err)
+				return
+			}
+			sum := sha256.Sum256(body)
+			bodyHash := hex.EncodeToString(sum[:])
+			key := idempotencyKey(idemKey, r, o.Identity(r))
+
+			// Only the first request with the key gets to run the handler
+			ctx := r.Context()
+			existing, err := store.Reserve(ctx, key, IdempotencyRecord{BodyHash: bodyHash}, o.TTL)
+			if err != nil {
+				handleError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			if existing != nil {
+				replayIdempotent(w, r, existing, bodyHash)
+				return
+			}
+
+			// Release the key unless the response is stored, so a retry runs again
+			capture := &captureWriter{ResponseWriter: w, max: o.MaxBody}
+			stored := false
+			defer func() {
+				if !stored {
+					store.Release(context.WithoutCancel(ctx), key)
+				}
+			}()
+			next.ServeHTTP(exposeFeatures(capture, writerFeatures(w)), r)
+
+			capture.finish()
+			if capture.status >= http.StatusInternalServerError || capture.overflow {
+				return
+			}
+			rec := IdempotencyRecord{
+				BodyHash: bodyHash,
+				Done:     true,
+				Status:   capture.status,
+				Header:   capture.header,
+				Body:     bytes.Clone(capture.body.Bytes()),
+			}
+			if err := store.Complete(context.WithoutCancel(ctx), key, rec, o.TTL); err == nil {
+				stored = true
+			}
+		})
+	}
+}
+
+// idempotencyKey digests the parts a record is keyed by, keeping secrets such as bearer
+// tokens out of the store
+func idempotencyKey(idemKey string, r *http.Request, identity string) string {
+	h := sha256.New()
+	for _, part := range []string{idemKey, r.Method, r.URL.Path, identity} {
+		h.Write([]byte(strconv.Itoa(len(part))))
+		h.Write([]byte{':'})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayIdempotent answers a repeated request from the record of the first one
+func replayIdempotent(w http.ResponseWriter, r *http.Request, rec *IdempotencyRecord, bodyHash string) {
+	switch {
+	case rec.BodyHash != bodyHash:
+		handleError(w, r, http.StatusConflict, &HTTPError{
+			Status:  http.StatusConflict,
+			Message: "Idempotency key reused with a different request body",
+		})
+	case !rec.Done:
+		w.Header().Set("Retry-After", "1")
+		handleError(w, r, http.StatusConflict, &HTTPError{
+			Status:  http.StatusConflict,
+			Message: "A request with this idempotency key is in progress",
+		})
+	default:
+		h := w.Header()
+		for name, values := range rec.Header {
+			h[name] = slices.Clone(values)
+		}
+		h.Set("Idempotent-Replayed", "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+}
@@ -0,0 +1,172 @@
+package tobingo
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// chargeRouter counts the payments its handler makes, behind Idempotency with opts
+func chargeRouter(opts IdempotencyOptions) (*Rastauter, *atomic.Int32) {
+	var charges atomic.Int32
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(Idempotency(NewMemoryIdempotencyStore(), opts))
+	charge := func(w http.ResponseWriter, r *http.Request) {
+		n := charges.Add(1)
+		w.Header().Set("X-Charge", strconv.Itoa(int(n)))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("charge " + strconv.Itoa(int(n))))
+	}
+	rt.addRoute("POST", "/charges", charge)
+	rt.addRoute("PUT", "/charges", charge)
+	rt.addRoute("POST", "/refunds", charge)
+	rt.addRoute("POST", "/broken", func(w http.ResponseWriter, r *http.Request) {
+		charges.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	return rt, &charges
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	rt, charges := chargeRouter(IdempotencyOptions{})
+	key := WithTestHeader("Idempotency-Key", "k1")
+
+	first := rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`), key)
+	if first.StatusCode() != http.StatusCreated || first.BodyString() != "charge 1" || first.Header("Idempotent-Replayed") != "" {
+		t.Fatalf("first: %d %q, replayed %q", first.StatusCode(), first.BodyString(), first.Header("Idempotent-Replayed"))
+	}
+	again := rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`), key)
+	if again.StatusCode() != http.StatusCreated || again.BodyString() != "charge 1" || again.Header("X-Charge") != "1" || again.Header("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: %d %q, X-Charge %q, replayed %q", again.StatusCode(), again.BodyString(), again.Header("X-Charge"), again.Header("Idempotent-Replayed"))
+	}
+	if charges.Load() != 1 {
+		t.Errorf("handler ran %d times", charges.Load())
+	}
+
+	// The key is scoped to the route and the client, and a request without one passes through
+	for name, res := range map[string]*TestResponse{
+		"other path":   rt.Test("POST", "/refunds", strings.NewReader(`{"amount":5}`), key),
+		"other client": rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`), key, WithTestHeader("Authorization", "Bearer other")),
+		"no key":       rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`)),
+		"PUT":          rt.Test("PUT", "/charges", strings.NewReader(`{"amount":5}`), key),
+	} {
+		if res.Header("Idempotent-Replayed") != "" {
+			t.Errorf("%s was replayed", name)
+		}
+	}
+	if charges.Load() != 5 {
+		t.Errorf("handler ran %d times, want 5", charges.Load())
+	}
+}
+
+func TestIdempotencyConflictingBody(t *testing.T) {
+	rt, charges := chargeRouter(IdempotencyOptions{})
+	key := WithTestHeader("Idempotency-Key", "k1")
+
+	rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`), key)
+	res := rt.Test("POST", "/charges", strings.NewReader(`{"amount":500}`), key)
+	if res.StatusCode() != http.StatusConflict || !strings.Contains(res.BodyString(), "different request body") {
+		t.Errorf("different body: %d %q", res.StatusCode(), res.BodyString())
+	}
+	if charges.Load() != 1 {
+		t.Errorf("handler ran %d times", charges.Load())
+	}
+
+	// The stored response is still replayed for the original body
+	if res := rt.Test("POST", "/charges", strings.NewReader(`{"amount":5}`), key); res.BodyString() != "charge 1" {
+		t.Errorf("original body after a conflict: %q", res.BodyString())
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	rt, charges := chargeRouter(IdempotencyOptions{TTL: 30 * time.Millisecond})
+	key := WithTestHeader("Idempotency-Key", "k1")
+
+	rt.Test("POST", "/charges", strings.NewReader("a"), key)
+	if res := rt.Test("POST", "/charges", strings.NewReader("a"), key); res.BodyString() != "charge 1" {
+		t.Fatalf("within the TTL: %q", res.BodyString())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if res := rt.Test("POST", "/charges", strings.NewReader("a"), key); res.BodyString() != "charge 2" || res.Header("Idempotent-Replayed") != "" {
+		t.Errorf("after the TTL: %q", res.BodyString())
+	}
+	if charges.Load() != 2 {
+		t.Errorf("handler ran %d times", charges.Load())
+	}
+}
+
+func TestIdempotencyConcurrentFirstRequests(t *testing.T) {
+	var runs atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(Idempotency(NewMemoryIdempotencyStore()))
+	rt.addRoute("POST", "/charges", func(w http.ResponseWriter, r *http.Request) {
+		runs.Add(1)
+		close(started)
+		<-release
+		w.Write([]byte("charged"))
+	})
+	key := WithTestHeader("Idempotency-Key", "k1")
+
+	first := make(chan *TestResponse)
+	go func() { first <- rt.Test("POST", "/charges", strings.NewReader("a"), key) }()
+	<-started
+
+	// Requests arriving while the first runs are turned away without running the handler
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := rt.Test("POST", "/charges", strings.NewReader("a"), key)
+			if res.StatusCode() != http.StatusConflict || res.Header("Retry-After") != "1" || !strings.Contains(res.BodyString(), "in progress") {
+				t.Errorf("concurrent repeat: %d, Retry-After %q, body %q", res.StatusCode(), res.Header("Retry-After"), res.BodyString())
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+	if res := <-first; res.StatusCode() != http.StatusOK || res.BodyString() != "charged" {
+		t.Fatalf("first: %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	if res := rt.Test("POST", "/charges", strings.NewReader("a"), key); res.BodyString() != "charged" || res.Header("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after completion: %q", res.BodyString())
+	}
+	if runs.Load() != 1 {
+		t.Errorf("handler ran %d times", runs.Load())
+	}
+}
+
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	rt, charges := chargeRouter(IdempotencyOptions{MaxBody: 4})
+	key := WithTestHeader("Idempotency-Key", "k1")
+
+	// 5xx responses may be retried
+	for range 2 {
+		if res := rt.Test("POST", "/broken", nil, key); res.StatusCode() != http.StatusBadGateway {
+			t.Errorf("broken: %d", res.StatusCode())
+		}
+	}
+	// So may responses over MaxBody, though they reach the client whole
+	for range 2 {
+		if res := rt.Test("POST", "/charges", strings.NewReader("a"), key); res.Header("Idempotent-Replayed") != "" || !strings.HasPrefix(res.BodyString(), "charge ") {
+			t.Errorf("oversized response: %q replayed %q", res.BodyString(), res.Header("Idempotent-Replayed"))
+		}
+	}
+	if charges.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", charges.Load())
+	}
+
+	if msg := panicMessage(func() { Idempotency(nil) }); msg == "" {
+		t.Error("nil store did not panic")
+	}
+}
@@ -0,0 +1,123 @@
+package tobingo
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDrainLogInterval is how often Shutdown logs the requests still running while draining
+const DefaultDrainLogInterval = 5 * time.Second
+
+// ActiveRequest describes a request being served, as reported by ActiveRequests
+type ActiveRequest struct {
+	Method  string        // Request method
+	Path    string        // Request path
+	Pattern string        // Pattern of the matched route, "" before matching or when none matched
+	Start   time.Time     // When the router received the request
+	Age     time.Duration // How long the request has been running at the time of the snapshot
+}
+
+// activeRequest is the registry entry of a request while TrackInFlight is on
+type activeRequest struct {
+	method string
+	path   string
+	start  time.Time
+	route  atomic.Pointer[Route] // Set by dispatch from the request goroutine once matched
+}
+
+// TrackInFlight turns on the registry of active requests reported by ActiveRequests and
+// logged during Shutdown; it costs a mutex per request, while InFlight is always counted
+// It may be changed while serving, affecting requests that start afterwards
+func (rt *Rastauter) TrackInFlight(enabled bool) {
+	rt.trackInFlight.Store(enabled)
+}
+
+// InFlight returns the number of requests being served
+func (rt *Rastauter) InFlight() int {
+	return int(rt.inFlight.Load())
+}
+
+// ActiveRequests returns a snapshot of the requests being served, oldest first, or nil
+// unless TrackInFlight is on
+func (rt *Rastauter) ActiveRequests() []ActiveRequest {
+	now := time.Now()
+	rt.activeMu.Lock()
+	active := make([]ActiveRequest, 0, len(rt.active))
+	for entry := range rt.active {
+		req := ActiveRequest{Method: entry.method, Path: entry.path, Start: entry.start, Age: now.Sub(entry.start)}
+		if route := entry.route.Load(); route != nil {
+			req.Pattern = route.Path
+		}
+		active = append(active, req)
+	}
+	rt.activeMu.Unlock()
+
+	slices.SortFunc(active, func(a, b ActiveRequest) int { return a.Start.Compare(b.Start) })
+	return active
+}
+
+// SetDrainLogInterval sets how often Shutdown logs the number of requests still running,
+// and with TrackInFlight each of them, while it drains; zero or less turns the logging off
+func (rt *Rastauter) SetDrainLogInterval(d time.Duration) {
+	rt.mu.Lock()
+	rt.drainLogInterval = cmp.Or(d, -1)
+	rt.mu.Unlock()
+}
+
+// beginActive registers a request being served when TrackInFlight is on, returning nil otherwise
+func (rt *Rastauter) beginActive(method, path string) *activeRequest {
+	if !rt.trackInFlight.Load() {
+		return nil
+	}
+	entry := &activeRequest{method: method, path: path, start: time.Now()}
+	rt.activeMu.Lock()
+	if rt.active == nil {
+		rt.active = make(map[*activeRequest]struct{})
+	}
+	rt.active[entry] = struct{}{}
+	rt.activeMu.Unlock()
+	return entry
+}
+
+// endActive removes a request registered by beginActive
+func (rt *Rastauter) endActive(entry *activeRequest) {
+	rt.activeMu.Lock()
+	delete(rt.active, entry)
+	rt.activeMu.Unlock()
+}
+
+// logDrain logs the requests still running every interval until done is closed
+func (rt *Rastauter) logDrain(done <-chan struct{}) {
+	rt.mu.Lock()
+	interval := cmp.Or(rt.drainLogInterval, DefaultDrainLogInterval)
+	rt.mu.Unlock()
+	if interval < 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// Idle connections can hold up the drain after the last request finished
+			n := rt.InFlight()
+			if n == 0 {
+				continue
+			}
+			attrs := []any{"in_flight", n}
+			if active := rt.ActiveRequests(); len(active) > 0 {
+				requests := make([]string, len(active))
+				for i, req := range active {
+					requests[i] = req.Method + " " + cmp.Or(req.Pattern, req.Path) + " (" + req.Age.Round(time.Millisecond).String() + ")"
+				}
+				attrs = append(attrs, "requests", requests)
+			}
+			rt.logger().Info("tobingo: draining", attrs...)
+		}
+	}
+}
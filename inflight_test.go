@@ -0,0 +1,149 @@
+package tobingo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestActiveRequests(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	block := func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}
+	rt := NewRastaRouterInitializer()
+	rt.TrackInFlight(true)
+	rt.GET("/reports/:id", block)
+	rt.addRoute("POST", "/exports", block)
+
+	var wg sync.WaitGroup
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/reports/7", nil),
+		httptest.NewRequest("POST", "/exports", nil),
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rt.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+		<-entered // One at a time, so the snapshot order is known
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := rt.InFlight(); n != 2 {
+		t.Errorf("InFlight = %d, want 2", n)
+	}
+	active := rt.ActiveRequests()
+	if len(active) != 2 {
+		t.Fatalf("ActiveRequests = %+v", active)
+	}
+	for i, want := range []ActiveRequest{
+		{Method: "GET", Path: "/reports/7", Pattern: "/reports/:id"},
+		{Method: "POST", Path: "/exports", Pattern: "/exports"},
+	} {
+		got := active[i]
+		if got.Method != want.Method || got.Path != want.Path || got.Pattern != want.Pattern {
+			t.Errorf("active[%d] = %+v, want %+v", i, got, want)
+		}
+		if got.Start.IsZero() || got.Age <= 0 || got.Age > time.Minute {
+			t.Errorf("active[%d] started %v, age %v", i, got.Start, got.Age)
+		}
+	}
+	if !active[0].Start.Before(active[1].Start) {
+		t.Error("snapshot is not oldest first")
+	}
+
+	close(release)
+	wg.Wait()
+	if n, active := rt.InFlight(), rt.ActiveRequests(); n != 0 || len(active) != 0 {
+		t.Errorf("after the requests: InFlight %d, ActiveRequests %+v", n, active)
+	}
+}
+
+func TestInFlightCountedWithoutTracking(t *testing.T) {
+	var during int
+	var active []ActiveRequest
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		during, active = rt.InFlight(), rt.ActiveRequests()
+	})
+	rt.GET("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	rt.Test("GET", "/", nil)
+	if during != 1 || len(active) != 0 {
+		t.Errorf("during the request: InFlight %d, ActiveRequests %+v", during, active)
+	}
+	rt.Test("GET", "/missing", nil)
+	rt.Test("GET", "/panic", nil)
+	if n := rt.InFlight(); n != 0 {
+		t.Errorf("InFlight = %d after a 404 and a panic, want 0", n)
+	}
+}
+
+func TestShutdownLogsDrain(t *testing.T) {
+	logs := newRecordHandler()
+	entered, release := make(chan struct{}), make(chan struct{})
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(logs))
+	rt.TrackInFlight(true)
+	rt.SetDrainLogInterval(10 * time.Millisecond)
+	rt.GET("/slow/:id", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow/1")
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		done <- string(body)
+	}()
+	<-entered
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop(context.Background()) }()
+
+	// Wait for a drain record naming the request, then let it finish
+	deadline := time.Now().Add(5 * time.Second)
+	var drain *logRecord
+	for drain == nil && time.Now().Before(deadline) {
+		for _, rec := range logs.all() {
+			if rec.message == "tobingo: draining" {
+				drain = &rec
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if body := <-done; body != "done" {
+		t.Errorf("drained request got %q", body)
+	}
+
+	if drain == nil {
+		t.Fatal("no drain record logged")
+	}
+	if drain.attrs["in_flight"] != "1" || !strings.HasPrefix(drain.attrs["requests"], "[GET /slow/:id (") {
+		t.Errorf("drain record %v", drain.attrs)
+	}
+}
@@ -0,0 +1,119 @@
+package tobingo
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync/atomic"
+)
+
+// IPFilterOptions configures IPFilter
+// Zero values fall back to the defaults noted on each field
+type IPFilterOptions struct {
+	Allow       []netip.Prefix  // Clients allowed in, everyone not denied when empty
+	Deny        []netip.Prefix  // Clients turned away, checked before Allow
+	Setter      *IPFilterSetter // Swaps the lists at runtime, seeded with Allow and Deny when set
+	Body        []byte          // Body of the 403 response, rendered by the error handler when empty
+	ContentType string          // Content-Type of Body, sniffed from it by default
+}
+
+// IPFilterSetter replaces the lists of the IPFilter middleware given it in its options while
+// the router serves; it is safe for concurrent use and its zero value is ready to use
+// Example: var admins tobingo.IPFilterSetter; admins.Set(officeAndVPN, nil)
+type IPFilterSetter struct {
+	lists atomic.Pointer[ipLists]
+}
+
+// ipLists is one version of the lists, replaced whole so requests never see half an update
+type ipLists struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// Set replaces both lists, taking effect for the next request
+func (s *IPFilterSetter) Set(allow, deny []netip.Prefix) {
+	s.lists.Store(&ipLists{allow: normalizePrefixes(allow), deny: normalizePrefixes(deny)})
+}
+
+// Lists returns copies of the lists currently in effect
+func (s *IPFilterSetter) Lists() (allow, deny []netip.Prefix) {
+	if l := s.lists.Load(); l != nil {
+		return slices.Clone(l.allow), slices.Clone(l.deny)
+	}
+	return nil, nil
+}
+
+// normalizePrefixes copies prefixes with their host bits cleared and IPv4-mapped IPv6 ones
+// turned into IPv4, so they compare with the unmapped client address
+func normalizePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	out := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if addr := p.Addr(); addr.Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(addr.Unmap(), p.Bits()-96)
+		}
+		out = append(out, p.Masked())
+	}
+	return out
+}
+
+// IPFilter returns middleware admitting requests by client IP, as resolved by ClientIP so
+// trusted proxies are looked through: addresses in Deny are answered with 403, then, when
+// Allow is not empty, so is every address outside it; requests without a client IP are
+// refused whenever a list is set
+// Pass a Setter to push new lists without a restart, the two being seeded with Allow and Deny
+// Example: admin.Use(tobingo.IPFilter(tobingo.IPFilterOptions{Allow: []netip.Prefix{netip.MustParsePrefix("10.8.0.0/16")}}))
+func IPFilter(opts IPFilterOptions) Middleware {
+	setter := opts.Setter
+	if setter == nil {
+		setter = new(IPFilterSetter)
+	}
+	if setter.lists.Load() == nil || opts.Allow != nil || opts.Deny != nil {
+		setter.Set(opts.Allow, opts.Deny)
+	}
+	if len(opts.Body) > 0 && opts.ContentType == "" {
+		opts.ContentType = http.DetectContentType(opts.Body)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if setter.lists.Load().admits(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(opts.Body) == 0 {
+				handleError(w, r, http.StatusForbidden, &HTTPError{Status: http.StatusForbidden})
+				return
+			}
+			w.Header().Set("Content-Type", opts.ContentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(opts.Body)))
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(opts.Body)
+		})
+	}
+}
+
+// admits reports whether the client of r passes the lists
+func (l *ipLists) admits(r *http.Request) bool {
+	addr, ok := ClientIP(r)
+	if !ok {
+		return len(l.allow) == 0 && len(l.deny) == 0
+	}
+	addr = addr.Unmap()
+
+	for _, p := range l.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(l.allow) == 0 {
+		return true
+	}
+	for _, p := range l.allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package tobingo
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+// prefixes parses CIDRs for the filter lists
+func prefixes(cidrs ...string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, c := range cidrs {
+		out = append(out, netip.MustParsePrefix(c))
+	}
+	return out
+}
+
+func TestIPFilterLists(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(IPFilter(IPFilterOptions{
+		Allow: prefixes("10.8.0.0/16", "2001:db8:ff::/48", "::ffff:192.0.2.0/120"),
+		Deny:  prefixes("10.8.66.0/24", "2001:db8:ff:bad::/64"),
+	}))
+	rt.GET("/admin", okHandler)
+
+	for remote, want := range map[string]int{
+		"10.8.1.2:1234":             http.StatusOK,
+		"10.9.1.2:1234":             http.StatusForbidden,
+		"10.8.66.7:1234":            http.StatusForbidden, // Deny wins over Allow
+		"192.0.2.9:1234":            http.StatusOK,        // Mapped IPv4 prefix
+		"[::ffff:10.8.1.2]:1234":    http.StatusOK,        // Mapped IPv4 client
+		"[2001:db8:ff:1::5]:1234":   http.StatusOK,
+		"[2001:db8:ff:bad::5]:1234": http.StatusForbidden,
+		"[2001:db8:aa::1]:1234":     http.StatusForbidden,
+		"not an address":            http.StatusForbidden,
+	} {
+		if rec := limitedRequest(rt, "/admin", remote); rec.Code != want {
+			t.Errorf("%s: %d, want %d", remote, rec.Code, want)
+		}
+	}
+}
+
+func TestIPFilterDenyOnly(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(IPFilter(IPFilterOptions{
+		Deny: prefixes("203.0.113.0/24"),
+		Body: []byte(`{"error":"blocked"}`),
+	}))
+	rt.GET("/", okHandler)
+
+	if rec := limitedRequest(rt, "/", "198.51.100.1:1"); rec.Code != http.StatusOK {
+		t.Errorf("empty allow list refused %d", rec.Code)
+	}
+	rec := limitedRequest(rt, "/", "203.0.113.50:1")
+	if rec.Code != http.StatusForbidden || rec.Body.String() != `{"error":"blocked"}` || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("denied: %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	// Without any list everyone passes, even without a parsable address
+	rt = NewRastaRouterInitializer()
+	rt.Use(IPFilter(IPFilterOptions{}))
+	rt.GET("/", okHandler)
+	if rec := limitedRequest(rt, "/", "pipe"); rec.Code != http.StatusOK {
+		t.Errorf("no lists: %d", rec.Code)
+	}
+}
+
+func TestIPFilterTrustedProxies(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	if err := rt.SetTrustedProxies("192.0.2.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	rt.Use(IPFilter(IPFilterOptions{Allow: prefixes("10.8.0.0/16")}))
+	rt.GET("/admin", okHandler)
+
+	serve := func(remote, forwarded string) int {
+		req := httptest.NewRequest("GET", "/admin", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := serve("192.0.2.1:1", "10.8.3.4"); code != http.StatusOK {
+		t.Errorf("office client behind a trusted proxy: %d", code)
+	}
+	if code := serve("192.0.2.1:1", "198.51.100.7"); code != http.StatusForbidden {
+		t.Errorf("outside client behind a trusted proxy: %d", code)
+	}
+	if code := serve("198.51.100.7:1", "10.8.3.4"); code != http.StatusForbidden {
+		t.Errorf("spoofed header from an untrusted peer: %d", code)
+	}
+}
+
+func TestIPFilterSetter(t *testing.T) {
+	var setter IPFilterSetter
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(IPFilter(IPFilterOptions{Allow: prefixes("10.0.0.0/8"), Setter: &setter}))
+	rt.GET("/", okHandler)
+
+	if allow, deny := setter.Lists(); len(allow) != 1 || allow[0] != netip.MustParsePrefix("10.0.0.0/8") || len(deny) != 0 {
+		t.Errorf("seeded lists: %v %v", allow, deny)
+	}
+	if rec := limitedRequest(rt, "/", "172.16.0.1:1"); rec.Code != http.StatusForbidden {
+		t.Errorf("before the swap: %d", rec.Code)
+	}
+
+	// Host bits are cleared, so a sloppy prefix still matches its range
+	setter.Set(prefixes("172.16.9.9/12"), prefixes("10.0.0.0/8"))
+	if allow, _ := setter.Lists(); allow[0] != netip.MustParsePrefix("172.16.0.0/12") {
+		t.Errorf("stored %v", allow)
+	}
+	if rec := limitedRequest(rt, "/", "172.16.0.1:1"); rec.Code != http.StatusOK {
+		t.Errorf("after the swap: %d", rec.Code)
+	}
+	if rec := limitedRequest(rt, "/", "10.1.1.1:1"); rec.Code != http.StatusForbidden {
+		t.Errorf("newly denied: %d", rec.Code)
+	}
+
+	// Swapping while serving is safe
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				setter.Set(prefixes("172.16.0.0/12"), nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if rec := limitedRequest(rt, "/", "172.16.0.1:1"); rec.Code != http.StatusOK {
+					t.Errorf("goroutine %d: %d", i, rec.Code)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A filter given a seeded Setter and no lists keeps the Setter's
+	rt = NewRastaRouterInitializer()
+	rt.Use(IPFilter(IPFilterOptions{Setter: &setter}))
+	rt.GET("/", okHandler)
+	if rec := limitedRequest(rt, "/", "10.1.1.1:1"); rec.Code != http.StatusForbidden {
+		t.Errorf("seeded Setter was reset: %d", rec.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrUnknownLocale is returned by LocalizedURL for a locale not passed to Locales
+var ErrUnknownLocale = errors.New("tobingo: unknown locale")
+
+// LocaleOptions configures the locale prefixes recognized by Locales
+type LocaleOptions struct {
+	Default        string   // Locale of paths without a prefix, the first locale when empty
+	Redirect       bool     // Redirect unprefixed GET and HEAD requests to the locale preferred by Accept-Language
+	RedirectStatus int      // Status of those redirects, http.StatusFound (302) when zero
+	Exclude        []string // Path prefixes never redirected, such as "/healthz" or "/static/"
+}
+
+// localeConfig is the setting of Locales, never mutated after publication
+type localeConfig struct {
+	locales []string
+	opts    LocaleOptions
+}
+
+// Locales makes the router recognize a leading path segment naming one of locales, such as
+// "/fr/about", and strip it before middleware and route matching so the routes registered
+// without it keep working; the locale is read with Locale
+// Paths without a prefix get the default locale, or are redirected to the locale the client
+// prefers when opts enables Redirect; calling it with no locales turns it off
+// Example: rt.Locales([]string{"en", "fr", "de"}, tobingo.LocaleOptions{Redirect: true})
+func (rt *Rastauter) Locales(locales []string, opts ...LocaleOptions) {
+	if len(locales) == 0 {
+		rt.locales.Store(nil)
+		return
+	}
+
+	cfg := &localeConfig{locales: append([]string(nil), locales...)}
+	if len(opts) > 0 {
+		cfg.opts = opts[0]
+	}
+	if cfg.opts.Default == "" {
+		cfg.opts.Default = locales[0]
+	}
+	if cfg.opts.RedirectStatus == 0 {
+		cfg.opts.RedirectStatus = http.StatusFound
+	}
+	rt.locales.Store(cfg)
+}
+
+// Locale returns the locale of the request's path prefix, the default locale when the path
+// had none, or "" when the router serving r doesn't use Locales
+func Locale(r *http.Request) string {
+	state := stateFrom(r)
+	if state == nil {
+		return ""
+	}
+	if state.locale != "" {
+		return state.locale
+	}
+	if cfg := routerFrom(r).locales.Load(); cfg != nil {
+		return cfg.opts.Default
+	}
+	return ""
+}
+
+// LocalizedURL generates the path of the named route like URL, prefixed with locale
+// Example: rt.LocalizedURL("fr", "user", "id", "42") returns "/fr/users/42"
+func (rt *Rastauter) LocalizedURL(locale, name string, params ...string) (string, error) {
+	cfg := rt.locales.Load()
+	if cfg == nil || cfg.find(locale) == "" {
+		return "", fmt.Errorf("%w: %q", ErrUnknownLocale, locale)
+	}
+	path, err := rt.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	if path == "/" {
+		return "/" + cfg.find(locale), nil
+	}
+	return "/" + cfg.find(locale) + path, nil
+}
+
+// find returns the configured spelling of locale, or "" when it isn't one of them
+func (cfg *localeConfig) find(locale string) string {
+	for _, l := range cfg.locales {
+		if strings.EqualFold(l, locale) {
+			return l
+		}
+	}
+	return ""
+}
+
+// strip removes a locale prefix from the path of r, recording the locale in state
+// r must be a copy owned by the router, its URL is replaced rather than modified
+func (cfg *localeConfig) strip(r *http.Request, state *requestState) {
+	first, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	locale := cfg.find(first)
+	if locale == "" {
+		return
+	}
+	state.locale = locale
+
+	u := *r.URL
+	u.Path = "/" + rest
+	u.RawPath = ""
+	if raw, ok := strings.CutPrefix(r.URL.RawPath, "/"+first); ok {
+		u.RawPath = raw
+		if u.RawPath == "" {
+			u.RawPath = "/"
+		}
+	}
+	r.URL = &u
+}
+
+// redirect answers an unprefixed request with a redirect to the preferred locale and reports
+// whether it did, leaving excluded paths and methods other than GET and HEAD alone
+func (cfg *localeConfig) redirect(w http.ResponseWriter, r *http.Request) bool {
+	if !cfg.opts.Redirect || stateFrom(r).locale != "" {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	for _, prefix := range cfg.opts.Exclude {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+
+	target := "/" + cfg.preferred(r)
+	if r.URL.Path != "/" {
+		target += r.URL.EscapedPath()
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	w.Header().Add("Vary", "Accept-Language")
+	http.Redirect(w, r, externalLocation(r, target), cfg.opts.RedirectStatus)
+	return true
+}
+
+// preferred returns the locale best matching Accept-Language, the default locale when none does
+// A range selects a locale it covers like in AcceptsLanguage, and a regional one such as
+// "fr-CA" also selects "fr"; ties go to the range listed first
+func (cfg *localeConfig) preferred(r *http.Request) string {
+	best, bestQ := cfg.opts.Default, 0.0
+	for _, token := range accepted(r).languages {
+		if token.q <= bestQ {
+			continue
+		}
+		primary, _, _ := strings.Cut(token.value, "-")
+		for _, locale := range cfg.locales {
+			tag := strings.ToLower(locale)
+			if token.value == "*" || tag == token.value || tag == primary || strings.HasPrefix(tag, token.value+"-") {
+				if token.value == "*" {
+					locale = cfg.opts.Default
+				}
+				best, bestQ = locale, token.q
+				break
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,156 @@
+package tobingo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// localeRouter answers with the locale and path its routes and middleware saw
+func localeRouter(opts ...LocaleOptions) *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.Locales([]string{"en", "fr", "de"}, opts...)
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Seen-Path", r.URL.Path)
+			next.ServeHTTP(w, r)
+		})
+	})
+	show := func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, Locale(r)+" "+r.URL.Path)
+	}
+	rt.GET("/", show).Named("home")
+	rt.GET("/about", show)
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, Locale(r)+" user "+Params(r)["id"])
+	}).Named("user")
+	rt.addRoute("POST", "/contact", show)
+	rt.GET("/healthz", show)
+	return rt
+}
+
+func TestLocalePrefixes(t *testing.T) {
+	rt := localeRouter()
+	for target, want := range map[string]string{
+		"/fr/about":    "fr /about",
+		"/de/users/42": "de user 42",
+		"/FR/about":    "fr /about", // The configured spelling is reported
+		"/fr":          "fr /",
+		"/fr/":         "fr /",
+		"/about":       "en /about", // Unprefixed paths get the default locale
+		"/":            "en /",
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != want {
+			t.Errorf("%s = %d %q, want %q", target, res.StatusCode(), res.BodyString(), want)
+		}
+	}
+	if res := rt.Test("GET", "/fr/about", nil); res.Header("X-Seen-Path") != "/about" {
+		t.Errorf("middleware saw %q, want the stripped path", res.Header("X-Seen-Path"))
+	}
+
+	// Only a whole leading segment is a locale, and only one is stripped
+	for _, target := range []string{"/french/about", "/es/about", "/fr/de/about"} {
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusNotFound {
+			t.Errorf("%s = %d, want 404", target, res.StatusCode())
+		}
+	}
+
+	// An explicit default
+	rt = localeRouter(LocaleOptions{Default: "de"})
+	if res := rt.Test("GET", "/about", nil); res.BodyString() != "de /about" {
+		t.Errorf("Default de: %q", res.BodyString())
+	}
+}
+
+func TestLocaleRedirect(t *testing.T) {
+	rt := localeRouter(LocaleOptions{Redirect: true, Exclude: []string{"/healthz"}})
+	for name, tc := range map[string]struct {
+		target, accept, location string
+	}{
+		"regional range":   {"/about", "fr-CA, en;q=0.5", "/fr/about"},
+		"weights":          {"/about", "en;q=0.3, de;q=0.9", "/de/about"},
+		"ties go first":    {"/about", "de;q=0.5, fr;q=0.5", "/de/about"},
+		"unsupported":      {"/about", "ja, es", "/en/about"},
+		"wildcard":         {"/about", "ja, *;q=0.1", "/en/about"},
+		"no header":        {"/users/7", "", "/en/users/7"},
+		"root":             {"/", "de", "/de"},
+		"query kept":       {"/about?tab=team", "fr", "/fr/about?tab=team"},
+		"escaped path":     {"/users/a%2Fb", "de", "/de/users/a%2Fb"},
+		"no open redirect": {"//evil.example/x", "fr", "/fr/evil.example/x"},
+	} {
+		var opts []TestOption
+		if tc.accept != "" {
+			opts = append(opts, WithTestHeader("Accept-Language", tc.accept))
+		}
+		res := rt.Test("GET", tc.target, nil, opts...)
+		if res.StatusCode() != http.StatusFound || res.Header("Location") != tc.location || res.Header("Vary") != "Accept-Language" {
+			t.Errorf("%s: %d to %q, Vary %q; want %q", name, res.StatusCode(), res.Header("Location"), res.Header("Vary"), tc.location)
+		}
+	}
+
+	// Prefixed paths, excluded paths, and other methods are served as they are
+	for target, want := range map[string]string{"/fr/about": "fr /about", "/healthz": "en /healthz"} {
+		if res := rt.Test("GET", target, nil, WithTestHeader("Accept-Language", "de")); res.StatusCode() != http.StatusOK || res.BodyString() != want {
+			t.Errorf("%s = %d %q", target, res.StatusCode(), res.BodyString())
+		}
+	}
+	if res := rt.Test("POST", "/contact", nil, WithTestHeader("Accept-Language", "de")); res.StatusCode() != http.StatusOK || res.BodyString() != "en /contact" {
+		t.Errorf("POST = %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	rt = localeRouter(LocaleOptions{Redirect: true, RedirectStatus: http.StatusMovedPermanently})
+	if res := rt.Test("HEAD", "/about", nil, WithTestHeader("Accept-Language", "fr")); res.StatusCode() != http.StatusMovedPermanently || res.Header("Location") != "/fr/about" {
+		t.Errorf("HEAD with 301: %d to %q", res.StatusCode(), res.Header("Location"))
+	}
+}
+
+func TestLocalizedURL(t *testing.T) {
+	rt := localeRouter()
+	for _, tc := range []struct {
+		locale, name string
+		params       []string
+		want         string
+	}{
+		{"fr", "user", []string{"id", "42"}, "/fr/users/42"},
+		{"DE", "user", []string{"id", "7"}, "/de/users/7"},
+		{"en", "home", nil, "/en"},
+	} {
+		if got, err := rt.LocalizedURL(tc.locale, tc.name, tc.params...); err != nil || got != tc.want {
+			t.Errorf("LocalizedURL(%s, %s) = %q, %v; want %q", tc.locale, tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := rt.LocalizedURL("es", "user", "id", "1"); !errors.Is(err, ErrUnknownLocale) {
+		t.Errorf("unknown locale: %v", err)
+	}
+	if _, err := rt.LocalizedURL("fr", "missing"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("unknown route: %v", err)
+	}
+
+	// The generated URLs are served by the routes they name
+	u, _ := rt.LocalizedURL("fr", "user", "id", "42")
+	if res := rt.Test("GET", u, nil); res.BodyString() != "fr user 42" {
+		t.Errorf("%s = %q", u, res.BodyString())
+	}
+
+	// Without Locales every locale is unknown
+	if _, err := NewRastaRouterInitializer().LocalizedURL("en", "home"); !errors.Is(err, ErrUnknownLocale) {
+		t.Errorf("no Locales: %v", err)
+	}
+}
+
+func TestLocaleOff(t *testing.T) {
+	rt := localeRouter()
+	rt.Locales(nil)
+	if res := rt.Test("GET", "/about", nil); res.BodyString() != " /about" {
+		t.Errorf("after turning Locales off: %q", res.BodyString())
+	}
+	if res := rt.Test("GET", "/fr/about", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("prefix still stripped: %d", res.StatusCode())
+	}
+	if got := Locale(httptest.NewRequest("GET", "/fr/about", nil)); got != "" {
+		t.Errorf("Locale outside a router = %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package tobingo
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogEntry describes one completed request for an access log formatter
+type LogEntry struct {
+	Time      time.Time     // When the request started
+	Method    string        // Request method
+	Route     string        // Pattern of the matched route, "" when no route matched
+	Path      string        // Request path as sent, still escaped
+	Query     string        // Raw query string without the "?"
+	Proto     string        // Protocol, e.g. "HTTP/1.1"
+	Status    int           // Status code sent, 200 when the handler wrote nothing and 500 when it panicked first
+	Bytes     int64         // Body bytes written
+	Duration  time.Duration // Time from the start of the request until the handler returned or panicked
+	ClientIP  string        // Client address as reported by ClientIP, "" when unknown
+	RequestID string        // X-Request-ID of the response, or of the request when the response has none
+	TraceID   string        // W3C trace ID set by the TraceContext middleware, "" without
+	SpanID    string        // W3C span ID set by the TraceContext middleware, "" without
+	User      string        // User name from basic auth, "" without
+	UserAgent string        // User-Agent header
+	Referer   string        // Referer header
+}
+
+// URI returns the path with the query string, as in the request line
+func (e LogEntry) URI() string {
+	if e.Query == "" {
+		return e.Path
+	}
+	return e.Path + "?" + e.Query
+}
+
+// LogFormatter turns a request into one access log line, including the trailing newline
+type LogFormatter func(entry LogEntry) []byte
+
+// LoggerOptions configures the Logger middleware
+type LoggerOptions struct {
+	Output io.Writer    // Where lines go, os.Stderr when nil; writes are serialized
+	Format LogFormatter // Line format, CombinedLogFormat when nil
+	Slog   bool         // Emit records through the router's slog logger instead of formatted lines
+
+	// Skip leaves requests it returns true for out of the log, after the handler so the
+	// matched route is known; nil skips the endpoints of EnableHealthEndpoints
+	Skip func(r *http.Request) bool
+}
+
+// Logger returns middleware that writes an access log line for every request after the
+// handler returns or panics, 404s included and health probes left out; formats are CommonLogFormat, CombinedLogFormat,
+// JSONLogFormat, or any LogFormatter; with Slog set they become records of the router's logger
+// at Info, or Error for 5xx, with the LogEntry fields as attributes
+// Example: rt.Use(tobingo.Logger(tobingo.LoggerOptions{Format: tobingo.JSONLogFormat}))
+func Logger(opts ...LoggerOptions) Middleware {
+	var o LoggerOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	out := o.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	format := o.Format
+	if format == nil {
+		format = CombinedLogFormat
+	}
+	skip := o.Skip
+	if skip == nil {
+		skip = isHealthRequest
+	}
+
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			completed := false
+			// Deferred so requests whose handler panics are logged too, with the 500 the
+			// router answers them with once the panic leaves the chain
+			defer func() {
+				if skip(r) {
+					return
+				}
+
+				entry := newLogEntry(w, r, start, !completed)
+				if o.Slog {
+					logAccess(r, entry)
+					return
+				}
+				line := format(entry)
+				mu.Lock()
+				out.Write(line)
+				mu.Unlock()
+			}()
+
+			next.ServeHTTP(w, r)
+			completed = true
+		})
+	}
+}
+
+// isHealthRequest reports whether r was served by an endpoint of EnableHealthEndpoints
+func isHealthRequest(r *http.Request) bool {
+	rt := routerFrom(r)
+	if rt == nil {
+		return false
+	}
+	paths := rt.healthPaths.Load()
+	return paths != nil && slices.Contains(*paths, MatchedPattern(r))
+}
+
+// newLogEntry collects the details of a request whose handler has returned or panicked
+func newLogEntry(w http.ResponseWriter, r *http.Request, start time.Time, panicked bool) LogEntry {
+	entry := LogEntry{
+		Time:      start,
+		Method:    r.Method,
+		Path:      r.URL.EscapedPath(),
+		Query:     r.URL.RawQuery,
+		Proto:     r.Proto,
+		Status:    finalStatus(w, panicked),
+		Duration:  time.Since(start),
+		RequestID: cmp.Or(w.Header().Get("X-Request-ID"), r.Header.Get("X-Request-ID")),
+		TraceID:   TraceID(r),
+		SpanID:    SpanID(r),
+		UserAgent: r.UserAgent(),
+		Referer:   r.Referer(),
+	}
+	if rw := trackedWriter(w); rw != nil {
+		entry.Bytes = rw.size
+	}
+	entry.Route = MatchedPattern(r)
+	if ip, ok := ClientIP(r); ok {
+		entry.ClientIP = ip.String()
+	}
+	entry.User, _, _ = r.BasicAuth()
+	return entry
+}
+
+// finalStatus returns the status of the response to w, 200 when nothing was written, or 500
+// when the handler panicked before writing since the router answers the panic with a 500
+func finalStatus(w http.ResponseWriter, panicked bool) int {
+	status := 0
+	if rw := trackedWriter(w); rw != nil {
+		status = rw.status
+	}
+	if status == 0 && panicked {
+		return http.StatusInternalServerError
+	}
+	return cmp.Or(status, http.StatusOK)
+}
+
+// CommonLogFormat writes the NCSA Common Log Format used by Apache and nginx
+// Example: 203.0.113.7 - alice [10/Oct/2024:13:55:36 +0000] "GET /users/42 HTTP/1.1" 200 2326
+func CommonLogFormat(e LogEntry) []byte {
+	return append(appendCommon(nil, e), '\n')
+}
+
+// CombinedLogFormat writes the Common Log Format followed by the quoted referer and user agent
+func CombinedLogFormat(e LogEntry) []byte {
+	b := appendCommon(nil, e)
+	b = append(b, ' ')
+	b = appendQuotedField(b, e.Referer)
+	b = append(b, ' ')
+	b = appendQuotedField(b, e.UserAgent)
+	return append(b, '\n')
+}
+
+// appendCommon appends the Common Log Format fields of e
+func appendCommon(b []byte, e LogEntry) []byte {
+	b = append(b, cmp.Or(e.ClientIP, "-")...)
+	b = append(b, " - "...)
+	b = append(b, cmp.Or(escapeLogField(e.User), "-")...)
+	b = append(b, " ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, "] "...)
+	b = appendQuotedField(b, e.Method+" "+e.URI()+" "+e.Proto)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	if e.Bytes == 0 {
+		return append(b, '-')
+	}
+	return strconv.AppendInt(b, e.Bytes, 10)
+}
+
+// appendQuotedField appends s in double quotes, "-" when empty
+func appendQuotedField(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, `"-"`...)
+	}
+	b = append(b, '"')
+	b = append(b, escapeLogField(s)...)
+	return append(b, '"')
+}
+
+// escapeLogField escapes quotes, backslashes, and control characters like Apache does, so a
+// crafted header can't forge extra fields or lines
+func escapeLogField(s string) string {
+	if !strings.ContainsFunc(s, func(c rune) bool { return c == '"' || c == '\\' || c < 0x20 || c == 0x7f }) {
+		return s
+	}
+
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// jsonLogLine is the shape of a JSONLogFormat line
+type jsonLogLine struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Route      string  `json:"route,omitempty"`
+	Path       string  `json:"path"`
+	Query      string  `json:"query,omitempty"`
+	Proto      string  `json:"proto"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMS float64 `json:"duration_ms"`
+	ClientIP   string  `json:"client_ip,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	TraceID    string  `json:"trace_id,omitempty"`
+	SpanID     string  `json:"span_id,omitempty"`
+	User       string  `json:"user,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Referer    string  `json:"referer,omitempty"`
+}
+
+// JSONLogFormat writes one JSON object per line with snake_case keys, the time in RFC 3339
+// with nanoseconds, and the duration in fractional milliseconds
+func JSONLogFormat(e LogEntry) []byte {
+	b, err := json.Marshal(jsonLogLine{
+		Time:       e.Time.Format(time.RFC3339Nano),
+		Method:     e.Method,
+		Route:      e.Route,
+		Path:       e.Path,
+		Query:      e.Query,
+		Proto:      e.Proto,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		DurationMS: float64(e.Duration) / float64(time.Millisecond),
+		ClientIP:   e.ClientIP,
+		RequestID:  e.RequestID,
+		TraceID:    e.TraceID,
+		SpanID:     e.SpanID,
+		User:       e.User,
+		UserAgent:  e.UserAgent,
+		Referer:    e.Referer,
+	})
+	if err != nil {
+		return nil // Only strings and numbers, Marshal can't fail
+	}
+	return append(b, '\n')
+}
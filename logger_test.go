@@ -0,0 +1,186 @@
+package tobingo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerCombinedFormat(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out}))
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+
+	rt.Test("GET", "/users/42?tab=posts", nil, WithTestHeader("User-Agent", `evil "agent"`), WithTestHeader("Referer", "https://example.com/"))
+
+	line := out.String()
+	for _, want := range []string{`"GET /users/42?tab=posts HTTP/1.1" 200 5`, `"https://example.com/"`, `"evil \"agent\""`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("line %q lacks %q", line, want)
+		}
+	}
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("got %d lines, want 1", strings.Count(line, "\n"))
+	}
+}
+
+func TestLoggerJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out, Format: JSONLogFormat}))
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+
+	rt.Test("POST", "/missing", nil)
+	rt.Test("GET", "/users/7", nil)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var notFound, created jsonLogLine
+	json.Unmarshal([]byte(lines[0]), &notFound)
+	json.Unmarshal([]byte(lines[1]), &created)
+	if notFound.Status != http.StatusNotFound || notFound.Route != "" {
+		t.Errorf("404 line = %+v", notFound)
+	}
+	if created.Status != http.StatusCreated || created.Route != "/users/:id" || created.Path != "/users/7" {
+		t.Errorf("201 line = %+v", created)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, created.Time); err != nil {
+		t.Errorf("time %q: %v", created.Time, err)
+	}
+}
+
+func TestLoggerSkipsHealthEndpoints(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out}))
+	rt.EnableHealthEndpoints(HealthOptions{LivenessPath: "/live"})
+	rt.GET("/work", func(w http.ResponseWriter, r *http.Request) {})
+
+	rt.Test("GET", "/live", nil)
+	rt.Test("GET", "/readyz", nil)
+	rt.Test("GET", "/work", nil)
+
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "/work") {
+		t.Errorf("log = %q, want only the /work line", got)
+	}
+}
+
+func TestLoggerCustomSkip(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out, Skip: func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/static/")
+	}}))
+	rt.EnableHealthEndpoints(HealthOptions{})
+
+	rt.Test("GET", "/static/app.js", nil)
+	rt.Test("GET", "/healthz", nil)
+
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "/healthz") {
+		t.Errorf("log = %q, want only the /healthz line once Skip replaces the default", got)
+	}
+}
+
+func TestLoggerEscapesControlCharacters(t *testing.T) {
+	if got := escapeLogField("a\nb\"c"); got != `a\x0ab\"c` {
+		t.Errorf("escapeLogField = %q", got)
+	}
+}
+
+// goldenEntry is a fully populated entry for checking the built-in formats byte for byte
+var goldenEntry = LogEntry{
+	Time:      time.Date(2024, 10, 10, 13, 55, 36, 123456789, time.FixedZone("", 2*60*60)),
+	Method:    "GET",
+	Route:     "/users/:id",
+	Path:      "/users/42",
+	Query:     "tab=posts",
+	Proto:     "HTTP/1.1",
+	Status:    200,
+	Bytes:     2326,
+	Duration:  1500 * time.Microsecond,
+	ClientIP:  "203.0.113.7",
+	RequestID: "req-1",
+	User:      "alice",
+	UserAgent: "curl/8.0",
+	Referer:   "https://example.com/",
+}
+
+func TestLogFormatsGolden(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format LogFormatter
+		entry  LogEntry
+		want   string
+	}{
+		"common": {CommonLogFormat, goldenEntry,
+			`203.0.113.7 - alice [10/Oct/2024:13:55:36 +0200] "GET /users/42?tab=posts HTTP/1.1" 200 2326` + "\n"},
+		"combined": {CombinedLogFormat, goldenEntry,
+			`203.0.113.7 - alice [10/Oct/2024:13:55:36 +0200] "GET /users/42?tab=posts HTTP/1.1" 200 2326 "https://example.com/" "curl/8.0"` + "\n"},
+		"combined empty": {CombinedLogFormat, LogEntry{Time: goldenEntry.Time, Method: "HEAD", Path: "/", Proto: "HTTP/2.0", Status: 204},
+			`- - - [10/Oct/2024:13:55:36 +0200] "HEAD / HTTP/2.0" 204 - "-" "-"` + "\n"},
+		"json": {JSONLogFormat, goldenEntry,
+			`{"time":"2024-10-10T13:55:36.123456789+02:00","method":"GET","route":"/users/:id","path":"/users/42","query":"tab=posts","proto":"HTTP/1.1","status":200,"bytes":2326,"duration_ms":1.5,"client_ip":"203.0.113.7","request_id":"req-1","user":"alice","user_agent":"curl/8.0","referer":"https://example.com/"}` + "\n"},
+		"json empty": {JSONLogFormat, LogEntry{Time: goldenEntry.Time, Method: "GET", Path: "/", Proto: "HTTP/1.1", Status: 404},
+			`{"time":"2024-10-10T13:55:36.123456789+02:00","method":"GET","path":"/","proto":"HTTP/1.1","status":404,"bytes":0,"duration_ms":0}` + "\n"},
+	} {
+		if got := string(tc.format(tc.entry)); got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestLoggerCustomFormatter(t *testing.T) {
+	var out bytes.Buffer
+	var entries []LogEntry
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out, Format: func(e LogEntry) []byte {
+		entries = append(entries, e)
+		return []byte("custom " + e.Route + "\n")
+	}}))
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "resp-id")
+		http.Error(w, "teapot", http.StatusTeapot)
+	})
+
+	before := time.Now()
+	rt.Test("GET", "/users/9?x=1", nil, WithTestHeader("User-Agent", "test-agent"))
+	if out.String() != "custom /users/:id\n" || len(entries) != 1 {
+		t.Fatalf("log = %q after %d calls", out.String(), len(entries))
+	}
+	e := entries[0]
+	if e.Method != "GET" || e.Path != "/users/9" || e.Query != "x=1" || e.Status != http.StatusTeapot || e.Bytes != int64(len("teapot\n")) {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.ClientIP != "192.0.2.1" || e.RequestID != "resp-id" || e.UserAgent != "test-agent" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Time.Before(before.Add(-time.Second)) || e.Duration < 0 || e.Duration > time.Minute {
+		t.Errorf("time %v, duration %v", e.Time, e.Duration)
+	}
+}
+
+func TestLoggerLogsPanics(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(Logger(LoggerOptions{Output: &out, Format: JSONLogFormat}))
+	rt.GET("/boom/:id", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	res := rt.Test("GET", "/boom/1", nil)
+	if res.StatusCode() != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", res.StatusCode())
+	}
+	var line jsonLogLine
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("log %q: %v", out.String(), err)
+	}
+	if line.Status != http.StatusInternalServerError || line.Route != "/boom/:id" || line.Path != "/boom/1" {
+		t.Errorf("line = %+v", line)
+	}
+}
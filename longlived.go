@@ -0,0 +1,138 @@
+package tobingo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultLongLivedGrace is how long Shutdown gives long-lived responses to end after being
+// told to close, before it starts draining the remaining requests
+const DefaultLongLivedGrace = 5 * time.Second
+
+// ErrServerClosing is returned by the writes of SSE streams and Stream once the router has
+// closed long-lived responses for shutdown
+var ErrServerClosing = errors.New("tobingo: server closing long-lived responses")
+
+// longLivedConn is the registration of a long-lived response made with RegisterLongLived
+type longLivedConn struct {
+	done chan struct{} // Closed by CloseLongLived
+	once sync.Once     // Guards closing done
+}
+
+// close signals the handler to end the response
+func (c *longLivedConn) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// RegisterLongLived marks the response to r as long-lived, such as a stream or long poll, and
+// returns a channel closed when the router asks it to end, during Shutdown or CloseLongLived;
+// the handler should then finish its response and return
+// SSE and Stream register their responses themselves, and the registration ends with the
+// request; outside a router the channel is never closed
+// Example: select { case <-tobingo.RegisterLongLived(r): return; case msg := <-updates: ... }
+func RegisterLongLived(r *http.Request) <-chan struct{} {
+	rt, state := routerFrom(r), stateFrom(r)
+	if rt == nil || state == nil {
+		return nil
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.longLived != nil {
+		return state.longLived.done
+	}
+
+	c := &longLivedConn{done: make(chan struct{})}
+	// A handler left running past its deadline has nothing left to stream to
+	if state.ended {
+		c.close()
+		return c.done
+	}
+	state.longLived = c
+	// Responses starting during shutdown are told to end straight away
+	if rt.shuttingDown.Load() {
+		c.close()
+		return c.done
+	}
+
+	rt.longLivedMu.Lock()
+	if rt.longLived == nil {
+		rt.longLived = make(map[*longLivedConn]struct{})
+	}
+	rt.longLived[c] = struct{}{}
+	rt.longLivedMu.Unlock()
+	return c.done
+}
+
+// endLongLived removes the registration of a finished request
+func (rt *Rastauter) endLongLived(c *longLivedConn) {
+	rt.longLivedMu.Lock()
+	delete(rt.longLived, c)
+	if len(rt.longLived) == 0 && rt.longLivedIdle != nil {
+		close(rt.longLivedIdle)
+		rt.longLivedIdle = nil
+	}
+	rt.longLivedMu.Unlock()
+}
+
+// CloseLongLived asks every long-lived response to end, sending SSE streams their close
+// event if they set one, and waits for their handlers to return or ctx to expire, returning ctx.Err() then
+// Shutdown calls it before draining with the SetLongLivedGrace period, since a stream would
+// otherwise keep the server from finishing its shutdown
+func (rt *Rastauter) CloseLongLived(ctx context.Context) error {
+	rt.longLivedMu.Lock()
+	if len(rt.longLived) == 0 {
+		rt.longLivedMu.Unlock()
+		return nil
+	}
+	conns := make([]*longLivedConn, 0, len(rt.longLived))
+	for c := range rt.longLived {
+		conns = append(conns, c)
+	}
+	if rt.longLivedIdle == nil {
+		rt.longLivedIdle = make(chan struct{})
+	}
+	idle := rt.longLivedIdle
+	rt.longLivedMu.Unlock()
+
+	rt.logger().Info("tobingo: closing long-lived responses", "count", len(conns))
+
+	for _, c := range conns {
+		c.close()
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetLongLivedGrace sets how long Shutdown waits for long-lived responses to end after
+// closing them, DefaultLongLivedGrace by default; zero or less skips waiting, though they are
+// still told to close
+func (rt *Rastauter) SetLongLivedGrace(d time.Duration) {
+	rt.mu.Lock()
+	rt.longLivedGrace = cmp.Or(d, -1)
+	rt.mu.Unlock()
+}
+
+// closeLongLived runs CloseLongLived with the grace period during Shutdown
+func (rt *Rastauter) closeLongLived(ctx context.Context) {
+	rt.mu.Lock()
+	grace := cmp.Or(rt.longLivedGrace, DefaultLongLivedGrace)
+	rt.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, max(grace, 0))
+	defer cancel()
+	if err := rt.CloseLongLived(ctx); err != nil {
+		rt.longLivedMu.Lock()
+		remaining := len(rt.longLived)
+		rt.longLivedMu.Unlock()
+		rt.logger().Warn("tobingo: long-lived responses still open", "count", remaining)
+	}
+}
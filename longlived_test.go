@@ -0,0 +1,181 @@
+package tobingo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdownClosesSSEStreams(t *testing.T) {
+	opened := make(chan struct{})
+	sendErr := make(chan error, 1)
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := SSE(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.CloseEvent("bye", "reconnect later")
+		conn.Send("hello", "1", "welcome")
+		close(opened)
+		<-conn.Done()
+		sendErr <- conn.Send("late", "", "x")
+	})
+
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get("http://" + addr + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	if f := readFrame(t, br); f.event != "hello" {
+		t.Fatalf("first frame %+v", f)
+	}
+	<-opened
+
+	// The stream would otherwise hold the shutdown until its context expired
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := stop(ctx); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Shutdown took %v with an open stream", d)
+	}
+
+	if f := readFrame(t, br); f.event != "bye" || len(f.data) != 1 || f.data[0] != "reconnect later" {
+		t.Errorf("close frame %+v", f)
+	}
+	if rest, _ := io.ReadAll(br); len(rest) != 0 {
+		t.Errorf("stream went on after the close event: %q", rest)
+	}
+	if err := <-sendErr; !errors.Is(err, ErrServerClosing) {
+		t.Errorf("Send after the close = %v, want ErrServerClosing", err)
+	}
+}
+
+func TestCloseLongLived(t *testing.T) {
+	streamErr := make(chan error, 1)
+	entered := make(chan struct{}, 2)
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/poll", func(w http.ResponseWriter, r *http.Request) {
+		done := RegisterLongLived(r)
+		if again := RegisterLongLived(r); again != done {
+			t.Error("registering twice gave another channel")
+		}
+		entered <- struct{}{}
+		<-done
+		io.WriteString(w, "closed")
+	})
+	rt.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		streamErr <- Stream(w, r, "text/plain", func(w io.Writer, flush func()) error {
+			entered <- struct{}{}
+			for {
+				if _, err := io.WriteString(w, "tick\n"); err != nil {
+					return err
+				}
+				flush()
+				time.Sleep(time.Millisecond)
+			}
+		})
+	})
+
+	// Nothing registered returns straight away
+	if err := rt.CloseLongLived(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan *httptest.ResponseRecorder, 2)
+	for _, path := range []string{"/poll", "/stream"} {
+		go func() {
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+			results <- rec
+		}()
+		<-entered
+	}
+
+	if err := rt.CloseLongLived(context.Background()); err != nil {
+		t.Fatalf("CloseLongLived = %v", err)
+	}
+	for range 2 {
+		select {
+		case <-results:
+		case <-time.After(time.Second):
+			t.Fatal("a handler did not return")
+		}
+	}
+	if err := <-streamErr; !errors.Is(err, ErrServerClosing) {
+		t.Errorf("Stream = %v, want ErrServerClosing", err)
+	}
+	rt.longLivedMu.Lock()
+	left := len(rt.longLived)
+	rt.longLivedMu.Unlock()
+	if left != 0 {
+		t.Errorf("%d registrations left", left)
+	}
+
+	// Outside a router there is nothing to close
+	if done := RegisterLongLived(httptest.NewRequest("GET", "/", nil)); done != nil {
+		t.Error("RegisterLongLived outside a router returned a channel")
+	}
+}
+
+func TestCloseLongLivedDeadline(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/stubborn", func(w http.ResponseWriter, r *http.Request) {
+		RegisterLongLived(r)
+		close(entered)
+		<-release
+	})
+	finished := make(chan struct{})
+	go func() {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stubborn", nil))
+		close(finished)
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := rt.CloseLongLived(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseLongLived = %v, want the deadline", err)
+	}
+	close(release)
+	<-finished
+}
+
+func TestLongLivedDuringShutdown(t *testing.T) {
+	var closed bool
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/poll", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-RegisterLongLived(r):
+			closed = true
+		default:
+		}
+	})
+	rt.shuttingDown.Store(true)
+	rt.Test("GET", "/poll", nil)
+	if !closed {
+		t.Error("a response starting during shutdown was not told to close")
+	}
+}
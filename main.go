@@ -2,121 +2,509 @@
 package tobingo
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Route represents a single HTTP route configuration
 type Route struct {
-	Method  string             // HTTP method (GET, POST, PUT, DELETE, etc.)
-	Path    string             // URL path pattern, can include parameters like "/users/:id"
-	Handler http.HandlerFunc   // Handler function to execute when route matches
+	Method     string            // HTTP method (GET, POST, PUT, DELETE, etc.)
+	Path       string            // URL path pattern, can include parameters like "/users/:id"
+	Handler    http.HandlerFunc  // Handler function to execute when route matches
+	Defaults   map[string]string // Values of trailing optional parameters declared like ":page=1", used when absent
+	Name       string            // Name given with Named, used to generate URLs with URL
+	Meta       map[string]any    // Metadata attached with WithMeta, read by middleware through MatchedRoute
+	Middleware []Middleware      // Run around Handler after matching, added with Use, inherited from groups, or given to Register
+
+	rt           *Rastauter          // Router the route is registered with, for options that need it
+	optional     int                 // Number of trailing optional parameter segments
+	doc          *RouteDoc           // Documentation attached with Doc, used by OpenAPI
+	site         string              // File:line of the registration, recorded with TrackRegistrationSites
+	timeout      time.Duration       // Deadline set with Timeout, -1 after NoTimeout, 0 to use the router's
+	maxBody      int64               // Body limit set with MaxBodySize, 0 for none
+	maxBodySet   bool                // Whether MaxBodySize was called, otherwise the router default applies
+	slash        TrailingSlashPolicy // Policy set with TrailingSlash, 0 to use the router's
+	redirect     *redirectTarget     // Destination of a route registered with Redirect or RedirectToNamed
+	group        *Group              // Group the route was registered through, nil for none
+	errorHandler ErrorHandlerFunc    // Set with ErrorHandler before serving, overriding the group's
+	limiter      *rateLimiter        // Set with RateLimit, nil for none
+	chain        http.Handler        // Handler wrapped in middleware, nil without any
+	cors         *corsPolicy         // Policy set with CORS, replacing the middleware's for this route
 }
 
 // contextKey is a custom type used for context keys to avoid collisions
 type contextKey string
 
 // ParamsKey is the context key used to store path parameters in the request context
+//
+// Deprecated: Read parameters with GetParam, Params, or ParamCount instead; the value
+// stored under this key is an implementation detail and may change representation
 const ParamsKey contextKey = "params"
 
 // Rastauter is the main router struct that holds all registered routes
 type Rastauter struct {
-	routes []Route // Slice containing all registered routes
+	routes   []*Route // Slice containing all registered routes
+	matching []*Route // Routes in the order dispatch tries them, see matchPosition
+
+	mu                 sync.Mutex                  // Guards the server lifecycle and configuration fields
+	server             *http.Server                // Underlying HTTP server, created by Server or when the server starts
+	serverOpts         []ServerOption              // Options applied to every server the router constructs
+	running            []*http.Server              // Servers that have been started and are stopped by Shutdown
+	onListen           []func(net.Addr)            // Hooks called once a listener is accepting connections
+	onShutdown         []func(context.Context)     // Cleanup hooks run by Shutdown after draining
+	shutdownTimeout    time.Duration               // How long StartServerWithGracefulShutdown waits for requests to drain
+	activationFallback string                      // Address bound by StartFromActivation without socket activation
+	values             atomic.Pointer[map[any]any] // Values injected into every request context by WithValue
+
+	healthMu        sync.RWMutex             // Guards readinessChecks
+	readinessChecks []readinessCheck         // Checks aggregated by the readiness endpoint
+	healthPaths     atomic.Pointer[[]string] // Routes of EnableHealthEndpoints, which Logger skips by default
+	shuttingDown    atomic.Bool              // Set once Shutdown begins so readiness reports unavailable
+
+	middleware         []Middleware                       // Middleware registered with Use, outermost first
+	handler            atomic.Pointer[http.Handler]       // Prebuilt middleware chain ending in dispatch, nil without middleware
+	paramErrorRenderer atomic.Pointer[ParamErrorRenderer] // Renderer used by the MustParam helpers, DefaultParamErrorRenderer when nil
+	paramTimeLocation  atomic.Pointer[time.Location]      // Location used by GetParamTime for values without an offset, UTC when nil
+	templates          atomic.Pointer[templateSet]        // Templates loaded with LoadTemplates, used by Render
+	jsonIndent         atomic.Pointer[string]             // Indentation used by JSON, compact when nil or empty
+	maxBodySize        atomic.Int64                       // Body limit of the binding helpers, DefaultMaxBodySize when zero and none when negative
+	validator          atomic.Pointer[Validator]          // Run by the Bind helpers after a successful bind
+	cookieKey          atomic.Pointer[[]byte]             // HMAC key of the signed cookie helpers
+	trustedProxies     atomic.Pointer[[]netip.Prefix]     // Proxies whose forwarding headers ClientIP believes
+	notFound           atomic.Pointer[http.HandlerFunc]   // Handler for unmatched requests set with NotFound, http.NotFound when nil
+	errorHandler       atomic.Pointer[ErrorHandlerFunc]   // Renders errors, DefaultErrorHandler when nil
+	stats              atomic.Pointer[routerStats]        // Counters published with PublishExpvars, nil until then
+	hooks              atomic.Pointer[routerHooks]        // Event subscribers added with OnMatch, OnNotFound, and OnPanic
+	slogger            atomic.Pointer[slog.Logger]        // Diagnostics logger set with SetLogger, slog.Default() when nil
+	slow               atomic.Pointer[slowRequestConfig]  // Setting of SlowRequestThreshold, nil when off
+	locales            atomic.Pointer[localeConfig]       // Setting of Locales, nil when off
+	basePath           atomic.Pointer[string]             // Prefix set with SetBasePath, nil when off
+	trailingSlash      atomic.Int32                       // TrailingSlashPolicy set with TrailingSlash, 0 for TrailingSlashIgnore
+	cachesMu           sync.Mutex                         // Guards caches
+	caches             []*responseCache                   // Cache middleware that served requests of this router, for CacheInvalidate
+	longLivedMu        sync.Mutex                         // Guards longLived and longLivedIdle
+	longLived          map[*longLivedConn]struct{}        // Long-lived responses registered with RegisterLongLived
+	longLivedIdle      chan struct{}                      // Closed once longLived empties while CloseLongLived waits
+	longLivedGrace     time.Duration                      // Set with SetLongLivedGrace, DefaultLongLivedGrace when zero and none when negative
+	groups             atomic.Pointer[[]*Group]           // Groups created with Group, searched for unmatched requests, replaced as a whole
+	optionsStar        atomic.Pointer[http.HandlerFunc]   // Handler set with OptionsStarHandler, the Allow listing when nil
+	jsonETags          atomic.Bool                        // Set with JSONETags, whether JSON tags responses by default
+	maintenance        atomic.Pointer[maintenanceConfig]  // Setting of SetMaintenance, nil when off
+	companions         []Companion                        // Servers added with TrackCompanion, stopped by Shutdown alongside running
+	trackSites         bool                               // Whether routes record their registration site, set with TrackRegistrationSites
+	unsafePaths        atomic.Bool                        // Whether AllowUnsafePaths turned off the request path check
+	requestTimeout     atomic.Int64                       // Default handler deadline in nanoseconds set with RequestTimeout, 0 for none
+	inFlight           atomic.Int64                       // Requests being served, reported by InFlight
+	trackInFlight      atomic.Bool                        // Whether ServeHTTP registers requests in active, set with TrackInFlight
+	activeMu           sync.Mutex                         // Guards active
+	active             map[*activeRequest]struct{}        // Requests being served while TrackInFlight is on
+	drainLogInterval   time.Duration                      // Set with SetDrainLogInterval, -1 for off, 0 for the default
+	namedHandlers      map[string]http.HandlerFunc        // Handlers for LoadRoutes, added with RegisterNamedHandler
 }
 
 // NewRastaRouterInitializer creates and returns a new instance of Rastauter
 // with an empty routes slice ready for route registration
 func NewRastaRouterInitializer() *Rastauter {
 	return &Rastauter{
-		routes: []Route{},
+		routes:          []*Route{},
+		shutdownTimeout: DefaultShutdownTimeout,
 	}
 }
 
 // StartServer starts the HTTP server on the specified port using this router
 // The port should be in the format ":8080" or "localhost:8080"
-// Returns an error if the server fails to start
+// The listener is bound before serving begins, so address errors are returned immediately
+// Returns an error if the server fails to start, or http.ErrServerClosed after Shutdown
 func (rt *Rastauter) StartServer(port string) error {
-	return http.ListenAndServe(port, rt)
+	l, err := listenTCP(port)
+	if err != nil {
+		return err
+	}
+	rt.newServer(port)
+	return rt.Serve(l)
 }
 
 // GET registers a new GET route with the specified path pattern and handler
 // Path can include parameters using colon notation (e.g., "/users/:id")
+// Trailing parameters can be made optional with "?" (":page?") or a default (":page=1")
 // The handler will be called when a GET request matches the path pattern
-func (rt *Rastauter) GET(path string, handler http.HandlerFunc) {
-	rt.routes = append(rt.routes, Route{
-		Method: "GET",
-		Path: path,
-		Handler: handler,
-	})
+// The returned route can be configured further, e.g. rt.GET("/users/:id", h).Named("user")
+func (rt *Rastauter) GET(path string, handler http.HandlerFunc) *Route {
+	return rt.addRoute("GET", path, handler)
 }
 
 // GetParam extracts a path parameter value from the request context
 // Returns the parameter value if found, otherwise returns an empty string
+// It is a convenience wrapper over GetParamOK for handlers that don't need to tell
+// a missing parameter apart from an empty one
 // Example: For route "/users/:id" and request "/users/123", GetParam(r, "id") returns "123"
 func GetParam(r *http.Request, key string) string {
-	// Attempt to retrieve parameters map from request context
-	if params, ok := r.Context().Value(ParamsKey).(map[string]string); ok {
-		return params[key]
+	value, _ := GetParamOK(r, key)
+	return value
+}
+
+// GetParamOK extracts a path parameter value and reports whether the matched route defines it
+// The value can be empty while ok is true, e.g. route "/a/:x/b" matching request "/a//b"
+// This lets handlers mounted under several patterns detect which parameters exist
+func GetParamOK(r *http.Request, key string) (string, bool) {
+	// A nil map (no matched route) reports every key as absent
+	value, ok := routeParams(r)[key]
+	return value, ok
+}
+
+// Middleware wraps an http.Handler to run code before and/or after it
+type Middleware func(http.Handler) http.Handler
+
+// Use appends middleware that wraps every request handled by the router, including 404s
+// Middleware runs in the order it was added and before route matching takes place
+// A nil middleware, or one returning a nil handler, panics here instead of on the first request
+func (rt *Rastauter) Use(mw ...Middleware) {
+	// A nil middleware would only fail once the first request arrives, so reject it now
+	for i, m := range mw {
+		if m == nil {
+			panic(fmt.Sprintf("tobingo: Use: middleware %d is nil", i))
+		}
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.middleware = append(rt.middleware, mw...)
+
+	// Build the chain once here rather than on every request
+	var h http.Handler = http.HandlerFunc(rt.dispatch)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		if h = rt.middleware[i](h); h == nil {
+			rt.middleware = rt.middleware[:len(rt.middleware)-len(mw)]
+			panic(fmt.Sprintf("tobingo: Use: middleware %d of the chain returned a nil handler", i))
+		}
 	}
-	return ""
+	rt.handler.Store(&h)
 }
 
 // ServeHTTP implements the http.Handler interface, making Rastauter compatible with net/http
-// This method is called for every HTTP request and handles route matching and parameter extraction
+// This method is called for every HTTP request, injects router values, and runs the middleware chain
 func (rt *Rastauter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	
-	// Iterate through all registered routes to find a match
-	for _, route := range rt.routes {
+	// "OPTIONS *" is about the server, not a path any route or middleware could match
+	if isAsteriskForm(r) {
+		rt.serve(w, r, http.HandlerFunc(rt.serveAsteriskForm), nil)
+		return
+	}
+
+	// Run the middleware chain when there is one, it ends in dispatch
+	if h := rt.handler.Load(); h != nil {
+		rt.serve(w, r, *h, nil)
+		return
+	}
+	rt.serve(w, r, http.HandlerFunc(rt.dispatch), nil)
+}
+
+// serve sets up the request context and response tracking, then runs h with panics recovered
+// overrides replace values injected with WithValue for this request, nil outside Test
+func (rt *Rastauter) serve(w http.ResponseWriter, r *http.Request, h http.Handler, overrides map[any]any) {
+
+	// Make the router and values injected with WithValue visible before anything else runs
+	ctx := &routerContext{Context: r.Context(), rt: rt}
+	if values := rt.values.Load(); values != nil {
+		ctx.values = *values
+	}
+	if overrides != nil {
+		values := maps.Clone(ctx.values)
+		if values == nil {
+			values = make(map[any]any, len(overrides))
+		}
+		maps.Copy(values, overrides)
+		ctx.values = values
+	}
+	r = r.WithContext(ctx)
+
+	// Strip the base path and a locale prefix so middleware and routes see the path they were written for
+	rt.stripBasePath(r, &ctx.state)
+	if cfg := rt.locales.Load(); cfg != nil {
+		cfg.strip(r, &ctx.state)
+	}
+
+	// Track the status so the response helpers know when the response is committed
+	// A router mounted inside another one reuses the outer writer instead of stacking a second
+	if outer, ok := exposedTarget(w).(*responseWriter); ok {
+		ctx.recorder = outer
+	} else {
+		ctx.rw.ResponseWriter = w
+		ctx.rw.req = r
+		ctx.recorder = &ctx.rw
+
+		// Answers to HEAD carry no body, whatever the handler writes
+		if r.Method == http.MethodHead {
+			ctx.head.ResponseWriter = w
+			ctx.rw.ResponseWriter = &ctx.head
+			defer ctx.head.Finish()
+		}
+
+		// Offer handlers the optional interfaces the connection really has, no more
+		w = exposeFeatures(&ctx.rw, writerFeatures(w))
+	}
+
+	// Count the request, and register it when TrackInFlight is on
+	rt.inFlight.Add(1)
+	if ctx.state.active = rt.beginActive(r.Method, r.URL.Path); ctx.state.active != nil {
+		defer rt.endActive(ctx.state.active)
+	}
+	defer rt.inFlight.Add(-1)
+
+	// Drop the registration of a long-lived response once it has ended
+	defer func() {
+		ctx.state.mu.Lock()
+		ctx.state.ended = true
+		c := ctx.state.longLived
+		ctx.state.mu.Unlock()
+		if c != nil {
+			rt.endLongLived(c)
+		}
+	}()
+
+	// Count the request when expvars are published
+	stats := rt.stats.Load()
+	if stats != nil {
+		stats.begin()
+	}
+
+	// Time the request when slow request reporting is on
+	var slow *slowWatch
+	if cfg := rt.slow.Load(); cfg != nil {
+		slow = cfg.watch()
+	}
+
+	// Recover panics so the client gets a 500 rather than a dropped connection
+	defer func() {
+		abort := false
+		if recovered := recover(); recovered != nil {
+			abort = rt.recoverPanic(w, r, recovered)
+		}
+		if stats != nil {
+			stats.end(cmp.Or(ctx.recorder.status, http.StatusOK))
+		}
+		if slow != nil {
+			slow.finish(r, cmp.Or(ctx.recorder.status, http.StatusOK))
+		}
+		rt.fireErrorResponse(w, r, cmp.Or(ctx.recorder.status, http.StatusOK))
+		if abort {
+			panic(http.ErrAbortHandler)
+		}
+	}()
+
+	// Hold requests back during maintenance, before any middleware or route sees them
+	if cfg := rt.activeMaintenance(); cfg != nil && cfg.applies(r) {
+		cfg.serve(w, r)
+		return
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+// dispatch handles route matching and parameter extraction, calling the matched route's handler
+func (rt *Rastauter) dispatch(w http.ResponseWriter, r *http.Request) {
+	// Paths with control characters or invalid UTF-8 never reach the routes and their parameters
+	if !rt.unsafePaths.Load() {
+		if err := checkPath(r); err != nil {
+			handleError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Paths outside the base path aren't addressed to any route
+	if stateFrom(r).outside {
+		rt.fireNotFound(r)
+		rt.handleNotFound(w, r)
+		return
+	}
+
+	// Send unprefixed requests to the client's locale when Locales asks for it
+	if cfg := rt.locales.Load(); cfg != nil && cfg.redirect(w, r) {
+		return
+	}
+
+	// Find the route and run its handler, HEAD falling back to the GET route
+	route, params, wildcardName := rt.match(r.Method, r.URL.Path)
+	if route == nil && r.Method == http.MethodHead {
+		route, params, wildcardName = rt.match(http.MethodGet, r.URL.Path)
+	}
+	if route != nil {
+		// Either form of the path matches, the route's policy decides whether it is served
+		if wildcardName == "" && !rt.checkTrailingSlash(w, r, route) {
+			return
+		}
+		rt.serveRoute(w, r, route, params, wildcardName)
+		return
+	}
+
+	// If no route matches the request method and path, return 404 Not Found
+	rt.fireNotFound(r)
+	rt.handleNotFound(w, r)
+}
+
+// match finds the route for method and path in dispatch order, with its extracted parameters
+// and the name of its catch-all parameter, "" when it has none; the route is nil when none matches
+func (rt *Rastauter) match(method, path string) (*Route, map[string]string, string) {
+	// Iterate through all registered routes to find a match, catch-all routes last
+routes:
+	for _, route := range rt.matching {
 		// First check if the HTTP method matches
-		if route.Method == r.Method {
+		if route.Method == method {
 
 			// Get the route path pattern from the registered route
 			routePath := route.Path
-			
+
 			// Split the route path into segments, trimming spaces and splitting by "/"
 			// Note: This trims spaces instead of "/" which might be intentional
-			routerPathSlice := strings.Split(strings.Trim(routePath, " "), "/")
-			
+			// A trailing slash is dropped like the request's, the TrailingSlash policy decides
+			routerPathSlice := strings.Split(trimTrailingSlash(strings.Trim(routePath, " ")), "/")
+
 			// Get the actual request path, trim trailing "/" and split into segments
-			requestPath := strings.Trim(r.URL.Path, "/")
+			requestPath := strings.Trim(path, "/")
 			requestPathSlice := strings.Split(requestPath, "/")
 
-			// Check if the number of path segments match
 			// Skip the first element in routerPathSlice with [1:] (assumes it's empty from leading "/")
-			if len(routerPathSlice[1:]) != len(requestPathSlice) {
-				continue // Try next route if segment count doesn't match
+			routeSegments := routerPathSlice[1:]
+
+			// A trailing "*name" segment captures the rest of the path, slashes included,
+			// so the request only needs at least as many segments as precede it
+			wildcardName := ""
+			if last := routeSegments[len(routeSegments)-1]; strings.HasPrefix(last, "*") {
+				wildcardName = last[1:]
+				routeSegments = routeSegments[:len(routeSegments)-1]
+				if len(requestPathSlice) < len(routeSegments) {
+					continue // Try next route if the fixed part is longer than the request
+				}
+			} else if n := len(requestPathSlice); n > len(routeSegments) || n < len(routeSegments)-route.optional {
+				continue // Try next route if segment count doesn't match, trailing optional segments may be absent
 			}
-			
+
 			// Initialize map to store extracted path parameters
 			params := make(map[string]string)
-			
+
 			// Iterate through each segment of the route pattern
-			for routerIndex, routerPathName := range routerPathSlice[1:] {
-				// Check if this segment is a parameter (starts with ":")
-				if after, ok := strings.CutPrefix(routerPathName, ":"); ok {
-					// Extract parameter name (everything after ":")
-					paramName := after
-					// Store the corresponding value from the request path
-					params[paramName] = requestPathSlice[routerIndex]
+			for routerIndex, routerPathName := range routeSegments {
+				// Check if this segment contains a parameter, either the whole segment (":id")
+				// or following a literal prefix ("v:version")
+				segment, isParam := parseSegment(routerPathName)
+
+				// Optional segments missing from the request take their declared default, if any
+				if routerIndex >= len(requestPathSlice) {
+					if segment.hasDef {
+						params[segment.name] = segment.def
+					}
+					continue
+				}
+				requestSegment := requestPathSlice[routerIndex]
+
+				if isParam {
+					// The literal prefix must match before the parameter value starts
+					value, found := strings.CutPrefix(requestSegment, segment.literal)
+					if !found {
+						continue routes
+					}
+					// An empty optional segment falls back to its default like an absent one
+					if value == "" && segment.hasDef {
+						value = segment.def
+					}
+					// Store the rest of the request segment under the parameter name
+					params[segment.name] = value
+					continue
+				}
+
+				// Literal segments must match the request exactly, otherwise try the next route
+				if routerPathName != requestSegment {
+					continue routes
 				}
-				// Note: This implementation doesn't validate exact matches for non-parameter segments
-				// All routes with matching segment counts will match, regardless of literal segment values
 			}
 
-			// Add the extracted parameters to the request context
-			// This makes them available to the handler via GetParam function
-			ctx := context.WithValue(r.Context(), ParamsKey, params)
-			r = r.WithContext(ctx)
-			
-			// Execute the matched route's handler
-			route.Handler(w, r)
+			// Join the remaining request segments into the catch-all capture
+			if wildcardName != "" {
+				params[wildcardName] = strings.Join(requestPathSlice[len(routeSegments):], "/")
+			}
+			return route, params, wildcardName
+		}
+	}
 
-			// Return early since we found a match and handled the request
-			return
+	return nil, nil, ""
+}
+
+// serveRoute runs the handler of the matched route with its parameters in the request context
+// wildcardName names the catch-all parameter, "" when the route has none
+func (rt *Rastauter) serveRoute(w http.ResponseWriter, r *http.Request, route *Route, params map[string]string, wildcardName string) {
+	// Add the extracted parameters to the request context
+	// This makes them available to the handler via GetParam function
+	ctx := context.WithValue(r.Context(), ParamsKey, params)
+	if wildcardName != "" {
+		ctx = context.WithValue(ctx, wildcardKey, params[wildcardName])
+	}
+	r = r.WithContext(ctx)
+
+	// Let middleware see the match once the handler returns, e.g. for access logs
+	if state := stateFrom(r); state != nil {
+		state.route, state.params = route, params
+		if state.active != nil {
+			state.active.route.Store(route)
 		}
 	}
-	
-	// If no route matches the request method and path, return 404 Not Found
-	http.NotFound(w, r)
-}
\ No newline at end of file
+
+	rt.fireMatch(r, route.Path)
+
+	// Route and group rate limits see the parameters, unlike middleware
+	if (route.limiter != nil || route.group != nil) && !allowRoute(w, r, route) {
+		return
+	}
+
+	// Execute the matched route's handler, under its body limit and deadline if it has them
+	h := route.Handler
+	if route.chain != nil {
+		h = route.chain.ServeHTTP
+	}
+	if route.maxBody > 0 {
+		inner := h
+		h = func(w http.ResponseWriter, r *http.Request) { serveLimited(w, r, route.maxBody, inner) }
+	}
+	if d := route.EffectiveTimeout(); d > 0 {
+		serveWithTimeout(w, r, d, h)
+		return
+	}
+	h(w, r)
+}
+
+// NotFound sets the handler for requests that no route matches, http.NotFound by default
+// Static mounts registered with SPA also use it for paths that don't fall back to the app
+func (rt *Rastauter) NotFound(h http.HandlerFunc) {
+	rt.notFound.Store(&h)
+}
+
+// handleNotFound answers a request with the NotFound handler, or as a 404 error with the
+// error handler of the innermost group covering the path when one sets it
+func (rt *Rastauter) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	var h http.HandlerFunc
+	if p := rt.notFound.Load(); p != nil {
+		h = *p
+	}
+	eh := rt.groupFor(r.URL.Path).inheritedErrorHandler()
+
+	err := &HTTPError{Status: http.StatusNotFound}
+	recordError(r, err)
+	if eh != nil {
+		eh(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	if h == nil {
+		h = http.NotFound
+	}
+	h(w, r)
+}
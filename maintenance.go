@@ -0,0 +1,118 @@
+package tobingo
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After sent in maintenance without an expiry or
+// an explicit RetryAfter
+const DefaultMaintenanceRetryAfter = 5 * time.Minute
+
+// defaultMaintenanceBody is the body sent in maintenance when MaintenanceOptions.Body is empty
+const defaultMaintenanceBody = `{"error":"service is under maintenance"}` + "\n"
+
+// MaintenanceOptions configures SetMaintenance
+// Zero values fall back to the defaults noted on each field
+type MaintenanceOptions struct {
+	Allow       []string      // Path prefixes still served, such as "/healthz" or the toggle endpoint
+	Groups      []*Group      // Groups put into maintenance, the whole router when empty
+	Duration    time.Duration // Maintenance ends by itself after this long, never when 0
+	RetryAfter  time.Duration // Sent as Retry-After, the time left or DefaultMaintenanceRetryAfter by default
+	Body        []byte        // Response body, a JSON error by default
+	ContentType string        // Content-Type of Body, sniffed from it by default
+}
+
+// maintenanceConfig is the state of SetMaintenance, stored whole so it switches atomically
+type maintenanceConfig struct {
+	opts     MaintenanceOptions
+	prefixes []string  // Prefixes of opts.Groups, empty for the whole router
+	until    time.Time // End of an auto-expiring maintenance, zero for none
+}
+
+// SetMaintenance puts the router into maintenance or takes it out again without a restart
+// While on, requests are answered with 503, Retry-After, and the configured body before any
+// middleware or route runs, except under the Allow prefixes and, when Groups is set, outside
+// those groups; a Duration ends it by itself, and opts is ignored when turning it off
+// Example: rt.SetMaintenance(true, tobingo.MaintenanceOptions{Allow: []string{"/healthz", "/admin/"}})
+func (rt *Rastauter) SetMaintenance(enabled bool, opts MaintenanceOptions) {
+	if !enabled {
+		rt.maintenance.Store(nil)
+		return
+	}
+
+	cfg := &maintenanceConfig{opts: opts}
+	cfg.opts.Allow = append([]string(nil), opts.Allow...)
+	for _, g := range opts.Groups {
+		cfg.prefixes = append(cfg.prefixes, g.prefix)
+	}
+	if opts.Duration > 0 {
+		cfg.until = time.Now().Add(opts.Duration)
+	}
+	if len(opts.Body) == 0 {
+		cfg.opts.Body = []byte(defaultMaintenanceBody)
+		cfg.opts.ContentType = "application/json"
+	} else if cfg.opts.ContentType == "" {
+		cfg.opts.ContentType = http.DetectContentType(opts.Body)
+	}
+	rt.maintenance.Store(cfg)
+}
+
+// Maintenance reports whether the router is in maintenance, false once a Duration has passed
+func (rt *Rastauter) Maintenance() bool {
+	return rt.activeMaintenance() != nil
+}
+
+// activeMaintenance returns the maintenance setting, nil when off or expired, clearing an
+// expired one unless it was replaced meanwhile
+func (rt *Rastauter) activeMaintenance() *maintenanceConfig {
+	cfg := rt.maintenance.Load()
+	if cfg != nil && !cfg.until.IsZero() && !time.Now().Before(cfg.until) {
+		rt.maintenance.CompareAndSwap(cfg, nil)
+		return nil
+	}
+	return cfg
+}
+
+// applies reports whether r is held back by the maintenance
+func (cfg *maintenanceConfig) applies(r *http.Request) bool {
+	path := r.URL.Path
+	for _, prefix := range cfg.opts.Allow {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	if len(cfg.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range cfg.prefixes {
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// serve answers r with 503 and the maintenance body
+func (cfg *maintenanceConfig) serve(w http.ResponseWriter, r *http.Request) {
+	retry := cfg.opts.RetryAfter
+	if retry <= 0 {
+		retry = DefaultMaintenanceRetryAfter
+		if !cfg.until.IsZero() {
+			retry = time.Until(cfg.until)
+		}
+	}
+
+	h := w.Header()
+	h.Set("Retry-After", strconv.Itoa(max(int(math.Ceil(retry.Seconds())), 1)))
+	h.Set("Content-Type", cfg.opts.ContentType)
+	h.Set("Content-Length", strconv.Itoa(len(cfg.opts.Body)))
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if r.Method != http.MethodHead {
+		w.Write(cfg.opts.Body)
+	}
+}
@@ -0,0 +1,169 @@
+package tobingo
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceToggle(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	var reached atomic.Int32
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached.Add(1)
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.GET("/orders", okHandler)
+	rt.GET("/healthz", okHandler)
+	rt.TryHandle("POST", "/admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		rt.SetMaintenance(r.URL.Query().Get("on") == "1", MaintenanceOptions{Allow: []string{"/healthz", "/admin/"}})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if rt.Maintenance() {
+		t.Fatal("in maintenance before SetMaintenance")
+	}
+	if res := rt.Test("POST", "/admin/maintenance?on=1", nil); res.StatusCode() != http.StatusNoContent || !rt.Maintenance() {
+		t.Fatalf("turning on: %d, Maintenance %v", res.StatusCode(), rt.Maintenance())
+	}
+
+	reached.Store(0)
+	res := rt.Test("GET", "/orders", nil)
+	if res.StatusCode() != http.StatusServiceUnavailable || res.Header("Retry-After") != "300" || res.Header("Cache-Control") != "no-store" {
+		t.Errorf("in maintenance: %d, Retry-After %q, Cache-Control %q", res.StatusCode(), res.Header("Retry-After"), res.Header("Cache-Control"))
+	}
+	if res.Header("Content-Type") != "application/json" || res.BodyString() != defaultMaintenanceBody {
+		t.Errorf("default body: %q %q", res.Header("Content-Type"), res.BodyString())
+	}
+	if reached.Load() != 0 {
+		t.Error("middleware ran during maintenance")
+	}
+	if res := rt.Test("GET", "/missing", nil); res.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("unknown path: %d", res.StatusCode())
+	}
+	if res := rt.Test("HEAD", "/orders", nil); res.StatusCode() != http.StatusServiceUnavailable || res.BodyString() != "" {
+		t.Errorf("HEAD: %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	// The allow-list is served, including the toggle endpoint
+	if res := rt.Test("GET", "/healthz", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("allowed health check: %d", res.StatusCode())
+	}
+	if res := rt.Test("POST", "/admin/maintenance?on=0", nil); res.StatusCode() != http.StatusNoContent || rt.Maintenance() {
+		t.Fatalf("turning off: %d, Maintenance %v", res.StatusCode(), rt.Maintenance())
+	}
+	if res := rt.Test("GET", "/orders", nil); res.StatusCode() != http.StatusOK || res.Header("Retry-After") != "" {
+		t.Errorf("after maintenance: %d, Retry-After %q", res.StatusCode(), res.Header("Retry-After"))
+	}
+}
+
+func TestMaintenanceConcurrentToggle(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/orders", okHandler)
+	rt.GET("/healthz", okHandler)
+	opts := MaintenanceOptions{Allow: []string{"/healthz"}, RetryAfter: 90 * time.Second}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for on := true; ; on = !on {
+			select {
+			case <-stop:
+				return
+			default:
+				rt.SetMaintenance(on, opts)
+			}
+		}
+	}()
+
+	var ok, held atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				switch res := rt.Test("GET", "/orders", nil); res.StatusCode() {
+				case http.StatusOK:
+					ok.Add(1)
+				case http.StatusServiceUnavailable:
+					held.Add(1)
+					if res.Header("Retry-After") != "90" {
+						t.Errorf("Retry-After %q", res.Header("Retry-After"))
+					}
+				default:
+					t.Errorf("status %d", res.StatusCode())
+				}
+				if res := rt.Test("GET", "/healthz", nil); res.StatusCode() != http.StatusOK {
+					t.Errorf("health check held back: %d", res.StatusCode())
+				}
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if ok.Load()+held.Load() != 1600 {
+		t.Errorf("%d served and %d held back, want 1600 in all", ok.Load(), held.Load())
+	}
+}
+
+func TestMaintenanceExpiry(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/orders", okHandler)
+
+	rt.SetMaintenance(true, MaintenanceOptions{Duration: time.Hour})
+	res := rt.Test("GET", "/orders", nil)
+	if retry, _ := strconv.Atoi(res.Header("Retry-After")); res.StatusCode() != http.StatusServiceUnavailable || retry < 3590 || retry > 3600 {
+		t.Errorf("with an hour left: %d, Retry-After %q", res.StatusCode(), res.Header("Retry-After"))
+	}
+
+	rt.SetMaintenance(true, MaintenanceOptions{Duration: 20 * time.Millisecond})
+	if !rt.Maintenance() {
+		t.Fatal("a new setting did not replace the old one")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if rt.Maintenance() {
+		t.Error("still in maintenance after the duration")
+	}
+	if res := rt.Test("GET", "/orders", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("after expiry: %d", res.StatusCode())
+	}
+}
+
+func TestMaintenanceGroupsAndBody(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	api := rt.Group("/api")
+	api.GET("/orders", okHandler)
+	api.GET("/status", okHandler)
+	rt.GET("/apiary", okHandler)
+	rt.GET("/", okHandler)
+
+	rt.SetMaintenance(true, MaintenanceOptions{
+		Groups: []*Group{api},
+		Allow:  []string{"/api/status"},
+		Body:   []byte("<html><body>Back soon</body></html>"),
+	})
+	for path, want := range map[string]int{
+		"/api":        http.StatusServiceUnavailable,
+		"/api/orders": http.StatusServiceUnavailable,
+		"/api/status": http.StatusOK,
+		"/apiary":     http.StatusOK,
+		"/":           http.StatusOK,
+	} {
+		if res := rt.Test("GET", path, nil); res.StatusCode() != want {
+			t.Errorf("GET %s = %d, want %d", path, res.StatusCode(), want)
+		}
+	}
+
+	res := rt.Test("GET", "/api/orders", nil)
+	if res.Header("Content-Type") != "text/html; charset=utf-8" || res.BodyString() != "<html><body>Back soon</body></html>" {
+		t.Errorf("custom body: %q %q", res.Header("Content-Type"), res.BodyString())
+	}
+}
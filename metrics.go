@@ -0,0 +1,54 @@
+package tobingo
+
+import (
+	"cmp"
+	"net/http"
+	"time"
+)
+
+// UnmatchedRoute is the route reported to a MetricsRecorder for requests no route matched,
+// so every 404 shares one label value instead of one per scanned URL
+const UnmatchedRoute = "unmatched"
+
+// MetricsRecorder receives measurements from the Metrics middleware
+// Implementations must be safe for concurrent use; the prommetrics module provides one for
+// Prometheus, keeping the core router free of the dependency
+type MetricsRecorder interface {
+	// InFlight adjusts the number of requests being served, by +1 or -1
+	InFlight(delta int)
+	// Observe records a finished request along with its route pattern, e.g. "/users/:id"
+	Observe(method, route string, status int, duration time.Duration)
+}
+
+// Metrics returns middleware that reports every request to rec, labeled by the registered
+// route pattern rather than the concrete path so the number of label values stays bounded
+// Methods outside the standard set are reported as "OTHER" for the same reason
+// Example: rt.Use(tobingo.Metrics(recorder))
+func Metrics(rec MetricsRecorder) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec.InFlight(1)
+			completed := false
+			// Deferred so panicking requests are counted too, as the 500 the router answers with
+			defer func() {
+				rec.InFlight(-1)
+				route := cmp.Or(MatchedPattern(r), UnmatchedRoute)
+				rec.Observe(metricsMethod(r.Method), route, finalStatus(w, !completed), time.Since(start))
+			}()
+
+			next.ServeHTTP(w, r)
+			completed = true
+		})
+	}
+}
+
+// metricsMethod returns method when it is a standard one and "OTHER" otherwise
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
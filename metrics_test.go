@@ -0,0 +1,73 @@
+package tobingo
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRecorder keeps what the Metrics middleware reports
+type fakeRecorder struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	observed []string
+}
+
+func (f *fakeRecorder) InFlight(delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight += delta
+	f.peak = max(f.peak, f.inFlight)
+}
+
+func (f *fakeRecorder) Observe(method, route string, status int, duration time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observed = append(f.observed, fmt.Sprintf("%s %s %d", method, route, status))
+}
+
+func TestMetrics(t *testing.T) {
+	rec := &fakeRecorder{}
+	rt := NewRastaRouterInitializer()
+	rt.Use(Metrics(rec))
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	rt.GET("/files/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	rt.Test("GET", "/users/1", nil)
+	rt.Test("GET", "/users/2", nil)
+	rt.Test("GET", "/files/a/b/c.txt", nil)
+	rt.Test("GET", "/nope", nil)
+	rt.Test("BREW", "/users/1", nil)
+
+	want := []string{"GET /users/:id 200", "GET /users/:id 200", "GET /files/*path 202", "GET unmatched 404", "OTHER unmatched 404"}
+	if fmt.Sprint(rec.observed) != fmt.Sprint(want) {
+		t.Errorf("observed %q, want %q", rec.observed, want)
+	}
+	if rec.inFlight != 0 || rec.peak != 1 {
+		t.Errorf("in flight %d, peak %d", rec.inFlight, rec.peak)
+	}
+}
+
+func TestMetricsCountsPanics(t *testing.T) {
+	rec := &fakeRecorder{}
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(Metrics(rec))
+	rt.GET("/boom/:id", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	if res := rt.Test("GET", "/boom/1", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", res.StatusCode())
+	}
+	if want := []string{"GET /boom/:id 500"}; fmt.Sprint(rec.observed) != fmt.Sprint(want) {
+		t.Errorf("observed %q, want %q", rec.observed, want)
+	}
+	if rec.inFlight != 0 {
+		t.Errorf("in flight %d after the panic", rec.inFlight)
+	}
+}
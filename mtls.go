@@ -0,0 +1,117 @@
+package tobingo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// clientIdentityKey is the context key under which ClientCertIdentity stores the identity
+const clientIdentityKey contextKey = "clientIdentity"
+
+// ErrUnknownClientCert is reported when ClientCertIdentity rejects a verified client
+// certificate matching none of its identities
+var ErrUnknownClientCert = errors.New("tobingo: client certificate matches no identity")
+
+// LoadClientCAs reads PEM encoded CA certificates from the given files into a pool
+// suitable for WithClientCAs
+// Returns an error if a file can't be read or contains no certificates
+func LoadClientCAs(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tobingo: no certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// WithClientCAs sets the CAs used to verify client certificates on TLS listeners
+// Combine with WithClientAuth to choose whether certificates are required
+func WithClientCAs(pool *x509.CertPool) ServerOption {
+	return func(srv *http.Server) {
+		ensureTLSConfig(srv).ClientCAs = pool
+	}
+}
+
+// WithClientAuth sets the client certificate policy of TLS listeners,
+// e.g. tls.RequireAndVerifyClientCert or tls.VerifyClientCertIfGiven
+func WithClientAuth(mode tls.ClientAuthType) ServerOption {
+	return func(srv *http.Server) {
+		ensureTLSConfig(srv).ClientAuth = mode
+	}
+}
+
+// ensureTLSConfig returns the server's TLS config, creating an empty one when missing
+func ensureTLSConfig(srv *http.Server) *tls.Config {
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{}
+	}
+	return srv.TLSConfig
+}
+
+// PeerCertificate returns the verified client certificate of a TLS request
+// Returns nil for plain HTTP requests and when the client presented no verified certificate
+func PeerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// ClientCertIdentity returns middleware that maps the verified client certificate to an identity
+// identities is keyed by certificate name: the subject common name or any DNS, email, or URI SAN
+// Requests with a certificate matching no entry are rejected with 403 Forbidden through the
+// error handler, as ErrUnknownClientCert, while requests without a certificate pass through
+// unchanged, as allowed by tls.VerifyClientCertIfGiven
+// The matched identity is available to handlers through ClientIdentity
+func ClientCertIdentity(identities map[string]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cert := PeerCertificate(r)
+			if cert == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, name := range certificateNames(cert) {
+				if identity, ok := identities[name]; ok {
+					ctx := context.WithValue(r.Context(), clientIdentityKey, identity)
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			handleError(w, r, http.StatusForbidden, fmt.Errorf("%w: %q", ErrUnknownClientCert, cert.Subject.CommonName))
+		})
+	}
+}
+
+// ClientIdentity returns the identity stored by ClientCertIdentity for this request
+// The boolean is false when the client presented no certificate or the middleware isn't installed
+func ClientIdentity(r *http.Request) (string, bool) {
+	identity, ok := r.Context().Value(clientIdentityKey).(string)
+	return identity, ok
+}
+
+// certificateNames lists the names a certificate can be matched by, common name first
+func certificateNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
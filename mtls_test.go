@@ -0,0 +1,160 @@
+package tobingo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the mTLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate signed by the CA for a server at 127.0.0.1 or a client named cn
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadClientCAs(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewRastaRouterInitializer()
+	rt.Use(ClientCertIdentity(map[string]string{"billing-service": "billing"}))
+	rt.GET("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := ClientIdentity(r)
+		if cert := PeerCertificate(r); ok && cert.Subject.CommonName != "billing-service" {
+			t.Errorf("peer certificate %q", cert.Subject.CommonName)
+		}
+		w.Write([]byte(identity))
+	})
+	rt.ConfigureServer(WithClientCAs(pool), WithClientAuth(tls.VerifyClientCertIfGiven), func(srv *http.Server) {
+		srv.TLSConfig.Certificates = []tls.Certificate{ca.issue(t, "server", x509.ExtKeyUsageServerAuth)}
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rt.ServeTLS(l, "", "")
+	defer rt.Shutdown(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (int, string) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		res, err := client.Get("https://" + l.Addr().String() + "/whoami")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	if status, body := get(ca.issue(t, "billing-service", x509.ExtKeyUsageClientAuth)); status != http.StatusOK || body != "billing" {
+		t.Errorf("authorized client got %d %q", status, body)
+	}
+	if status, _ := get(ca.issue(t, "intruder", x509.ExtKeyUsageClientAuth)); status != http.StatusForbidden {
+		t.Errorf("unknown client got %d, want 403", status)
+	}
+	if status, body := get(); status != http.StatusOK || body != "" {
+		t.Errorf("client without a certificate got %d %q", status, body)
+	}
+}
+
+func TestLoadClientCAsRejectsEmptyFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(file, []byte("not a certificate"), 0o600)
+	if _, err := LoadClientCAs(file); err == nil {
+		t.Error("a file without certificates was accepted")
+	}
+}
+
+func TestClientCertIdentityRejectsThroughErrorHandler(t *testing.T) {
+	var reported error
+	rt := NewRastaRouterInitializer()
+	rt.ErrorHandler(jsonErrors)
+	rt.OnErrorResponse(func(info ErrorResponseInfo) { reported = info.Err }, ErrorResponseOptions{MinStatus: 400})
+	rt.Use(ClientCertIdentity(map[string]string{"billing-service": "billing"}))
+	rt.GET("/whoami", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest("GET", "https://example.com/whoami", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "intruder"}}}}}
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden || rec.Body.String() != `{"status":403}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("got %d %q %q, want the JSON error handler's 403", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if !errors.Is(reported, ErrUnknownClientCert) || !strings.Contains(reported.Error(), `"intruder"`) {
+		t.Errorf("reported error = %v, want ErrUnknownClientCert naming the certificate", reported)
+	}
+}
@@ -0,0 +1,101 @@
+package tobingo
+
+import (
+	"errors"
+	"net"
+	"net/http"
+)
+
+// StartServers serves this router on every given address at once, for example an IPv4 and
+// an IPv6 address or an internal and an external interface
+// All addresses are bound before any serving starts; if one fails the listeners already
+// bound are closed and the bind error is returned
+// The first address uses the server returned by Server, the others get a copy of its settings
+// Shutdown drains every server; StartServers then returns http.ErrServerClosed
+// If one server fails while running, the others are shut down and that error is returned
+func (rt *Rastauter) StartServers(addrs ...string) error {
+	if len(addrs) == 0 {
+		return errors.New("tobingo: StartServers requires at least one address")
+	}
+
+	// Bind everything up front so a partial failure leaves nothing running
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		l, err := listenTCP(addr)
+		if err != nil {
+			for _, bound := range listeners {
+				bound.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+
+	return rt.serveListeners(listeners)
+}
+
+// serveListeners serves the router on every listener with one server each and waits for
+// all of them to stop; the first listener uses the primary server, the rest get copies
+func (rt *Rastauter) serveListeners(listeners []net.Listener) error {
+	// One server per listener, all sharing the router and the primary server's settings
+	primary := rt.newServer(listeners[0].Addr().String())
+	servers := []*http.Server{primary}
+	for _, l := range listeners[1:] {
+		srv := cloneServer(primary)
+		srv.Addr = l.Addr().String()
+		rt.track(srv)
+		servers = append(servers, srv)
+	}
+
+	errCh := make(chan error, len(servers))
+	for i, srv := range servers {
+		l := listeners[i]
+		rt.notifyListen(l)
+		go func() {
+			errCh <- srv.Serve(l)
+		}()
+	}
+
+	// Wait for every serve loop, remembering the first real failure
+	var firstErr error
+	for range servers {
+		err := <-errCh
+		if errors.Is(err, http.ErrServerClosed) || firstErr != nil {
+			continue
+		}
+		firstErr = err
+
+		// Stop the remaining servers so the caller isn't left with a half-running set
+		go rt.shutdownWithTimeout()
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return http.ErrServerClosed
+}
+
+// cloneServer returns a new *http.Server with the same configuration as src
+// Runtime state is not copied, which is why the struct can't simply be copied by value
+func cloneServer(src *http.Server) *http.Server {
+	srv := &http.Server{
+		Handler:                      src.Handler,
+		DisableGeneralOptionsHandler: src.DisableGeneralOptionsHandler,
+		ReadTimeout:                  src.ReadTimeout,
+		ReadHeaderTimeout:            src.ReadHeaderTimeout,
+		WriteTimeout:                 src.WriteTimeout,
+		IdleTimeout:                  src.IdleTimeout,
+		MaxHeaderBytes:               src.MaxHeaderBytes,
+		TLSNextProto:                 src.TLSNextProto,
+		ConnState:                    src.ConnState,
+		ErrorLog:                     src.ErrorLog,
+		BaseContext:                  src.BaseContext,
+		ConnContext:                  src.ConnContext,
+		HTTP2:                        src.HTTP2,
+		Protocols:                    src.Protocols,
+	}
+	if src.TLSConfig != nil {
+		srv.TLSConfig = src.TLSConfig.Clone()
+	}
+	return srv
+}
@@ -0,0 +1,77 @@
+package tobingo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestStartServersServesEveryAddress(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("user " + GetParam(r, "id"))) })
+	addrs := make(chan net.Addr, 2)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- rt.StartServers("127.0.0.1:0", "127.0.0.1:0") }()
+	bound := []net.Addr{<-addrs, <-addrs}
+	if bound[0].String() == bound[1].String() {
+		t.Fatalf("both servers on %s", bound[0])
+	}
+
+	for _, addr := range bound {
+		res, err := http.Get("http://" + addr.String() + "/users/42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "user 42" {
+			t.Errorf("%s: body = %q", addr, body)
+		}
+	}
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("StartServers = %v, want ErrServerClosed", err)
+	}
+	for _, addr := range bound {
+		if conn, err := net.Dial("tcp", addr.String()); err == nil {
+			conn.Close()
+			t.Errorf("%s still accepting after shutdown", addr)
+		}
+	}
+}
+
+func TestStartServersReleasesListenersOnBindFailure(t *testing.T) {
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeAddr := free.Addr().String()
+	free.Close()
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+
+	if err := NewRastaRouterInitializer().StartServers(freeAddr, used.Addr().String()); err == nil {
+		t.Fatal("StartServers succeeded with an address in use")
+	}
+
+	// The first address was bound and must have been released again
+	l, err := net.Listen("tcp", freeAddr)
+	if err != nil {
+		t.Fatalf("first listener left open: %v", err)
+	}
+	l.Close()
+
+	if err := NewRastaRouterInitializer().StartServers(); err == nil {
+		t.Error("StartServers without addresses succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ParamErrorRenderer writes the response for a path parameter rejected by a MustParam helper
+// key is the parameter name and err wraps ErrParamMissing or ErrParamInvalid
+type ParamErrorRenderer func(w http.ResponseWriter, r *http.Request, key string, err error)
+
+// SetParamErrorRenderer replaces the renderer used by the MustParam helpers for requests
+// served by this router, allowing a custom status code and body
+func (rt *Rastauter) SetParamErrorRenderer(fn ParamErrorRenderer) {
+	rt.paramErrorRenderer.Store(&fn)
+}
+
+// DefaultParamErrorRenderer writes 400 Bad Request with a JSON body when the client
+// accepts JSON and a plain text body otherwise
+func DefaultParamErrorRenderer(w http.ResponseWriter, r *http.Request, key string, err error) {
+	message := "invalid path parameter " + key
+	if errors.Is(err, ErrParamMissing) {
+		message = "missing path parameter " + key
+	}
+
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": message, "param": key})
+		return
+	}
+	http.Error(w, message, http.StatusBadRequest)
+}
+
+// acceptsJSON reports whether the Accept header names JSON or a +json media type
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "+json")
+}
+
+// renderParamError writes err through the router's renderer, or the default outside a router
+func renderParamError(w http.ResponseWriter, r *http.Request, key string, err error) {
+	render := DefaultParamErrorRenderer
+	if rt := routerFrom(r); rt != nil {
+		if fn := rt.paramErrorRenderer.Load(); fn != nil && *fn != nil {
+			render = *fn
+		}
+	}
+	render(w, r, key, err)
+}
+
+// mustParam writes the error response when err is non-nil and reports whether value can be used
+func mustParam[T any](w http.ResponseWriter, r *http.Request, key string, value T, err error) (T, bool) {
+	if err != nil {
+		renderParamError(w, r, key, err)
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
+
+// MustParam returns a non-empty path parameter, or writes the error response and returns ok=false
+// Nothing is written on success, so handlers can simply return when ok is false
+// Example: id, ok := tobingo.MustParam(w, r, "id"); if !ok { return }
+func MustParam(w http.ResponseWriter, r *http.Request, key string) (string, bool) {
+	value, err := P(r).required(key)
+	return mustParam(w, r, key, value, err)
+}
+
+// MustParamInt is like GetParamInt but writes the error response on failure
+func MustParamInt(w http.ResponseWriter, r *http.Request, key string) (int, bool) {
+	value, err := GetParamInt(r, key)
+	return mustParam(w, r, key, value, err)
+}
+
+// MustParamInt64 is like GetParamInt64 but writes the error response on failure
+func MustParamInt64(w http.ResponseWriter, r *http.Request, key string) (int64, bool) {
+	value, err := GetParamInt64(r, key)
+	return mustParam(w, r, key, value, err)
+}
+
+// MustParamUUID is like GetParamUUID but writes the error response on failure
+func MustParamUUID(w http.ResponseWriter, r *http.Request, key string, opts ...ParamOption) (string, bool) {
+	value, err := GetParamUUID(r, key, opts...)
+	return mustParam(w, r, key, value, err)
+}
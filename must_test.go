@@ -0,0 +1,95 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// mustRouter serves the MustParam helpers under /int, /int64, /uuid, and /str
+func mustRouter() *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/int/:id", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := MustParamInt(w, r, "id"); ok {
+			w.Write([]byte("int " + strconv.Itoa(id)))
+		}
+	})
+	rt.GET("/int64/:id", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := MustParamInt64(w, r, "id"); ok {
+			w.Write([]byte("int64 " + strconv.FormatInt(id, 10)))
+		}
+	})
+	rt.GET("/uuid/:id", func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := MustParamUUID(w, r, "id"); ok {
+			w.Write([]byte("uuid " + id))
+		}
+	})
+	rt.GET("/str/:name?", func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := MustParam(w, r, "name"); ok {
+			w.Write([]byte("str " + name))
+		}
+	})
+	return rt
+}
+
+func TestMustParamSuccessWritesNothing(t *testing.T) {
+	rt := mustRouter()
+	for path, want := range map[string]string{
+		"/int/7":   "int 7",
+		"/int64/8": "int64 8",
+		"/uuid/6BA7B810-9DAD-11D1-80B4-00C04FD430C8": "uuid 6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"/str/ann": "str ann",
+	} {
+		res := rt.Test("GET", path, nil)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, res.StatusCode(), res.BodyString(), want)
+		}
+	}
+}
+
+func TestMustParamFailureResponses(t *testing.T) {
+	rt := mustRouter()
+
+	res := rt.Test("GET", "/int/abc", nil)
+	if res.StatusCode() != http.StatusBadRequest || res.BodyString() != "invalid path parameter id\n" {
+		t.Errorf("text failure = %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	res = rt.Test("GET", "/uuid/nope", nil, WithTestHeader("Accept", "application/json"))
+	var body map[string]string
+	if err := res.JSON(&body); err != nil || res.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("JSON failure = %d %q: %v", res.StatusCode(), res.BodyString(), err)
+	}
+	if body["error"] != "invalid path parameter id" || body["param"] != "id" {
+		t.Errorf("JSON body = %v", body)
+	}
+
+	res = rt.Test("GET", "/str", nil, WithTestHeader("Accept", "application/problem+json"))
+	if err := res.JSON(&body); err != nil || body["error"] != "missing path parameter name" {
+		t.Errorf("missing parameter body = %q", res.BodyString())
+	}
+
+	if res := rt.Test("GET", "/int64/99999999999999999999", nil); res.StatusCode() != http.StatusBadRequest {
+		t.Errorf("int64 overflow = %d", res.StatusCode())
+	}
+}
+
+func TestSetParamErrorRenderer(t *testing.T) {
+	rt := mustRouter()
+	rt.SetParamErrorRenderer(func(w http.ResponseWriter, r *http.Request, key string, err error) {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, ErrParamMissing) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("bad " + key))
+	})
+
+	if res := rt.Test("GET", "/int/abc", nil); res.StatusCode() != http.StatusUnprocessableEntity || res.BodyString() != "bad id" {
+		t.Errorf("custom renderer = %d %q", res.StatusCode(), res.BodyString())
+	}
+	if res := rt.Test("GET", "/str", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("custom renderer for a missing parameter = %d", res.StatusCode())
+	}
+}
@@ -0,0 +1,132 @@
+package tobingo
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is one entry of an Accept header such as "text/html;q=0.8"
+type acceptRange struct {
+	typ, sub string  // Media type and subtype, either may be "*"
+	q        float64 // Quality between 0 and 1
+	order    int     // Position in the header, earlier wins ties
+}
+
+// parseAccept parses an Accept header, skipping malformed entries
+// A missing header accepts everything, as RFC 9110 specifies
+func parseAccept(header string) []acceptRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptRange{{typ: "*", sub: "*", q: 1}}
+	}
+
+	var ranges []acceptRange
+	for i, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if !ok || typ == "" || sub == "" || (typ == "*" && sub != "*") {
+			continue
+		}
+
+		ar := acceptRange{typ: typ, sub: sub, q: 1, order: i}
+		for param := range strings.SplitSeq(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				q, err := strconv.ParseFloat(value, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				ar.q = q
+			}
+		}
+		ranges = append(ranges, ar)
+	}
+	return ranges
+}
+
+// specificity ranks how closely a range names a media type: exact, type/*, or */*
+func (ar acceptRange) specificity() int {
+	switch {
+	case ar.typ == "*":
+		return 0
+	case ar.sub == "*":
+		return 1
+	}
+	return 2
+}
+
+// matches reports whether the range covers the media type typ/sub
+func (ar acceptRange) matches(typ, sub string) bool {
+	return (ar.typ == "*" || ar.typ == typ) && (ar.sub == "*" || ar.sub == sub)
+}
+
+// bestMediaType returns the offer the client prefers, or "" when none is acceptable
+// Each offer takes the quality of the most specific range covering it; ties go to the more
+// specific range, then to the range listed first, then to the offer listed first
+func bestMediaType(ranges []acceptRange, offers []string) string {
+	best := ""
+	var bestRange acceptRange
+	for _, offer := range offers {
+		typ, sub, ok := strings.Cut(strings.ToLower(offer), "/")
+		if !ok {
+			continue
+		}
+
+		// The most specific covering range decides, so "text/html;q=0" overrides "*/*"
+		matched, found := acceptRange{}, false
+		for _, ar := range ranges {
+			if ar.matches(typ, sub) && (!found || ar.specificity() > matched.specificity()) {
+				matched, found = ar, true
+			}
+		}
+		if !found || matched.q == 0 {
+			continue
+		}
+
+		if best == "" || matched.q > bestRange.q ||
+			(matched.q == bestRange.q && (matched.specificity() > bestRange.specificity() ||
+				(matched.specificity() == bestRange.specificity() && matched.order < bestRange.order))) {
+			best, bestRange = offer, matched
+		}
+	}
+	return best
+}
+
+// Negotiate picks the offer that best matches the Accept header and runs its render function,
+// so one handler can answer API clients with JSON and browsers with HTML
+// Offers are keyed by media type; the "*/*" key is a default used when no other offer is
+// acceptable. Vary: Accept is always set, and when nothing matches Negotiate replies
+// 406 Not Acceptable listing the available types
+// status is sent if the chosen function writes a body without setting a status itself;
+// helpers such as JSON use the status they are given
+// Example: return tobingo.Negotiate(w, r, http.StatusOK, map[string]func() error{"application/json": func() error { return tobingo.JSON(w, http.StatusOK, u) }, "text/html": func() error { return tobingo.Render(w, r, http.StatusOK, "user.html", u) }})
+func Negotiate(w http.ResponseWriter, r *http.Request, status int, offers map[string]func() error) error {
+	w.Header().Add("Vary", "Accept")
+
+	// Sort the keys so ties resolve the same way on every request
+	types := make([]string, 0, len(offers))
+	for offer := range offers {
+		if offer != "*/*" {
+			types = append(types, offer)
+		}
+	}
+	slices.Sort(types)
+
+	chosen := bestMediaType(accepted(r).media, types)
+	render := offers[chosen]
+	if render == nil {
+		render = offers["*/*"]
+	}
+	if render == nil {
+		return Text(w, http.StatusNotAcceptable, "406 not acceptable, available types: "+strings.Join(types, ", "))
+	}
+
+	if chosen != "" {
+		w.Header().Set("Content-Type", chosen)
+	}
+	if rw := trackedWriter(w); rw != nil && rw.status == 0 {
+		rw.implicitStatus = status
+	}
+	return render()
+}
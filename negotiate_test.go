@@ -0,0 +1,84 @@
+package tobingo
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// negotiateRouter offers JSON, HTML, and XML, plus a */* default when withDefault is set
+func negotiateRouter(withDefault bool) *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/user", func(w http.ResponseWriter, r *http.Request) {
+		offers := map[string]func() error{
+			"application/json": func() error { return JSON(w, http.StatusOK, "json") },
+			"text/html":        func() error { _, err := io.WriteString(w, "<p>html</p>"); return err },
+			"application/xml": func() error {
+				return XML(w, http.StatusOK, struct {
+					XMLName struct{} `xml:"user"`
+				}{})
+			},
+		}
+		if withDefault {
+			offers["*/*"] = func() error { return Text(w, http.StatusOK, "default") }
+		}
+		Negotiate(w, r, http.StatusAccepted, offers)
+	})
+	return rt
+}
+
+func TestNegotiate(t *testing.T) {
+	rt := negotiateRouter(false)
+	for accept, want := range map[string]string{
+		"":                                       "application/json", // Everything accepted, first type in order
+		"*/*":                                    "application/json",
+		"text/html":                              "text/html",
+		"text/*":                                 "text/html",
+		"application/xml;q=0.9, text/html;q=0.8": "application/xml",
+		"text/html;q=0.5, application/json;q=0.5":         "text/html", // Equal q goes to the range listed first
+		"*/*;q=0.5, text/html;q=0.5":                      "text/html", // A more specific range beats an earlier one
+		"*/*, application/json;q=0":                       "application/xml",
+		"TEXT/HTML":                                       "text/html",
+		"text/html;level=1;q=0.4, application/json;q=0.3": "text/html",
+		"application/xml;q=bogus, text/html;q=0.1":        "text/html",
+	} {
+		res := rt.Test("GET", "/user", nil, WithTestHeader("Accept", accept))
+		if got, _, _ := strings.Cut(res.Header("Content-Type"), ";"); got != want {
+			t.Errorf("Accept %q chose %q, want %q", accept, got, want)
+		}
+		if res.Header("Vary") != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", accept, res.Header("Vary"))
+		}
+	}
+}
+
+func TestNegotiateStatus(t *testing.T) {
+	rt := negotiateRouter(false)
+
+	// HTML writes without a status and gets the one given to Negotiate
+	if code := rt.Test("GET", "/user", nil, WithTestHeader("Accept", "text/html")).StatusCode(); code != http.StatusAccepted {
+		t.Errorf("html status = %d, want 202", code)
+	}
+	// JSON sends its own
+	if code := rt.Test("GET", "/user", nil, WithTestHeader("Accept", "application/json")).StatusCode(); code != http.StatusOK {
+		t.Errorf("json status = %d, want 200", code)
+	}
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	res := negotiateRouter(false).Test("GET", "/user", nil, WithTestHeader("Accept", "image/png"))
+	if res.StatusCode() != http.StatusNotAcceptable {
+		t.Fatalf("status = %d, want 406", res.StatusCode())
+	}
+	if want := "application/json, application/xml, text/html"; !strings.Contains(res.BodyString(), want) {
+		t.Errorf("body %q does not list %q", res.BodyString(), want)
+	}
+}
+
+func TestNegotiateDefault(t *testing.T) {
+	res := negotiateRouter(true).Test("GET", "/user", nil, WithTestHeader("Accept", "image/png"))
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "default" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
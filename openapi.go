@@ -0,0 +1,167 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// OpenAPIInfo describes the API in the document generated by OpenAPI
+type OpenAPIInfo struct {
+	Title       string   // API title, required by OpenAPI
+	Version     string   // API version, required by OpenAPI
+	Description string   // Longer description, may use CommonMark
+	Servers     []string // Base URLs the API is served from, e.g. "https://api.example.com"
+}
+
+// RouteDoc documents a route in the OpenAPI document
+type RouteDoc struct {
+	Summary     string         // Short summary of the operation
+	Description string         // Longer description, may use CommonMark
+	Tags        []string       // Tags grouping the operation
+	OperationID string         // Unique operation ID, the route name when empty
+	Deprecated  bool           // Marks the operation as deprecated
+	Responses   map[string]any // Response objects by status code, e.g. {"200": {"description": "The user"}}
+}
+
+// Doc attaches documentation that OpenAPI includes for the route
+// Example: rt.GET("/users/:id", getUser).Doc(tobingo.RouteDoc{Summary: "Get a user", Tags: []string{"users"}})
+func (route *Route) Doc(doc RouteDoc) *Route {
+	route.doc = &doc
+	return route
+}
+
+// openAPIDocument is the top-level OpenAPI 3 object
+type openAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    openAPIInfo                            `json:"info"`
+	Servers []openAPIServer                        `json:"servers,omitempty"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+// openAPIInfo is the info object of the document
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// openAPIServer is one entry of the servers list
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+// openAPIOperation describes one method of a path
+type openAPIOperation struct {
+	Summary     string             `json:"summary,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	OperationID string             `json:"operationId,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter `json:"parameters,omitempty"`
+	Responses   map[string]any     `json:"responses"`
+}
+
+// openAPIParameter describes a path parameter
+type openAPIParameter struct {
+	Name        string        `json:"name"`
+	In          string        `json:"in"`
+	Required    bool          `json:"required"`
+	Description string        `json:"description,omitempty"`
+	Schema      openAPISchema `json:"schema"`
+}
+
+// openAPISchema is the schema of a path parameter
+type openAPISchema struct {
+	Type    string `json:"type"`
+	Default string `json:"default,omitempty"`
+}
+
+// OpenAPI generates an OpenAPI 3 JSON document describing the registered routes
+// Patterns are converted to template syntax ("/users/:id" becomes "/users/{id}") with a
+// string parameter per path parameter; a route with trailing optional parameters appears
+// once per accepted length since OpenAPI path parameters are always required
+// Documentation attached with Route.Doc fills in summaries, tags, and responses; operations
+// without documented responses get a generic default response
+func (rt *Rastauter) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: info.Title, Version: info.Version, Description: info.Description},
+		Paths:   make(map[string]map[string]openAPIOperation),
+	}
+	for _, server := range info.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: server})
+	}
+
+	for _, route := range rt.routes {
+		segments := strings.Split(strings.Trim(route.Path, " "), "/")[1:]
+		for n := len(segments) - route.optional; n <= len(segments); n++ {
+			path, params := openAPIPath(segments[:n])
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]openAPIOperation)
+			}
+			op := openAPIOperationFor(route, params)
+			if n < len(segments) {
+				op.OperationID = "" // Operation IDs must be unique, keep it on the full path
+			}
+			doc.Paths[path][strings.ToLower(route.Method)] = op
+		}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath converts pattern segments to an OpenAPI path template and its parameters
+func openAPIPath(segments []string) (string, []openAPIParameter) {
+	var params []openAPIParameter
+	parts := make([]string, len(segments))
+	for i, seg := range segments {
+		if name, ok := strings.CutPrefix(seg, "*"); ok {
+			parts[i] = "{" + name + "}"
+			params = append(params, openAPIParameter{Name: name, In: "path", Required: true, Description: "Rest of the path, may contain slashes", Schema: openAPISchema{Type: "string"}})
+			continue
+		}
+
+		ps, isParam := parseSegment(seg)
+		if !isParam {
+			parts[i] = seg
+			continue
+		}
+		parts[i] = ps.literal + "{" + ps.name + "}"
+		params = append(params, openAPIParameter{Name: ps.name, In: "path", Required: true, Schema: openAPISchema{Type: "string", Default: ps.def}})
+	}
+	return "/" + strings.Join(parts, "/"), params
+}
+
+// openAPIOperationFor describes route with the given path parameters
+func openAPIOperationFor(route *Route, params []openAPIParameter) openAPIOperation {
+	op := openAPIOperation{
+		OperationID: route.Name,
+		Parameters:  params,
+		Responses:   map[string]any{"default": map[string]string{"description": "Response"}},
+	}
+	if d := route.doc; d != nil {
+		op.Summary, op.Description, op.Tags, op.Deprecated = d.Summary, d.Description, d.Tags, d.Deprecated
+		if d.OperationID != "" {
+			op.OperationID = d.OperationID
+		}
+		if len(d.Responses) > 0 {
+			op.Responses = d.Responses
+		}
+	}
+	return op
+}
+
+// ServeOpenAPI registers a GET route at path answering with the OpenAPI document, generated
+// on every request so routes registered later are included
+// Example: rt.ServeOpenAPI("/openapi.json", tobingo.OpenAPIInfo{Title: "Shop API", Version: "1.4.0"})
+func (rt *Rastauter) ServeOpenAPI(path string, info OpenAPIInfo) *Route {
+	return rt.GET(path, func(w http.ResponseWriter, r *http.Request) {
+		doc, err := rt.OpenAPI(info)
+		if err != nil {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		Blob(w, http.StatusOK, "application/json", doc)
+	})
+}
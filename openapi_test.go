@@ -0,0 +1,126 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openAPIRouter registers a representative API
+func openAPIRouter() *Rastauter {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users", h).Doc(RouteDoc{Summary: "List users", Tags: []string{"users"}})
+	rt.GET("/users/:id", h).Named("getUser").Doc(RouteDoc{
+		Summary:     "Get a user",
+		Description: "Looks a user up by **ID**",
+		Tags:        []string{"users"},
+		Responses:   map[string]any{"200": map[string]string{"description": "The user"}, "404": map[string]string{"description": "No such user"}},
+	})
+	rt.addRoute(http.MethodDelete, "/users/:id", h).Doc(RouteDoc{OperationID: "deleteUser", Deprecated: true})
+	rt.GET("/posts/:page=1", h).Named("posts")
+	rt.GET("/files/*path", h)
+	rt.GET("/v:version/status", h)
+	return rt
+}
+
+// openAPIGolden is the document expected for openAPIRouter
+const openAPIGolden = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Shop API", "version": "1.4.0", "description": "Orders and users"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/files/{path}": {
+      "get": {
+        "parameters": [{"name": "path", "in": "path", "required": true, "description": "Rest of the path, may contain slashes", "schema": {"type": "string"}}],
+        "responses": {"default": {"description": "Response"}}
+      }
+    },
+    "/posts": {
+      "get": {"responses": {"default": {"description": "Response"}}}
+    },
+    "/posts/{page}": {
+      "get": {
+        "operationId": "posts",
+        "parameters": [{"name": "page", "in": "path", "required": true, "schema": {"type": "string", "default": "1"}}],
+        "responses": {"default": {"description": "Response"}}
+      }
+    },
+    "/users": {
+      "get": {"summary": "List users", "tags": ["users"], "responses": {"default": {"description": "Response"}}}
+    },
+    "/users/{id}": {
+      "delete": {
+        "operationId": "deleteUser",
+        "deprecated": true,
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"default": {"description": "Response"}}
+      },
+      "get": {
+        "summary": "Get a user",
+        "description": "Looks a user up by **ID**",
+        "tags": ["users"],
+        "operationId": "getUser",
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "The user"}, "404": {"description": "No such user"}}
+      }
+    },
+    "/v{version}/status": {
+      "get": {
+        "parameters": [{"name": "version", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"default": {"description": "Response"}}
+      }
+    }
+  }
+}`
+
+// sameJSON reports whether a and b hold the same JSON value, ignoring layout and key order
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("%v in %s", err, a)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("%v in %s", err, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestOpenAPI(t *testing.T) {
+	info := OpenAPIInfo{Title: "Shop API", Version: "1.4.0", Description: "Orders and users", Servers: []string{"https://api.example.com"}}
+	doc, err := openAPIRouter().OpenAPI(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameJSON(t, doc, []byte(openAPIGolden)) {
+		t.Errorf("document differs from the golden one:\n%s", doc)
+	}
+
+	// Generation is deterministic, so the document can be checked in and diffed
+	if again, _ := openAPIRouter().OpenAPI(info); string(again) != string(doc) {
+		t.Error("two generations differ")
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "T", Version: "1"})
+	rt.GET("/later", func(w http.ResponseWriter, r *http.Request) {})
+
+	res := rt.Test("GET", "/openapi.json", nil)
+	if res.StatusCode() != http.StatusOK || !strings.HasPrefix(res.Header("Content-Type"), "application/json") {
+		t.Fatalf("got %d, Content-Type %q", res.StatusCode(), res.Header("Content-Type"))
+	}
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(res.BodyString()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths["/later"]["get"] == nil || doc.Paths["/openapi.json"]["get"] == nil {
+		t.Errorf("paths = %v, want the document's own route and one registered later", doc.Paths)
+	}
+}
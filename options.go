@@ -0,0 +1,142 @@
+package tobingo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Default limits applied to every server the router constructs
+// They protect internet-facing deployments against slow clients holding connections open
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second  // Time allowed to read request headers
+	DefaultReadTimeout       = 30 * time.Second  // Time allowed to read the full request including body
+	DefaultWriteTimeout      = 60 * time.Second  // Time allowed to write the response
+	DefaultIdleTimeout       = 120 * time.Second // Time a keep-alive connection may sit idle
+	DefaultMaxHeaderBytes    = 1 << 20           // Maximum size of request headers (1 MB)
+)
+
+// ServerOption configures the *http.Server constructed by the router
+type ServerOption func(*http.Server)
+
+// WithReadTimeout sets the maximum duration for reading the entire request, including the body
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(srv *http.Server) {
+		srv.ReadTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading the request headers
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(srv *http.Server) {
+		srv.ReadHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(srv *http.Server) {
+		srv.WriteTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum time to wait for the next request on a keep-alive connection
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(srv *http.Server) {
+		srv.IdleTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum number of bytes the server reads parsing request headers
+func WithMaxHeaderBytes(n int) ServerOption {
+	return func(srv *http.Server) {
+		srv.MaxHeaderBytes = n
+	}
+}
+
+// ConfigureServer applies the given options to the router's server
+// Options are remembered and also applied to any server the router constructs later
+func (rt *Rastauter) ConfigureServer(opts ...ServerOption) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.serverOpts = append(rt.serverOpts, opts...)
+
+	// Apply straight away if the server already exists
+	if rt.server != nil {
+		for _, opt := range opts {
+			opt(rt.server)
+		}
+	}
+}
+
+// Server returns the *http.Server the router will use when started, constructing it with
+// the default limits and any configured options on first call
+// Fields may be modified freely before starting, giving full control over the server
+func (rt *Rastauter) Server() *http.Server {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if rt.server == nil {
+		rt.server = rt.buildServer()
+	}
+	return rt.server
+}
+
+// buildServer constructs a new *http.Server serving this router with the default limits
+// and all configured options applied; the caller must hold rt.mu
+func (rt *Rastauter) buildServer() *http.Server {
+	srv := &http.Server{
+		Handler:           rt,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		MaxHeaderBytes:    DefaultMaxHeaderBytes,
+
+		// Let "OPTIONS *" reach the router, which answers it with the registered methods
+		DisableGeneralOptionsHandler: true,
+	}
+	for _, opt := range rt.serverOpts {
+		opt(srv)
+	}
+	return srv
+}
+
+// StartServerWithOptions applies the given options to the router's server and starts it
+// on the specified address, behaving like StartServer otherwise
+func (rt *Rastauter) StartServerWithOptions(addr string, opts ...ServerOption) error {
+	rt.ConfigureServer(opts...)
+	return rt.StartServer(addr)
+}
+
+// WithH2C enables HTTP/2 over cleartext TCP (h2c) alongside HTTP/1.1 on the same listener
+// Clients must use HTTP/2 with prior knowledge; the HTTP/1.1 Upgrade dance is not supported
+// HTTPS listeners keep negotiating HTTP/2 through ALPN as usual
+func WithH2C() ServerOption {
+	return func(srv *http.Server) {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = protocols
+	}
+}
+
+// WithBaseContext sets the function that provides the base context for every request
+// accepted on a listener, forwarded to http.Server.BaseContext
+// Use it to attach long-lived dependencies such as tracers or feature-flag clients
+func WithBaseContext(fn func(net.Listener) context.Context) ServerOption {
+	return func(srv *http.Server) {
+		srv.BaseContext = fn
+	}
+}
+
+// WithConnContext sets the function that derives the context for each new connection,
+// forwarded to http.Server.ConnContext
+func WithConnContext(fn func(ctx context.Context, c net.Conn) context.Context) ServerOption {
+	return func(srv *http.Server) {
+		srv.ConnContext = fn
+	}
+}
@@ -0,0 +1,50 @@
+package tobingo
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// OptionsStarHandler sets the handler for asterisk-form "OPTIONS *" requests, which ask about
+// the server as a whole rather than a resource; nil restores the default, which answers 204
+// with an Allow header listing every method the routes are registered for
+// Such requests never reach middleware or route matching, so no catch-all route can see them
+func (rt *Rastauter) OptionsStarHandler(h http.HandlerFunc) {
+	rt.optionsStar.Store(&h)
+}
+
+// isAsteriskForm reports whether r was sent with the request target "*"
+func isAsteriskForm(r *http.Request) bool {
+	return r.RequestURI == "*" || r.URL.Path == "*"
+}
+
+// serveAsteriskForm answers a request with the target "*", which only OPTIONS may use
+func (rt *Rastauter) serveAsteriskForm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodOptions {
+		handleError(w, r, http.StatusBadRequest, &HTTPError{Status: http.StatusBadRequest, Message: "the * request target is only allowed for OPTIONS"})
+		return
+	}
+
+	if h := rt.optionsStar.Load(); h != nil && *h != nil {
+		(*h)(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(rt.allowedMethods(), ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowedMethods returns the sorted union of the methods of all routes, with HEAD when
+// GET routes answer it and OPTIONS, which the router always answers for "*"
+func (rt *Rastauter) allowedMethods() []string {
+	methods := []string{http.MethodOptions}
+	for _, route := range rt.routes {
+		methods = append(methods, route.Method)
+		if route.Method == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	slices.Sort(methods)
+	return slices.Compact(methods)
+}
@@ -0,0 +1,94 @@
+package tobingo
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rawRequest sends request over a new connection to addr and reads the response
+func rawRequest(t *testing.T, addr, request string) *http.Response {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestOptionsStar(t *testing.T) {
+	var reached []string
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = append(reached, "middleware "+r.Method+" "+r.RequestURI)
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.GET("/users", okHandler)
+	rt.addRoute("POST", "/users", okHandler)
+	rt.addRoute("DELETE", "/users/:id", okHandler)
+	rt.addRoute("OPTIONS", "/*path", func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, "catch-all "+r.URL.Path)
+	})
+
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(context.Background())
+
+	res := rawRequest(t, addr, "OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if res.StatusCode != http.StatusNoContent || res.Header.Get("Allow") != "DELETE, GET, HEAD, OPTIONS, POST" {
+		t.Errorf("OPTIONS * = %d, Allow %q", res.StatusCode, res.Header.Get("Allow"))
+	}
+	if len(reached) != 0 {
+		t.Errorf("OPTIONS * reached %v", reached)
+	}
+
+	// Only OPTIONS may use the asterisk form
+	if res := rawRequest(t, addr, "GET * HTTP/1.1\r\nHost: example.com\r\n\r\n"); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET * = %d, want 400", res.StatusCode)
+	}
+
+	// An ordinary OPTIONS request still goes through middleware to the routes
+	if res := rawRequest(t, addr, "OPTIONS /anything HTTP/1.1\r\nHost: example.com\r\n\r\n"); res.StatusCode != http.StatusOK {
+		t.Errorf("OPTIONS /anything = %d", res.StatusCode)
+	}
+	if strings.Join(reached, "; ") != "middleware OPTIONS /anything; catch-all /anything" {
+		t.Errorf("OPTIONS /anything reached %v", reached)
+	}
+
+	// A handler of its own
+	rt.OptionsStarHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET")
+		w.Header().Set("X-Capabilities", "basic")
+		w.WriteHeader(http.StatusOK)
+	})
+	res = rawRequest(t, addr, "OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if res.StatusCode != http.StatusOK || res.Header.Get("Allow") != "GET" || res.Header.Get("X-Capabilities") != "basic" {
+		t.Errorf("custom OPTIONS * = %d, headers %v", res.StatusCode, res.Header)
+	}
+
+	// nil restores the default
+	rt.OptionsStarHandler(nil)
+	if res := rawRequest(t, addr, "OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n"); res.StatusCode != http.StatusNoContent {
+		t.Errorf("after restoring = %d", res.StatusCode)
+	}
+}
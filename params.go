@@ -0,0 +1,179 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+// Sentinel errors returned by the typed parameter helpers
+// Handlers can map them with errors.Is, typically ErrParamMissing to 404 and ErrParamInvalid to 400
+var (
+	ErrParamMissing = errors.New("tobingo: missing path parameter")
+	ErrParamInvalid = errors.New("tobingo: invalid path parameter")
+)
+
+// routeParams returns the parameter map stored by the router, or nil outside a matched route
+// Middleware wrapping the router holds the request from before matching, so once its next
+// handler returns the parameters are found in the request state instead of the context
+// Callers must treat the map as read-only since other readers share it
+func routeParams(r *http.Request) map[string]string {
+	if params, ok := r.Context().Value(ParamsKey).(map[string]string); ok {
+		return params
+	}
+	if state := stateFrom(r); state != nil {
+		return state.params
+	}
+	return nil
+}
+
+// Params returns all path parameters extracted for the matched route
+// The result is a copy, so modifying it never affects GetParam or other readers
+// Returns an empty, non-nil map for routes without parameters or outside a matched route
+func Params(r *http.Request) map[string]string {
+	params := make(map[string]string, len(routeParams(r)))
+	maps.Copy(params, routeParams(r))
+	return params
+}
+
+// ParamCount returns the number of path parameters extracted for the matched route
+func ParamCount(r *http.Request) int {
+	return len(routeParams(r))
+}
+
+// invalidParam wraps a conversion error so it matches both ErrParamInvalid and the original error
+func invalidParam(key string, err error) error {
+	return fmt.Errorf("%w: %q: %w", ErrParamInvalid, key, err)
+}
+
+// GetParamInt extracts a path parameter and converts it to an int
+// Returns ErrParamMissing when the parameter is absent and ErrParamInvalid when it isn't
+// a base 10 integer that fits in an int
+// Example: For route "/users/:id" and request "/users/123", GetParamInt(r, "id") returns 123
+func GetParamInt(r *http.Request, key string) (int, error) {
+	return P(r).Int(key)
+}
+
+// GetParamInt64 extracts a path parameter and converts it to an int64
+// Returns ErrParamMissing or ErrParamInvalid like GetParamInt
+func GetParamInt64(r *http.Request, key string) (int64, error) {
+	return P(r).Int64(key)
+}
+
+// GetParamUint extracts a path parameter and converts it to a uint, for IDs that must not be negative
+// Returns ErrParamMissing or ErrParamInvalid like GetParamInt; negative values are invalid
+func GetParamUint(r *http.Request, key string) (uint, error) {
+	return P(r).Uint(key)
+}
+
+// GetParamUint64 extracts a path parameter and converts it to a uint64
+// Returns ErrParamMissing or ErrParamInvalid like GetParamUint
+func GetParamUint64(r *http.Request, key string) (uint64, error) {
+	return P(r).Uint64(key)
+}
+
+// ParamOption relaxes the parsing rules of the typed parameter helpers that accept options
+type ParamOption func(*paramConfig)
+
+// paramConfig collects the settings of ParamOption values
+type paramConfig struct {
+	yesNo        bool // Accept "yes" and "no" as booleans
+	uuidBraces   bool // Accept UUIDs wrapped in braces
+	uuidNoDashes bool // Accept UUIDs written as 32 hex digits without dashes
+	onOff        bool // Accept "on" and "off" as booleans, for form checkboxes
+}
+
+// newParamConfig applies the options to an empty configuration
+func newParamConfig(opts []ParamOption) paramConfig {
+	var cfg paramConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// AllowYesNo makes GetParamBool also accept "yes" and "no" (case-insensitive)
+func AllowYesNo() ParamOption {
+	return func(cfg *paramConfig) {
+		cfg.yesNo = true
+	}
+}
+
+// GetParamBool extracts a path parameter and converts it to a bool
+// Accepts "true", "false", "1", and "0" case-insensitively; AllowYesNo adds "yes" and "no"
+// Returns ErrParamMissing when the parameter is absent and ErrParamInvalid for any other spelling
+func GetParamBool(r *http.Request, key string, opts ...ParamOption) (bool, error) {
+	return P(r).Bool(key, opts...)
+}
+
+// GetParamFloat extracts a path parameter and converts it to a float64
+// Returns ErrParamMissing when the parameter is absent and ErrParamInvalid when it doesn't
+// parse or is NaN or infinite, which are never meaningful in a URL
+func GetParamFloat(r *http.Request, key string) (float64, error) {
+	return P(r).Float(key)
+}
+
+// GetParamUUID extracts a path parameter that must be a UUID in the RFC 4122 textual form
+// (8-4-4-4-12 hexadecimal digits) and returns it in canonical lowercase form
+// Uppercase input is accepted; braces and the 32-digit form without dashes are rejected
+// unless AllowUUIDBraces or AllowUUIDWithoutDashes is passed
+// Returns ErrParamMissing when the parameter is absent and ErrParamInvalid when it is malformed
+func GetParamUUID(r *http.Request, key string, opts ...ParamOption) (string, error) {
+	return P(r).UUID(key, opts...)
+}
+
+// AllowUUIDBraces makes GetParamUUID accept the Microsoft style "{...}" wrapped form
+func AllowUUIDBraces() ParamOption {
+	return func(cfg *paramConfig) {
+		cfg.uuidBraces = true
+	}
+}
+
+// AllowUUIDWithoutDashes makes GetParamUUID accept the 32 hex digit form without dashes
+func AllowUUIDWithoutDashes() ParamOption {
+	return func(cfg *paramConfig) {
+		cfg.uuidNoDashes = true
+	}
+}
+
+// parseUUID validates s as a UUID and returns its canonical lowercase dashed form
+func parseUUID(s string, cfg paramConfig) (string, bool) {
+	// Strip optional braces first so both dash variants can be wrapped
+	if cfg.uuidBraces && len(s) >= 2 && s[0] == '{' && s[len(s)-1] == '}' {
+		s = s[1 : len(s)-1]
+	}
+
+	var hex []byte
+	switch {
+	case len(s) == 36:
+		// Dashes must sit exactly between the 8-4-4-4-12 groups
+		for i := 0; i < len(s); i++ {
+			if i == 8 || i == 13 || i == 18 || i == 23 {
+				if s[i] != '-' {
+					return "", false
+				}
+				continue
+			}
+			hex = append(hex, s[i])
+		}
+	case len(s) == 32 && cfg.uuidNoDashes:
+		hex = []byte(s)
+	default:
+		return "", false
+	}
+
+	// Validate and lowercase the 32 hex digits
+	for i, c := range hex {
+		switch {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f':
+		case 'A' <= c && c <= 'F':
+			hex[i] = c + ('a' - 'A')
+		default:
+			return "", false
+		}
+	}
+
+	h := string(hex)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], true
+}
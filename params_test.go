@@ -0,0 +1,230 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// withParams serves path through a route registered for pattern and calls fn with the
+// routed request, to test helpers reading its parameters
+func withParams(t *testing.T, pattern, path string, fn func(r *http.Request)) {
+	t.Helper()
+	rt := NewRastaRouterInitializer()
+	called := false
+	rt.GET(pattern, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		fn(r)
+	})
+	rt.Test("GET", path, nil)
+	if !called {
+		t.Fatalf("%s did not match %s", path, pattern)
+	}
+}
+
+func TestGetParamInt(t *testing.T) {
+	for value, want := range map[string]error{
+		"123":                  nil,
+		"-7":                   nil,
+		"0":                    nil,
+		"2147483648":           nil, // Beyond int32, within int
+		"9223372036854775808":  ErrParamInvalid,
+		"12abc":                ErrParamInvalid,
+		"1.5":                  ErrParamInvalid,
+		"%20":                  ErrParamInvalid,
+		"99999999999999999999": ErrParamInvalid,
+	} {
+		withParams(t, "/users/:id", "/users/"+value, func(r *http.Request) {
+			n, err := GetParamInt(r, "id")
+			if !errors.Is(err, want) || want == nil && err != nil {
+				t.Errorf("GetParamInt(%q) error = %v, want %v", value, err, want)
+			}
+			if want == nil && strconv.Itoa(n) != value {
+				t.Errorf("GetParamInt(%q) = %d", value, n)
+			}
+		})
+	}
+
+	withParams(t, "/users/:id", "/users/42", func(r *http.Request) {
+		if _, err := GetParamInt(r, "missing"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing parameter: %v, want ErrParamMissing", err)
+		}
+		if n, err := GetParamInt64(r, "id"); n != 42 || err != nil {
+			t.Errorf("GetParamInt64 = %d, %v", n, err)
+		}
+	})
+}
+
+func TestGetParamUint(t *testing.T) {
+	withParams(t, "/users/:id/:big", "/users/-1/18446744073709551615", func(r *http.Request) {
+		if _, err := GetParamUint(r, "id"); !errors.Is(err, ErrParamInvalid) {
+			t.Errorf("negative uint: %v, want ErrParamInvalid", err)
+		}
+		if n, err := GetParamUint64(r, "big"); n != 1<<64-1 || err != nil {
+			t.Errorf("GetParamUint64 = %d, %v", n, err)
+		}
+		if _, err := GetParamUint64(r, "nope"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing uint: %v, want ErrParamMissing", err)
+		}
+	})
+}
+
+func TestGetParamBool(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		opts  []ParamOption
+		want  bool
+		err   error
+	}{
+		{"true", nil, true, nil},
+		{"TRUE", nil, true, nil},
+		{"1", nil, true, nil},
+		{"false", nil, false, nil},
+		{"False", nil, false, nil},
+		{"0", nil, false, nil},
+		{"yes", nil, false, ErrParamInvalid},
+		{"no", nil, false, ErrParamInvalid},
+		{"YES", []ParamOption{AllowYesNo()}, true, nil},
+		{"no", []ParamOption{AllowYesNo()}, false, nil},
+		{"t", nil, false, ErrParamInvalid},
+		{"2", nil, false, ErrParamInvalid},
+		{"on", []ParamOption{AllowYesNo()}, false, ErrParamInvalid},
+	} {
+		withParams(t, "/flags/:on", "/flags/"+tc.value, func(r *http.Request) {
+			got, err := GetParamBool(r, "on", tc.opts...)
+			if got != tc.want || !errors.Is(err, tc.err) || tc.err == nil && err != nil {
+				t.Errorf("GetParamBool(%q) = %v, %v, want %v, %v", tc.value, got, err, tc.want, tc.err)
+			}
+		})
+	}
+	withParams(t, "/flags", "/flags", func(r *http.Request) {
+		if _, err := GetParamBool(r, "on"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing bool: %v", err)
+		}
+	})
+}
+
+func TestGetParamFloat(t *testing.T) {
+	for value, want := range map[string]float64{"1.5": 1.5, "-2": -2, "1e3": 1000, "0": 0} {
+		withParams(t, "/price/:p", "/price/"+value, func(r *http.Request) {
+			if got, err := GetParamFloat(r, "p"); got != want || err != nil {
+				t.Errorf("GetParamFloat(%q) = %v, %v, want %v", value, got, err, want)
+			}
+		})
+	}
+	for _, value := range []string{"NaN", "nan", "Inf", "-Inf", "+infinity", "1e400", "abc", "1,5"} {
+		withParams(t, "/price/:p", "/price/"+value, func(r *http.Request) {
+			if _, err := GetParamFloat(r, "p"); !errors.Is(err, ErrParamInvalid) {
+				t.Errorf("GetParamFloat(%q) error = %v, want ErrParamInvalid", value, err)
+			}
+		})
+	}
+	withParams(t, "/price", "/price", func(r *http.Request) {
+		if _, err := GetParamFloat(r, "p"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing float: %v", err)
+		}
+	})
+}
+
+func TestGetParamUUID(t *testing.T) {
+	const canonical = "6ba7b810-9dad-41d1-80b4-00c04fd430c8"
+	for _, tc := range []struct {
+		value string
+		opts  []ParamOption
+		want  string
+	}{
+		{canonical, nil, canonical},
+		{"6BA7B810-9DAD-41D1-80B4-00C04FD430C8", nil, canonical},
+		{"00000000-0000-0000-0000-000000000000", nil, "00000000-0000-0000-0000-000000000000"},
+		{"{" + canonical + "}", []ParamOption{AllowUUIDBraces()}, canonical},
+		{"6ba7b8109dad41d180b400c04fd430c8", []ParamOption{AllowUUIDWithoutDashes()}, canonical},
+		{"{" + canonical + "}", nil, ""},
+		{"6ba7b8109dad41d180b400c04fd430c8", nil, ""},
+		{"6ba7b810-9dad-41d1-80b4-00c04fd430cz", nil, ""},
+		{"6ba7b810-9dad41d1-80b4-00c04fd430c8-", nil, ""},
+		{"6ba7b810-9dad-41d1-80b4", nil, ""},
+		{"not-a-uuid", nil, ""},
+	} {
+		withParams(t, "/items/:id", "/items/"+tc.value, func(r *http.Request) {
+			got, err := GetParamUUID(r, "id", tc.opts...)
+			if tc.want == "" {
+				if !errors.Is(err, ErrParamInvalid) {
+					t.Errorf("GetParamUUID(%q) = %q, %v, want ErrParamInvalid", tc.value, got, err)
+				}
+				return
+			}
+			if got != tc.want || err != nil {
+				t.Errorf("GetParamUUID(%q) = %q, %v, want %q", tc.value, got, err, tc.want)
+			}
+		})
+	}
+	withParams(t, "/items", "/items", func(r *http.Request) {
+		if _, err := GetParamUUID(r, "id"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing UUID: %v", err)
+		}
+	})
+}
+
+func TestParams(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          map[string]string
+	}{
+		{"/about", "/about", map[string]string{}},
+		{"/users/:id", "/users/42", map[string]string{"id": "42"}},
+		{"/orgs/:org/repos/:repo", "/orgs/acme/repos/api", map[string]string{"org": "acme", "repo": "api"}},
+	} {
+		pattern, want := tc.pattern, tc.want
+		withParams(t, pattern, tc.path, func(r *http.Request) {
+			got := Params(r)
+			if got == nil || len(got) != len(want) || ParamCount(r) != len(want) {
+				t.Errorf("%s: Params = %v, ParamCount = %d, want %v", pattern, got, ParamCount(r), want)
+			}
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("%s: %s = %q, want %q", pattern, k, got[k], v)
+				}
+			}
+
+			// The result is a copy
+			got["id"] = "mutated"
+			delete(got, "org")
+			if v, ok := want["id"]; ok && GetParam(r, "id") != v {
+				t.Errorf("%s: mutating Params changed GetParam to %q", pattern, GetParam(r, "id"))
+			}
+			if v, ok := want["org"]; ok && Params(r)["org"] != v {
+				t.Errorf("%s: deleting from Params affected later calls", pattern)
+			}
+		})
+	}
+
+	// Outside a matched route
+	r, _ := http.NewRequest("GET", "/", nil)
+	if got := Params(r); got == nil || len(got) != 0 || ParamCount(r) != 0 {
+		t.Errorf("unrouted request: Params = %v", got)
+	}
+}
+
+func TestGetParamOK(t *testing.T) {
+	shared := func(r *http.Request) (string, bool) { return GetParamOK(r, "org") }
+
+	withParams(t, "/orgs/:org/users", "/orgs/acme/users", func(r *http.Request) {
+		if v, ok := shared(r); v != "acme" || !ok {
+			t.Errorf("present: %q, %v", v, ok)
+		}
+	})
+	withParams(t, "/orgs/:org/users", "/orgs//users", func(r *http.Request) {
+		if v, ok := shared(r); v != "" || !ok {
+			t.Errorf("present but empty: %q, %v", v, ok)
+		}
+	})
+	withParams(t, "/users", "/users", func(r *http.Request) {
+		if v, ok := shared(r); v != "" || ok {
+			t.Errorf("absent: %q, %v", v, ok)
+		}
+		if GetParam(r, "org") != "" {
+			t.Error("GetParam of an absent parameter is not empty")
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package tobingo
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UnixLayout is a layout token for GetParamTime that parses the parameter as Unix seconds
+const UnixLayout = "unix"
+
+// defaultTimeLayouts are tried by GetParamTime when no layouts are given
+var defaultTimeLayouts = []string{time.RFC3339, time.DateOnly}
+
+// SetParamTimeLocation sets the location GetParamTime uses for values without a zone offset,
+// such as "2024-06-01", on requests served by this router; the default is UTC
+func (rt *Rastauter) SetParamTimeLocation(loc *time.Location) {
+	rt.paramTimeLocation.Store(loc)
+}
+
+// GetParamTime extracts a path parameter and parses it with the first matching layout,
+// defaulting to RFC3339 and "2006-01-02"; the UnixLayout token accepts integer Unix seconds
+// Values without an offset are interpreted in UTC unless SetParamTimeLocation chose another location
+// Returns ErrParamMissing or ErrParamInvalid like the other typed helpers
+// Example: For route "/reports/:date/summary" and request "/reports/2024-06-01/summary",
+// GetParamTime(r, "date") returns midnight UTC on June 1st 2024
+func GetParamTime(r *http.Request, key string, layouts ...string) (time.Time, error) {
+	value, err := P(r).required(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+	}
+
+	loc := time.UTC
+	if rt := routerFrom(r); rt != nil {
+		if l := rt.paramTimeLocation.Load(); l != nil {
+			loc = l
+		}
+	}
+
+	for _, layout := range layouts {
+		if layout == UnixLayout {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(n, 0).In(loc), nil
+			}
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalidParam(key, fmt.Errorf("%q matches none of the layouts %q", value, layouts))
+}
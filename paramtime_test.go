@@ -0,0 +1,64 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetParamTime(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		layouts []string
+		want    time.Time
+	}{
+		{"2024-06-01", nil, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-06-01T10:30:00Z", nil, time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)},
+		{"2024-06-01T10:30:00+02:00", nil, time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)},
+		{"1717236000", []string{UnixLayout}, time.Unix(1717236000, 0)},
+		{"01.06.2024", []string{"02.01.2006"}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		withParams(t, "/reports/:date/summary", "/reports/"+tc.value+"/summary", func(r *http.Request) {
+			got, err := GetParamTime(r, "date", tc.layouts...)
+			if err != nil || !got.Equal(tc.want) {
+				t.Errorf("GetParamTime(%q) = %v, %v, want %v", tc.value, got, err, tc.want)
+			}
+			if got.Location() != time.UTC && tc.layouts == nil && tc.value == "2024-06-01" {
+				t.Errorf("date without offset parsed in %v", got.Location())
+			}
+		})
+	}
+
+	for _, value := range []string{"yesterday", "2024-13-01", "1717236000"} {
+		withParams(t, "/reports/:date", "/reports/"+value, func(r *http.Request) {
+			if _, err := GetParamTime(r, "date"); !errors.Is(err, ErrParamInvalid) {
+				t.Errorf("GetParamTime(%q) error = %v, want ErrParamInvalid", value, err)
+			}
+		})
+	}
+	withParams(t, "/reports", "/reports", func(r *http.Request) {
+		if _, err := GetParamTime(r, "date"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("missing date: %v", err)
+		}
+	})
+}
+
+func TestSetParamTimeLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	rt := NewRastaRouterInitializer()
+	rt.SetParamTimeLocation(loc)
+	var got, withOffset time.Time
+	rt.GET("/reports/:date/:at", func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetParamTime(r, "date")
+		withOffset, _ = GetParamTime(r, "at")
+	})
+	rt.Test("GET", "/reports/2024-06-01/2024-06-01T00:00:00Z", nil)
+
+	if !got.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, loc)) || got.Location() != loc {
+		t.Errorf("date without offset = %v, want midnight in %v", got, loc)
+	}
+	if !withOffset.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("explicit offset was overridden: %v", withOffset)
+	}
+}
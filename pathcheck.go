@@ -0,0 +1,41 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// ErrInvalidPath is passed to the error handler for request paths rejected with 400
+var ErrInvalidPath = errors.New("tobingo: invalid request path")
+
+// AllowUnsafePaths turns off the request path check for deployments that must accept paths
+// with control characters or invalid UTF-8, which are otherwise answered with 400 before
+// route matching so they never reach parameters, logs, or queries
+// It may be changed while serving
+func (rt *Rastauter) AllowUnsafePaths(allow bool) {
+	rt.unsafePaths.Store(allow)
+}
+
+// checkPath reports why the path of r can't be served, nil when it's fine
+// The decoded path must be valid UTF-8 without control characters, which also rules out
+// overlong encodings such as "%C0%AE", and a raw path must be correctly percent-encoded
+func checkPath(r *http.Request) error {
+	path := r.URL.Path
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c < 0x20 || c == 0x7f {
+			return fmt.Errorf("%w: %q: control character at byte %d", ErrInvalidPath, path, i)
+		}
+	}
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("%w: %q: invalid UTF-8", ErrInvalidPath, path)
+	}
+	if r.URL.RawPath != "" {
+		if _, err := url.PathUnescape(r.URL.RawPath); err != nil {
+			return fmt.Errorf("%w: %q: %w", ErrInvalidPath, r.URL.RawPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnsafePathsRejected(t *testing.T) {
+	var params []string
+	var errs []error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/files/:name", func(w http.ResponseWriter, r *http.Request) {
+		params = append(params, Params(r)["name"])
+	})
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		errs = append(errs, err)
+		DefaultErrorHandler(w, r, status, err)
+	})
+
+	for name, target := range map[string]string{
+		"NUL":               "/files/a%00b",
+		"CRLF":              "/files/a%0D%0ASet-Cookie:%20x=1",
+		"tab":               "/files/a%09b",
+		"DEL":               "/files/a%7Fb",
+		"overlong dot":      "/files/%C0%AE%C0%AE",
+		"overlong slash":    "/files/%C0%AFetc",
+		"truncated UTF-8":   "/files/%E2%82",
+		"lone continuation": "/files/%80",
+	} {
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusBadRequest {
+			t.Errorf("%s: %s = %d, want 400", name, target, res.StatusCode())
+		}
+	}
+
+	// A raw path with a broken escape is rejected too, it can't come through httptest.NewRequest
+	r := httptest.NewRequest("GET", "/files/x", nil)
+	r.URL.Path, r.URL.RawPath = "/files/a%zz", "/files/a%zz"
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid escape = %d, want 400", rec.Code)
+	}
+
+	if len(params) != 0 {
+		t.Errorf("rejected paths reached the handler with params %q", params)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("error handler got %v, want ErrInvalidPath", err)
+		}
+	}
+
+	// Clean paths, including valid multibyte UTF-8, still match
+	for target, want := range map[string]string{"/files/report.pdf": "report.pdf", "/files/%E2%82%AC": "€", "/files/caf%C3%A9": "café"} {
+		params = nil
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusOK || len(params) != 1 || params[0] != want {
+			t.Errorf("%s = %d, params %q", target, res.StatusCode(), params)
+		}
+	}
+}
+
+func TestAllowUnsafePaths(t *testing.T) {
+	var got string
+	rt := NewRastaRouterInitializer()
+	rt.GET("/files/:name", func(w http.ResponseWriter, r *http.Request) { got = Params(r)["name"] })
+
+	rt.AllowUnsafePaths(true)
+	if res := rt.Test("GET", "/files/a%00b", nil); res.StatusCode() != http.StatusOK || got != "a\x00b" {
+		t.Errorf("relaxed: %d, param %q", res.StatusCode(), got)
+	}
+
+	rt.AllowUnsafePaths(false)
+	got = ""
+	if res := rt.Test("GET", "/files/a%00b", nil); res.StatusCode() != http.StatusBadRequest || strings.ContainsRune(got, 0) {
+		t.Errorf("checked again: %d, param %q", res.StatusCode(), got)
+	}
+}
@@ -0,0 +1,200 @@
+package tobingo
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PathParams is a read-only view of the path parameters of a matched route with typed accessors
+// It wraps the router's storage without copying, so it is cheap to create and safe for
+// concurrent reads; the zero value behaves like a route without parameters
+type PathParams struct {
+	m map[string]string // Parameters stored by the router, never modified
+}
+
+// P returns the path parameters of the request as a PathParams view
+// Example: id, err := tobingo.P(r).Int("id")
+func P(r *http.Request) PathParams {
+	return PathParams{m: routeParams(r)}
+}
+
+// Get returns the parameter value, or an empty string when it doesn't exist
+func (p PathParams) Get(key string) string {
+	return p.m[key]
+}
+
+// Has reports whether the matched route defines the parameter, even if its value is empty
+func (p PathParams) Has(key string) bool {
+	_, ok := p.m[key]
+	return ok
+}
+
+// Len returns the number of parameters
+func (p PathParams) Len() int {
+	return len(p.m)
+}
+
+// required returns the value of a parameter, or ErrParamMissing when it is absent or empty
+func (p PathParams) required(key string) (string, error) {
+	value := p.m[key]
+	if value == "" {
+		return "", fmt.Errorf("%w: %q", ErrParamMissing, key)
+	}
+	return value, nil
+}
+
+// Int converts the parameter to an int, see GetParamInt
+func (p PathParams) Int(key string) (int, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(value, 10, strconv.IntSize)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return int(n), nil
+}
+
+// IntDefault converts the parameter to an int, returning def when it is missing or invalid
+func (p PathParams) IntDefault(key string, def int) int {
+	if n, err := p.Int(key); err == nil {
+		return n
+	}
+	return def
+}
+
+// Int64 converts the parameter to an int64, see GetParamInt64
+func (p PathParams) Int64(key string) (int64, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return n, nil
+}
+
+// Int64Default converts the parameter to an int64, returning def when it is missing or invalid
+func (p PathParams) Int64Default(key string, def int64) int64 {
+	if n, err := p.Int64(key); err == nil {
+		return n
+	}
+	return def
+}
+
+// Uint converts the parameter to a uint, see GetParamUint
+func (p PathParams) Uint(key string) (uint, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(value, 10, strconv.IntSize)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return uint(n), nil
+}
+
+// Uint64 converts the parameter to a uint64, see GetParamUint64
+func (p PathParams) Uint64(key string) (uint64, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return n, nil
+}
+
+// Bool converts the parameter to a bool, see GetParamBool
+func (p PathParams) Bool(key string, opts ...ParamOption) (bool, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := parseBool(value, newParamConfig(opts))
+	if !ok {
+		return false, invalidParam(key, fmt.Errorf("%q is not a boolean", value))
+	}
+	return b, nil
+}
+
+// parseBool accepts "true", "false", "1", and "0" case-insensitively, plus "yes" and "no"
+// or "on" and "off" when enabled, reporting false for any other spelling
+func parseBool(value string, cfg paramConfig) (b bool, ok bool) {
+	switch strings.ToLower(value) {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	case "yes":
+		return true, cfg.yesNo
+	case "no":
+		return false, cfg.yesNo
+	case "on":
+		return true, cfg.onOff
+	case "off":
+		return false, cfg.onOff
+	}
+	return false, false
+}
+
+// BoolDefault converts the parameter to a bool, returning def when it is missing or invalid
+func (p PathParams) BoolDefault(key string, def bool, opts ...ParamOption) bool {
+	if b, err := p.Bool(key, opts...); err == nil {
+		return b
+	}
+	return def
+}
+
+// Float converts the parameter to a float64, see GetParamFloat
+func (p PathParams) Float(key string) (float64, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, invalidParam(key, fmt.Errorf("%q is not a finite number", value))
+	}
+	return f, nil
+}
+
+// FloatDefault converts the parameter to a float64, returning def when it is missing or invalid
+func (p PathParams) FloatDefault(key string, def float64) float64 {
+	if f, err := p.Float(key); err == nil {
+		return f
+	}
+	return def
+}
+
+// UUID validates the parameter as a UUID and returns its canonical form, see GetParamUUID
+func (p PathParams) UUID(key string, opts ...ParamOption) (string, error) {
+	value, err := p.required(key)
+	if err != nil {
+		return "", err
+	}
+
+	id, ok := parseUUID(value, newParamConfig(opts))
+	if !ok {
+		return "", invalidParam(key, fmt.Errorf("%q is not a UUID", value))
+	}
+	return id, nil
+}
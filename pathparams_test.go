@@ -0,0 +1,99 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestPathParamsMethods(t *testing.T) {
+	const path = "/v/42/-3/7/true/2.5/6ba7b810-9dad-11d1-80b4-00c04fd430c8/x"
+	withParams(t, "/v/:int/:neg/:uint/:bool/:float/:uuid/:word", path, func(r *http.Request) {
+		p := P(r)
+		if p.Get("word") != "x" || p.Get("nope") != "" || !p.Has("int") || p.Has("nope") || p.Len() != 7 {
+			t.Errorf("Get/Has/Len: %q %q %v %v %d", p.Get("word"), p.Get("nope"), p.Has("int"), p.Has("nope"), p.Len())
+		}
+
+		if n, err := p.Int("int"); n != 42 || err != nil {
+			t.Errorf("Int = %d, %v", n, err)
+		}
+		if n, err := p.Int64("neg"); n != -3 || err != nil {
+			t.Errorf("Int64 = %d, %v", n, err)
+		}
+		if n, err := p.Uint("uint"); n != 7 || err != nil {
+			t.Errorf("Uint = %d, %v", n, err)
+		}
+		if n, err := p.Uint64("uint"); n != 7 || err != nil {
+			t.Errorf("Uint64 = %d, %v", n, err)
+		}
+		if b, err := p.Bool("bool"); !b || err != nil {
+			t.Errorf("Bool = %v, %v", b, err)
+		}
+		if f, err := p.Float("float"); f != 2.5 || err != nil {
+			t.Errorf("Float = %v, %v", f, err)
+		}
+		if id, err := p.UUID("uuid"); id != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" || err != nil {
+			t.Errorf("UUID = %q, %v", id, err)
+		}
+
+		if _, err := p.Uint("neg"); !errors.Is(err, ErrParamInvalid) {
+			t.Errorf("Uint of a negative = %v", err)
+		}
+		if _, err := p.UUID("word"); !errors.Is(err, ErrParamInvalid) {
+			t.Errorf("UUID of a word = %v", err)
+		}
+		if _, err := p.Int("nope"); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("Int of a missing parameter = %v", err)
+		}
+	})
+}
+
+func TestPathParamsDefaults(t *testing.T) {
+	withParams(t, "/d/:n/:b/:f/:word", "/d/5/false/1.25/x", func(r *http.Request) {
+		p := P(r)
+		for _, tc := range []struct {
+			name      string
+			got, want any
+		}{
+			{"IntDefault present", p.IntDefault("n", 9), 5},
+			{"IntDefault invalid", p.IntDefault("word", 9), 9},
+			{"IntDefault missing", p.IntDefault("nope", 9), 9},
+			{"Int64Default present", p.Int64Default("n", 9), int64(5)},
+			{"Int64Default invalid", p.Int64Default("word", 9), int64(9)},
+			{"BoolDefault present", p.BoolDefault("b", true), false},
+			{"BoolDefault invalid", p.BoolDefault("word", true), true},
+			{"FloatDefault present", p.FloatDefault("f", 9), 1.25},
+			{"FloatDefault missing", p.FloatDefault("nope", 9), 9.0},
+		} {
+			if tc.got != tc.want {
+				t.Errorf("%s = %v, want %v", tc.name, tc.got, tc.want)
+			}
+		}
+	})
+}
+
+func TestPathParamsZeroValueAndConcurrentReads(t *testing.T) {
+	var zero PathParams
+	if zero.Len() != 0 || zero.Has("id") || zero.Get("id") != "" {
+		t.Error("zero PathParams is not empty")
+	}
+	if _, err := zero.Int("id"); !errors.Is(err, ErrParamMissing) {
+		t.Errorf("zero Int = %v", err)
+	}
+
+	withParams(t, "/users/:id", "/users/42", func(r *http.Request) {
+		p := P(r)
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if n, _ := p.Int("id"); n != 42 || GetParam(r, "id") != "42" {
+					t.Error("concurrent read failed")
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
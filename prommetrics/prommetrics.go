@@ -0,0 +1,107 @@
+// Package prommetrics records tobingo request metrics with the Prometheus client library
+// It lives in its own module so the core router stays free of third-party dependencies
+package prommetrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ShourovRoy/tobingo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// config holds the settings collected from Option values
+type config struct {
+	namespace string    // Prefix of every metric name, e.g. "myapp"
+	subsystem string    // Middle part of every metric name, "http" by default
+	buckets   []float64 // Histogram buckets in seconds, prometheus.DefBuckets by default
+}
+
+// Option configures the recorder
+type Option func(*config)
+
+// WithNamespace sets the namespace prefixed to every metric name
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithSubsystem sets the subsystem part of every metric name, "http" by default
+func WithSubsystem(subsystem string) Option {
+	return func(c *config) {
+		c.subsystem = subsystem
+	}
+}
+
+// WithBuckets sets the buckets of the request duration histogram, in seconds
+func WithBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// Recorder is a tobingo.MetricsRecorder backed by Prometheus collectors:
+// requests_total{method,route,status}, request_duration_seconds{method,route}, and
+// requests_in_flight
+type Recorder struct {
+	requests *prometheus.CounterVec   // Finished requests
+	duration *prometheus.HistogramVec // Request latency
+	inFlight prometheus.Gauge         // Requests being served
+}
+
+// New creates a recorder and registers its collectors with reg
+// Returns the registration error, e.g. when the metrics are already registered
+// Example: rec, err := prommetrics.New(prometheus.DefaultRegisterer, prommetrics.WithNamespace("shop"))
+func New(reg prometheus.Registerer, opts ...Option) (*Recorder, error) {
+	c := config{subsystem: "http", buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	rec := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: c.subsystem,
+			Name:      "requests_total",
+			Help:      "Requests served, by method, route pattern, and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: c.subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Time spent serving requests, by method and route pattern.",
+			Buckets:   c.buckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Subsystem: c.subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Requests currently being served.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{rec.requests, rec.duration, rec.inFlight} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// Middleware returns the tobingo.Metrics middleware reporting to this recorder
+// Example: rt.Use(rec.Middleware())
+func (rec *Recorder) Middleware() tobingo.Middleware {
+	return tobingo.Metrics(rec)
+}
+
+// InFlight implements tobingo.MetricsRecorder
+func (rec *Recorder) InFlight(delta int) {
+	rec.inFlight.Add(float64(delta))
+}
+
+// Observe implements tobingo.MetricsRecorder
+func (rec *Recorder) Observe(method, route string, status int, duration time.Duration) {
+	rec.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	rec.duration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
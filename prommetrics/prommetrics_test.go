@@ -0,0 +1,153 @@
+package prommetrics
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ShourovRoy/tobingo"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// labels renders the labels of m as name=value pairs in their sorted order
+func labels(m *dto.Metric) string {
+	var pairs []string
+	for _, l := range m.GetLabel() {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	return strings.Join(pairs, ",")
+}
+
+// gather scrapes reg into the metric families by name
+func gather(t *testing.T, reg *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	return byName
+}
+
+func TestRecorder(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := New(reg, WithNamespace("shop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := tobingo.NewRastaRouterInitializer()
+	rt.Use(rec.Middleware())
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if tobingo.GetParam(r, "id") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	for _, target := range []string{"/users/1", "/users/2", "/users/0", "/scanner/wp-login.php", "/scanner/.env"} {
+		rt.Test("GET", target, nil)
+	}
+
+	families := gather(t, reg)
+	requests := families["shop_http_requests_total"]
+	if requests == nil {
+		t.Fatalf("no requests_total among %v", families)
+	}
+	counts := map[string]float64{}
+	for _, m := range requests.GetMetric() {
+		counts[labels(m)] = m.GetCounter().GetValue()
+	}
+	want := map[string]float64{
+		"method=GET,route=/users/:id,status=200": 2,
+		"method=GET,route=/users/:id,status=404": 1,
+		"method=GET,route=unmatched,status=404":  2,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("requests_total = %v, want %v", counts, want)
+	}
+	for series, n := range want {
+		if counts[series] != n {
+			t.Errorf("requests_total{%s} = %v, want %v", series, counts[series], n)
+		}
+	}
+
+	duration := families["shop_http_request_duration_seconds"]
+	if duration == nil || len(duration.GetMetric()) != 2 {
+		t.Fatalf("request_duration_seconds = %v", duration)
+	}
+	for _, m := range duration.GetMetric() {
+		if route := labels(m); route == "method=GET,route=/users/:id" && m.GetHistogram().GetSampleCount() != 3 {
+			t.Errorf("%s observed %d requests, want 3", route, m.GetHistogram().GetSampleCount())
+		}
+	}
+
+	inFlight := families["shop_http_requests_in_flight"]
+	if inFlight == nil || inFlight.GetMetric()[0].GetGauge().GetValue() != 0 {
+		t.Errorf("requests_in_flight = %v, want 0 once served", inFlight)
+	}
+}
+
+func TestRecorderInFlight(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := New(reg, WithSubsystem("api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := tobingo.NewRastaRouterInitializer()
+	rt.Use(rec.Middleware())
+	var during float64
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		during = gather(t, reg)["api_requests_in_flight"].GetMetric()[0].GetGauge().GetValue()
+	})
+	rt.Test("GET", "/", nil)
+	if during != 1 {
+		t.Errorf("requests_in_flight while serving = %v, want 1", during)
+	}
+}
+
+func TestNewRegistersOnce(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := New(reg, WithBuckets(0.01, 0.1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(reg); err == nil {
+		t.Error("registering the metrics twice succeeded")
+	}
+}
+
+func TestRecorderCountsPanics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := tobingo.NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(rec.Middleware())
+	rt.GET("/boom", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	rt.Test("GET", "/boom", nil)
+
+	families := gather(t, reg)
+	var found bool
+	for _, m := range families["http_requests_total"].GetMetric() {
+		if labels(m) == "method=GET,route=/boom,status=500" && m.GetCounter().GetValue() == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("requests_total = %v, want one GET /boom 500", families["http_requests_total"])
+	}
+	duration := families["http_request_duration_seconds"]
+	if duration == nil || duration.GetMetric()[0].GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("request_duration_seconds = %v, want one sample", duration)
+	}
+	if v := families["http_requests_in_flight"].GetMetric()[0].GetGauge().GetValue(); v != 0 {
+		t.Errorf("requests_in_flight = %v after the panic", v)
+	}
+}
@@ -0,0 +1,58 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ErrNotProvided is returned by Get when no value of the requested type was provided
+var ErrNotProvided = errors.New("tobingo: dependency not provided")
+
+// providedKey is the context key of the value provided for T, one distinct key per type
+type providedKey[T any] struct{}
+
+// Provide makes value available to every request handled by rt through Get and MustGet,
+// keyed by its type T, so services like a database pool need no package-level globals
+// Provided values share the single values map of WithValue, attached once per request
+// before middleware runs; providing T again replaces the previous value
+// Example: tobingo.Provide[*sql.DB](rt, db)
+func Provide[T any](rt *Rastauter, value T) {
+	rt.WithValue(providedKey[T]{}, value)
+}
+
+// Get returns the value provided for T to the router serving r, or an error matching
+// ErrNotProvided naming the type when there is none
+// Example: db, err := tobingo.Get[*sql.DB](r)
+func Get[T any](r *http.Request) (T, error) {
+	value, ok := r.Context().Value(providedKey[T]{}).(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%w: %s", ErrNotProvided, reflect.TypeFor[T]())
+	}
+	return value, nil
+}
+
+// MustGet returns the value provided for T like Get, panicking when there is none, which
+// is a wiring mistake the recovery middleware turns into a 500
+// Example: cfg := tobingo.MustGet[*Config](r)
+func MustGet[T any](r *http.Request) T {
+	value, err := Get[T](r)
+	if err != nil {
+		panic(err.Error())
+	}
+	return value
+}
+
+// WithTestProvided replaces the value provided for T during one Test request, e.g. with a
+// fake; unlike WithTestValue it wins over the router's own values
+// Example: rt.Test("GET", "/users/1", nil, tobingo.WithTestProvided[UserStore](fakeStore))
+func WithTestProvided[T any](value T) TestOption {
+	return func(tr *testRequest) {
+		if tr.overrides == nil {
+			tr.overrides = make(map[any]any)
+		}
+		tr.overrides[providedKey[T]{}] = value
+	}
+}
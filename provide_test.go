@@ -0,0 +1,112 @@
+package tobingo
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// userStore and the types below are dependencies a handler might need
+type userStore interface{ Name(id string) string }
+
+type mapStore map[string]string
+
+func (m mapStore) Name(id string) string { return m[id] }
+
+type appConfig struct{ Greeting string }
+
+type requestLimit int
+
+func TestProvide(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	Provide[userStore](rt, mapStore{"7": "ann"})
+	Provide(rt, &appConfig{Greeting: "hello"})
+	Provide(rt, requestLimit(10))
+	Provide(rt, 42) // A plain int is another type than requestLimit
+
+	var fromMiddleware string
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromMiddleware = MustGet[*appConfig](r).Greeting
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		store := MustGet[userStore](r)
+		cfg := MustGet[*appConfig](r)
+		limit, err := Get[requestLimit](r)
+		if err != nil || limit != 10 || MustGet[int](r) != 42 {
+			t.Errorf("limit %d, %v; int %d", limit, err, MustGet[int](r))
+		}
+		io.WriteString(w, cfg.Greeting+" "+store.Name(Params(r)["id"]))
+	})
+
+	if res := rt.Test("GET", "/users/7", nil); res.BodyString() != "hello ann" {
+		t.Errorf("body %q", res.BodyString())
+	}
+	if fromMiddleware != "hello" {
+		t.Errorf("middleware saw %q", fromMiddleware)
+	}
+
+	// Providing a type again replaces its value
+	Provide(rt, &appConfig{Greeting: "hi"})
+	if res := rt.Test("GET", "/users/7", nil); res.BodyString() != "hi ann" {
+		t.Errorf("after replacing: %q", res.BodyString())
+	}
+}
+
+func TestProvideMissing(t *testing.T) {
+	var getErr error
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	Provide(rt, mapStore{}) // The concrete type doesn't satisfy a request for the interface
+	rt.GET("/get", func(w http.ResponseWriter, r *http.Request) {
+		_, getErr = Get[userStore](r)
+	})
+	rt.GET("/must", func(w http.ResponseWriter, r *http.Request) {
+		MustGet[*appConfig](r)
+		io.WriteString(w, "unreachable")
+	})
+
+	rt.Test("GET", "/get", nil)
+	if !errors.Is(getErr, ErrNotProvided) || !strings.Contains(getErr.Error(), "tobingo.userStore") {
+		t.Errorf("Get = %v, want ErrNotProvided naming the type", getErr)
+	}
+	if res := rt.Test("GET", "/must", nil); res.StatusCode() != http.StatusInternalServerError || res.BodyString() == "unreachable" {
+		t.Errorf("MustGet of a missing value: %d %q", res.StatusCode(), res.BodyString())
+	}
+	msg := panicMessage(func() { MustGet[*appConfig](httptest.NewRequest("GET", "/", nil)) })
+	if !strings.Contains(msg, ErrNotProvided.Error()) || !strings.Contains(msg, "*tobingo.appConfig") {
+		t.Errorf("MustGet panicked with %q", msg)
+	}
+}
+
+func TestWithTestProvided(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	Provide[userStore](rt, mapStore{"1": "real"})
+	Provide(rt, &appConfig{Greeting: "hello"})
+	rt.WithValue("tenant", "acme")
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, MustGet[*appConfig](r).Greeting+" "+MustGet[userStore](r).Name(Params(r)["id"])+" "+r.Context().Value("tenant").(string))
+	})
+
+	res := rt.Test("GET", "/users/1", nil, WithTestProvided[userStore](mapStore{"1": "fake"}))
+	if res.BodyString() != "hello fake acme" {
+		t.Errorf("with the fake: %q", res.BodyString())
+	}
+	// The override is one request's only
+	if res := rt.Test("GET", "/users/1", nil); res.BodyString() != "hello real acme" {
+		t.Errorf("after the override: %q", res.BodyString())
+	}
+
+	// An override can supply a dependency the router lacks
+	bare := NewRastaRouterInitializer()
+	bare.GET("/", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, MustGet[*appConfig](r).Greeting) })
+	if res := bare.Test("GET", "/", nil, WithTestProvided(&appConfig{Greeting: "test"})); res.BodyString() != "test" {
+		t.Errorf("override without Provide: %q", res.BodyString())
+	}
+}
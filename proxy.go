@@ -0,0 +1,83 @@
+package tobingo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// proxyMethods are the methods a Proxy route forwards
+var proxyMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// ProxyOptions configures a route registered with Proxy
+type ProxyOptions struct {
+	PreserveHost   bool                            // Send the client's Host instead of the target's
+	Director       func(pr *httputil.ProxyRequest) // Adjusts the outgoing request last; pr.In carries the path parameters
+	ModifyResponse func(resp *http.Response) error // Edits the backend response, an error answers 502
+	Transport      http.RoundTripper               // Transport to the backend, http.DefaultTransport when nil
+	FlushInterval  time.Duration                   // Flush interval while copying the body, -1 flushes after every write
+}
+
+// Proxy forwards requests matching pattern to target with httputil.ReverseProxy
+// pattern must end in a catch-all, whose capture replaces the matched prefix and is joined
+// to the target's path: "/legacy/*path" sends "/legacy/a/b" to target.Path + "/a/b"
+// X-Forwarded-For, X-Forwarded-Host, and X-Forwarded-Proto are set, trailers copied, and
+// backend failures answered with 502 through the router's error handler
+// Requests with any common method are forwarded
+// Example: rt.Proxy("/tenants/:tenant/legacy/*path", backend, tobingo.ProxyOptions{Director: func(pr *httputil.ProxyRequest) { pr.Out.Header.Set("X-Tenant", tobingo.GetParam(pr.In, "tenant")) }})
+func (rt *Rastauter) Proxy(pattern string, target *url.URL, opts ...ProxyOptions) {
+	var o ProxyOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if err := rt.tryProxy(pattern, target, o); err != nil {
+		panic(err.Error())
+	}
+}
+
+// tryProxy implements Proxy, returning an error where Proxy panics
+func (rt *Rastauter) tryProxy(pattern string, target *url.URL, o ProxyOptions) error {
+	if !strings.HasPrefix(strings.Trim(pattern, " "), "/") {
+		return fmt.Errorf("%w: %q: must start with /", ErrInvalidPattern, pattern)
+	}
+	if _, catchAll := catchAllPrefix(pattern); !catchAll {
+		return fmt.Errorf("%w: %q: proxy routes must end in a catch-all", ErrInvalidPattern, pattern)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			rest, _ := GetWildcard(pr.In)
+			pr.Out.URL.Path = "/" + strings.TrimPrefix(rest, "/")
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if o.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			if o.Director != nil {
+				o.Director(pr)
+			}
+		},
+		ModifyResponse: o.ModifyResponse,
+		Transport:      o.Transport,
+		FlushInterval:  o.FlushInterval,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			// r is the outgoing request, its context still leads to the router
+			loggerFor(r).Error("tobingo: proxy request failed", "method", r.Method, "url", r.URL.String(), "error", err)
+			handleError(w, r, http.StatusBadGateway, err)
+		},
+	}
+
+	for _, method := range proxyMethods {
+		if _, err := rt.tryAddRoute(method, pattern, proxy.ServeHTTP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package tobingo
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// seenRequest is what the backend received
+type seenRequest struct {
+	method, uri, host string
+	header            http.Header
+	body              string
+}
+
+// proxyBackend starts a backend that reports each request on seen and answers with a trailer
+func proxyBackend(t *testing.T) (*url.URL, <-chan seenRequest) {
+	t.Helper()
+	seen := make(chan seenRequest, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		seen <- seenRequest{r.Method, r.RequestURI, r.Host, r.Header.Clone(), string(body)}
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("X-Backend", "legacy")
+		io.WriteString(w, "from backend")
+		w.Header().Set("X-Checksum", "abc123")
+	}))
+	t.Cleanup(backend.Close)
+	target, _ := url.Parse(backend.URL + "/api")
+	return target, seen
+}
+
+func TestProxy(t *testing.T) {
+	target, seen := proxyBackend(t)
+	rt := NewRastaRouterInitializer()
+	rt.Proxy("/legacy/*path", target)
+	rt.Proxy("/kept/*path", target, ProxyOptions{PreserveHost: true})
+	front := httptest.NewServer(rt)
+	defer front.Close()
+
+	res, err := http.Post(front.URL+"/legacy/orders/7?expand=items", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	got := <-seen
+
+	if got.method != "POST" || got.uri != "/api/orders/7?expand=items" || got.body != "payload" {
+		t.Errorf("backend got %s %s %q", got.method, got.uri, got.body)
+	}
+	if got.host != target.Host {
+		t.Errorf("Host = %q, want the target's %q", got.host, target.Host)
+	}
+	frontHost := strings.TrimPrefix(front.URL, "http://")
+	for name, want := range map[string]string{
+		"X-Forwarded-For":   "127.0.0.1",
+		"X-Forwarded-Host":  frontHost,
+		"X-Forwarded-Proto": "http",
+	} {
+		if v := got.header.Get(name); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+	if res.StatusCode != http.StatusOK || string(body) != "from backend" || res.Header.Get("X-Backend") != "legacy" {
+		t.Errorf("response %d %q, X-Backend %q", res.StatusCode, body, res.Header.Get("X-Backend"))
+	}
+	if res.Trailer.Get("X-Checksum") != "abc123" {
+		t.Errorf("trailers = %v", res.Trailer)
+	}
+
+	// The bare prefix goes to the target's own path
+	if res, err := http.Get(front.URL + "/legacy/"); err == nil {
+		res.Body.Close()
+	}
+	if got := <-seen; got.uri != "/api/" {
+		t.Errorf("bare prefix reached %s", got.uri)
+	}
+
+	// PreserveHost keeps the client's Host
+	if res, err := http.Get(front.URL + "/kept/x"); err == nil {
+		res.Body.Close()
+	}
+	if got := <-seen; got.host != frontHost || got.uri != "/api/x" {
+		t.Errorf("PreserveHost: backend got Host %q, %s", got.host, got.uri)
+	}
+}
+
+func TestProxyHooks(t *testing.T) {
+	target, seen := proxyBackend(t)
+	rt := NewRastaRouterInitializer()
+	rt.Proxy("/tenants/:tenant/legacy/*path", target, ProxyOptions{
+		Director: func(pr *httputil.ProxyRequest) {
+			pr.Out.Header.Set("X-Tenant", Params(pr.In)["tenant"])
+			pr.Out.URL.Path = "/" + Params(pr.In)["tenant"] + pr.Out.URL.Path
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			resp.Header.Set("X-Proxied", "yes")
+			return nil
+		},
+	})
+
+	res := rt.Test("GET", "/tenants/acme/legacy/reports", nil)
+	got := <-seen
+	if got.header.Get("X-Tenant") != "acme" || got.uri != "/acme/api/reports" {
+		t.Errorf("backend got %s with X-Tenant %q", got.uri, got.header.Get("X-Tenant"))
+	}
+	if res.Header("X-Proxied") != "yes" || res.BodyString() != "from backend" {
+		t.Errorf("response X-Proxied %q, body %q", res.Header("X-Proxied"), res.BodyString())
+	}
+}
+
+func TestProxyBadGateway(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	target, _ := url.Parse(down.URL)
+	down.Close()
+
+	rejected := errors.New("response rejected")
+	var statuses []int
+	var errs []error
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		statuses, errs = append(statuses, status), append(errs, err)
+		DefaultErrorHandler(w, r, status, err)
+	})
+	rt.Proxy("/down/*path", target)
+
+	live, _ := proxyBackend(t)
+	rt.Proxy("/rejected/*path", live, ProxyOptions{ModifyResponse: func(*http.Response) error { return rejected }})
+
+	if res := rt.Test("GET", "/down/x", nil); res.StatusCode() != http.StatusBadGateway {
+		t.Errorf("backend down = %d, want 502", res.StatusCode())
+	}
+	if res := rt.Test("GET", "/rejected/x", nil); res.StatusCode() != http.StatusBadGateway || strings.Contains(res.BodyString(), "from backend") {
+		t.Errorf("ModifyResponse error = %d %q, want 502", res.StatusCode(), res.BodyString())
+	}
+	if len(statuses) != 2 || statuses[0] != http.StatusBadGateway || !errors.Is(errs[1], rejected) {
+		t.Errorf("error handler got %v %v", statuses, errs)
+	}
+}
+
+func TestProxyInvalidPattern(t *testing.T) {
+	target, _ := url.Parse("http://backend.invalid")
+	for _, pattern := range []string{"/legacy", "legacy/*path", "/legacy/:id"} {
+		msg := panicMessage(func() { NewRastaRouterInitializer().Proxy(pattern, target) })
+		if !strings.Contains(msg, ErrInvalidPattern.Error()) {
+			t.Errorf("Proxy(%q) panicked with %q", pattern, msg)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Sentinel errors returned by the typed query helpers, mirroring the path parameter errors
+var (
+	ErrQueryMissing = errors.New("tobingo: missing query parameter")
+	ErrQueryInvalid = errors.New("tobingo: invalid query parameter")
+)
+
+// queryValues returns the parsed query string of r and the first parse error
+// Within the router the result is computed once per request and cached in its context
+func queryValues(r *http.Request) (url.Values, error) {
+	state := stateFrom(r)
+	if state == nil {
+		return url.ParseQuery(r.URL.RawQuery)
+	}
+
+	state.queryOnce.Do(func() {
+		state.query, state.queryErr = url.ParseQuery(r.URL.RawQuery)
+	})
+	return state.query, state.queryErr
+}
+
+// QueryErr returns the error hit while parsing the query string, such as bad percent-encoding
+// The other query helpers skip malformed pairs silently, so check this when strictness matters
+func QueryErr(r *http.Request) error {
+	_, err := queryValues(r)
+	return err
+}
+
+// Query returns the first value of the query parameter, or an empty string when it is absent
+func Query(r *http.Request, key string) string {
+	values, _ := queryValues(r)
+	return values.Get(key)
+}
+
+// QueryDefault returns the first value of the query parameter, or def when it is absent or empty
+func QueryDefault(r *http.Request, key, def string) string {
+	if value := Query(r, key); value != "" {
+		return value
+	}
+	return def
+}
+
+// QueryStrings returns every value of a repeated query parameter in order, e.g.
+// []string{"a", "b"} for "?tag=a&tag=b"; returns nil when the parameter is absent
+func QueryStrings(r *http.Request, key string) []string {
+	values, _ := queryValues(r)
+	return values[key]
+}
+
+// requiredQuery returns the first value of a query parameter, or ErrQueryMissing when it is absent or empty
+func requiredQuery(r *http.Request, key string) (string, error) {
+	value := Query(r, key)
+	if value == "" {
+		return "", fmt.Errorf("%w: %q", ErrQueryMissing, key)
+	}
+	return value, nil
+}
+
+// QueryInt converts the query parameter to an int
+// Returns ErrQueryMissing when it is absent and ErrQueryInvalid when it isn't an integer
+func QueryInt(r *http.Request, key string) (int, error) {
+	value, err := requiredQuery(r, key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(value, 10, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q: %w", ErrQueryInvalid, key, err)
+	}
+	return int(n), nil
+}
+
+// QueryIntDefault converts the query parameter to an int, returning def when it is missing or invalid
+func QueryIntDefault(r *http.Request, key string, def int) int {
+	if n, err := QueryInt(r, key); err == nil {
+		return n
+	}
+	return def
+}
+
+// QueryBool converts the query parameter to a bool using the same spellings as GetParamBool
+// Returns ErrQueryMissing when it is absent and ErrQueryInvalid for any other spelling
+func QueryBool(r *http.Request, key string, opts ...ParamOption) (bool, error) {
+	value, err := requiredQuery(r, key)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := parseBool(value, newParamConfig(opts))
+	if !ok {
+		return false, fmt.Errorf("%w: %q: %q is not a boolean", ErrQueryInvalid, key, value)
+	}
+	return b, nil
+}
+
+// queryBinder binds query parameters; fields are optional unless tagged "required"
+var queryBinder = binder{
+	tag:     "query",
+	source:  "query parameters",
+	missing: ErrQueryMissing,
+	invalid: ErrQueryInvalid,
+}
+
+// BindQuery populates the struct pointed to by dst from the query string using the `query` tag,
+// supporting the same field types as BindForm, e.g. `query:"page,required"`
+// Pointer fields stay nil when the parameter is absent, telling "absent" apart from zero; a
+// `default:"20"` tag supplies a value instead; slices collect repeated keys and, with the
+// SplitCommaValues option, comma separated values
+// Every failing field is reported together in a *BindError whose entries wrap ErrQueryMissing
+// or ErrQueryInvalid
+// Example: var f struct{ Page int `query:"page" default:"1"`; Tags []string `query:"tag"` }; err := tobingo.BindQuery(r, &f)
+func BindQuery(r *http.Request, dst any, opts ...BindOption) error {
+	return validateBound(r, dst, bindQuery(r, dst, opts...))
+}
+
+// bindQuery implements BindQuery without running validation, so Bind can validate once at the end
+func bindQuery(r *http.Request, dst any, opts ...BindOption) error {
+	cfg := newBindConfig(r, opts)
+	values, _ := queryValues(r)
+
+	b := queryBinder
+	b.splitCommas = cfg.splitCommas
+	return b.bind(dst, func(key string) ([]string, bool) {
+		v, ok := values[key]
+		return v, ok
+	})
+}
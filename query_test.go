@@ -0,0 +1,154 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryHelpers(t *testing.T) {
+	withParams(t, "/search", "/search?q=go&tag=a&tag=b&page=3&bad=x&on=TRUE&empty=", func(r *http.Request) {
+		if Query(r, "q") != "go" || Query(r, "nope") != "" {
+			t.Errorf("Query = %q %q", Query(r, "q"), Query(r, "nope"))
+		}
+		if QueryDefault(r, "q", "x") != "go" || QueryDefault(r, "empty", "x") != "x" || QueryDefault(r, "nope", "x") != "x" {
+			t.Error("QueryDefault did not fall back for empty and absent values")
+		}
+		if got := QueryStrings(r, "tag"); !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("QueryStrings = %v", got)
+		}
+		if QueryStrings(r, "nope") != nil {
+			t.Error("QueryStrings of an absent key is not nil")
+		}
+
+		if n, err := QueryInt(r, "page"); n != 3 || err != nil {
+			t.Errorf("QueryInt = %d, %v", n, err)
+		}
+		if _, err := QueryInt(r, "bad"); !errors.Is(err, ErrQueryInvalid) {
+			t.Errorf("QueryInt of a word = %v", err)
+		}
+		if _, err := QueryInt(r, "nope"); !errors.Is(err, ErrQueryMissing) {
+			t.Errorf("QueryInt of an absent key = %v", err)
+		}
+		if QueryIntDefault(r, "page", 1) != 3 || QueryIntDefault(r, "bad", 1) != 1 || QueryIntDefault(r, "nope", 1) != 1 {
+			t.Error("QueryIntDefault did not fall back")
+		}
+
+		if b, err := QueryBool(r, "on"); !b || err != nil {
+			t.Errorf("QueryBool = %v, %v", b, err)
+		}
+		if _, err := QueryBool(r, "bad"); !errors.Is(err, ErrQueryInvalid) {
+			t.Errorf("QueryBool of a word = %v", err)
+		}
+		if QueryErr(r) != nil {
+			t.Errorf("QueryErr = %v", QueryErr(r))
+		}
+	})
+}
+
+func TestQueryParsedOnce(t *testing.T) {
+	withParams(t, "/search", "/search?q=first", func(r *http.Request) {
+		if Query(r, "q") != "first" {
+			t.Fatal("first read failed")
+		}
+		r.URL.RawQuery = "q=second"
+		if Query(r, "q") != "first" {
+			t.Error("query string was parsed again")
+		}
+	})
+}
+
+func TestQueryErr(t *testing.T) {
+	withParams(t, "/search", "/search?ok=1&bad=%zz", func(r *http.Request) {
+		if QueryErr(r) == nil {
+			t.Error("QueryErr did not report bad percent-encoding")
+		}
+		if Query(r, "ok") != "1" {
+			t.Error("valid pairs are lost next to a malformed one")
+		}
+	})
+
+	// Outside a router the query is parsed directly
+	r, _ := http.NewRequest("GET", "/?a=1", nil)
+	if Query(r, "a") != "1" {
+		t.Error("Query outside a router failed")
+	}
+}
+
+type listFilter struct {
+	Page   int       `query:"page" default:"1"`
+	Limit  int       `query:"limit,required"`
+	Active *bool     `query:"active"`
+	Min    *int      `query:"min"`
+	IDs    []int     `query:"id"`
+	Since  time.Time `query:"since,layout=2006-01-02"`
+	Sort   string    `query:"sort" default:"name"`
+}
+
+func TestBindQuery(t *testing.T) {
+	withParams(t, "/items", "/items?limit=20&active=false&min=0&id=1&id=2&since=2024-01-31&sort=", func(r *http.Request) {
+		var f listFilter
+		if err := BindQuery(r, &f); err != nil {
+			t.Fatal(err)
+		}
+		// An empty value counts as absent, so the default applies
+		if f.Page != 1 || f.Limit != 20 || f.Sort != "name" {
+			t.Errorf("bound %+v", f)
+		}
+		if f.Active == nil || *f.Active || f.Min == nil || *f.Min != 0 {
+			t.Errorf("optional pointers = %v %v, want set to their zero values", f.Active, f.Min)
+		}
+		if !slices.Equal(f.IDs, []int{1, 2}) || f.Since.Day() != 31 {
+			t.Errorf("IDs %v, Since %v", f.IDs, f.Since)
+		}
+	})
+
+	withParams(t, "/items", "/items?limit=5&page=3", func(r *http.Request) {
+		var f listFilter
+		if err := BindQuery(r, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Page != 3 || f.Active != nil || f.Min != nil || f.IDs != nil {
+			t.Errorf("bound %+v, want absent pointers and slices nil", f)
+		}
+	})
+}
+
+func TestBindQueryCommaValues(t *testing.T) {
+	withParams(t, "/items", "/items?limit=1&id=1,2&id=3", func(r *http.Request) {
+		var f listFilter
+		if err := BindQuery(r, &f, SplitCommaValues()); err != nil || !slices.Equal(f.IDs, []int{1, 2, 3}) {
+			t.Errorf("split IDs = %v, %v", f.IDs, err)
+		}
+
+		// Without the option "1,2" is one value that isn't an int
+		err := BindQuery(r, &listFilter{})
+		if !errors.Is(err, ErrQueryInvalid) {
+			t.Errorf("unsplit err = %v", err)
+		}
+	})
+}
+
+func TestBindQueryErrors(t *testing.T) {
+	withParams(t, "/items", "/items?page=x&min=1.5", func(r *http.Request) {
+		err := BindQuery(r, &listFilter{})
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) || len(bindErr.Fields) != 3 {
+			t.Fatalf("err = %v, want three field errors", err)
+		}
+		for i, want := range []struct {
+			key string
+			err error
+		}{{"page", ErrQueryInvalid}, {"limit", ErrQueryMissing}, {"min", ErrQueryInvalid}} {
+			if f := bindErr.Fields[i]; f.Key != want.key || !errors.Is(f, want.err) {
+				t.Errorf("field %d = %v, want %s %v", i, f, want.key, want.err)
+			}
+		}
+		if !strings.Contains(err.Error(), "Limit (limit): "+ErrQueryMissing.Error()) {
+			t.Errorf("message %q does not name the missing parameter", err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package tobingo
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteRange is one resolved range of a Range header, end inclusive
+type byteRange struct {
+	start, end int64
+}
+
+// ServeStream sends content with the given Content-Type, answering single-range requests
+// with 206 and only the requested bytes, so media players can seek in videos streamed from
+// object storage; a range past the end gets 416 with Content-Range "bytes */size"
+// Requests for several ranges and malformed Range headers get the whole content with 200
+// An If-Range naming an ETag set on the response beforehand, or a date matching modTime,
+// keeps the range; any other If-Range gets the whole content so stale parts can't mix
+// Pass -1 as size to have it found by seeking to the end, and a zero modTime to send no
+// Last-Modified
+// Example: obj, _ := bucket.Open(key); return tobingo.ServeStream(w, r, "video/mp4", obj, obj.Size(), obj.ModTime())
+func ServeStream(w http.ResponseWriter, r *http.Request, contentType string, content io.ReadSeeker, size int64, modTime time.Time) error {
+	if size < 0 {
+		end, err := content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		size = end
+	}
+
+	h := w.Header()
+	h.Set("Accept-Ranges", "bytes")
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	// Only GET and HEAD take ranges, and only while If-Range still holds
+	rangeHeader := r.Header.Get("Range")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rangeHeader = ""
+	}
+	if rangeHeader != "" && !ifRangeHolds(r.Header.Get("If-Range"), h.Get("ETag"), modTime) {
+		rangeHeader = ""
+	}
+
+	rng, ok, satisfiable := parseRange(rangeHeader, size)
+	if !satisfiable {
+		h.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+		handleError(w, r, http.StatusRequestedRangeNotSatisfiable, &HTTPError{Status: http.StatusRequestedRangeNotSatisfiable})
+		return nil
+	}
+	if !ok {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return BlobReader(w, http.StatusOK, contentType, content, size)
+	}
+
+	if _, err := content.Seek(rng.start, io.SeekStart); err != nil {
+		return err
+	}
+	h.Set("Content-Range", "bytes "+strconv.FormatInt(rng.start, 10)+"-"+strconv.FormatInt(rng.end, 10)+"/"+strconv.FormatInt(size, 10))
+	return BlobReader(w, http.StatusPartialContent, contentType, content, rng.end-rng.start+1)
+}
+
+// parseRange resolves a Range header against size, reporting ok for a single usable range
+// and satisfiable false when the only range lies past the end; headers asking for several
+// ranges or that don't parse are ignored, yielding ok false so the whole content is sent
+func parseRange(header string, size int64) (rng byteRange, ok, satisfiable bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return byteRange{}, false, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return byteRange{}, false, true
+	}
+
+	// "-n" asks for the last n bytes
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, false, true
+		}
+		if n == 0 || size == 0 {
+			return byteRange{}, false, false
+		}
+		return byteRange{start: size - min(n, size), end: size - 1}, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false, true
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return byteRange{}, false, true
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return byteRange{}, false, false
+	}
+	return byteRange{start: start, end: end}, true, true
+}
+
+// ifRangeHolds reports whether an If-Range header, if any, still names the representation:
+// a strong ETag equal to etag, or a date equal to modTime at second precision
+func ifRangeHolds(ifRange, etag string, modTime time.Time) bool {
+	switch {
+	case ifRange == "":
+		return true
+	case strings.HasPrefix(ifRange, `"`), strings.HasPrefix(ifRange, "W/"):
+		// Weak tags never validate a range
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+	if modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && t.Equal(modTime.Truncate(time.Second))
+}
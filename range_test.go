@@ -0,0 +1,111 @@
+package tobingo
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rangeModTime is the modification time of the streamed content, with a fraction of a second
+// that Last-Modified drops
+var rangeModTime = time.Unix(1700000000, 500_000_000)
+
+// rangeRouter streams the 26 letters of the alphabet at /video, tagged "v1" at /tagged
+func rangeRouter() *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	stream := func(w http.ResponseWriter, r *http.Request) {
+		content := strings.NewReader("abcdefghijklmnopqrstuvwxyz")
+		if err := ServeStream(w, r, "video/mp4", content, -1, rangeModTime); err != nil {
+			panic(err)
+		}
+	}
+	rt.GET("/video", stream)
+	rt.addRoute("POST", "/video", stream)
+	rt.GET("/tagged", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", r.URL.Query().Get("etag"))
+		stream(w, r)
+	})
+	return rt
+}
+
+func TestServeStreamRanges(t *testing.T) {
+	rt := rangeRouter()
+	for _, tc := range []struct {
+		rangeHeader, contentRange, body string
+	}{
+		{"bytes=0-4", "bytes 0-4/26", "abcde"},
+		{"bytes=20-", "bytes 20-25/26", "uvwxyz"},
+		{"bytes=-3", "bytes 23-25/26", "xyz"},
+		{"bytes=-100", "bytes 0-25/26", "abcdefghijklmnopqrstuvwxyz"},
+		{"bytes=24-100", "bytes 24-25/26", "yz"},
+		{"bytes=25-25", "bytes 25-25/26", "z"},
+	} {
+		res := rt.Test("GET", "/video", nil, WithTestHeader("Range", tc.rangeHeader))
+		if res.StatusCode() != http.StatusPartialContent || res.Header("Content-Range") != tc.contentRange || res.BodyString() != tc.body {
+			t.Errorf("%s: %d, Content-Range %q, body %q", tc.rangeHeader, res.StatusCode(), res.Header("Content-Range"), res.BodyString())
+		}
+		if res.Header("Content-Length") != strconv.Itoa(len(tc.body)) {
+			t.Errorf("%s: Content-Length %q", tc.rangeHeader, res.Header("Content-Length"))
+		}
+	}
+
+	full := rt.Test("GET", "/video", nil)
+	if full.StatusCode() != http.StatusOK || full.BodyString() != "abcdefghijklmnopqrstuvwxyz" || full.Header("Accept-Ranges") != "bytes" {
+		t.Errorf("no range: %d %q, Accept-Ranges %q", full.StatusCode(), full.BodyString(), full.Header("Accept-Ranges"))
+	}
+	if full.Header("Content-Type") != "video/mp4" || full.Header("Last-Modified") != rangeModTime.UTC().Format(http.TimeFormat) {
+		t.Errorf("headers: %q %q", full.Header("Content-Type"), full.Header("Last-Modified"))
+	}
+}
+
+func TestServeStreamUnsatisfiable(t *testing.T) {
+	rt := rangeRouter()
+	for _, rangeHeader := range []string{"bytes=26-", "bytes=100-200", "bytes=-0"} {
+		res := rt.Test("GET", "/video", nil, WithTestHeader("Range", rangeHeader))
+		if res.StatusCode() != http.StatusRequestedRangeNotSatisfiable || res.Header("Content-Range") != "bytes */26" {
+			t.Errorf("%s: %d, Content-Range %q", rangeHeader, res.StatusCode(), res.Header("Content-Range"))
+		}
+	}
+}
+
+func TestServeStreamDeclinedRanges(t *testing.T) {
+	rt := rangeRouter()
+	for _, rangeHeader := range []string{"bytes=0-1,5-6", "bytes=5-2", "bytes=a-b", "items=0-4", "bytes=4"} {
+		res := rt.Test("GET", "/video", nil, WithTestHeader("Range", rangeHeader))
+		if res.StatusCode() != http.StatusOK || res.Header("Content-Range") != "" || len(res.BodyString()) != 26 {
+			t.Errorf("%s: %d, Content-Range %q, %d bytes", rangeHeader, res.StatusCode(), res.Header("Content-Range"), len(res.BodyString()))
+		}
+	}
+	if res := rt.Test("POST", "/video", nil, WithTestHeader("Range", "bytes=0-4")); res.StatusCode() != http.StatusOK || len(res.BodyString()) != 26 {
+		t.Errorf("POST: %d, %d bytes", res.StatusCode(), len(res.BodyString()))
+	}
+}
+
+func TestServeStreamIfRange(t *testing.T) {
+	rt := rangeRouter()
+	lastModified := rangeModTime.UTC().Format(http.TimeFormat)
+	for _, tc := range []struct {
+		name, target, ifRange string
+		status                int
+	}{
+		{"matching ETag", "/tagged?etag=%22v1%22", `"v1"`, http.StatusPartialContent},
+		{"changed ETag", "/tagged?etag=%22v2%22", `"v1"`, http.StatusOK},
+		{"weak ETag", "/tagged?etag=W/%22v1%22", `W/"v1"`, http.StatusOK},
+		{"ETag without one set", "/video", `"v1"`, http.StatusOK},
+		{"matching date", "/video", lastModified, http.StatusPartialContent},
+		{"older date", "/video", rangeModTime.Add(-time.Hour).UTC().Format(http.TimeFormat), http.StatusOK},
+		{"malformed date", "/video", "yesterday", http.StatusOK},
+	} {
+		res := rt.Test("GET", tc.target, nil, WithTestHeader("Range", "bytes=0-4"), WithTestHeader("If-Range", tc.ifRange))
+		if res.StatusCode() != tc.status {
+			t.Errorf("%s: %d, want %d", tc.name, res.StatusCode(), tc.status)
+		}
+		if tc.status == http.StatusOK && len(res.BodyString()) != 26 {
+			t.Errorf("%s: %d bytes, want the whole content", tc.name, len(res.BodyString()))
+		}
+	}
+}
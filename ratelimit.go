@@ -0,0 +1,160 @@
+package tobingo
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitKey picks the identity a request is rate limited as, e.g. its client IP or tenant
+type RateLimitKey func(r *http.Request) string
+
+// KeyIP limits requests per client IP, as resolved by ClientIP
+func KeyIP() RateLimitKey {
+	return func(r *http.Request) string {
+		if addr, ok := ClientIP(r); ok {
+			return addr.String()
+		}
+		return r.RemoteAddr
+	}
+}
+
+// KeyParam limits requests per value of the named path parameter, such as an API key or
+// tenant, falling back to the client IP when the parameter is empty
+// Example: rt.GET("/t/:tenant/orders", orders).RateLimit(10, 20, tobingo.KeyParam("tenant"))
+func KeyParam(name string) RateLimitKey {
+	ip := KeyIP()
+	return func(r *http.Request) string {
+		if value := GetParam(r, name); value != "" {
+			return "param:" + value
+		}
+		return "ip:" + ip(r)
+	}
+}
+
+// rateLimiter holds one token bucket per identity, refilled at rps up to burst tokens
+type rateLimiter struct {
+	rps   float64
+	burst float64
+	key   RateLimitKey
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is the state of one identity
+type tokenBucket struct {
+	tokens float64   // Tokens left at last
+	last   time.Time // When tokens was last brought up to date
+}
+
+// newRateLimiter returns a limiter keyed by the first key, KeyIP when none is given
+func newRateLimiter(rps float64, burst int, key []RateLimitKey) *rateLimiter {
+	if rps <= 0 || burst <= 0 {
+		panic("tobingo: RateLimit needs a positive rate and burst")
+	}
+	l := &rateLimiter{rps: rps, burst: float64(burst), key: KeyIP(), buckets: make(map[string]*tokenBucket)}
+	if len(key) > 0 && key[0] != nil {
+		l.key = key[0]
+	}
+	return l
+}
+
+// RateLimit returns middleware allowing each identity rps requests per second on average
+// with bursts of up to burst, keyed by the client IP unless a key is given
+// Every response carries X-RateLimit-Limit and X-RateLimit-Remaining, and rejected requests
+// are answered with 429 and Retry-After through the error handler
+// Middleware runs before route matching, so keys using path parameters belong on routes or
+// groups with Route.RateLimit and Group.RateLimit instead
+// Example: rt.Use(tobingo.RateLimit(5, 10))
+func RateLimit(rps float64, burst int, key ...RateLimitKey) Middleware {
+	l := newRateLimiter(rps, burst, key)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.allow(w, r) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimit limits the route like the RateLimit middleware, but after route matching, so
+// the key may use path parameters as KeyParam does; it must be set before serving
+func (route *Route) RateLimit(rps float64, burst int, key ...RateLimitKey) *Route {
+	route.limiter = newRateLimiter(rps, burst, key)
+	return route
+}
+
+// RateLimit limits the routes of the group and its nested groups like Route.RateLimit, with
+// one bucket per identity shared by all of them, on top of any limits of the routes
+// themselves; it must be set before serving
+func (g *Group) RateLimit(rps float64, burst int, key ...RateLimitKey) *Group {
+	g.limiter = newRateLimiter(rps, burst, key)
+	return g
+}
+
+// allowRoute applies the limits of route and its groups, reporting whether r may proceed;
+// otherwise the 429 response was written
+func allowRoute(w http.ResponseWriter, r *http.Request, route *Route) bool {
+	if route.limiter != nil && !route.limiter.allow(w, r) {
+		return false
+	}
+	for g := route.group; g != nil; g = g.parent {
+		if g.limiter != nil && !g.limiter.allow(w, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// allow takes a token from the bucket of r's identity, reporting whether one was left;
+// otherwise it answers 429 with the time until the next token in Retry-After
+func (l *rateLimiter) allow(w http.ResponseWriter, r *http.Request) bool {
+	key := l.key(r)
+	now := time.Now()
+
+	l.mu.Lock()
+	l.sweep(now)
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	tokens := b.tokens
+	l.mu.Unlock()
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(int(l.burst)))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(int(tokens)))
+	if allowed {
+		return true
+	}
+
+	wait := math.Ceil((1 - tokens) / l.rps)
+	h.Set("Retry-After", strconv.Itoa(max(int(wait), 1)))
+	handleError(w, r, http.StatusTooManyRequests, &HTTPError{Status: http.StatusTooManyRequests})
+	return false
+}
+
+// sweep drops the buckets that have refilled completely, at most once a minute, so idle
+// identities don't accumulate; the caller must hold l.mu
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rps >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
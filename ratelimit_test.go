@@ -0,0 +1,173 @@
+package tobingo
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// limitedRequest serves GET path from remote, an "ip:port" peer address
+func limitedRequest(rt *Rastauter, path, remote string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = remote
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouteRateLimitPerTenant(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	// A rate this low never refills during the test, so only the burst is allowed
+	rt.GET("/t/:tenant/orders", okHandler).RateLimit(0.001, 5, KeyParam("tenant"))
+
+	const perTenant = 20
+	tenants := []string{"acme", "globex"}
+	var mu sync.Mutex
+	allowed := map[string]int{}
+	var wg sync.WaitGroup
+	for _, tenant := range tenants {
+		for range perTenant {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := limitedRequest(rt, "/t/"+tenant+"/orders", "192.0.2.1:1234")
+				mu.Lock()
+				defer mu.Unlock()
+				switch rec.Code {
+				case http.StatusOK:
+					allowed[tenant]++
+				case http.StatusTooManyRequests:
+					if rec.Header().Get("Retry-After") == "" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+						t.Errorf("429 headers: %v", rec.Header())
+					}
+				default:
+					t.Errorf("status %d", rec.Code)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	// Each tenant had its own bucket, though every request came from the same IP
+	for _, tenant := range tenants {
+		if allowed[tenant] != 5 {
+			t.Errorf("%s: %d allowed, want the burst of 5", tenant, allowed[tenant])
+		}
+	}
+
+	// A new tenant still has a full bucket
+	rec := limitedRequest(rt, "/t/initech/orders", "192.0.2.1:1234")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "5" || rec.Header().Get("X-RateLimit-Remaining") != "4" {
+		t.Errorf("new tenant: %d, headers %v", rec.Code, rec.Header())
+	}
+
+	// Retry-After is the wait for the next token, in whole seconds
+	rec = limitedRequest(rt, "/t/acme/orders", "192.0.2.1:1234")
+	if wait, _ := strconv.Atoi(rec.Header().Get("Retry-After")); rec.Code != http.StatusTooManyRequests || wait < 900 {
+		t.Errorf("exhausted tenant: %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRouteRateLimitFallsBackToIP(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.GET("/orders/:tenant?", okHandler).RateLimit(0.001, 2, KeyParam("tenant"))
+
+	for i := range 3 {
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if rec := limitedRequest(rt, "/orders", "192.0.2.1:1234"); rec.Code != want {
+			t.Errorf("request %d without a tenant: %d, want %d", i, rec.Code, want)
+		}
+	}
+
+	// Another IP, and a tenant named after the exhausted IP, have buckets of their own
+	if rec := limitedRequest(rt, "/orders", "192.0.2.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("other IP: %d", rec.Code)
+	}
+	if rec := limitedRequest(rt, "/orders/192.0.2.1", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("tenant named like the IP: %d", rec.Code)
+	}
+}
+
+func TestGroupRateLimit(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	var status []int
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, code int, err error) {
+		status = append(status, code)
+		DefaultErrorHandler(w, r, code, err)
+	})
+	api := rt.Group("/api/:apikey").RateLimit(0.001, 3, KeyParam("apikey"))
+	api.GET("/users", okHandler)
+	api.Group("/admin").GET("/stats", okHandler).RateLimit(0.001, 1, KeyParam("apikey"))
+	rt.GET("/open", okHandler)
+
+	// The group's bucket is shared by its routes, nested groups included
+	for i, path := range []string{"/api/k1/users", "/api/k1/admin/stats", "/api/k1/users", "/api/k1/users"} {
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if rec := limitedRequest(rt, path, "192.0.2.1:1234"); rec.Code != want {
+			t.Errorf("request %d to %s: %d, want %d", i, path, rec.Code, want)
+		}
+	}
+
+	// The route's own limit applies on top of the group's
+	if rec := limitedRequest(rt, "/api/k2/admin/stats", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("first stats for k2: %d", rec.Code)
+	}
+	if rec := limitedRequest(rt, "/api/k2/admin/stats", "192.0.2.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second stats for k2: %d", rec.Code)
+	}
+	if rec := limitedRequest(rt, "/api/k2/users", "192.0.2.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("users for k2: %d", rec.Code)
+	}
+
+	// Routes outside the group are not limited
+	for range 5 {
+		if rec := limitedRequest(rt, "/open", "192.0.2.1:1234"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("unlimited route: %d, headers %v", rec.Code, rec.Header())
+		}
+	}
+
+	// Rejections went through the error handler
+	if len(status) != 2 || status[0] != http.StatusTooManyRequests || status[1] != http.StatusTooManyRequests {
+		t.Errorf("error handler saw %v", status)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.Use(RateLimit(0.001, 2))
+	rt.GET("/", okHandler)
+
+	for i, remote := range []string{"192.0.2.1:1", "192.0.2.1:2", "192.0.2.1:3", "192.0.2.2:1"} {
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if rec := limitedRequest(rt, "/", remote); rec.Code != want {
+			t.Errorf("request %d from %s: %d, want %d", i, remote, rec.Code, want)
+		}
+	}
+	// Missing routes are limited too, as the middleware runs before matching
+	if rec := limitedRequest(rt, "/missing", "192.0.2.1:4"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("unmatched path: %d", rec.Code)
+	}
+
+	if msg := panicMessage(func() { RateLimit(0, 1) }); msg == "" {
+		t.Error("zero rate did not panic")
+	}
+	if msg := panicMessage(func() { RateLimit(1, 0) }); msg == "" {
+		t.Error("zero burst did not panic")
+	}
+}
@@ -0,0 +1,101 @@
+package tobingo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedirectOptions configures the HTTP to HTTPS redirect server
+type RedirectOptions struct {
+	StatusCode int           // Redirect status, http.StatusMovedPermanently (301) when zero; use 308 to preserve the method
+	AllowHTTP  []string      // Path prefixes served by the router over plain HTTP instead of redirected, e.g. ACME challenges
+	HSTSMaxAge time.Duration // When positive, a Strict-Transport-Security header with this max-age is sent on redirects
+}
+
+// StartRedirectServer starts a companion server on httpAddr that redirects every request to https,
+// preserving the host, path, and query string
+// target is the https base URL such as "https://example.com:8443"; when empty the request's host is kept
+// The companion is stopped together with the main server by Shutdown
+func (rt *Rastauter) StartRedirectServer(httpAddr, target string) error {
+	return rt.StartRedirectServerWithOptions(httpAddr, target, RedirectOptions{})
+}
+
+// StartRedirectServerWithOptions behaves like StartRedirectServer with a configurable
+// status code, HSTS header, and list of paths that are served over plain HTTP
+func (rt *Rastauter) StartRedirectServerWithOptions(httpAddr, target string, opts RedirectOptions) error {
+	handler, err := rt.HTTPSRedirectHandler(target, opts)
+	if err != nil {
+		return err
+	}
+
+	l, err := listenTCP(httpAddr)
+	if err != nil {
+		return err
+	}
+	return rt.ServeCompanion(l, handler)
+}
+
+// HTTPSRedirectHandler returns the handler used by StartRedirectServer so it can be mounted
+// on a server managed elsewhere; requests matching opts.AllowHTTP are passed to the router
+// Returns an error if target is not a valid absolute URL
+func (rt *Rastauter) HTTPSRedirectHandler(target string, opts RedirectOptions) (http.Handler, error) {
+	// Parse the target once up front so a typo fails at startup rather than per request
+	var base *url.URL
+	if target != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("tobingo: redirect target %q must be an absolute URL such as https://example.com", target)
+		}
+		base = u
+	}
+
+	status := opts.StatusCode
+	if status == 0 {
+		status = http.StatusMovedPermanently
+	}
+
+	var hsts string
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Paths on the allow-list are served by the router as normal
+		for _, prefix := range opts.AllowHTTP {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				rt.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		// Build the destination from the target, or from the request host without its port,
+		// keeping the brackets of an IPv6 literal such as "[::1]"
+		dest := url.URL{Scheme: "https", Host: r.Host}
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			dest.Host = h
+			if strings.Contains(h, ":") {
+				dest.Host = "[" + h + "]"
+			}
+		}
+		if base != nil {
+			dest.Scheme = base.Scheme
+			dest.Host = base.Host
+		}
+		dest.Path = r.URL.Path
+		dest.RawPath = r.URL.RawPath
+		dest.RawQuery = r.URL.RawQuery
+
+		if hsts != "" {
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
+		http.Redirect(w, r, dest.String(), status)
+	}), nil
+}
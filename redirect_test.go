@@ -0,0 +1,97 @@
+package tobingo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/.well-known/acme-challenge/:token", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("token")) })
+	h, err := rt.HTTPSRedirectHandler("", RedirectOptions{AllowHTTP: []string{"/.well-known/"}, HSTSMaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for host, want := range map[string]string{
+		"example.com":      "https://example.com/a/b?q=1",
+		"example.com:8080": "https://example.com/a/b?q=1",
+		"[::1]:8080":       "https://[::1]/a/b?q=1",
+		"[::1]":            "https://[::1]/a/b?q=1",
+	} {
+		req := httptest.NewRequest("GET", "http://placeholder/a/b?q=1", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != want {
+			t.Errorf("Host %s: %d %q, want 301 %q", host, rec.Code, rec.Header().Get("Location"), want)
+		}
+		if rec.Header().Get("Strict-Transport-Security") != "max-age=3600" {
+			t.Errorf("Host %s: HSTS %q", host, rec.Header().Get("Strict-Transport-Security"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/.well-known/acme-challenge/abc", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "token" {
+		t.Errorf("allowed path: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPSRedirectHandlerTarget(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	if _, err := rt.HTTPSRedirectHandler("example.com", RedirectOptions{}); err == nil {
+		t.Error("relative target accepted")
+	}
+
+	h, err := rt.HTTPSRedirectHandler("https://[2001:db8::1]:8443", RedirectOptions{StatusCode: http.StatusPermanentRedirect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "http://example.com/x", nil))
+	if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "https://[2001:db8::1]:8443/x" {
+		t.Errorf("got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestRedirectServerStopsWithShutdown(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	addrs := make(chan net.Addr, 2)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+
+	mainErr, redirectErr := make(chan error, 1), make(chan error, 1)
+	go func() { mainErr <- rt.StartServer("127.0.0.1:0") }()
+	go func() { redirectErr <- rt.StartRedirectServer("127.0.0.1:0", "https://example.com") }()
+	first, second := <-addrs, <-addrs
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	var redirected bool
+	for _, addr := range []net.Addr{first, second} {
+		res, err := client.Get("http://" + addr.String() + "/users/42?tab=posts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusMovedPermanently {
+			redirected = res.Header.Get("Location") == "https://example.com/users/42?tab=posts"
+		}
+	}
+	if !redirected {
+		t.Error("redirect server did not answer with the https location")
+	}
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-mainErr; err != http.ErrServerClosed {
+		t.Errorf("StartServer = %v", err)
+	}
+	if err := <-redirectErr; err != http.ErrServerClosed {
+		t.Errorf("StartRedirectServer = %v", err)
+	}
+}
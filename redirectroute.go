@@ -0,0 +1,216 @@
+package tobingo
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redirectTarget is the destination of a route registered with Redirect or RedirectToNamed
+type redirectTarget struct {
+	status   int      // Redirect status
+	display  string   // Target template or named route pattern, for introspection
+	segments []string // Path segments of the template, parameters still in place
+	base     url.URL  // Template with its path removed, keeping scheme, host, and query
+	name     string   // Named route to generate the location from, "" for a template
+	params   []string // Parameters of the named route, taken from the source's
+}
+
+// Redirect registers a route answering with a redirect to the to template, in which ":name"
+// and "*name" segments are replaced by the parameters captured from the from pattern; the
+// request's query string is kept, after any query of the template
+// Use http.StatusPermanentRedirect or http.StatusTemporaryRedirect to keep the method and
+// body of POST requests; it panics like GET when the route can't be added or the template
+// references a parameter from doesn't capture
+// Example: rt.Redirect("GET", "/old/users/:id", "/users/:id", http.StatusMovedPermanently)
+func (rt *Rastauter) Redirect(method, from, to string, status int) *Route {
+	route, err := rt.TryRedirect(method, from, to, status)
+	if err != nil {
+		panic(err.Error())
+	}
+	return route
+}
+
+// TryRedirect registers a route like Redirect, returning an error instead of panicking
+func (rt *Rastauter) TryRedirect(method, from, to string, status int) (*Route, error) {
+	target, err := newRedirectTarget(from, to, status)
+	if err != nil {
+		return nil, err
+	}
+	return rt.addRedirect(method, from, target)
+}
+
+// newRedirectTarget parses the to template of a redirect from the from pattern
+func newRedirectTarget(from, to string, status int) (*redirectTarget, error) {
+	if err := checkRedirectStatus(status); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: redirect target %q: %v", ErrInvalidPattern, to, err)
+	}
+
+	target := &redirectTarget{status: status, display: to, base: *u}
+	target.base.Path, target.base.RawPath = "", ""
+	// A trailing "?", as in "/items/:page?", marks an optional parameter rather than an empty query
+	target.base.ForceQuery = false
+	if u.Path != "" {
+		target.segments = strings.Split(u.Path, "/")
+	}
+
+	// Every parameter of the template must be captured by the source pattern
+	captured := patternParams(from)
+	for _, seg := range target.segments {
+		if name, ok := templateParam(seg); ok && !captured[name] {
+			return nil, fmt.Errorf("%w: redirect target %q references parameter %q not captured by %q", ErrInvalidPattern, to, name, from)
+		}
+	}
+	return target, nil
+}
+
+// RedirectToNamed registers a route answering with a redirect to the named route, whose
+// parameters are filled from those captured by the from pattern, so the redirect follows the
+// target when its pattern changes; the target must be registered first
+// Example: rt.RedirectToNamed("POST", "/old/orders", "orders", http.StatusPermanentRedirect)
+func (rt *Rastauter) RedirectToNamed(method, from, routeName string, status int) *Route {
+	if err := checkRedirectStatus(status); err != nil {
+		panic(err.Error())
+	}
+
+	var named *Route
+	for _, route := range rt.routes {
+		if route.Name == routeName {
+			named = route
+			break
+		}
+	}
+	if named == nil {
+		panic(fmt.Sprintf("%v: %q", ErrRouteNotFound, routeName))
+	}
+
+	target := &redirectTarget{status: status, display: named.Path, name: routeName}
+	captured := patternParams(from)
+	for name := range patternParams(named.Path) {
+		if !captured[name] {
+			panic(fmt.Sprintf("%v: route %q needs parameter %q not captured by %q", ErrInvalidPattern, routeName, name, from))
+		}
+		target.params = append(target.params, name)
+	}
+
+	route, err := rt.addRedirect(method, from, target)
+	if err != nil {
+		panic(err.Error())
+	}
+	return route
+}
+
+// RedirectTarget returns the target and status of a route registered with Redirect or
+// RedirectToNamed, the pattern of the named route for the latter; status is 0 for other routes
+func (route *Route) RedirectTarget() (target string, status int) {
+	if route.redirect == nil {
+		return "", 0
+	}
+	return route.redirect.display, route.redirect.status
+}
+
+// addRedirect registers the route serving target
+func (rt *Rastauter) addRedirect(method, from string, target *redirectTarget) (*Route, error) {
+	route, err := rt.tryAddRoute(method, from, func(w http.ResponseWriter, r *http.Request) {
+		location, err := target.location(r)
+		if err != nil {
+			handleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		http.Redirect(w, r, externalLocation(r, location), target.status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	route.redirect = target
+	return route, nil
+}
+
+// location builds the redirect location for r from its path parameters and query string
+func (target *redirectTarget) location(r *http.Request) (string, error) {
+	var u url.URL
+	if target.name != "" {
+		params := make([]string, 0, 2*len(target.params))
+		for _, name := range target.params {
+			params = append(params, name, GetParam(r, name))
+		}
+		path, err := routerFrom(r).URL(target.name, params...)
+		if err != nil {
+			return "", err
+		}
+		u.Path = path
+	} else {
+		u = target.base
+		u.Path, u.RawPath = target.path(r)
+	}
+
+	// The request's query follows any query of the template
+	if r.URL.RawQuery != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += r.URL.RawQuery
+	}
+	return u.String(), nil
+}
+
+// path fills the parameters of the template's path with those of r, returning the decoded
+// and the escaped form so values containing slashes stay within their segment
+func (target *redirectTarget) path(r *http.Request) (string, string) {
+	var path, raw []string
+	for i, seg := range target.segments {
+		name, isParam := templateParam(seg)
+		switch {
+		case !isParam:
+			path, raw = append(path, seg), append(raw, url.PathEscape(seg))
+		case strings.HasPrefix(seg, "*"):
+			// A catch-all keeps its slashes, so each of its segments is escaped separately
+			for part := range strings.SplitSeq(GetParam(r, name), "/") {
+				path, raw = append(path, part), append(raw, url.PathEscape(part))
+			}
+		default:
+			ps, _ := parseSegment(seg)
+			value := GetParam(r, name)
+			// An absent optional parameter leaves no empty segment behind
+			if value == "" && ps.literal == "" && i > 0 {
+				continue
+			}
+			path, raw = append(path, ps.literal+value), append(raw, url.PathEscape(ps.literal)+url.PathEscape(value))
+		}
+	}
+	return strings.Join(path, "/"), strings.Join(raw, "/")
+}
+
+// patternParams returns the names of the parameters a route pattern captures
+func patternParams(pattern string) map[string]bool {
+	names := make(map[string]bool)
+	for _, seg := range strings.Split(strings.Trim(pattern, " "), "/") {
+		if name, ok := templateParam(seg); ok {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// templateParam returns the parameter name of a pattern segment, either ":name" with an
+// optional literal prefix or a "*name" catch-all
+func templateParam(seg string) (string, bool) {
+	if name, ok := strings.CutPrefix(seg, "*"); ok {
+		return name, true
+	}
+	ps, isParam := parseSegment(seg)
+	return ps.name, isParam
+}
+
+// checkRedirectStatus rejects statuses that aren't redirects
+func checkRedirectStatus(status int) error {
+	if status < 300 || status > 308 || status == http.StatusNotModified {
+		return fmt.Errorf("tobingo: redirect status %d is not a redirect", status)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedirectRoutes(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Redirect("GET", "/old/users/:id", "/users/:id", http.StatusMovedPermanently)
+	rt.Redirect("GET", "/old/docs/*rest", "/manual/*rest", http.StatusFound)
+	rt.Redirect("GET", "/old/releases/v:version", "/releases/release-:version", http.StatusFound)
+	rt.Redirect("GET", "/old/search", "/search?source=legacy", http.StatusFound)
+	rt.Redirect("GET", "/blog/:slug", "https://blog.example.com/posts/:slug", http.StatusMovedPermanently)
+	rt.Redirect("GET", "/list/:page?", "/items/:page?", http.StatusFound)
+	rt.Redirect("GET", "/tags/:tag?", "/topics/:tag", http.StatusFound)
+
+	for target, want := range map[string]struct {
+		status   int
+		location string
+	}{
+		"/old/users/42":           {http.StatusMovedPermanently, "/users/42"},
+		"/old/users/42?tab=posts": {http.StatusMovedPermanently, "/users/42?tab=posts"},
+		"/old/docs/guide/setup":   {http.StatusFound, "/manual/guide/setup"},
+		"/old/docs/a%20b/c":       {http.StatusFound, "/manual/a%20b/c"},
+		"/old/releases/v2.1":      {http.StatusFound, "/releases/release-2.1"},
+		"/old/search?q=go":        {http.StatusFound, "/search?source=legacy&q=go"},
+		"/blog/hello-world":       {http.StatusMovedPermanently, "https://blog.example.com/posts/hello-world"},
+		"/list/3":                 {http.StatusFound, "/items/3"},
+		"/list":                   {http.StatusFound, "/items"},
+		"/list?sort=new":          {http.StatusFound, "/items?sort=new"},
+		"/tags/go":                {http.StatusFound, "/topics/go"},
+		"/tags":                   {http.StatusFound, "/topics"},
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != want.status || res.Header("Location") != want.location {
+			t.Errorf("%s = %d to %q, want %d to %q", target, res.StatusCode(), res.Header("Location"), want.status, want.location)
+		}
+	}
+}
+
+func TestRedirectKeepsMethod(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Redirect("POST", "/old/orders/:id", "/orders/:id", http.StatusPermanentRedirect)
+	rt.Redirect("PUT", "/old/carts/:id", "/carts/:id", http.StatusTemporaryRedirect)
+
+	for method, tc := range map[string]struct {
+		target, location string
+		status           int
+	}{
+		"POST": {"/old/orders/7?retry=1", "/orders/7?retry=1", http.StatusPermanentRedirect},
+		"PUT":  {"/old/carts/3", "/carts/3", http.StatusTemporaryRedirect},
+	} {
+		res := rt.Test(method, tc.target, strings.NewReader(`{"qty":1}`))
+		if res.StatusCode() != tc.status || res.Header("Location") != tc.location {
+			t.Errorf("%s %s = %d to %q", method, tc.target, res.StatusCode(), res.Header("Location"))
+		}
+	}
+	// Only the registered method redirects
+	if res := rt.Test("GET", "/old/orders/7", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("GET = %d, want 404", res.StatusCode())
+	}
+}
+
+func TestRedirectToNamed(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/accounts/:id/orders/:order", okHandler).Named("order")
+	rt.GET("/", okHandler).Named("home")
+	route := rt.RedirectToNamed("GET", "/orders/:order/of/:id", "order", http.StatusMovedPermanently)
+	rt.RedirectToNamed("POST", "/start", "home", http.StatusSeeOther)
+
+	res := rt.Test("GET", "/orders/9/of/4?x=1", nil)
+	if res.StatusCode() != http.StatusMovedPermanently || res.Header("Location") != "/accounts/4/orders/9?x=1" {
+		t.Errorf("named redirect = %d to %q", res.StatusCode(), res.Header("Location"))
+	}
+	if res := rt.Test("POST", "/start", nil); res.StatusCode() != http.StatusSeeOther || res.Header("Location") != "/" {
+		t.Errorf("to home = %d to %q", res.StatusCode(), res.Header("Location"))
+	}
+	if target, status := route.RedirectTarget(); target != "/accounts/:id/orders/:order" || status != http.StatusMovedPermanently {
+		t.Errorf("RedirectTarget = %q, %d", target, status)
+	}
+
+	for name, fn := range map[string]func(){
+		"unknown route":   func() { rt.RedirectToNamed("GET", "/x", "missing", http.StatusFound) },
+		"missing param":   func() { rt.RedirectToNamed("GET", "/o/:order", "order", http.StatusFound) },
+		"not a redirect":  func() { rt.RedirectToNamed("GET", "/y", "home", http.StatusOK) },
+		"duplicate route": func() { rt.RedirectToNamed("POST", "/start", "home", http.StatusFound) },
+	} {
+		if msg := panicMessage(fn); msg == "" {
+			t.Errorf("%s: no panic", name)
+		}
+	}
+}
+
+func TestRedirectRegistrationErrors(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	for name, tc := range map[string]struct {
+		from, to string
+		status   int
+		want     string
+	}{
+		"unknown param":    {"/old/:id", "/new/:slug", http.StatusFound, `references parameter "slug" not captured by "/old/:id"`},
+		"unknown catchall": {"/old/:id", "/new/*rest", http.StatusFound, `parameter "rest"`},
+		"status 200":       {"/a", "/b", http.StatusOK, "redirect status 200 is not a redirect"},
+		"status 304":       {"/a", "/b", http.StatusNotModified, "redirect status 304"},
+		"bad target":       {"/a", "http://[::1", http.StatusFound, "redirect target"},
+	} {
+		route, err := rt.TryRedirect("GET", tc.from, tc.to, tc.status)
+		if err == nil || route != nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: %v, want an error with %q", name, err, tc.want)
+		}
+	}
+	if _, err := rt.TryRedirect("GET", "/old/:id", "/new/:slug", http.StatusFound); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("unknown param = %v, want ErrInvalidPattern", err)
+	}
+	if len(rt.routes) != 0 {
+		t.Errorf("%d routes registered by failed redirects", len(rt.routes))
+	}
+	if msg := panicMessage(func() { rt.Redirect("GET", "/a", "/b/:id", http.StatusFound) }); !strings.Contains(msg, `parameter "id"`) {
+		t.Errorf("Redirect panicked with %q", msg)
+	}
+
+	// Ordinary routes have no target
+	if target, status := rt.GET("/plain", okHandler).RedirectTarget(); target != "" || status != 0 {
+		t.Errorf("plain route RedirectTarget = %q, %d", target, status)
+	}
+	if target, status := rt.Redirect("GET", "/from/:id", "/to/:id", http.StatusFound).RedirectTarget(); target != "/to/:id" || status != http.StatusFound {
+		t.Errorf("RedirectTarget = %q, %d", target, status)
+	}
+}
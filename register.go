@@ -0,0 +1,83 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidMethod is matched by the errors of Register for an empty or malformed method
+var ErrInvalidMethod = errors.New("tobingo: invalid method")
+
+// Register adds a batch of declared routes, such as those a module returns from its Routes
+// function, all or none: every entry is checked and, if any is rejected, the route table is
+// left as it was and all problems are reported at once, each naming the entry by index
+// Entries use Method, Path, Handler, and optionally Name, Meta, and Middleware, the latter
+// applied like Route.Use; Defaults are taken from the pattern
+// Errors match ErrInvalidMethod, ErrNilHandler, ErrInvalidPattern, or ErrConflict with
+// errors.Is, and duplicate names are reported too
+// Example: if err := rt.Register(users.Routes()); err != nil { log.Fatal(err) }
+func (rt *Rastauter) Register(routes []Route) error {
+	before, matching := slices.Clone(rt.routes), slices.Clone(rt.matching)
+
+	var errs []error
+	for i, entry := range routes {
+		if err := rt.registerEntry(entry); err != nil {
+			errs = append(errs, fmt.Errorf("route %d (%s %q): %w", i, entry.Method, entry.Path, err))
+		}
+	}
+	if len(errs) > 0 {
+		rt.routes, rt.matching = before, matching
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// RegisterRoutes is the variadic form of Register
+// Example: err := rt.RegisterRoutes(tobingo.Route{Method: "GET", Path: "/ping", Handler: ping})
+func (rt *Rastauter) RegisterRoutes(routes ...Route) error {
+	return rt.Register(routes)
+}
+
+// registerEntry adds one entry of Register, later entries seeing it for conflicts and names
+func (rt *Rastauter) registerEntry(entry Route) error {
+	if !validMethod(entry.Method) {
+		return fmt.Errorf("%w: %q", ErrInvalidMethod, entry.Method)
+	}
+	for i, m := range entry.Middleware {
+		if m == nil {
+			return fmt.Errorf("middleware %d is nil", i)
+		}
+	}
+	if entry.Name != "" {
+		for _, other := range rt.routes {
+			if other.Name == entry.Name {
+				return fmt.Errorf("name %q is already used by %s %s", entry.Name, other.Method, other.Path)
+			}
+		}
+	}
+
+	route, err := rt.tryAddRoute(entry.Method, entry.Path, entry.Handler)
+	if err != nil {
+		return err
+	}
+	route.Name = entry.Name
+	route.Meta = maps.Clone(entry.Meta)
+	if len(entry.Middleware) > 0 {
+		route.Middleware = slices.Clone(entry.Middleware)
+		route.buildChain()
+	}
+	return nil
+}
+
+// validMethod reports whether method is a non-empty HTTP token, as methods must be
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	return !strings.ContainsFunc(method, func(c rune) bool {
+		return c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c)
+	})
+}
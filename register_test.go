@@ -0,0 +1,120 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// usersModule is a batch of routes as a module's Routes function would return it
+func usersModule() []Route {
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Module", "users")
+			next.ServeHTTP(w, r)
+		})
+	}
+	return []Route{
+		{Method: "GET", Path: "/users", Handler: okHandler},
+		{Method: "POST", Path: "/users", Handler: okHandler, Middleware: []Middleware{header}},
+		{Method: "GET", Path: "/users/:id", Handler: func(w http.ResponseWriter, r *http.Request) {
+			info, _ := MatchedRoute(r)
+			w.Write([]byte(info.Name + " " + info.Meta["scope"].(string)))
+		}, Name: "user", Meta: map[string]any{"scope": "users:read"}},
+		{Method: "GET", Path: "/users/:id/posts/:page=1", Handler: okHandler},
+	}
+}
+
+func TestRegisterCleanBatch(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	if err := rt.Register(usersModule()); err != nil {
+		t.Fatal(err)
+	}
+	if res := rt.Test("GET", "/users", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("GET /users = %d", res.StatusCode())
+	}
+	if res := rt.Test("POST", "/users", nil); res.StatusCode() != http.StatusOK || res.Header("X-Module") != "users" {
+		t.Errorf("POST /users = %d, X-Module %q", res.StatusCode(), res.Header("X-Module"))
+	}
+	if res := rt.Test("GET", "/users/7", nil); res.BodyString() != "user users:read" {
+		t.Errorf("named route with meta: %q", res.BodyString())
+	}
+	if path, err := rt.URL("user", "id", "7"); err != nil || path != "/users/7" {
+		t.Errorf("URL(user) = %q, %v", path, err)
+	}
+	if route := rt.routes[3]; route.Defaults["page"] != "1" {
+		t.Errorf("defaults from the pattern: %v", route.Defaults)
+	}
+
+	// The variadic form adds to the same table
+	if err := rt.RegisterRoutes(Route{Method: "DELETE", Path: "/users/:id", Handler: okHandler}); err != nil {
+		t.Fatal(err)
+	}
+	if res := rt.Test("DELETE", "/users/7", nil); res.StatusCode() != http.StatusOK {
+		t.Errorf("DELETE /users/7 = %d", res.StatusCode())
+	}
+}
+
+func TestRegisterReportsEveryError(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	err := rt.Register([]Route{
+		{Method: "GET", Path: "/ok", Handler: okHandler},
+		{Method: "GET", Path: "/nil"},
+		{Method: "GET", Path: "/ok", Handler: okHandler},
+		{Method: "GE T", Path: "/method", Handler: okHandler},
+		{Method: "GET", Path: "no-slash", Handler: okHandler},
+		{Method: "GET", Path: "/mw", Handler: okHandler, Middleware: []Middleware{nil}},
+		{Method: "GET", Path: "/a", Handler: okHandler, Name: "dup"},
+		{Method: "GET", Path: "/b", Handler: okHandler, Name: "dup"},
+	})
+	if err == nil {
+		t.Fatal("invalid batch registered")
+	}
+	for _, sentinel := range []error{ErrNilHandler, ErrConflict, ErrInvalidMethod, ErrInvalidPattern} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("error does not match %v: %v", sentinel, err)
+		}
+	}
+	for _, part := range []string{"route 1 ", "route 2 ", "route 3 ", "route 4 ", "route 5 ", "route 7 ", "middleware 0 is nil", `name "dup"`} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error lacks %q:\n%v", part, err)
+		}
+	}
+	if strings.Contains(err.Error(), "route 0 ") || strings.Contains(err.Error(), "route 6 ") {
+		t.Errorf("valid entries reported:\n%v", err)
+	}
+	if n := len(strings.Split(err.Error(), "\n")); n != 6 {
+		t.Errorf("%d errors, want 6:\n%v", n, err)
+	}
+}
+
+func TestRegisterIsAtomic(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/health", okHandler).Named("health")
+	before := rt.String()
+
+	// One bad entry, here clashing with an existing name, keeps the whole batch out
+	batch := append(usersModule(), Route{Method: "GET", Path: "/status", Handler: okHandler, Name: "health"})
+	if err := rt.Register(batch); err == nil {
+		t.Fatal("batch with a duplicate name registered")
+	}
+	if after := rt.String(); after != before {
+		t.Errorf("route table changed:\n%s\nwant:\n%s", after, before)
+	}
+	if res := rt.Test("GET", "/users", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("GET /users after a failed batch = %d", res.StatusCode())
+	}
+	if _, err := rt.URL("user", "id", "7"); err == nil {
+		t.Error("name from a failed batch is usable")
+	}
+
+	// The same batch without the bad entry registers, its routes no longer clashing with
+	// those of the failed attempt
+	if err := rt.Register(usersModule()); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(rt.routes) != 5 {
+		t.Errorf("%d routes, want 5", len(rt.routes))
+	}
+}
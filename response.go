@@ -0,0 +1,469 @@
+package tobingo
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// responseWriter wraps the ResponseWriter handed to middleware and handlers so the router
+// and the response helpers know whether the status line has already been sent
+type responseWriter struct {
+	http.ResponseWriter
+	req    *http.Request // Request being answered, named in diagnostics
+	status int           // Status code sent to the client, 0 until the response is committed
+	size   int64         // Number of body bytes written
+	warned bool          // Whether a write to a bodyless response was already logged
+
+	implicitStatus int // Status committed by a Write without WriteHeader, 200 when zero
+}
+
+// WriteHeader records the status and forwards it; informational 1xx statuses don't commit the response
+// A second final status is logged with the request it belongs to and dropped, replacing
+// net/http's anonymous "superfluous WriteHeader" warning
+func (w *responseWriter) WriteHeader(code int) {
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		if w.status != 0 {
+			loggerFor(w.req).Warn("tobingo: ignoring WriteHeader after the status was sent", "method", w.req.Method, "path", w.req.URL.Path, "status", code, "sent", w.status)
+			return
+		}
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write commits an implicit 200 on first use like net/http and counts the bytes written
+// Writes after a status that forbids a body, e.g. from NoContent, are logged once and
+// discarded, still counted so the handler carries on
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(cmp.Or(w.implicitStatus, http.StatusOK))
+	}
+	if !bodyAllowed(w.status) && len(b) > 0 {
+		if !w.warned {
+			w.warned = true
+			loggerFor(w.req).Warn("tobingo: discarding body written after a bodyless status", "method", w.req.Method, "path", w.req.URL.Path, "status", w.status)
+		}
+		w.size += int64(len(b))
+		return len(b), nil
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, keeping http.Flusher available to handlers
+func (w *responseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError flushes like Flush and reports http.ErrNotSupported when the underlying
+// writer can't flush; http.ResponseController prefers it over Flush
+func (w *responseWriter) FlushError() error {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over to the caller, keeping http.Hijacker available to handlers
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Push initiates an HTTP/2 server push when the underlying writer supports it
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	return pushTo(w.ResponseWriter, target, opts)
+}
+
+// ReadFrom copies src into the response, letting the underlying writer use sendfile or
+// splice when it implements io.ReaderFrom as net/http's own writer does
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.WriteHeader(cmp.Or(w.implicitStatus, http.StatusOK))
+	}
+	rf, ok := w.ResponseWriter.(io.ReaderFrom)
+	if !ok || !bodyAllowed(w.status) {
+		// Go through Write so bodyless statuses are still enforced and bytes counted
+		return io.Copy(writerOnly{w}, src)
+	}
+	n, err := rf.ReadFrom(src)
+	w.size += n
+	return n, err
+}
+
+// writerOnly hides every method but Write so io.Copy can't recurse into ReadFrom
+type writerOnly struct {
+	io.Writer
+}
+
+// Status returns the status code sent to the client, 0 until the response is committed
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// BytesWritten returns the number of body bytes written so far
+func (w *responseWriter) BytesWritten() int64 {
+	return w.size
+}
+
+// Written reports whether the status line was sent, after which headers can't change
+func (w *responseWriter) Written() bool {
+	return w.status != 0
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ResponseRecorder is the writer the router installs once per request to track the response
+// Middleware reads it with Recorder instead of wrapping the writer again, which would hide
+// optional interfaces; the writer handed to handlers implements exactly the http.Flusher,
+// http.Hijacker, http.Pusher, and io.ReaderFrom the connection supports, as do those of the
+// shipped middleware that wrap it, so type assertions stay truthful
+type ResponseRecorder interface {
+	http.ResponseWriter
+	Status() int         // Status code sent, 0 until the response is committed
+	BytesWritten() int64 // Body bytes written so far
+	Written() bool       // Whether the response is committed
+}
+
+// Recorder returns the ResponseRecorder of the request, or nil when r didn't come through a router
+// Example: defer func() { log.Println(tobingo.Recorder(r).Status()) }()
+func Recorder(r *http.Request) ResponseRecorder {
+	if rw, _ := r.Context().Value(recorderKey).(*responseWriter); rw != nil {
+		return rw
+	}
+	return nil
+}
+
+// trackedWriter finds the router's responseWriter beneath any middleware wrappers that
+// implement Unwrap, returning nil when w didn't come through a router
+func trackedWriter(w http.ResponseWriter) *responseWriter {
+	for w != nil {
+		if rw, ok := w.(*responseWriter); ok {
+			return rw
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// writeStatus sends the status line unless the response was already committed, in which
+// case the earlier status stands and net/http's "superfluous WriteHeader" warning is avoided
+func writeStatus(w http.ResponseWriter, status int) {
+	if rw := trackedWriter(w); rw != nil && rw.status != 0 {
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// maxPooledBuffer caps the size of buffers returned to bufferPool so one huge response
+// doesn't pin its memory for the life of the process
+const maxPooledBuffer = 64 << 10
+
+// bufferPool recycles the buffers responses are encoded into
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool unless it grew too large
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// JSONOpts overrides the encoding of a single JSON response
+type JSONOpts struct {
+	Indent     string // Indentation per nesting level, "" for compact output regardless of the router setting
+	EscapeHTML bool   // Escape <, >, and & as the encoding/json default does; leave false to embed URLs verbatim
+	ETag       bool   // Tag 200 responses to GET and HEAD with an ETag and answer a matching If-None-Match with 304
+}
+
+// JSON encodes v and writes it with the given status and Content-Type application/json
+// The value is encoded before anything is written, so an encoding error is returned with
+// the response still untouched and the caller free to send a 500 instead
+// If the response was already committed the earlier status is kept and only the body is written
+// Output is compact with HTML escaping unless the router sets an indent with rt.JSONIndent,
+// and tagged with an ETag when it turns on rt.JSONETags; passing a JSONOpts replaces those
+// settings for this call
+// Example: return tobingo.JSON(w, http.StatusOK, user)
+func JSON(w http.ResponseWriter, status int, v any, opts ...JSONOpts) error {
+	o := JSONOpts{EscapeHTML: true}
+	if len(opts) > 0 {
+		o = opts[0]
+	} else if rw := trackedWriter(w); rw != nil {
+		rt := routerFrom(rw.req)
+		if indent := rt.jsonIndent.Load(); indent != nil {
+			o.Indent = *indent
+		}
+		o.ETag = rt.jsonETags.Load()
+	}
+	return writeJSON(w, status, v, o)
+}
+
+// JSONIndent behaves like JSON but indents the output with indent for each nesting level
+func JSONIndent(w http.ResponseWriter, status int, v any, indent string) error {
+	return writeJSON(w, status, v, JSONOpts{Indent: indent, EscapeHTML: true})
+}
+
+// JSONIndent sets the indentation the JSON helper uses for responses of this router, e.g.
+// "  " in development; the default "" keeps output compact
+// It may be changed while serving
+func (rt *Rastauter) JSONIndent(indent string) {
+	rt.jsonIndent.Store(&indent)
+}
+
+// writeJSON encodes v into a pooled buffer and writes it with Content-Type and Content-Length set
+func writeJSON(w http.ResponseWriter, status int, v any, opts JSONOpts) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(opts.EscapeHTML)
+	if opts.Indent != "" {
+		enc.SetIndent("", opts.Indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	// The body is already buffered, so hashing it costs no second copy
+	if opts.ETag {
+		if rw := trackedWriter(w); rw != nil && rw.status == 0 && notModified(w, rw.req, status, buf.Bytes()) {
+			return nil
+		}
+	}
+	writeStatus(w, status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// XML encodes v with encoding/xml and writes it with the given status, Content-Type
+// application/xml, and the standard XML declaration
+// Like JSON the value is encoded before anything is written, so an encoding error is
+// returned with the response untouched; it also works as a Negotiate offer
+// Example: return tobingo.XML(w, http.StatusOK, invoice)
+func XML(w http.ResponseWriter, status int, v any) error {
+	return writeXML(w, status, v, "")
+}
+
+// XMLIndent behaves like XML but indents nested elements with indent
+func XMLIndent(w http.ResponseWriter, status int, v any, indent string) error {
+	return writeXML(w, status, v, indent)
+}
+
+// writeXML encodes v into a pooled buffer and writes it with Content-Type and Content-Length set
+func writeXML(w http.ResponseWriter, status int, v any, indent string) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if indent != "" {
+		enc.Indent("", indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	h := w.Header()
+	h.Set("Content-Type", "application/xml; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	writeStatus(w, status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ErrInvalidCallback is returned by JSONP for callback names that aren't plain identifiers
+var ErrInvalidCallback = errors.New("tobingo: invalid JSONP callback name")
+
+// jsonpCallback matches dotted JavaScript identifiers such as "cb" or "jQuery1.handlers_2",
+// which is all a legitimate callback needs and leaves no room for injected script
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// JSONP wraps the JSON encoding of v in a call to callback for legacy cross-origin clients,
+// sent as application/javascript with the "/**/" prefix that defeats content sniffing attacks
+// callback must be a dotted identifier of at most 128 characters, otherwise ErrInvalidCallback
+// is returned without writing; an empty callback falls back to plain JSON
+// Example: return tobingo.JSONP(w, r, http.StatusOK, tobingo.Query(r, "callback"), data)
+func JSONP(w http.ResponseWriter, r *http.Request, status int, callback string, v any) error {
+	if callback == "" {
+		return JSON(w, status, v)
+	}
+	if len(callback) > 128 || !jsonpCallback.MatchString(callback) {
+		return fmt.Errorf("%w: %q", ErrInvalidCallback, callback)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString("/**/" + callback + "(")
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	// Drop the encoder's trailing newline so the call stays on one line
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteString(");")
+
+	h := w.Header()
+	h.Set("Content-Type", "application/javascript; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	writeStatus(w, status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Text writes s with the given status and Content-Type text/plain, returning the write error
+// Like JSON it keeps the earlier status when the response was already committed
+func Text(w http.ResponseWriter, status int, s string) error {
+	h := w.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(s)))
+	writeStatus(w, status)
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// Textf formats according to format and writes the result like Text
+// Example: return tobingo.Textf(w, http.StatusOK, "hello %s", name)
+func Textf(w http.ResponseWriter, status int, format string, args ...any) error {
+	return Text(w, status, fmt.Sprintf(format, args...))
+}
+
+// bodyAllowed reports whether a response with status may carry a body (RFC 9110 section 6.4.1)
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// Blob writes b with the given status, Content-Type, and Content-Length
+// For statuses that forbid a body, such as 204 and 304, only the status is sent and b is discarded
+// Example: return tobingo.Blob(w, http.StatusOK, "image/png", png)
+func Blob(w http.ResponseWriter, status int, contentType string, b []byte) error {
+	if !bodyAllowed(status) {
+		writeStatus(w, status)
+		return nil
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(b)))
+	writeStatus(w, status)
+	_, err := w.Write(b)
+	return err
+}
+
+// BlobReader streams the contents of rd with the given status and Content-Type
+// size sets Content-Length when known; pass -1 to send the body chunked
+// A reader that fails or ends before size bytes returns an error after the status was sent,
+// so the client sees a truncated response rather than a complete one
+// Like Blob, rd is not read for statuses that forbid a body
+func BlobReader(w http.ResponseWriter, status int, contentType string, rd io.Reader, size int64) error {
+	if !bodyAllowed(status) {
+		writeStatus(w, status)
+		return nil
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	if size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	writeStatus(w, status)
+
+	if size < 0 {
+		_, err := io.Copy(w, rd)
+		return err
+	}
+	n, err := io.Copy(w, io.LimitReader(rd, size))
+	if err == nil && n < size {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// NoContent sends 204 No Content, for DELETE handlers and webhook acknowledgements
+// Any body written afterwards is dropped and logged instead of reaching the client
+func NoContent(w http.ResponseWriter) {
+	Status(w, http.StatusNoContent)
+}
+
+// Status sends a status-only response with an empty body
+// Like the other helpers it keeps the earlier status when the response was already committed
+func Status(w http.ResponseWriter, code int) {
+	writeStatus(w, code)
+}
+
+// Redirect replies with a redirect to location, resolved against the request path like
+// http.Redirect when relative, with the values of query appended to its query string
+// status must be a 3xx code; anything else is a programming error and panics
+// Example: tobingo.Redirect(w, r, http.StatusSeeOther, "/login", url.Values{"next": {r.URL.Path}})
+func Redirect(w http.ResponseWriter, r *http.Request, status int, location string, query ...url.Values) {
+	if status < 300 || status > 399 {
+		panic(fmt.Sprintf("tobingo: Redirect called with non-redirect status %d", status))
+	}
+
+	// Query values go before any fragment
+	location, fragment, hasFragment := strings.Cut(location, "#")
+	for _, q := range query {
+		if len(q) == 0 {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(location, "?") {
+			sep = "&"
+		}
+		location += sep + q.Encode()
+	}
+	if hasFragment {
+		location += "#" + fragment
+	}
+	http.Redirect(w, r, externalLocation(r, location), status)
+}
+
+// RedirectToRoute redirects to the URL generated for the named route of the router serving r,
+// so redirects follow the route when its pattern changes; params are name/value pairs as for URL
+// Returns the URL generation error without writing anything
+// Example: err := tobingo.RedirectToRoute(w, r, http.StatusFound, "user", "id", id)
+func RedirectToRoute(w http.ResponseWriter, r *http.Request, status int, routeName string, params ...string) error {
+	rt := routerFrom(r)
+	if rt == nil {
+		return fmt.Errorf("%w: %q: request not served by a router", ErrRouteNotFound, routeName)
+	}
+
+	location, err := rt.URL(routeName, params...)
+	if err != nil {
+		return err
+	}
+	Redirect(w, r, status, location)
+	return nil
+}
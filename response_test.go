@@ -0,0 +1,496 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	for name, tc := range map[string]struct {
+		v    any
+		want string
+	}{
+		"struct": {user{1, "ann"}, `{"id":1,"name":"ann"}` + "\n"},
+		"map":    {map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}` + "\n"},
+		"nil":    {nil, "null\n"},
+		"html":   {"<a>&", `"\u003ca\u003e\u0026"` + "\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rt := NewRastaRouterInitializer()
+			rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+				if err := JSON(w, http.StatusCreated, tc.v); err != nil {
+					t.Error(err)
+				}
+			})
+			res := rt.Test("GET", "/", nil)
+			if res.StatusCode() != http.StatusCreated || res.BodyString() != tc.want {
+				t.Errorf("got %d %q, want 201 %q", res.StatusCode(), res.BodyString(), tc.want)
+			}
+			if ct := res.Header("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestJSONUnencodable(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := JSON(w, http.StatusOK, map[string]any{"c": make(chan int)}); err == nil {
+			t.Error("encoding a channel succeeded")
+		}
+		// Nothing was written, so the handler can still choose the status
+		if Recorder(r).Written() {
+			t.Error("response committed by a failed encode")
+		}
+		Text(w, http.StatusInternalServerError, "oops")
+	})
+	res := rt.Test("GET", "/", nil)
+	if res.StatusCode() != http.StatusInternalServerError || res.BodyString() != "oops" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if ct := res.Header("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestJSONKeepsSentStatus(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		JSON(w, http.StatusOK, "late")
+	})
+	res := rt.Test("GET", "/", nil)
+	if res.StatusCode() != http.StatusAccepted || res.BodyString() != `"late"`+"\n" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestJSONIndent(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.JSONIndent("  ")
+	rt.GET("/router", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]int{"a": 1})
+	})
+	rt.GET("/compact", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]int{"a": 1}, JSONOpts{})
+	})
+	rt.GET("/tabs", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]int{"a": 1}, JSONOpts{Indent: "\t"})
+	})
+	rt.GET("/helper", func(w http.ResponseWriter, r *http.Request) {
+		JSONIndent(w, http.StatusOK, map[string]int{"a": 1}, " ")
+	})
+	for path, want := range map[string]string{
+		"/router":  "{\n  \"a\": 1\n}\n",
+		"/compact": "{\"a\":1}\n",
+		"/tabs":    "{\n\t\"a\": 1\n}\n",
+		"/helper":  "{\n \"a\": 1\n}\n",
+	} {
+		if got := rt.Test("GET", path, nil).BodyString(); got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestJSONVerbatimHTML(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, "<a>", JSONOpts{})
+	})
+	if got := rt.Test("GET", "/", nil).BodyString(); got != `"<a>"`+"\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestJSONIndentToggledWhileServing(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]int{"a": 1})
+	})
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				if i == 0 {
+					rt.JSONIndent(strings.Repeat(" ", j%3))
+					continue
+				}
+				switch got := rt.Test("GET", "/", nil).BodyString(); got {
+				case "{\"a\":1}\n", "{\n \"a\": 1\n}\n", "{\n  \"a\": 1\n}\n":
+				default:
+					t.Errorf("body = %q", got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestText(t *testing.T) {
+	large := strings.Repeat("x", 1<<20)
+	rt := NewRastaRouterInitializer()
+	rt.GET("/empty", func(w http.ResponseWriter, r *http.Request) { Text(w, http.StatusOK, "") })
+	rt.GET("/large", func(w http.ResponseWriter, r *http.Request) { Text(w, http.StatusOK, large) })
+	rt.GET("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		Textf(w, http.StatusTeapot, "hello %s", GetParam(r, "name"))
+	})
+
+	for path, want := range map[string]string{"/empty": "", "/large": large, "/hello/ann": "hello ann"} {
+		res := rt.Test("GET", path, nil)
+		if res.BodyString() != want {
+			t.Errorf("GET %s returned %d bytes, want %d", path, len(res.BodyString()), len(want))
+		}
+		if res.Header("Content-Type") != "text/plain; charset=utf-8" || res.Header("Content-Length") != strconv.Itoa(len(want)) {
+			t.Errorf("GET %s headers = %v", path, res.Recorder.Header())
+		}
+	}
+	if code := rt.Test("GET", "/hello/ann", nil).StatusCode(); code != http.StatusTeapot {
+		t.Errorf("Textf status = %d", code)
+	}
+}
+
+// headerWrapper stands in for third-party middleware that wraps the writer and exposes Unwrap
+type headerWrapper struct {
+	http.ResponseWriter
+}
+
+func (w headerWrapper) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestTextThroughWrappedWriter(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: &out}))
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(headerWrapper{w}, r)
+		})
+	})
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		Text(w, http.StatusCreated, "first")
+		// Already committed through the wrapper, so the status stands
+		Text(w, http.StatusInternalServerError, "second")
+	})
+
+	res := rt.Test("GET", "/", nil)
+	if res.StatusCode() != http.StatusCreated || res.BodyString() != "firstsecond" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if !strings.Contains(out.String(), `"GET / HTTP/1.1" 201 11`) {
+		t.Errorf("log line %q lacks the status and size", out.String())
+	}
+}
+
+// failingReader yields its data and then fails
+type failingReader struct {
+	data []byte
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, errors.New("disk on fire")
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestBlob(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/blob", func(w http.ResponseWriter, r *http.Request) {
+		Blob(w, http.StatusOK, "image/png", []byte("\x89PNG"))
+	})
+	rt.GET("/sized", func(w http.ResponseWriter, r *http.Request) {
+		BlobReader(w, http.StatusOK, "text/csv", strings.NewReader("a,b\nc,d"), 7)
+	})
+	rt.GET("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		BlobReader(w, http.StatusOK, "text/csv", strings.NewReader("a,b"), -1)
+	})
+
+	for path, want := range map[string][3]string{
+		"/blob":    {"\x89PNG", "image/png", "4"},
+		"/sized":   {"a,b\nc,d", "text/csv", "7"},
+		"/chunked": {"a,b", "text/csv", ""},
+	} {
+		res := rt.Test("GET", path, nil)
+		if got := [3]string{res.BodyString(), res.Header("Content-Type"), res.Header("Content-Length")}; got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestBlobBodylessStatuses(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		read := false
+		rt := NewRastaRouterInitializer()
+		rt.GET("/blob", func(w http.ResponseWriter, r *http.Request) {
+			Blob(w, status, "text/plain", []byte("body"))
+		})
+		rt.GET("/reader", func(w http.ResponseWriter, r *http.Request) {
+			BlobReader(w, status, "text/plain", readFunc(func() { read = true }), 4)
+		})
+		for _, path := range []string{"/blob", "/reader"} {
+			res := rt.Test("GET", path, nil)
+			if res.StatusCode() != status || res.BodyString() != "" || res.Header("Content-Type") != "" {
+				t.Errorf("%d %s = %d %q %q", status, path, res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+			}
+		}
+		if read {
+			t.Errorf("%d: reader was read", status)
+		}
+	}
+}
+
+// readFunc is a reader that calls fn and reports EOF
+type readFunc func()
+
+func (f readFunc) Read([]byte) (int, error) {
+	f()
+	return 0, io.EOF
+}
+
+func TestBlobReaderErrors(t *testing.T) {
+	var failErr, shortErr error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/fail", func(w http.ResponseWriter, r *http.Request) {
+		failErr = BlobReader(w, http.StatusOK, "text/plain", &failingReader{[]byte("half")}, 8)
+	})
+	rt.GET("/short", func(w http.ResponseWriter, r *http.Request) {
+		shortErr = BlobReader(w, http.StatusOK, "text/plain", strings.NewReader("half"), 8)
+	})
+
+	res := rt.Test("GET", "/fail", nil)
+	if failErr == nil || failErr.Error() != "disk on fire" {
+		t.Errorf("failing reader err = %v", failErr)
+	}
+	// The status was already sent, so the client sees a truncated body
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "half" {
+		t.Errorf("failing reader = %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	rt.Test("GET", "/short", nil)
+	if !errors.Is(shortErr, io.ErrUnexpectedEOF) {
+		t.Errorf("short reader err = %v, want io.ErrUnexpectedEOF", shortErr)
+	}
+}
+
+func TestNoContentAndStatus(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.addRoute("DELETE", "/items/:id", func(w http.ResponseWriter, r *http.Request) { NoContent(w) })
+	rt.GET("/cached", func(w http.ResponseWriter, r *http.Request) { Status(w, http.StatusNotModified) })
+	rt.addRoute("POST", "/accepted", func(w http.ResponseWriter, r *http.Request) {
+		Status(w, http.StatusAccepted)
+		Status(w, http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{"DELETE", "/items/1", http.StatusNoContent},
+		{"GET", "/cached", http.StatusNotModified},
+		{"POST", "/accepted", http.StatusAccepted},
+	} {
+		res := rt.Test(tc.method, tc.path, nil)
+		if res.StatusCode() != tc.want || res.BodyString() != "" {
+			t.Errorf("%s %s = %d %q, want %d", tc.method, tc.path, res.StatusCode(), res.BodyString(), tc.want)
+		}
+	}
+}
+
+func TestWriteAfterNoContent(t *testing.T) {
+	var logs bytes.Buffer
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	rt.addRoute("DELETE", "/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		NoContent(w)
+		n, err := w.Write([]byte("deleted"))
+		if n != 7 || err != nil {
+			t.Errorf("Write = %d, %v; the handler should carry on", n, err)
+		}
+		w.Write([]byte("again"))
+		if got := Recorder(r).BytesWritten(); got != 12 {
+			t.Errorf("BytesWritten = %d, want 12", got)
+		}
+	})
+
+	res := rt.Test("DELETE", "/items/1", nil)
+	if res.StatusCode() != http.StatusNoContent || res.BodyString() != "" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+	if n := strings.Count(logs.String(), "discarding body written after a bodyless status"); n != 1 {
+		t.Errorf("logged %d warnings, want 1: %s", n, logs.String())
+	}
+	if !strings.Contains(logs.String(), "path=/items/1") {
+		t.Errorf("warning does not name the request: %s", logs.String())
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id/edit", func(w http.ResponseWriter, r *http.Request) {
+		switch Query(r, "to") {
+		case "absolute":
+			Redirect(w, r, http.StatusFound, "https://example.com/elsewhere")
+		case "rooted":
+			Redirect(w, r, http.StatusSeeOther, "/login", url.Values{"next": {r.URL.Path}})
+		case "relative":
+			Redirect(w, r, http.StatusMovedPermanently, "../profile")
+		case "fragment":
+			Redirect(w, r, http.StatusFound, "/docs?v=1#intro", url.Values{"q": {"a b"}})
+		}
+	})
+
+	for to, want := range map[string]struct {
+		status   int
+		location string
+	}{
+		"absolute": {http.StatusFound, "https://example.com/elsewhere"},
+		"rooted":   {http.StatusSeeOther, "/login?next=%2Fusers%2F7%2Fedit"},
+		"relative": {http.StatusMovedPermanently, "/users/profile"},
+		"fragment": {http.StatusFound, "/docs?v=1&q=a+b#intro"},
+	} {
+		res := rt.Test("GET", "/users/7/edit?to="+to, nil)
+		if res.StatusCode() != want.status || res.Header("Location") != want.location {
+			t.Errorf("%s = %d %q, want %d %q", to, res.StatusCode(), res.Header("Location"), want.status, want.location)
+		}
+	}
+}
+
+func TestRedirectToRoute(t *testing.T) {
+	var missingErr, paramErr error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id/posts/:post", writeRoute("post")).Named("user_post")
+	rt.GET("/old", func(w http.ResponseWriter, r *http.Request) {
+		if err := RedirectToRoute(w, r, http.StatusMovedPermanently, "user_post", "id", "a b", "post", "7"); err != nil {
+			t.Error(err)
+		}
+	})
+	rt.GET("/missing", func(w http.ResponseWriter, r *http.Request) {
+		missingErr = RedirectToRoute(w, r, http.StatusFound, "nope")
+		paramErr = RedirectToRoute(w, r, http.StatusFound, "user_post", "id", "1")
+	})
+
+	res := rt.Test("GET", "/old", nil)
+	if res.StatusCode() != http.StatusMovedPermanently || res.Header("Location") != "/users/a%20b/posts/7" {
+		t.Errorf("got %d %q", res.StatusCode(), res.Header("Location"))
+	}
+
+	res = rt.Test("GET", "/missing", nil)
+	if !errors.Is(missingErr, ErrRouteNotFound) || !errors.Is(paramErr, ErrParamMissing) {
+		t.Errorf("errors = %v, %v", missingErr, paramErr)
+	}
+	// Failed lookups leave the response untouched
+	if res.Header("Location") != "" {
+		t.Errorf("Location %q written on error", res.Header("Location"))
+	}
+}
+
+func TestRedirectToRouteOutsideRouter(t *testing.T) {
+	err := RedirectToRoute(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), http.StatusFound, "home")
+	if !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("err = %v, want ErrRouteNotFound", err)
+	}
+}
+
+func TestRedirectInvalidStatus(t *testing.T) {
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(string), "non-redirect status 200") {
+			t.Errorf("recovered %v", v)
+		}
+	}()
+	Redirect(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), http.StatusOK, "/")
+}
+
+func TestJSONP(t *testing.T) {
+	var err error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/data", func(w http.ResponseWriter, r *http.Request) {
+		err = JSONP(w, r, http.StatusOK, Query(r, "callback"), map[string]string{"a": "</script>"})
+	})
+
+	res := rt.Test("GET", "/data?callback=jQuery1.handlers_2", nil)
+	// HTML escaping keeps the payload from closing a surrounding script element
+	if want := `/**/jQuery1.handlers_2({"a":"\u003c/script\u003e"});`; err != nil || res.BodyString() != want {
+		t.Errorf("got %q, %v; want %q", res.BodyString(), err, want)
+	}
+	if res.Header("Content-Type") != "application/javascript; charset=utf-8" || res.Header("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("headers = %v", res.Recorder.Header())
+	}
+
+	for _, callback := range []string{"alert(1)//", "a;b", "1abc", "a..b", strings.Repeat("a", 129)} {
+		res := rt.Test("GET", "/data?callback="+url.QueryEscape(callback), nil)
+		if !errors.Is(err, ErrInvalidCallback) || strings.Contains(res.BodyString(), "(") {
+			t.Errorf("callback %q = %q, %v", callback, res.BodyString(), err)
+		}
+	}
+
+	res = rt.Test("GET", "/data", nil)
+	if err != nil || res.BodyString() != `{"a":"\u003c/script\u003e"}`+"\n" || res.Header("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("fallback = %q %q, %v", res.BodyString(), res.Header("Content-Type"), err)
+	}
+}
+
+func TestXML(t *testing.T) {
+	type line struct {
+		SKU string `xml:"sku,attr"`
+		Qty int    `xml:"qty"`
+	}
+	type invoice struct {
+		XMLName struct{} `xml:"invoice"`
+		ID      string   `xml:"id,attr"`
+		Lines   []line   `xml:"lines>line"`
+	}
+	v := invoice{ID: "A&1", Lines: []line{{"x", 2}}}
+
+	rt := NewRastaRouterInitializer()
+	rt.GET("/compact", func(w http.ResponseWriter, r *http.Request) { XML(w, http.StatusCreated, v) })
+	rt.GET("/indent", func(w http.ResponseWriter, r *http.Request) { XMLIndent(w, http.StatusOK, v, "  ") })
+
+	res := rt.Test("GET", "/compact", nil)
+	want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<invoice id="A&amp;1"><lines><line sku="x"><qty>2</qty></line></lines></invoice>` + "\n"
+	if res.StatusCode() != http.StatusCreated || res.BodyString() != want {
+		t.Errorf("got %d %q, want %q", res.StatusCode(), res.BodyString(), want)
+	}
+	if res.Header("Content-Type") != "application/xml; charset=utf-8" || res.Header("Content-Length") != strconv.Itoa(len(want)) {
+		t.Errorf("headers = %v", res.Recorder.Header())
+	}
+
+	want = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		"<invoice id=\"A&amp;1\">\n  <lines>\n    <line sku=\"x\">\n      <qty>2</qty>\n    </line>\n  </lines>\n</invoice>\n"
+	if got := rt.Test("GET", "/indent", nil).BodyString(); got != want {
+		t.Errorf("indented = %q, want %q", got, want)
+	}
+}
+
+func TestXMLUnencodable(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := XML(w, http.StatusOK, map[string]int{"a": 1}); err == nil {
+			t.Error("encoding a map succeeded")
+		}
+		if Recorder(r).Written() || w.Header().Get("Content-Type") != "" {
+			t.Error("failed encode touched the response")
+		}
+		Status(w, http.StatusInternalServerError)
+	})
+	if res := rt.Test("GET", "/", nil); res.StatusCode() != http.StatusInternalServerError || res.BodyString() != "" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
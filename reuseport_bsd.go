@@ -0,0 +1,8 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tobingo
+
+import "syscall"
+
+// soReusePort is SO_REUSEPORT, the socket option letting processes bind the same address
+var soReusePort = syscall.SO_REUSEPORT
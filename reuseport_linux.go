@@ -0,0 +1,16 @@
+package tobingo
+
+import (
+	"runtime"
+	"strings"
+)
+
+// soReusePort is SO_REUSEPORT, which package syscall doesn't define on Linux; MIPS numbers
+// it apart from the other architectures
+var soReusePort = 0xf
+
+func init() {
+	if strings.HasPrefix(runtime.GOARCH, "mips") {
+		soReusePort = 0x200
+	}
+}
@@ -0,0 +1,180 @@
+package tobingo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ErrRouteConfig is matched by the errors of LoadRoutes
+var ErrRouteConfig = errors.New("tobingo: invalid route config")
+
+// RouteConfig declares a route for LoadRoutes, typically unmarshalled from a JSON or YAML file
+// Action selects which of the remaining fields apply
+type RouteConfig struct {
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`       // Route name for URL generation, used in errors too
+	Method  string `json:"method,omitempty" yaml:"method,omitempty"`   // Method for "redirect" and "handler", GET by default
+	Path    string `json:"path" yaml:"path"`                           // Route pattern, or the mount prefix for "static"
+	Action  string `json:"action" yaml:"action"`                       // One of "redirect", "static", "proxy", or "handler"
+	Target  string `json:"target,omitempty" yaml:"target,omitempty"`   // Location of a "redirect", which may use the parameters of Path
+	Status  int    `json:"status,omitempty" yaml:"status,omitempty"`   // Redirect status, 302 by default
+	Dir     string `json:"dir,omitempty" yaml:"dir,omitempty"`         // Directory served by "static"
+	URL     string `json:"url,omitempty" yaml:"url,omitempty"`         // Upstream of a "proxy", whose Path must end in a catch-all
+	Handler string `json:"handler,omitempty" yaml:"handler,omitempty"` // Name given to RegisterNamedHandler, for "handler"
+}
+
+// RegisterNamedHandler makes h available to "handler" entries of LoadRoutes under name
+func (rt *Rastauter) RegisterNamedHandler(name string, h http.HandlerFunc) {
+	if h == nil {
+		panic(fmt.Sprintf("tobingo: RegisterNamedHandler %q: handler is nil", name))
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.namedHandlers == nil {
+		rt.namedHandlers = make(map[string]http.HandlerFunc)
+	}
+	rt.namedHandlers[name] = h
+}
+
+// LoadRoutes registers routes declared in configuration, so redirects, static mounts,
+// proxies, and named handlers can change without recompiling
+// Every entry is validated before any is registered, and all problems are reported at once,
+// each naming the entry by index and name; a conflict found while registering stops there,
+// leaving the entries before it registered
+// Example: var cfg []tobingo.RouteConfig; json.Unmarshal(data, &cfg); err := rt.LoadRoutes(cfg)
+func (rt *Rastauter) LoadRoutes(cfg []RouteConfig) error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, rc := range cfg {
+		if err := rt.validateRouteConfig(rc); err != nil {
+			errs = append(errs, routeConfigError(i, rc, err))
+		}
+		if rc.Name != "" {
+			if names[rc.Name] {
+				errs = append(errs, routeConfigError(i, rc, errors.New("name used by an earlier entry")))
+			}
+			names[rc.Name] = true
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for i, rc := range cfg {
+		if err := rt.loadRoute(rc); err != nil {
+			return routeConfigError(i, rc, err)
+		}
+	}
+	return nil
+}
+
+// routeConfigError identifies the entry an error belongs to
+func routeConfigError(i int, rc RouteConfig, err error) error {
+	return fmt.Errorf("%w: route %d (%q): %w", ErrRouteConfig, i, cmp.Or(rc.Name, rc.Path), err)
+}
+
+// validateRouteConfig checks an entry without registering it
+func (rt *Rastauter) validateRouteConfig(rc RouteConfig) error {
+	if rc.Path == "" {
+		return errors.New("path is required")
+	}
+	if rc.Action != "static" {
+		if err := parsePattern(&Route{Path: rc.Path}); err != nil {
+			return err
+		}
+	}
+	if rc.Name != "" {
+		for _, route := range rt.routes {
+			if route.Name == rc.Name {
+				return fmt.Errorf("name is already used by %s %s", route.Method, route.Path)
+			}
+		}
+	}
+
+	switch rc.Action {
+	case "redirect":
+		if rc.Target == "" {
+			return errors.New("redirect needs a target")
+		}
+		if _, err := newRedirectTarget(rc.Path, rc.Target, cmp.Or(rc.Status, http.StatusFound)); err != nil {
+			return err
+		}
+	case "static":
+		info, err := os.Stat(rc.Dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", rc.Dir)
+		}
+	case "proxy":
+		u, err := url.Parse(rc.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("proxy url %q must be an absolute http or https URL", rc.URL)
+		}
+		if _, catchAll := catchAllPrefix(rc.Path); !catchAll {
+			return fmt.Errorf("%w: %q: proxy routes must end in a catch-all", ErrInvalidPattern, rc.Path)
+		}
+	case "handler":
+		rt.mu.Lock()
+		_, ok := rt.namedHandlers[rc.Handler]
+		rt.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("no handler registered as %q", rc.Handler)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", rc.Action)
+	}
+	return nil
+}
+
+// loadRoute registers a validated entry
+func (rt *Rastauter) loadRoute(rc RouteConfig) error {
+	var route *Route
+	var err error
+	method := strings.ToUpper(cmp.Or(rc.Method, http.MethodGet))
+
+	switch rc.Action {
+	case "redirect":
+		route, err = rt.TryRedirect(method, rc.Path, rc.Target, cmp.Or(rc.Status, http.StatusFound))
+	case "static":
+		if _, err = rt.tryMountStatic(rc.Path, rootFS(rc.Dir), StaticOptions{}); err == nil {
+			route = rt.findRoute(http.MethodGet, strings.TrimSuffix(rc.Path, "/")+"/*filepath")
+		}
+	case "proxy":
+		target, _ := url.Parse(rc.URL)
+		if err = rt.tryProxy(rc.Path, target, ProxyOptions{}); err == nil {
+			route = rt.findRoute(http.MethodGet, rc.Path)
+		}
+	case "handler":
+		rt.mu.Lock()
+		h := rt.namedHandlers[rc.Handler]
+		rt.mu.Unlock()
+		route, err = rt.tryAddRoute(method, rc.Path, h)
+	}
+	if err != nil {
+		return err
+	}
+
+	if rc.Name != "" && route != nil {
+		route.Name = rc.Name
+	}
+	return nil
+}
+
+// findRoute returns the route registered for method and pattern, nil if there is none
+func (rt *Rastauter) findRoute(method, pattern string) *Route {
+	for _, route := range rt.routes {
+		if route.Method == method && route.Path == pattern {
+			return route
+		}
+	}
+	return nil
+}
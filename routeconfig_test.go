@@ -0,0 +1,142 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadRoutes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "backend "+r.URL.Path)
+	}))
+	defer backend.Close()
+	dir := staticDir(t, map[string]string{"app.css": "body{}"})
+
+	rt := NewRastaRouterInitializer()
+	rt.RegisterNamedHandler("health", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	rt.RegisterNamedHandler("echo", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, r.Method+" "+Params(r)["id"]) })
+
+	data := `[
+		{"name": "old-docs", "path": "/docs/:page", "action": "redirect", "target": "/manual/:page", "status": 301},
+		{"path": "/blog", "action": "redirect", "target": "https://blog.example.com/"},
+		{"name": "assets", "path": "/assets", "action": "static", "dir": ` + jsonString(t, dir) + `},
+		{"name": "legacy", "path": "/legacy/*path", "action": "proxy", "url": "` + backend.URL + `/v1"},
+		{"name": "health", "path": "/healthz", "action": "handler", "handler": "health"},
+		{"path": "/items/:id", "method": "delete", "action": "handler", "handler": "echo"}
+	]`
+	var cfg []RouteConfig
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.LoadRoutes(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		method, target string
+		status         int
+		body, location string
+	}{
+		"redirect with params": {"GET", "/docs/intro", http.StatusMovedPermanently, "", "/manual/intro"},
+		"default status":       {"GET", "/blog", http.StatusFound, "", "https://blog.example.com/"},
+		"static":               {"GET", "/assets/app.css", http.StatusOK, "body{}", ""},
+		"proxy":                {"GET", "/legacy/orders/7", http.StatusOK, "backend /v1/orders/7", ""},
+		"proxy POST":           {"POST", "/legacy/orders", http.StatusOK, "backend /v1/orders", ""},
+		"handler":              {"GET", "/healthz", http.StatusOK, "ok", ""},
+		"handler method":       {"DELETE", "/items/9", http.StatusOK, "DELETE 9", ""},
+		"handler other method": {"GET", "/items/9", http.StatusNotFound, "", ""},
+	} {
+		res := rt.Test(tc.method, tc.target, nil)
+		if res.StatusCode() != tc.status || tc.body != "" && res.BodyString() != tc.body || res.Header("Location") != tc.location {
+			t.Errorf("%s: %d %q, Location %q", name, res.StatusCode(), res.BodyString(), res.Header("Location"))
+		}
+	}
+
+	// Names are usable for URL generation
+	if u, err := rt.URL("old-docs", "page", "faq"); err != nil || u != "/docs/faq" {
+		t.Errorf(`URL("old-docs") = %q, %v`, u, err)
+	}
+	if u, err := rt.URL("health"); err != nil || u != "/healthz" {
+		t.Errorf(`URL("health") = %q, %v`, u, err)
+	}
+}
+
+// jsonString encodes s as a JSON string
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLoadRoutesInvalid(t *testing.T) {
+	dir := staticDir(t, map[string]string{"file.txt": "x"})
+	rt := NewRastaRouterInitializer()
+	rt.GET("/taken", writeRoute("taken")).Named("taken")
+	rt.RegisterNamedHandler("ok", writeRoute("ok"))
+
+	cfg := []RouteConfig{
+		{Name: "fine", Path: "/fine", Action: "handler", Handler: "ok"},
+		{Name: "no-path", Action: "handler", Handler: "ok"},
+		{Path: "/jump", Action: "redirect"},
+		{Name: "bad-status", Path: "/moved", Action: "redirect", Target: "/new", Status: 200},
+		{Name: "missing-dir", Path: "/files", Action: "static", Dir: dir + "/nope"},
+		{Name: "file-dir", Path: "/file", Action: "static", Dir: dir + "/file.txt"},
+		{Name: "relative-proxy", Path: "/up/*rest", Action: "proxy", URL: "backend:8080"},
+		{Name: "proxy-no-catch-all", Path: "/up", Action: "proxy", URL: "http://backend"},
+		{Name: "unknown-handler", Path: "/h", Action: "handler", Handler: "missing"},
+		{Name: "typo", Path: "/t", Action: "redirct", Target: "/"},
+		{Name: "fine", Path: "/again", Action: "handler", Handler: "ok"},
+		{Name: "taken", Path: "/dup", Action: "handler", Handler: "ok"},
+		{Name: "bad-pattern", Path: "no-slash", Action: "handler", Handler: "ok"},
+	}
+	err := rt.LoadRoutes(cfg)
+	if !errors.Is(err, ErrRouteConfig) {
+		t.Fatalf("err = %v, want ErrRouteConfig", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`route 1 ("no-path"): path is required`,
+		`route 2 ("/jump"): redirect needs a target`,
+		`route 3 ("bad-status"): tobingo: redirect status 200`,
+		`route 4 ("missing-dir")`,
+		`route 5 ("file-dir"): ` + dir + `/file.txt is not a directory`,
+		`route 6 ("relative-proxy"): proxy url "backend:8080" must be an absolute`,
+		`route 7 ("proxy-no-catch-all"): tobingo: invalid route pattern`,
+		`route 8 ("unknown-handler"): no handler registered as "missing"`,
+		`route 9 ("typo"): unknown action "redirct"`,
+		`route 10 ("fine"): name used by an earlier entry`,
+		`route 11 ("taken"): name is already used by GET /taken`,
+		`route 12 ("bad-pattern"): tobingo: invalid route pattern`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error lacks %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "route 0 ") {
+		t.Errorf("the valid entry was reported:\n%s", msg)
+	}
+	if len(rt.routes) != 1 {
+		t.Errorf("%d routes registered, want none from a rejected config", len(rt.routes)-1)
+	}
+
+	// A conflict found while registering keeps the entries before it
+	err = rt.LoadRoutes([]RouteConfig{
+		{Path: "/first", Action: "handler", Handler: "ok"},
+		{Path: "/taken", Action: "handler", Handler: "ok"},
+		{Path: "/third", Action: "handler", Handler: "ok"},
+	})
+	if !errors.Is(err, ErrRouteConfig) || !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), `route 1 ("/taken")`) {
+		t.Errorf("conflict = %v", err)
+	}
+	if rt.Test("GET", "/first", nil).StatusCode() != http.StatusOK || rt.Test("GET", "/third", nil).StatusCode() != http.StatusNotFound {
+		t.Error("entries around the conflict were not left as documented")
+	}
+}
@@ -0,0 +1,468 @@
+package tobingo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRouteNotFound is returned by URL when no route was registered under the given name
+var ErrRouteNotFound = errors.New("tobingo: no route with that name")
+
+// Sentinel errors returned by TryHandle and TryGET, and the cause of the panics of GET
+var (
+	ErrNilHandler     = errors.New("tobingo: nil handler")
+	ErrInvalidPattern = errors.New("tobingo: invalid route pattern")
+	ErrConflict       = errors.New("tobingo: conflicting route")
+)
+
+// paramSegment is the parsed form of a route pattern segment
+type paramSegment struct {
+	literal  string // Text that must precede the parameter value, "" for whole-segment parameters
+	name     string // Parameter name, "" for literal segments
+	def      string // Default used when an optional parameter is absent
+	hasDef   bool   // Whether the pattern declared a default, which may be empty
+	optional bool   // Whether the segment may be missing from the request
+}
+
+// parseSegment splits a pattern segment into its literal prefix and parameter, if any
+// Parameters are optional when suffixed with "?" (":page?") or given a default (":page=1")
+func parseSegment(seg string) (paramSegment, bool) {
+	literal, param, ok := strings.Cut(seg, ":")
+	if !ok {
+		return paramSegment{literal: seg}, false
+	}
+
+	ps := paramSegment{literal: literal, name: param}
+	if name, def, found := strings.Cut(param, "="); found {
+		ps.name, ps.def, ps.hasDef, ps.optional = name, def, true, true
+	}
+	if name, found := strings.CutSuffix(ps.name, "?"); found {
+		ps.name, ps.optional = name, true
+	}
+	return ps, true
+}
+
+// addRoute validates the pattern and appends the route
+// Invalid patterns, conflicts, and nil handlers are programming errors and panic at
+// registration rather than misbehaving per request
+func (rt *Rastauter) addRoute(method, path string, handler http.HandlerFunc) *Route {
+	route, err := rt.tryAddRoute(method, path, handler)
+	if err != nil {
+		panic(err.Error())
+	}
+	return route
+}
+
+// TryHandle registers a route like GET does for any method, returning an error instead of
+// panicking when the route can't be added, in which case the route table is left untouched
+// Errors match ErrNilHandler, ErrInvalidPattern, or ErrConflict with errors.Is, so routes
+// loaded from configuration can be rejected gracefully
+// Example: if err := rt.TryHandle("GET", cfg.Path, h); errors.Is(err, tobingo.ErrConflict) { ... }
+func (rt *Rastauter) TryHandle(method, path string, handler http.HandlerFunc) error {
+	_, err := rt.tryAddRoute(method, path, handler)
+	return err
+}
+
+// TryGET registers a GET route like GET, returning an error instead of panicking
+func (rt *Rastauter) TryGET(path string, handler http.HandlerFunc) (*Route, error) {
+	return rt.tryAddRoute(http.MethodGet, path, handler)
+}
+
+// tryAddRoute validates the route and appends it, changing nothing when it's rejected
+func (rt *Rastauter) tryAddRoute(method, path string, handler http.HandlerFunc) (*Route, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("%w: route %s %q", ErrNilHandler, method, path)
+	}
+
+	route := &Route{
+		Method:  method,
+		Path:    path,
+		Handler: handler,
+		rt:      rt,
+	}
+	if err := parsePattern(route); err != nil {
+		return nil, err
+	}
+
+	// Two routes accepting the same requests would leave the later one unreachable
+	if other := rt.conflicting(route); other != nil {
+		return nil, fmt.Errorf("%w: %s", ErrConflict, conflictMessage(route, other))
+	}
+	if rt.trackSites {
+		route.site = registrationSite()
+	}
+
+	rt.routes = append(rt.routes, route)
+	rt.matching = slices.Insert(rt.matching, matchPosition(rt.matching, route), route)
+	return route, nil
+}
+
+// parsePattern validates the pattern of route and records its optional parameters and defaults
+func parsePattern(route *Route) error {
+	path := route.Path
+	if !strings.HasPrefix(strings.Trim(path, " "), "/") {
+		return fmt.Errorf("%w: %q: must start with /", ErrInvalidPattern, path)
+	}
+
+	// Optional parameters may only appear as a trailing run of whole segments
+	var firstOptional string
+	segments := strings.Split(strings.Trim(path, " "), "/")[1:]
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "*") && i != len(segments)-1 {
+			return fmt.Errorf("%w: %q: catch-all %q must be the last segment", ErrInvalidPattern, path, seg)
+		}
+
+		ps, isParam := parseSegment(seg)
+		switch {
+		case isParam && ps.name == "":
+			return fmt.Errorf("%w: %q: parameter in segment %q has no name", ErrInvalidPattern, path, seg)
+		case isParam && ps.optional:
+			if ps.literal != "" {
+				return fmt.Errorf("%w: %q: optional parameter %q must be a whole segment", ErrInvalidPattern, path, ps.name)
+			}
+			if firstOptional == "" {
+				firstOptional = ps.name
+			}
+			route.optional++
+			if ps.hasDef {
+				if route.Defaults == nil {
+					route.Defaults = make(map[string]string)
+				}
+				route.Defaults[ps.name] = ps.def
+			}
+		case firstOptional != "":
+			return fmt.Errorf("%w: %q: optional parameter %q must not be followed by required segment %q", ErrInvalidPattern, path, firstOptional, seg)
+		}
+	}
+	return nil
+}
+
+// TrackRegistrationSites records the file:line of each route registration so conflict
+// panics name where both routes were registered; it costs a stack walk per registration,
+// so enable it in development or tests before registering routes
+func (rt *Rastauter) TrackRegistrationSites(enabled bool) {
+	rt.trackSites = enabled
+}
+
+// registrationSite returns the file:line of the first caller outside this package, counting
+// the package's own tests as outside
+func registrationSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		pkg, _, _ := strings.Cut(frame.Function[strings.LastIndex(frame.Function, "/")+1:], ".")
+		if pkg != "tobingo" || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// conflicting returns a registered route with the same method that accepts a request path
+// route also accepts in the same way, ignoring parameter names, or nil when there is none
+// Optional parameters count for every length the route accepts
+func (rt *Rastauter) conflicting(route *Route) *Route {
+	shapes := routeShapes(route)
+	for _, other := range rt.routes {
+		if other.Method != route.Method {
+			continue
+		}
+		for _, shape := range routeShapes(other) {
+			if slices.Contains(shapes, shape) {
+				return other
+			}
+		}
+	}
+	return nil
+}
+
+// routeShapes returns the pattern of route with parameter names erased, e.g. "/users/:"
+// for "/users/:id", once for each path length it accepts
+func routeShapes(route *Route) []string {
+	segments := strings.Split(trimTrailingSlash(strings.Trim(route.Path, " ")), "/")[1:]
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "*") {
+			segments[i] = "*"
+		} else if ps, isParam := parseSegment(seg); isParam {
+			segments[i] = ps.literal + ":"
+		}
+	}
+
+	shapes := make([]string, 0, route.optional+1)
+	for n := len(segments) - route.optional; n <= len(segments); n++ {
+		shapes = append(shapes, "/"+strings.Join(segments[:n], "/"))
+	}
+	return shapes
+}
+
+// conflictMessage describes a conflict between a new route and a registered one, naming
+// where both were registered when TrackRegistrationSites is on
+func conflictMessage(route, other *Route) string {
+	msg := fmt.Sprintf("route %s %q conflicts with %s %q", route.Method, route.Path, other.Method, other.Path)
+	if route.rt.trackSites {
+		msg += fmt.Sprintf(" (registered at %s, existing route registered at %s)", cmp.Or(registrationSite(), "unknown"), cmp.Or(other.site, "unknown"))
+	}
+	return msg
+}
+
+// matchPosition returns where route goes in the order dispatch tries routes: routes without
+// a catch-all come first, then catch-alls with longer fixed prefixes, each in registration
+// order, so a mount like "/assets/*filepath" never shadows "/assets/manifest.json"
+// A route also goes before routes that have a parameter where it has a literal segment, so
+// "/users/new" registered after "/users/:id" is still reachable
+func matchPosition(matching []*Route, route *Route) int {
+	fixed, catchAll := catchAllPrefix(route.Path)
+	for i, other := range matching {
+		otherFixed, otherCatchAll := catchAllPrefix(other.Path)
+		if otherCatchAll && (!catchAll || fixed > otherFixed) {
+			return i
+		}
+		if otherCatchAll == catchAll && (!catchAll || fixed == otherFixed) && literalFirst(route.Path, other.Path) {
+			return i
+		}
+	}
+	return len(matching)
+}
+
+// literalFirst reports whether pattern a has a literal segment where b has a parameter at
+// the first position where the two differ in kind, e.g. "/users/new" against "/users/:id"
+func literalFirst(a, b string) bool {
+	aSegments := strings.Split(trimTrailingSlash(strings.Trim(a, " ")), "/")[1:]
+	bSegments := strings.Split(trimTrailingSlash(strings.Trim(b, " ")), "/")[1:]
+	for i := range min(len(aSegments), len(bSegments)) {
+		_, aParam := parseSegment(aSegments[i])
+		_, bParam := parseSegment(bSegments[i])
+		aParam = aParam || strings.HasPrefix(aSegments[i], "*")
+		bParam = bParam || strings.HasPrefix(bSegments[i], "*")
+		if aParam != bParam {
+			return bParam
+		}
+		if !aParam && aSegments[i] != bSegments[i] {
+			return false
+		}
+	}
+	return false
+}
+
+// catchAllPrefix reports whether the pattern ends in a catch-all and how many segments precede it
+func catchAllPrefix(path string) (int, bool) {
+	segments := strings.Split(strings.Trim(path, " "), "/")[1:]
+	if !strings.HasPrefix(segments[len(segments)-1], "*") {
+		return len(segments), false
+	}
+	return len(segments) - 1, true
+}
+
+// Routes returns a copy of the registered routes in registration order
+// Defaults declared in patterns such as "/list/:page=1" are reported in Route.Defaults
+func (rt *Rastauter) Routes() []Route {
+	routes := make([]Route, len(rt.routes))
+	for i, route := range rt.routes {
+		routes[i] = *route
+		routes[i].Defaults = maps.Clone(route.Defaults)
+		routes[i].Meta = maps.Clone(route.Meta)
+	}
+	return routes
+}
+
+// String lists the routes one per line as "METHOD PATTERN", followed by "name=NAME" for named
+// routes, "timeout=D" for routes with a deadline, "slash=POLICY" for routes not ignoring the
+// trailing slash, "redirect=TARGET status=CODE" for redirect routes, and the number of middleware wrapping it, for golden-file tests of the route table
+// Lines are ordered by match priority, as in dispatch: catch-alls come after other routes and
+// longer fixed prefixes first, literal segments go before parameters, then by pattern and
+// method, so registration order and handler names don't change the output
+func (rt *Rastauter) String() string {
+	rt.mu.Lock()
+	middleware := len(rt.middleware)
+	rt.mu.Unlock()
+
+	routes := slices.Clone(rt.routes)
+	slices.SortFunc(routes, func(a, b *Route) int {
+		aFixed, aCatchAll := catchAllPrefix(a.Path)
+		bFixed, bCatchAll := catchAllPrefix(b.Path)
+		switch {
+		case aCatchAll != bCatchAll && aCatchAll:
+			return 1
+		case aCatchAll != bCatchAll:
+			return -1
+		case aCatchAll && aFixed != bFixed:
+			return cmp.Compare(bFixed, aFixed)
+		case literalFirst(a.Path, b.Path):
+			return -1
+		case literalFirst(b.Path, a.Path):
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Method, b.Method))
+	})
+
+	var sb strings.Builder
+	for _, route := range routes {
+		sb.WriteString(route.Method + " " + route.Path)
+		if route.Name != "" {
+			sb.WriteString(" name=" + route.Name)
+		}
+		if d := route.EffectiveTimeout(); d > 0 {
+			sb.WriteString(" timeout=" + d.String())
+		}
+		if target, status := route.RedirectTarget(); status != 0 {
+			sb.WriteString(" redirect=" + target + " status=" + strconv.Itoa(status))
+		}
+		if p := route.EffectiveTrailingSlash(); p != TrailingSlashIgnore {
+			sb.WriteString(" slash=" + p.String())
+		}
+		sb.WriteString(" middleware=" + strconv.Itoa(middleware+len(route.Middleware)) + "\n")
+	}
+	return sb.String()
+}
+
+// Named gives the route a name for URL generation with URL and RedirectToRoute
+// Names must be unique per router; reusing one panics at registration
+func (route *Route) Named(name string) *Route {
+	for _, other := range route.rt.routes {
+		if other != route && other.Name == name {
+			panic(fmt.Sprintf("tobingo: route name %q is already used by %s %s", name, other.Method, other.Path))
+		}
+	}
+	route.Name = name
+	return route
+}
+
+// WithMeta attaches metadata to the route, e.g. a required permission for an authorization
+// middleware to read through MatchedRoute; metadata must be set before serving
+// Example: rt.GET("/admin", h).WithMeta("role", "admin")
+func (route *Route) WithMeta(key string, value any) *Route {
+	if route.Meta == nil {
+		route.Meta = make(map[string]any)
+	}
+	route.Meta[key] = value
+	return route
+}
+
+// RouteInfo describes the route that matched a request
+type RouteInfo struct {
+	Method  string         // Method the route was registered for
+	Pattern string         // Registered pattern such as "/users/:id"
+	Name    string         // Name given with Named, "" if none
+	Meta    map[string]any // Metadata attached with WithMeta, a copy
+	Timeout time.Duration  // Deadline of the handler, 0 for none
+
+	TrailingSlash TrailingSlashPolicy // Effective trailing slash policy of the route
+}
+
+// MatchedRoute returns the route that matched r, with ok false before matching and for
+// requests no route matched, such as inside the NotFound handler
+// Middleware sees the match once the next handler returns, or directly when it wraps a route
+func MatchedRoute(r *http.Request) (RouteInfo, bool) {
+	state := stateFrom(r)
+	if state == nil || state.route == nil {
+		return RouteInfo{}, false
+	}
+	route := state.route
+	return RouteInfo{Method: route.Method, Pattern: route.Path, Name: route.Name, Meta: maps.Clone(route.Meta), Timeout: route.EffectiveTimeout(), TrailingSlash: route.EffectiveTrailingSlash()}, true
+}
+
+// MatchedPattern returns the pattern of the route that matched r, "" when none did
+// Example: metrics.WithLabelValues(tobingo.MatchedPattern(r)).Inc()
+func MatchedPattern(r *http.Request) string {
+	if state := stateFrom(r); state != nil && state.route != nil {
+		return state.route.Path
+	}
+	return ""
+}
+
+// URL builds the path of the named route from alternating parameter names and values
+// Values are path-escaped, except that a catch-all keeps its slashes; trailing optional
+// parameters that are omitted or equal to their pattern default are left out
+// The path is the router's own; URLFor adds the external prefix of SetBasePath
+// Example: rt.URL("user_post", "id", "42", "post", "7") returns "/users/42/posts/7"
+func (rt *Rastauter) URL(name string, params ...string) (string, error) {
+	var route *Route
+	for _, r := range rt.routes {
+		if r.Name == name {
+			route = r
+			break
+		}
+	}
+	if route == nil {
+		return "", fmt.Errorf("%w: %q", ErrRouteNotFound, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("tobingo: route %q: parameters must be name/value pairs", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	// omittable counts the trailing parts that only repeat their default
+	var parts []string
+	omittable := 0
+	used := 0
+	for _, seg := range strings.Split(strings.Trim(route.Path, " "), "/")[1:] {
+		ps, isParam := parseSegment(seg)
+
+		// A catch-all keeps its slashes, so each of its segments is escaped separately
+		if !isParam && strings.HasPrefix(seg, "*") {
+			if value, ok := values[seg[1:]]; ok {
+				used++
+				for part := range strings.SplitSeq(value, "/") {
+					parts = append(parts, url.PathEscape(part))
+				}
+			} else {
+				parts = append(parts, "")
+			}
+			omittable = 0
+			continue
+		}
+
+		if !isParam {
+			parts = append(parts, seg)
+			omittable = 0
+			continue
+		}
+
+		value, ok := values[ps.name]
+		if ok {
+			used++
+		}
+		switch {
+		case !ok && ps.optional:
+			// Omitted optional parameters end the path, later ones can't be expressed
+			parts = parts[:len(parts)-omittable]
+			return buildURL(name, parts, used, len(values))
+		case !ok:
+			return "", fmt.Errorf("%w: %q: route %q", ErrParamMissing, ps.name, name)
+		}
+
+		parts = append(parts, ps.literal+url.PathEscape(value))
+		if ps.hasDef && value == ps.def {
+			omittable++
+		} else {
+			omittable = 0
+		}
+	}
+
+	parts = parts[:len(parts)-omittable]
+	return buildURL(name, parts, used, len(values))
+}
+
+// buildURL joins the generated parts after checking that every given parameter was used
+func buildURL(name string, parts []string, used, given int) (string, error) {
+	if used != given {
+		return "", fmt.Errorf("tobingo: route %q: given parameters that are unknown or follow an omitted optional one", name)
+	}
+	return "/" + strings.Join(parts, "/"), nil
+}
@@ -0,0 +1,374 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRoute answers with the given body
+func writeRoute(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+}
+
+func TestLiteralRouteAfterParamRoute(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id", writeRoute("show"))
+	rt.GET("/users/new", writeRoute("new"))
+	rt.GET("/users/:id/posts", writeRoute("posts"))
+	rt.GET("/users/new/posts", writeRoute("new posts"))
+	rt.GET("/files/*path", writeRoute("files"))
+	rt.GET("/files/readme", writeRoute("readme"))
+
+	for path, want := range map[string]string{
+		"/users/new":       "new",
+		"/users/42":        "show",
+		"/users/42/posts":  "posts",
+		"/users/new/posts": "new posts",
+		"/files/readme":    "readme",
+		"/files/a/b":       "files",
+	} {
+		if got := rt.Test("GET", path, nil).BodyString(); got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestConflictNamesBothPatterns(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id", writeRoute("a"))
+	err := rt.TryHandle("GET", "/users/:name", writeRoute("b"))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+	for _, want := range []string{`GET "/users/:name"`, `GET "/users/:id"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q does not name %s", err, want)
+		}
+	}
+
+	if err := rt.TryHandle("GET", "/tags/:tag?", writeRoute("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.TryHandle("GET", "/tags", writeRoute("d")); !errors.Is(err, ErrConflict) {
+		t.Errorf("optional parameter overlap: err = %v, want ErrConflict", err)
+	}
+	if err := rt.TryHandle("POST", "/users/:name", writeRoute("e")); err != nil {
+		t.Errorf("other method: %v", err)
+	}
+}
+
+func TestConflictRegistrationSites(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.TrackRegistrationSites(true)
+	rt.GET("/users/:id", writeRoute("a"))
+
+	defer func() {
+		msg, _ := recover().(string)
+		sites := regexp.MustCompile(`routes_test\.go:\d+`).FindAllString(msg, -1)
+		if len(sites) != 2 || sites[0] == sites[1] {
+			t.Errorf("panic %q, want two distinct registration sites", msg)
+		}
+	}()
+	rt.GET("/users/:name", writeRoute("b"))
+}
+
+func TestConflictDuplicatesAndCatchAlls(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/files/*path", writeRoute("a"))
+	rt.GET("/users", writeRoute("b"))
+
+	for pattern, other := range map[string]string{
+		"/users":       `GET "/users"`,
+		"/files/*rest": `GET "/files/*path"`,
+	} {
+		err := rt.TryHandle("GET", pattern, writeRoute("c"))
+		if !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), other) || !strings.Contains(err.Error(), `GET "`+pattern+`"`) {
+			t.Errorf("%s: err = %v, want a conflict naming %s", pattern, err, other)
+		}
+	}
+
+	// Without TrackRegistrationSites the message carries no file:line
+	msg := panicMessage(func() { rt.GET("/users", writeRoute("d")) })
+	if !strings.Contains(msg, `"/users"`) || regexp.MustCompile(`\.go:\d+`).MatchString(msg) {
+		t.Errorf("panic %q", msg)
+	}
+}
+
+func TestOptionalParameterDefaults(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/list/:page=1/:size=20", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetParam(r, "page") + "," + GetParam(r, "size")))
+	}).Named("list")
+
+	for path, want := range map[string]string{
+		"/list":       "1,20",
+		"/list/3":     "3,20",
+		"/list/3/50":  "3,50",
+		"/list/1/20/": "1,20",
+	} {
+		if got := rt.Test("GET", path, nil).BodyString(); got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+
+	routes := rt.Routes()
+	if len(routes) != 1 || routes[0].Defaults["page"] != "1" || routes[0].Defaults["size"] != "20" {
+		t.Errorf("Routes reports defaults %v", routes[0].Defaults)
+	}
+
+	for _, tc := range []struct {
+		params []string
+		want   string
+	}{
+		{nil, "/list"},
+		{[]string{"page", "1", "size", "20"}, "/list"},
+		{[]string{"page", "3", "size", "20"}, "/list/3"},
+		{[]string{"page", "1", "size", "50"}, "/list/1/50"},
+	} {
+		if got, err := rt.URL("list", tc.params...); got != tc.want || err != nil {
+			t.Errorf("URL(%v) = %q, %v, want %q", tc.params, got, err, tc.want)
+		}
+	}
+}
+
+func TestOptionalParameterPatternsRejected(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	for _, pattern := range []string{
+		"/a/:x=1/b",
+		"/a/:x?/:y",
+		"/a/v:x=1",
+		"/a/:=1",
+	} {
+		if err := rt.TryHandle("GET", pattern, writeRoute("")); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("%s: err = %v, want ErrInvalidPattern", pattern, err)
+		}
+	}
+}
+
+func TestMatchedRoute(t *testing.T) {
+	var seen []string
+	record := func(where string, r *http.Request) {
+		info, ok := MatchedRoute(r)
+		seen = append(seen, fmt.Sprintf("%s %v %s %s %s %v", where, ok, info.Method, info.Pattern, info.Name, info.Meta["scope"]))
+	}
+
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record("before", r)
+			next.ServeHTTP(w, r)
+			record("after", r)
+		})
+	})
+	rt.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		record("notfound", r)
+		if MatchedPattern(r) != "" {
+			t.Error("MatchedPattern set in the NotFound handler")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		record("handler", r)
+		if MatchedPattern(r) != "/users/:id" {
+			t.Errorf("MatchedPattern = %q", MatchedPattern(r))
+		}
+	}).Named("user").WithMeta("scope", "users:read").Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record("route middleware", r)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	rt.Test("GET", "/users/7", nil)
+	rt.Test("GET", "/nowhere", nil)
+	want := []string{
+		"before false    <nil>",
+		"route middleware true GET /users/:id user users:read",
+		"handler true GET /users/:id user users:read",
+		"after true GET /users/:id user users:read",
+		"before false    <nil>",
+		"notfound false    <nil>",
+		"after false    <nil>",
+	}
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("seen:\n%s\nwant:\n%s", strings.Join(seen, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMatchedRouteMetaIsACopy(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		info, _ := MatchedRoute(r)
+		info.Meta["scope"] = "admin"
+	}).WithMeta("scope", "public")
+
+	rt.Test("GET", "/", nil)
+	for _, route := range rt.Routes() {
+		if route.Path == "/" && route.Meta["scope"] != "public" {
+			t.Errorf("handler changed the route's metadata to %v", route.Meta["scope"])
+		}
+	}
+}
+
+// panicMessage runs fn and returns the string it panicked with, "" when it didn't panic
+func panicMessage(fn func()) (msg string) {
+	defer func() {
+		if v := recover(); v != nil {
+			msg = fmt.Sprint(v)
+		}
+	}()
+	fn()
+	return ""
+}
+
+func TestNilHandlersAndMiddleware(t *testing.T) {
+	identity := func(next http.Handler) http.Handler { return next }
+	returnsNil := func(http.Handler) http.Handler { return nil }
+
+	for name, tc := range map[string]struct {
+		register func(rt *Rastauter)
+		want     []string
+	}{
+		"nil handler":              {func(rt *Rastauter) { rt.GET("/x", nil) }, []string{"nil handler", `GET "/x"`}},
+		"nil handler other method": {func(rt *Rastauter) { rt.addRoute(http.MethodDelete, "/items/:id", nil) }, []string{"nil handler", `DELETE "/items/:id"`}},
+		"nil group handler":        {func(rt *Rastauter) { rt.Group("/api").GET("/users", nil) }, []string{"nil handler", `GET "/api/users"`}},
+		"nil Use":                  {func(rt *Rastauter) { rt.Use(identity, nil) }, []string{"Use: middleware 1 is nil"}},
+		"Use returning nil":        {func(rt *Rastauter) { rt.Use(returnsNil) }, []string{"Use: middleware 0 of the chain returned a nil handler"}},
+		"nil Group.Use":            {func(rt *Rastauter) { rt.Group("/api").Use(nil) }, []string{"Group.Use: middleware 0 is nil"}},
+		"nil Route.Use":            {func(rt *Rastauter) { rt.GET("/x", writeRoute("x")).Use(nil) }, []string{"Route.Use: middleware 0 is nil"}},
+		"Route.Use returning nil":  {func(rt *Rastauter) { rt.GET("/x", writeRoute("x")).Use(identity, returnsNil) }, []string{`route GET "/x": middleware 1 returned a nil handler`}},
+	} {
+		msg := panicMessage(func() { tc.register(NewRastaRouterInitializer()) })
+		for _, want := range tc.want {
+			if !strings.Contains(msg, want) {
+				t.Errorf("%s: panic %q lacks %q", name, msg, want)
+			}
+		}
+	}
+}
+
+func TestUseReturningNilKeepsTheChain(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", writeRoute("ok"))
+	panicMessage(func() { rt.Use(func(http.Handler) http.Handler { return nil }) })
+
+	if res := rt.Test("GET", "/", nil); res.BodyString() != "ok" {
+		t.Errorf("after a rejected Use: %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestTryHandleErrors(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id", writeRoute("user"))
+	before := rt.Routes()
+
+	for name, tc := range map[string]struct {
+		method, path string
+		handler      http.HandlerFunc
+		want         error
+	}{
+		"nil handler":       {"GET", "/a", nil, ErrNilHandler},
+		"no leading slash":  {"GET", "a", writeRoute("a"), ErrInvalidPattern},
+		"inner catch-all":   {"GET", "/a/*rest/b", writeRoute("a"), ErrInvalidPattern},
+		"unnamed parameter": {"GET", "/a/:", writeRoute("a"), ErrInvalidPattern},
+		"conflict":          {"GET", "/users/:name", writeRoute("a"), ErrConflict},
+	} {
+		err := rt.TryHandle(tc.method, tc.path, tc.handler)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+		for _, other := range []error{ErrNilHandler, ErrInvalidPattern, ErrConflict} {
+			if other != tc.want && errors.Is(err, other) {
+				t.Errorf("%s: err = %v also matches %v", name, err, other)
+			}
+		}
+	}
+
+	// Failed registrations leave the table and the routing as they were
+	if after := rt.Routes(); len(after) != len(before) || after[0].Path != "/users/:id" {
+		t.Errorf("routes after failures = %v", after)
+	}
+	if res := rt.Test("GET", "/users/7", nil); res.BodyString() != "user" {
+		t.Errorf("GET /users/7 = %q", res.BodyString())
+	}
+	if res := rt.Test("GET", "/a", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("GET /a = %d, want 404", res.StatusCode())
+	}
+
+	route, err := rt.TryGET("/a", writeRoute("a"))
+	if err != nil || route.Path != "/a" {
+		t.Fatalf("TryGET = %v, %v", route, err)
+	}
+	if res := rt.Test("GET", "/a", nil); res.BodyString() != "a" {
+		t.Errorf("GET /a after TryGET = %q", res.BodyString())
+	}
+	if _, err := rt.TryGET("/a", writeRoute("again")); !errors.Is(err, ErrConflict) {
+		t.Errorf("second TryGET: err = %v", err)
+	}
+}
+
+func TestParamsInRouterMiddleware(t *testing.T) {
+	var got string
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			got = GetParam(r, "org") + "/" + GetParam(r, "id") + fmt.Sprint(ParamCount(r))
+		})
+	})
+	rt.GET("/orgs/:org/users/:id", writeRoute("ok"))
+
+	rt.Test("GET", "/orgs/acme/users/7", nil)
+	if got != "acme/72" {
+		t.Errorf("middleware saw %q after next returned", got)
+	}
+	rt.Test("GET", "/nowhere", nil)
+	if got != "/0" {
+		t.Errorf("middleware saw %q for a miss", got)
+	}
+}
+
+func TestRouterString(t *testing.T) {
+	noop := func(next http.Handler) http.Handler { return next }
+	register := func(order []int, body string) *Rastauter {
+		rt := NewRastaRouterInitializer()
+		rt.Use(noop)
+		steps := []func(){
+			func() { rt.GET("/users/:id", writeRoute(body)).Named("user") },
+			func() { rt.GET("/users/new", writeRoute(body)).Use(noop, noop) },
+			func() { rt.addRoute("POST", "/users", writeRoute(body)).Timeout(2 * time.Second) },
+			func() { rt.GET("/users", writeRoute(body)) },
+			func() { rt.GET("/*rest", writeRoute(body)) },
+			func() { rt.GET("/assets/*file", writeRoute(body)) },
+			func() { rt.GET("/hooks/github", writeRoute(body)).TrailingSlash(TrailingSlashStrict) },
+			func() { rt.Redirect("GET", "/old", "/users", http.StatusMovedPermanently) },
+		}
+		for _, i := range order {
+			steps[i]()
+		}
+		return rt
+	}
+
+	want := "GET /hooks/github slash=strict middleware=1\n" +
+		"GET /old redirect=/users status=301 middleware=1\n" +
+		"GET /users middleware=1\n" +
+		"POST /users timeout=2s middleware=1\n" +
+		"GET /users/new middleware=3\n" +
+		"GET /users/:id name=user middleware=1\n" +
+		"GET /assets/*file middleware=1\n" +
+		"GET /*rest middleware=1\n"
+	if got := register([]int{0, 1, 2, 3, 4, 5, 6, 7}, "a").String(); got != want {
+		t.Errorf("String =\n%s\nwant\n%s", got, want)
+	}
+	if got := register([]int{4, 7, 0, 5, 3, 6, 2, 1}, "b").String(); got != want {
+		t.Errorf("String after registering in another order =\n%s\nwant\n%s", got, want)
+	}
+	if got := NewRastaRouterInitializer().String(); got != "" {
+		t.Errorf("empty router String = %q", got)
+	}
+}
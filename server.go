@@ -0,0 +1,381 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is the drain timeout used by StartServerWithGracefulShutdown
+// when no other value has been configured with SetShutdownTimeout
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ErrServerNotStarted is returned by Shutdown when no server has been started yet
+var ErrServerNotStarted = errors.New("tobingo: server not started")
+
+// newServer returns the router's *http.Server prepared to listen on the given address
+// The same server is returned by Server, so settings made there before starting are kept
+// A server that has been shut down cannot be started again
+func (rt *Rastauter) newServer(addr string) *http.Server {
+	srv := rt.primaryServer()
+	srv.Addr = addr
+	return srv
+}
+
+// primaryServer returns the router's *http.Server and records it as running
+func (rt *Rastauter) primaryServer() *http.Server {
+	srv := rt.Server()
+	rt.track(srv)
+	return srv
+}
+
+// track records srv as running so that Shutdown stops it together with the others
+func (rt *Rastauter) track(srv *http.Server) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if !slices.Contains(rt.running, srv) {
+		rt.running = append(rt.running, srv)
+	}
+}
+
+// StartServerTLS starts an HTTPS server on the specified port using this router
+// certFile and keyFile are paths to the PEM encoded certificate and private key
+// Returns an error if the server fails to start, or http.ErrServerClosed after Shutdown
+func (rt *Rastauter) StartServerTLS(port, certFile, keyFile string) error {
+	l, err := listenTCP(port)
+	if err != nil {
+		return err
+	}
+	rt.newServer(port)
+	return rt.ServeTLS(l, certFile, keyFile)
+}
+
+// StartServerAsync binds the address and serves this router in the background
+// It returns once the listener is accepting connections, reporting the bound address
+// (useful with ":0") and a stop function equivalent to Shutdown
+// Bind errors are returned immediately; later serve errors other than a clean shutdown are discarded
+func (rt *Rastauter) StartServerAsync(addr string) (boundAddr string, stop func(ctx context.Context) error, err error) {
+	l, err := listenTCP(addr)
+	if err != nil {
+		return "", nil, err
+	}
+	rt.newServer(addr)
+
+	go rt.Serve(l)
+
+	return l.Addr().String(), rt.Shutdown, nil
+}
+
+// OnListen registers a hook called with the bound address each time the router starts
+// accepting connections on a listener, including companion listeners
+// Hooks run synchronously before the serve loop starts, so they must not block
+func (rt *Rastauter) OnListen(fn func(addr net.Addr)) {
+	rt.mu.Lock()
+	rt.onListen = append(rt.onListen, fn)
+	rt.mu.Unlock()
+}
+
+// notifyListen calls every OnListen hook with the address of l
+func (rt *Rastauter) notifyListen(l net.Listener) {
+	rt.NotifyListen(l.Addr())
+}
+
+// NotifyListen calls every OnListen hook with addr, for servers run outside the router such
+// as an HTTP/3 server on a UDP socket; the router's own listeners are reported automatically
+func (rt *Rastauter) NotifyListen(addr net.Addr) {
+	rt.mu.Lock()
+	hooks := slices.Clone(rt.onListen)
+	rt.mu.Unlock()
+
+	rt.logger().Info("tobingo: listening", "addr", addr.String(), "network", addr.Network())
+	for _, fn := range hooks {
+		fn(addr)
+	}
+}
+
+// Companion is a server serving the router that the router doesn't construct itself, such
+// as the HTTP/3 server of the h3 module
+type Companion interface {
+	Shutdown(ctx context.Context) error // Stops accepting and drains in-flight requests until ctx expires
+}
+
+// TrackCompanion makes Shutdown drain c in parallel with the router's own servers, sharing
+// their deadline, so every listener stops together
+func (rt *Rastauter) TrackCompanion(c Companion) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if !slices.Contains(rt.companions, c) {
+		rt.companions = append(rt.companions, c)
+	}
+}
+
+// ServeCompanion serves h on l with a server built like the router's own, with the default
+// limits and every ConfigureServer option, for companion listeners such as an ACME challenge
+// server; l is reported to OnListen and Shutdown stops the server together with the others
+// Returns http.ErrServerClosed after Shutdown
+// Example: go rt.ServeCompanion(l, manager.HTTPHandler(nil))
+func (rt *Rastauter) ServeCompanion(l net.Listener, h http.Handler) error {
+	// Reuse the router's defaults and options so the companion gets the same protections
+	rt.mu.Lock()
+	srv := rt.buildServer()
+	rt.mu.Unlock()
+
+	srv.Addr = l.Addr().String()
+	srv.Handler = h
+	rt.track(srv)
+
+	rt.notifyListen(l)
+	return srv.Serve(l)
+}
+
+// listenTCP binds a TCP listener on addr, defaulting to ":http" like http.ListenAndServe
+func listenTCP(addr string) (net.Listener, error) {
+	if addr == "" {
+		addr = ":http"
+	}
+	return net.Listen("tcp", addr)
+}
+
+// StartServerH2C starts the server on the given address serving both HTTP/1.1
+// and HTTP/2 cleartext requests, equivalent to StartServerWithOptions(addr, WithH2C())
+func (rt *Rastauter) StartServerH2C(addr string) error {
+	return rt.StartServerWithOptions(addr, WithH2C())
+}
+
+// Serve accepts incoming connections on the listener l and serves this router on them
+// The caller controls listener creation, which allows unix sockets or ":0" addresses
+// Returns http.ErrServerClosed after Shutdown
+func (rt *Rastauter) Serve(l net.Listener) error {
+	srv := rt.primaryServer()
+	rt.notifyListen(l)
+	return srv.Serve(l)
+}
+
+// ServeTLS accepts incoming connections on the listener l and serves HTTPS on them
+// certFile and keyFile may be empty if the server's TLSConfig already provides certificates
+// Returns http.ErrServerClosed after Shutdown
+func (rt *Rastauter) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	srv := rt.primaryServer()
+	rt.notifyListen(l)
+	return srv.ServeTLS(l, certFile, keyFile)
+}
+
+// StartUnixServer serves this router on a unix domain socket at socketPath
+// A stale socket file left behind by a previous process is removed before listening,
+// the socket's permissions are set to perm, and the file is removed again on shutdown
+// Returns an error if another process is still accepting connections on the socket
+func (rt *Rastauter) StartUnixServer(socketPath string, perm os.FileMode) error {
+	if err := removeStaleSocket(socketPath); err != nil {
+		return err
+	}
+
+	// Closing a listener created by net.Listen unlinks the socket file, so Shutdown cleans up after us
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(socketPath, perm); err != nil {
+		l.Close()
+		return err
+	}
+
+	return rt.Serve(l)
+}
+
+// removeStaleSocket deletes a leftover socket file at path if nothing is listening on it
+// Regular files are never removed so a mistyped path can't destroy data
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("tobingo: %s exists and is not a socket", path)
+	}
+
+	// A successful dial means a live server still owns the socket
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("tobingo: socket %s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// Shutdown gracefully stops every server started by this router, including companion
+// servers such as the one started by StartRedirectServer and those added with TrackCompanion
+// Listeners are closed immediately and in-flight requests are allowed to finish
+// until ctx expires, after which the context error is returned
+// Returns ErrServerNotStarted if no server has been started yet
+func (rt *Rastauter) Shutdown(ctx context.Context) error {
+	rt.mu.Lock()
+	servers := make([]Companion, 0, len(rt.running)+len(rt.companions))
+	for _, srv := range rt.running {
+		servers = append(servers, srv)
+	}
+	servers = append(servers, rt.companions...)
+	rt.mu.Unlock()
+
+	if len(servers) == 0 {
+		return ErrServerNotStarted
+	}
+
+	// Flip readiness first so load balancers stop routing here while we drain
+	rt.shuttingDown.Store(true)
+	rt.logger().Info("tobingo: shutting down", "servers", len(servers))
+
+	// Streams never finish on their own, so tell them to end before draining
+	rt.closeLongLived(ctx)
+
+	// Drain all servers in parallel so they share the same deadline
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}()
+	}
+
+	// Report what the drain is waiting for until it completes
+	drained := make(chan struct{})
+	go rt.logDrain(drained)
+	wg.Wait()
+	close(drained)
+
+	// Connections have drained (or ctx expired), so it is now safe to release shared resources
+	rt.runShutdownHooks(ctx)
+
+	err := errors.Join(errs...)
+	if err != nil {
+		rt.logger().Warn("tobingo: shutdown incomplete", "error", err)
+	} else {
+		rt.logger().Info("tobingo: shutdown complete")
+	}
+	return err
+}
+
+// OnShutdown registers a cleanup hook run by Shutdown, for example to close database pools,
+// flush metrics, or deregister from service discovery
+// Hooks run once, after every listener has stopped accepting and in-flight requests have
+// drained or ctx has expired; they receive the shutdown context so they share its deadline
+// Hooks run in reverse registration order, like deferred calls, so resources set up later
+// are released first; a panicking hook is recovered and logged and the remaining hooks still run
+func (rt *Rastauter) OnShutdown(fn func(ctx context.Context)) {
+	rt.mu.Lock()
+	rt.onShutdown = append(rt.onShutdown, fn)
+	rt.mu.Unlock()
+}
+
+// runShutdownHooks runs and clears the registered OnShutdown hooks
+func (rt *Rastauter) runShutdownHooks(ctx context.Context) {
+	rt.mu.Lock()
+	hooks := rt.onShutdown
+	rt.onShutdown = nil
+	rt.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		rt.runShutdownHook(ctx, hooks[i])
+	}
+}
+
+// runShutdownHook calls fn, recovering and logging a panic so other hooks are unaffected
+func (rt *Rastauter) runShutdownHook(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			rt.logger().Error("tobingo: shutdown hook panicked", "panic", rec)
+		}
+	}()
+	fn(ctx)
+}
+
+// shutdownWithTimeout calls Shutdown bounded by the configured shutdown timeout
+func (rt *Rastauter) shutdownWithTimeout() error {
+	rt.mu.Lock()
+	timeout := rt.shutdownTimeout
+	rt.mu.Unlock()
+
+	// A non-positive timeout waits for in-flight requests indefinitely
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	return rt.Shutdown(ctx)
+}
+
+// SetShutdownTimeout sets how long StartServerWithGracefulShutdown waits for
+// in-flight requests to complete once a shutdown signal has been received
+// A zero or negative duration waits without a deadline
+func (rt *Rastauter) SetShutdownTimeout(d time.Duration) {
+	rt.mu.Lock()
+	rt.shutdownTimeout = d
+	rt.mu.Unlock()
+}
+
+// StartServerWithGracefulShutdown starts the server like StartServer and blocks until
+// one of the given signals is received, then drains in-flight requests before returning
+// When no signals are given it listens for os.Interrupt and SIGTERM
+// Returns nil when the server shut down cleanly, or the error that stopped it otherwise
+func (rt *Rastauter) StartServerWithGracefulShutdown(addr string, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	// Start listening for signals before the server so none are missed
+	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+
+	// Bind and track the server before waiting, so a signal arriving right away finds it to
+	// shut down, then run it in the background so we can wait on both it and the signal
+	l, err := listenTCP(addr)
+	if err != nil {
+		return err
+	}
+	rt.newServer(addr)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rt.Serve(l)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server stopped on its own, either failing to start or via a direct Shutdown call
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	// Restore default signal behaviour so a second signal terminates immediately
+	stop()
+
+	if err := rt.shutdownWithTimeout(); err != nil {
+		return err
+	}
+
+	// Wait for the serve loop to exit before reporting a clean shutdown
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownBeforeStart(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	if err := rt.Shutdown(context.Background()); !errors.Is(err, ErrServerNotStarted) {
+		t.Errorf("Shutdown = %v, want ErrServerNotStarted", err)
+	}
+}
+
+func TestServerDefaults(t *testing.T) {
+	srv := NewRastaRouterInitializer().Server()
+	if srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || srv.ReadTimeout != DefaultReadTimeout ||
+		srv.WriteTimeout != DefaultWriteTimeout || srv.IdleTimeout != DefaultIdleTimeout ||
+		srv.MaxHeaderBytes != DefaultMaxHeaderBytes {
+		t.Errorf("server limits %v %v %v %v %d, want the defaults", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout, srv.MaxHeaderBytes)
+	}
+}
+
+func TestConfigureServer(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.ConfigureServer(WithReadTimeout(time.Second), WithReadHeaderTimeout(2*time.Second), WithMaxHeaderBytes(4096))
+	srv := rt.Server()
+	rt.ConfigureServer(WithWriteTimeout(3*time.Second), WithIdleTimeout(4*time.Second))
+
+	if srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second || srv.MaxHeaderBytes != 4096 {
+		t.Errorf("options before Server not applied: %v %v %d", srv.ReadTimeout, srv.ReadHeaderTimeout, srv.MaxHeaderBytes)
+	}
+	if srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second {
+		t.Errorf("options after Server not applied: %v %v", srv.WriteTimeout, srv.IdleTimeout)
+	}
+	if srv.Handler != rt {
+		t.Error("server does not serve the router")
+	}
+	if rt.Server() != srv {
+		t.Error("Server returned a different server")
+	}
+}
+
+func TestH2C(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "ran")
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto + " " + GetParam(r, "id")))
+		if f, ok := w.(http.Flusher); !ok {
+			t.Error("writer lost http.Flusher over HTTP/2")
+		} else {
+			f.Flush()
+		}
+	})
+	rt.ConfigureServer(WithH2C())
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(context.Background())
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	res, err := client.Get("http://" + addr + "/users/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "HTTP/2.0 42" || res.Header.Get("X-Middleware") != "ran" {
+		t.Errorf("body = %q, want an HTTP/2 request for user 42 through the middleware", body)
+	}
+
+	// HTTP/1.1 keeps working on the same listener
+	res, err = http.Get("http://" + addr + "/users/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if body, _ := io.ReadAll(res.Body); string(body) != "HTTP/1.1 7" {
+		t.Errorf("body = %q, want an HTTP/1.1 request for user 7", body)
+	}
+}
+
+func TestStartServerAsyncBindsPortZero(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/ping", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("pong")) })
+	heard := make(chan net.Addr, 1)
+	rt.OnListen(func(addr net.Addr) { heard <- addr })
+
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	if host != "127.0.0.1" || port == "0" {
+		t.Fatalf("bound address %q, want a real port", addr)
+	}
+
+	// The listener accepts as soon as StartServerAsync returns, no sleep needed
+	res, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("body = %q", body)
+	}
+	if err := stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-heard; got.String() != addr {
+		t.Errorf("OnListen heard %v, want %s", got, addr)
+	}
+}
+
+func TestStartServerReturnsBindErrors(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	rt := NewRastaRouterInitializer()
+	if _, _, err := rt.StartServerAsync(l.Addr().String()); err == nil {
+		t.Error("StartServerAsync bound a used address")
+	}
+	if err := rt.StartServer(l.Addr().String()); err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("StartServer = %v, want the bind error", err)
+	}
+}
+
+func TestShutdownHooks(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	_, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	var deadline time.Time
+	rt.OnShutdown(func(ctx context.Context) {
+		deadline, _ = ctx.Deadline()
+		order = append(order, "db")
+	})
+	rt.OnShutdown(func(ctx context.Context) { panic("flush failed") })
+	rt.OnShutdown(func(ctx context.Context) { order = append(order, "discovery") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reverse registration order, and the panicking hook doesn't stop the first one
+	if !slices.Equal(order, []string{"discovery", "db"}) {
+		t.Errorf("hooks ran as %v, want [discovery db]", order)
+	}
+	if want, _ := ctx.Deadline(); !deadline.Equal(want) {
+		t.Errorf("hook deadline %v, want the shutdown deadline %v", deadline, want)
+	}
+
+	// Hooks run once
+	order = nil
+	rt.Shutdown(context.Background())
+	if len(order) != 0 {
+		t.Errorf("hooks ran again: %v", order)
+	}
+}
+
+// fakeCompanion records the Shutdown calls of a server run outside the router
+type fakeCompanion struct {
+	calls chan context.Context
+	err   error
+}
+
+func (c *fakeCompanion) Shutdown(ctx context.Context) error {
+	c.calls <- ctx
+	return c.err
+}
+
+func TestTrackCompanion(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	heard := make(chan net.Addr, 1)
+	rt.OnListen(func(addr net.Addr) { heard <- addr })
+
+	// Reported like the router's own listeners
+	udp := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443}
+	rt.NotifyListen(udp)
+	if got := <-heard; got != net.Addr(udp) {
+		t.Errorf("OnListen heard %v, want %v", got, udp)
+	}
+
+	quic := &fakeCompanion{calls: make(chan context.Context, 2)}
+	rt.TrackCompanion(quic)
+	rt.TrackCompanion(quic) // Tracked once
+	addr, _, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-heard
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := rt.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-quic.calls; got != ctx {
+		t.Error("companion drained with another context")
+	}
+	if len(quic.calls) != 0 {
+		t.Error("companion shut down twice")
+	}
+	if _, err := http.Get("http://" + addr); err == nil {
+		t.Error("router's own server still serves")
+	}
+
+	// A companion alone is enough to shut down, and its error is reported
+	rt = NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	failing := &fakeCompanion{calls: make(chan context.Context, 1), err: errors.New("quic: drain failed")}
+	rt.TrackCompanion(failing)
+	if err := rt.Shutdown(context.Background()); err == nil || err.Error() != "quic: drain failed" {
+		t.Errorf("Shutdown = %v, want the companion's error", err)
+	}
+}
+
+func TestServeCompanion(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.ConfigureServer(WithMaxHeaderBytes(2048))
+	heard := make(chan net.Addr, 1)
+	rt.OnListen(func(addr net.Addr) { heard <- addr })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- rt.ServeCompanion(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("challenge"))
+		}))
+	}()
+	if got := <-heard; got.String() != l.Addr().String() {
+		t.Errorf("OnListen heard %v, want %v", got, l.Addr())
+	}
+
+	res, err := http.Get("http://" + l.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "challenge" {
+		t.Errorf("body %q, want the companion's handler", body)
+	}
+
+	// Built with the router's options, the 2048 byte header limit rejects a big header
+	req, _ := http.NewRequest("GET", "http://"+l.Addr().String()+"/", nil)
+	req.Header.Set("X-Big", strings.Repeat("a", 32<<10))
+	if res, err := http.DefaultClient.Do(req); err == nil {
+		res.Body.Close()
+		if res.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+			t.Errorf("status %d with a 32 KiB header, want 431", res.StatusCode)
+		}
+	}
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("ServeCompanion = %v, want http.ErrServerClosed", err)
+	}
+}
@@ -0,0 +1,145 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tobingo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdownDrainsInFlightRequests(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	started := make(chan struct{})
+	rt.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	addrs := make(chan net.Addr, 1)
+	rt.OnListen(func(addr net.Addr) { addrs <- addr })
+	var hookRan bool
+	rt.OnShutdown(func(ctx context.Context) { hookRan = true })
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- rt.StartServerWithGracefulShutdown("127.0.0.1:0", syscall.SIGUSR1) }()
+	addr := <-addrs
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + addr.String() + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		resCh <- result{string(body), err}
+	}()
+	<-started
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("StartServerWithGracefulShutdown = %v, want nil", err)
+	}
+	select {
+	case res := <-resCh:
+		if res.err != nil || res.body != "done" {
+			t.Errorf("in-flight request got %q, %v", res.body, res.err)
+		}
+	case <-time.After(time.Second):
+		t.Error("in-flight request did not finish")
+	}
+	if !hookRan {
+		t.Error("OnShutdown hook did not run")
+	}
+	if _, err := net.Dial("tcp", addr.String()); err == nil {
+		t.Error("listener still accepting after shutdown")
+	}
+}
+
+func TestGracefulShutdownBindError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	rt := NewRastaRouterInitializer()
+	if err := rt.StartServerWithGracefulShutdown(l.Addr().String(), syscall.SIGUSR1); err == nil {
+		t.Error("binding a used address succeeded")
+	}
+}
+
+func TestUnixServerRoundTrip(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+
+	// A stale socket left behind by a previous process is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	rt := NewRastaRouterInitializer()
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("user " + GetParam(r, "id"))) })
+	listening := make(chan struct{})
+	rt.OnListen(func(net.Addr) { close(listening) })
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- rt.StartUnixServer(socket, 0o600) }()
+	<-listening
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	res, err := client.Get("http://unix/users/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "user 42" {
+		t.Errorf("body = %q", body)
+	}
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("StartUnixServer = %v, want ErrServerClosed", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket file left after shutdown: %v", err)
+	}
+}
+
+func TestUnixServerRefusesRegularFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRastaRouterInitializer().StartUnixServer(path, 0o600); err == nil {
+		t.Error("served on a regular file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep" {
+		t.Error("regular file was removed")
+	}
+}
@@ -0,0 +1,109 @@
+package tobingo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TrailingSlashPolicy decides how requests whose trailing slash differs from the route's
+// pattern are handled, e.g. "/about/" for a route registered as "/about"
+type TrailingSlashPolicy int
+
+// Trailing slash policies, set for the router with TrailingSlash or per route
+const (
+	TrailingSlashIgnore   TrailingSlashPolicy = iota + 1 // Serve either form, the default
+	TrailingSlashStrict                                  // Answer 404 unless the form matches the pattern
+	TrailingSlashRedirect                                // Redirect to the form of the pattern, 301 for GET and HEAD and 308 otherwise
+)
+
+// String returns "ignore", "strict", or "redirect"
+func (p TrailingSlashPolicy) String() string {
+	switch p {
+	case TrailingSlashStrict:
+		return "strict"
+	case TrailingSlashRedirect:
+		return "redirect"
+	}
+	return "ignore"
+}
+
+// TrailingSlash sets the policy of every route that doesn't set its own, TrailingSlashIgnore
+// by default; catch-all routes serve either form regardless
+// It may be changed while serving
+func (rt *Rastauter) TrailingSlash(p TrailingSlashPolicy) {
+	rt.trailingSlash.Store(int32(p))
+}
+
+// TrailingSlash overrides the router's policy for this route
+// Example: rt.GET("/hooks/github", receive).TrailingSlash(tobingo.TrailingSlashIgnore)
+func (route *Route) TrailingSlash(p TrailingSlashPolicy) *Route {
+	route.slash = p
+	return route
+}
+
+// EffectiveTrailingSlash returns the policy applied to the route, its own or the router's,
+// and TrailingSlashIgnore for catch-all routes
+func (route *Route) EffectiveTrailingSlash() TrailingSlashPolicy {
+	if _, catchAll := catchAllPrefix(route.Path); catchAll {
+		return TrailingSlashIgnore
+	}
+	if route.slash != 0 {
+		return route.slash
+	}
+	if route.rt != nil {
+		if p := TrailingSlashPolicy(route.rt.trailingSlash.Load()); p != 0 {
+			return p
+		}
+	}
+	return TrailingSlashIgnore
+}
+
+// hasTrailingSlash reports whether path ends in a slash other than the root's
+func hasTrailingSlash(path string) bool {
+	return len(path) > 1 && strings.HasSuffix(path, "/")
+}
+
+// trimTrailingSlash removes a trailing slash from a pattern, so both forms share one shape
+func trimTrailingSlash(pattern string) string {
+	if hasTrailingSlash(pattern) {
+		return strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// checkTrailingSlash applies the policy of route, which matched r ignoring the trailing
+// slash, and reports whether r should be served; otherwise the response was written
+func (rt *Rastauter) checkTrailingSlash(w http.ResponseWriter, r *http.Request, route *Route) bool {
+	want := hasTrailingSlash(strings.Trim(route.Path, " "))
+	if hasTrailingSlash(r.URL.Path) == want {
+		return true
+	}
+
+	switch route.EffectiveTrailingSlash() {
+	case TrailingSlashStrict:
+		rt.fireNotFound(r)
+		rt.handleNotFound(w, r)
+		return false
+	case TrailingSlashRedirect:
+		// Matching ignores repeated leading slashes, but "//host" in Location is another site
+		target := "/" + strings.Trim(r.URL.EscapedPath(), "/")
+		if want && target != "/" {
+			target += "/"
+		}
+		// Put back a locale prefix stripped before matching
+		if state := stateFrom(r); state != nil && state.locale != "" {
+			target = "/" + state.locale + target
+		}
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, externalLocation(r, target), status)
+		return false
+	}
+	return true
+}
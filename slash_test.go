@@ -0,0 +1,169 @@
+package tobingo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func TestTrailingSlashPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   TrailingSlashPolicy
+		method   string
+		pattern  string
+		target   string
+		status   int
+		location string
+	}{
+		{"ignore serves either form", TrailingSlashIgnore, "GET", "/about", "/about/", http.StatusOK, ""},
+		{"strict rejects the other form", TrailingSlashStrict, "GET", "/about", "/about/", http.StatusNotFound, ""},
+		{"strict serves the pattern form", TrailingSlashStrict, "GET", "/about/", "/about/", http.StatusOK, ""},
+		{"redirect removes the slash", TrailingSlashRedirect, "GET", "/about", "/about/?x=1", http.StatusMovedPermanently, "/about?x=1"},
+		{"redirect adds the slash", TrailingSlashRedirect, "GET", "/docs/", "/docs", http.StatusMovedPermanently, "/docs/"},
+		{"redirect keeps the method", TrailingSlashRedirect, "POST", "/about", "/about/", http.StatusPermanentRedirect, "/about"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewRastaRouterInitializer()
+			rt.TrailingSlash(tt.policy)
+			rt.TryHandle(tt.method, tt.pattern, okHandler)
+
+			res := rt.Test(tt.method, tt.target, nil)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode(), tt.status)
+			}
+			if got := res.Header("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestTrailingSlashRouteOverride(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.TrailingSlash(TrailingSlashStrict)
+	rt.GET("/hooks", okHandler).TrailingSlash(TrailingSlashIgnore)
+
+	if res := rt.Test("GET", "/hooks/", nil); res.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d, want 200 from the route's own policy", res.StatusCode())
+	}
+}
+
+func TestTrailingSlashOverrideMatrix(t *testing.T) {
+	policies := []TrailingSlashPolicy{TrailingSlashIgnore, TrailingSlashStrict, TrailingSlashRedirect}
+	// What a GET of "/page/" gets from a route registered as "/page" under each policy
+	want := map[TrailingSlashPolicy]struct {
+		status   int
+		location string
+	}{
+		TrailingSlashIgnore:   {http.StatusOK, ""},
+		TrailingSlashStrict:   {http.StatusNotFound, ""},
+		TrailingSlashRedirect: {http.StatusMovedPermanently, "/page"},
+	}
+	for _, global := range policies {
+		for _, override := range policies {
+			if override == global {
+				continue
+			}
+			rt := NewRastaRouterInitializer()
+			rt.TrailingSlash(global)
+			route := rt.GET("/page", okHandler).TrailingSlash(override)
+			rt.GET("/other", okHandler)
+
+			res := rt.Test("GET", "/page/", nil)
+			if w := want[override]; res.StatusCode() != w.status || res.Header("Location") != w.location {
+				t.Errorf("global %s, route %s: %d to %q, want %d to %q", global, override, res.StatusCode(), res.Header("Location"), w.status, w.location)
+			}
+			// The route without an override follows the router
+			res = rt.Test("GET", "/other/", nil)
+			if w := want[global]; res.StatusCode() != w.status {
+				t.Errorf("global %s: unoverridden route %d, want %d", global, res.StatusCode(), w.status)
+			}
+			// The exact form is always served
+			if res := rt.Test("GET", "/page", nil); res.StatusCode() != http.StatusOK {
+				t.Errorf("global %s, route %s: exact form %d", global, override, res.StatusCode())
+			}
+
+			if p := route.EffectiveTrailingSlash(); p != override {
+				t.Errorf("EffectiveTrailingSlash = %s, want %s", p, override)
+			}
+			if p := rt.routes[1].EffectiveTrailingSlash(); p != global {
+				t.Errorf("unoverridden EffectiveTrailingSlash = %s, want %s", p, global)
+			}
+		}
+	}
+}
+
+func TestTrailingSlashPolicyOfTheMatchedRoute(t *testing.T) {
+	// The policy is that of the route the other form matches, not of another candidate
+	rt := NewRastaRouterInitializer()
+	rt.TrailingSlash(TrailingSlashRedirect)
+	rt.GET("/users/:id", writeRoute("show")).TrailingSlash(TrailingSlashStrict)
+	rt.GET("/users/new", writeRoute("new")).TrailingSlash(TrailingSlashIgnore)
+	rt.GET("/users/:id/edit/", writeRoute("edit"))
+
+	for target, tc := range map[string]struct {
+		status         int
+		body, location string
+	}{
+		"/users/new/":   {http.StatusOK, "new", ""},
+		"/users/7/":     {http.StatusNotFound, "", ""},
+		"/users/7":      {http.StatusOK, "show", ""},
+		"/users/7/edit": {http.StatusMovedPermanently, "", "/users/7/edit/"},
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != tc.status || tc.body != "" && res.BodyString() != tc.body || res.Header("Location") != tc.location {
+			t.Errorf("%s = %d %q to %q", target, res.StatusCode(), res.BodyString(), res.Header("Location"))
+		}
+	}
+}
+
+func TestTrailingSlashIntrospection(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	hooks := rt.GET("/hooks", okHandler).TrailingSlash(TrailingSlashIgnore)
+	page := rt.GET("/page", okHandler)
+	if page.EffectiveTrailingSlash() != TrailingSlashIgnore {
+		t.Errorf("default policy = %s, want ignore", page.EffectiveTrailingSlash())
+	}
+
+	// A later change of the router's policy shows on routes without an override
+	rt.TrailingSlash(TrailingSlashRedirect)
+	if page.EffectiveTrailingSlash() != TrailingSlashRedirect || hooks.EffectiveTrailingSlash() != TrailingSlashIgnore {
+		t.Errorf("after the change: page %s, hooks %s", page.EffectiveTrailingSlash(), hooks.EffectiveTrailingSlash())
+	}
+	if got, want := rt.String(), "GET /hooks middleware=0\nGET /page slash=redirect middleware=0\n"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
+
+func TestTrailingSlashRedirectStaysOnSite(t *testing.T) {
+	for _, target := range []string{"//evil.com/", "///evil.com/", "//evil.com//"} {
+		rt := NewRastaRouterInitializer()
+		rt.TrailingSlash(TrailingSlashRedirect)
+		rt.GET("/:page", okHandler)
+
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusMovedPermanently {
+			t.Fatalf("%s: status = %d, want 301", target, res.StatusCode())
+		}
+		if got := res.Header("Location"); got != "/evil.com" {
+			t.Errorf("%s: Location = %q, want /evil.com", target, got)
+		}
+	}
+}
+
+func TestTrailingSlashRedirectUnderBasePath(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetBasePath("/myapp")
+	rt.TrailingSlash(TrailingSlashRedirect)
+	rt.GET("/:page", okHandler)
+
+	res := rt.Test("GET", "/myapp//evil.com/", nil)
+	if got := res.Header("Location"); got != "/myapp/evil.com" {
+		t.Errorf("Location = %q, want /myapp/evil.com", got)
+	}
+}
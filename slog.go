@@ -0,0 +1,94 @@
+package tobingo
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// SetLogger sets the logger the router reports its own diagnostics to, such as listeners
+// starting, shutdown, recovered panics, and misused response writers; slog.Default() when
+// nil or never set
+func (rt *Rastauter) SetLogger(l *slog.Logger) {
+	rt.slogger.Store(l)
+}
+
+// logger returns the logger set with SetLogger, or slog.Default()
+func (rt *Rastauter) logger() *slog.Logger {
+	if l := rt.slogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// loggerFor returns the logger of the router serving r, or slog.Default()
+func loggerFor(r *http.Request) *slog.Logger {
+	if r != nil {
+		if rt := routerFrom(r); rt != nil {
+			return rt.logger()
+		}
+	}
+	return slog.Default()
+}
+
+// LogWith returns logger annotated with the request: method, path, the matched route
+// pattern, its path parameters as a "params" group, the request ID when the request
+// carries an X-Request-ID header, and the trace and span IDs set by TraceContext; a nil
+// logger means the router's logger
+// Example: tobingo.LogWith(r, nil).Info("user updated", "user", u.ID)
+func LogWith(r *http.Request, logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		logger = loggerFor(r)
+	}
+
+	attrs := []any{slog.String("method", r.Method), slog.String("path", r.URL.Path)}
+	if pattern := MatchedPattern(r); pattern != "" {
+		attrs = append(attrs, slog.String("route", pattern))
+	}
+	if params := routeParams(r); len(params) > 0 {
+		names := slices.Sorted(maps.Keys(params))
+		group := make([]any, 0, len(names))
+		for _, name := range names {
+			group = append(group, slog.String(name, params[name]))
+		}
+		attrs = append(attrs, slog.Group("params", group...))
+	}
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if tc := traceFrom(r); tc != nil {
+		attrs = append(attrs, slog.String("trace_id", tc.traceID), slog.String("span_id", tc.spanID))
+	}
+	return logger.With(attrs...)
+}
+
+// logAccess emits an access log record through the router's logger for Logger with Slog set
+func logAccess(r *http.Request, e LogEntry) {
+	level := slog.LevelInfo
+	if e.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", e.Method),
+		slog.String("path", e.Path),
+		slog.Int("status", e.Status),
+		slog.Int64("bytes", e.Bytes),
+		slog.Duration("duration", e.Duration),
+	}
+	for _, attr := range []struct{ key, value string }{
+		{"route", e.Route},
+		{"query", e.Query},
+		{"client_ip", e.ClientIP},
+		{"request_id", e.RequestID},
+		{"trace_id", e.TraceID},
+		{"span_id", e.SpanID},
+		{"user_agent", e.UserAgent},
+	} {
+		if attr.value != "" {
+			attrs = append(attrs, slog.String(attr.key, attr.value))
+		}
+	}
+	loggerFor(r).LogAttrs(r.Context(), level, "request", attrs...)
+}
@@ -0,0 +1,153 @@
+package tobingo
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// logRecord is a record kept by recordHandler, its attributes flattened to dotted keys
+type logRecord struct {
+	level   slog.Level
+	message string
+	attrs   map[string]string
+}
+
+// recordHandler keeps every record it handles, with the attributes added by With
+type recordHandler struct {
+	mu      *sync.Mutex
+	records *[]logRecord
+	attrs   []slog.Attr
+}
+
+func newRecordHandler() *recordHandler {
+	return &recordHandler{mu: new(sync.Mutex), records: new([]logRecord)}
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := logRecord{level: r.Level, message: r.Message, attrs: map[string]string{}}
+	for _, a := range h.attrs {
+		flattenAttr(rec.attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(rec.attrs, "", a)
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, rec)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &c
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h } // Unused by the router
+
+// all returns the records handled so far
+func (h *recordHandler) all() []logRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]logRecord{}, *h.records...)
+}
+
+// flattenAttr stores a under its key, groups as dotted keys
+func flattenAttr(into map[string]string, prefix string, a slog.Attr) {
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			flattenAttr(into, prefix+a.Key+".", g)
+		}
+		return
+	}
+	into[prefix+a.Key] = a.Value.String()
+}
+
+func TestLogWith(t *testing.T) {
+	h := newRecordHandler()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(h))
+	rt.GET("/orgs/:org/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		LogWith(r, nil).Info("user updated", "user", "ann")
+	})
+
+	rt.Test("GET", "/orgs/acme/users/42", nil, WithTestHeader("X-Request-ID", "req-9"))
+
+	records := h.all()
+	if len(records) != 1 || records[0].message != "user updated" {
+		t.Fatalf("records = %+v", records)
+	}
+	for key, want := range map[string]string{
+		"method": "GET", "path": "/orgs/acme/users/42", "route": "/orgs/:org/users/:id",
+		"params.org": "acme", "params.id": "42", "request_id": "req-9", "user": "ann",
+	} {
+		if got := records[0].attrs[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLogWithExplicitLogger(t *testing.T) {
+	routerLog, own := newRecordHandler(), newRecordHandler()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(routerLog))
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		LogWith(r, slog.New(own)).Warn("hi")
+	})
+	rt.Test("GET", "/", nil)
+
+	if len(routerLog.all()) != 0 || len(own.all()) != 1 {
+		t.Fatalf("router logger got %d records, own logger %d", len(routerLog.all()), len(own.all()))
+	}
+	if attrs := own.all()[0].attrs; attrs["route"] != "/" || attrs["request_id"] != "" {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
+
+func TestRouterLogsPanics(t *testing.T) {
+	h := newRecordHandler()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(h))
+	rt.GET("/boom", func(w http.ResponseWriter, r *http.Request) { panic("kaboom") })
+	rt.Test("GET", "/boom", nil)
+
+	records := h.all()
+	if len(records) != 1 || records[0].level != slog.LevelError || records[0].attrs["panic"] != "kaboom" || records[0].attrs["path"] != "/boom" {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestLoggerSlog(t *testing.T) {
+	h := newRecordHandler()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(h))
+	rt.Use(Logger(LoggerOptions{Slog: true}))
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+	rt.GET("/fail", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) })
+
+	rt.Test("GET", "/users/5?x=1", nil, WithTestHeader("X-Request-ID", "req-1"))
+	rt.Test("GET", "/fail", nil)
+
+	records := h.all()
+	if len(records) != 2 {
+		t.Fatalf("records = %+v", records)
+	}
+	ok, fail := records[0], records[1]
+	if ok.level != slog.LevelInfo || ok.message != "request" {
+		t.Errorf("record = %+v", ok)
+	}
+	for key, want := range map[string]string{"route": "/users/:id", "path": "/users/5", "query": "x=1", "status": "200", "bytes": "5", "request_id": "req-1"} {
+		if got := ok.attrs[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if fail.level != slog.LevelError || fail.attrs["status"] != "502" {
+		t.Errorf("5xx record = %+v", fail)
+	}
+}
@@ -0,0 +1,127 @@
+package tobingo
+
+import (
+	"bytes"
+	"maps"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// SlowRequestInfo describes a request that took longer than the SlowRequestThreshold
+type SlowRequestInfo struct {
+	Method   string            // Request method
+	Route    string            // Matched route pattern, "" when no route matched
+	Path     string            // Concrete request path
+	Params   map[string]string // Path parameters of the matched route, a copy the callback may keep
+	Duration time.Duration     // Time from the router receiving the request to the handler returning
+	Status   int               // Status sent, 200 when the handler wrote nothing
+	Stack    []byte            // Stack of the request goroutine when the threshold passed, nil if unavailable
+}
+
+// slowRequestConfig is the setting published by SlowRequestThreshold
+type slowRequestConfig struct {
+	threshold time.Duration         // Duration after which a request counts as slow
+	fn        func(SlowRequestInfo) // Callback run on its own goroutine for each slow request
+}
+
+// slowWatch times one request against the threshold
+type slowWatch struct {
+	cfg   *slowRequestConfig
+	start time.Time
+	timer *time.Timer
+	done  chan struct{} // Closed once the timer captured stack
+	stack []byte        // Written by the timer before done is closed
+}
+
+// SlowRequestThreshold calls fn for every request that takes longer than d, with the
+// matched pattern, parameters, duration, and status; a d of zero or a nil fn turns it off
+// When the threshold passes a timer snapshots the handler's goroutine stack, so the report
+// shows where the request was stuck even if it completes much later
+// fn runs on its own goroutine once the request completes and never delays the response;
+// it may be changed while serving
+// Example: rt.SlowRequestThreshold(5*time.Second, func(info tobingo.SlowRequestInfo) { log.Printf("slow %s (%s)\n%s", info.Route, info.Duration, info.Stack) })
+func (rt *Rastauter) SlowRequestThreshold(d time.Duration, fn func(info SlowRequestInfo)) {
+	if d <= 0 || fn == nil {
+		rt.slow.Store(nil)
+		return
+	}
+	rt.slow.Store(&slowRequestConfig{threshold: d, fn: fn})
+}
+
+// watch starts timing the request served by the calling goroutine
+func (cfg *slowRequestConfig) watch() *slowWatch {
+	sw := &slowWatch{cfg: cfg, start: time.Now(), done: make(chan struct{})}
+	id := goroutineID()
+	sw.timer = time.AfterFunc(cfg.threshold, func() {
+		sw.stack = goroutineStack(id)
+		close(sw.done)
+	})
+	return sw
+}
+
+// finish reports the request if the threshold passed before it completed
+func (sw *slowWatch) finish(r *http.Request, status int) {
+	if sw.timer.Stop() {
+		return
+	}
+	<-sw.done
+
+	info := SlowRequestInfo{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Params:   map[string]string{},
+		Duration: time.Since(sw.start),
+		Status:   status,
+		Stack:    sw.stack,
+	}
+	if state := stateFrom(r); state != nil && state.route != nil {
+		info.Route = state.route.Path
+		maps.Copy(info.Params, state.params)
+	}
+
+	fn := sw.cfg.fn
+	go runHook(r, "SlowRequestThreshold", func() { fn(info) })
+}
+
+// goroutineID parses the ID of the calling goroutine from the header of its stack trace,
+// "goroutine 42 [running]:", returning 0 if the format is ever not understood
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b, ok := bytes.CutPrefix(b, []byte("goroutine "))
+	if !ok {
+		return 0
+	}
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID, or nil once it exited
+// The runtime only dumps other goroutines all at once, so the trace is picked out of a full dump
+func goroutineStack(id uint64) []byte {
+	if id == 0 {
+		return nil
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for trace := range bytes.SplitSeq(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(trace, header) {
+			return bytes.Clone(trace)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package tobingo
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// sleepyHandler sleeps for d, showing up by name in captured stacks
+func sleepyHandler(d time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(d)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestSlowRequestThreshold(t *testing.T) {
+	reports := make(chan SlowRequestInfo, 4)
+	rt := NewRastaRouterInitializer()
+	rt.SlowRequestThreshold(10*time.Millisecond, func(info SlowRequestInfo) { reports <- info })
+	rt.GET("/reports/:id", sleepyHandler(60*time.Millisecond))
+	rt.GET("/fast", func(w http.ResponseWriter, r *http.Request) {})
+
+	rt.Test("GET", "/fast", nil)
+	rt.Test("GET", "/reports/7", nil)
+
+	select {
+	case info := <-reports:
+		if info.Route != "/reports/:id" || info.Path != "/reports/7" || info.Params["id"] != "7" || info.Method != "GET" || info.Status != http.StatusAccepted {
+			t.Errorf("info = %+v", info)
+		}
+		if info.Duration < 60*time.Millisecond || info.Duration > 5*time.Second {
+			t.Errorf("Duration = %v", info.Duration)
+		}
+		// Captured while the handler was still sleeping
+		if !bytes.Contains(info.Stack, []byte("sleepyHandler")) || !bytes.Contains(info.Stack, []byte("time.Sleep")) {
+			t.Errorf("Stack lacks the sleeping handler:\n%s", info.Stack)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no report for the slow request")
+	}
+	select {
+	case info := <-reports:
+		t.Errorf("unexpected report %+v", info)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSlowRequestCallbackDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	rt := NewRastaRouterInitializer()
+	rt.SlowRequestThreshold(time.Millisecond, func(info SlowRequestInfo) { <-release })
+	rt.GET("/", sleepyHandler(10*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		rt.Test("GET", "/", nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a blocked callback held up the response")
+	}
+}
+
+func TestSlowRequestThresholdUnmatchedAndOff(t *testing.T) {
+	reports := make(chan SlowRequestInfo, 4)
+	rt := NewRastaRouterInitializer()
+	rt.NotFound(sleepyHandler(20 * time.Millisecond))
+	rt.SlowRequestThreshold(time.Millisecond, func(info SlowRequestInfo) { reports <- info })
+	rt.Test("GET", "/nowhere", nil)
+	select {
+	case info := <-reports:
+		if info.Route != "" || info.Path != "/nowhere" || len(info.Params) != 0 {
+			t.Errorf("info = %+v", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no report for the slow miss")
+	}
+
+	rt.SlowRequestThreshold(0, nil)
+	rt.Test("GET", "/nowhere", nil)
+	select {
+	case info := <-reports:
+		t.Errorf("report after turning it off: %+v", info)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
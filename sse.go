@@ -0,0 +1,213 @@
+package tobingo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEConn is an open Server-Sent Events stream created by SSE
+// Its methods are safe for concurrent use, so a heartbeat can run alongside the sender
+type SSEConn struct {
+	w  http.ResponseWriter
+	r  *http.Request
+	rc *http.ResponseController
+
+	mu   sync.Mutex     // Serializes writes to the stream
+	err  error          // First write or flush error, sticky
+	stop chan struct{}  // Closed by Close to end the heartbeat
+	once sync.Once      // Guards closing stop
+	wg   sync.WaitGroup // Tracks the heartbeat goroutine so Close can wait for it
+
+	done       chan struct{} // Closed when the client disconnects or the router closes the stream
+	closeEvent *sseEvent     // Event sent when the router closes the stream, nil for none
+}
+
+// sseEvent is an event set with CloseEvent
+type sseEvent struct {
+	event string
+	data  any
+}
+
+// errSSEClosed is returned by writes after Close
+var errSSEClosed = errors.New("tobingo: SSE stream closed")
+
+// SSE starts a Server-Sent Events response, setting text/event-stream and disabling caching
+// and proxy buffering, and flushes the headers straight away
+// Returns ErrStreamingUnsupported when the writer can't flush, since events would never arrive
+// Call Close before the handler returns so no heartbeat writes to a finished response
+// Example: conn, err := tobingo.SSE(w, r); defer conn.Close(); conn.Heartbeat(15 * time.Second)
+func SSE(w http.ResponseWriter, r *http.Request) (*SSEConn, error) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	// Connection is a hop-by-hop header that HTTP/2 forbids
+	if r.ProtoMajor == 1 {
+		h.Set("Connection", "keep-alive")
+	}
+	writeStatus(w, http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil, ErrStreamingUnsupported
+		}
+		return nil, err
+	}
+
+	c := &SSEConn{w: w, r: r, rc: rc, stop: make(chan struct{}), done: make(chan struct{})}
+	go c.watch(RegisterLongLived(r))
+	return c, nil
+}
+
+// watch closes done once the client disconnects or the router closes long-lived responses,
+// in which case the close event is sent first and later writes fail with ErrServerClosing
+func (c *SSEConn) watch(closing <-chan struct{}) {
+	select {
+	case <-closing:
+		c.mu.Lock()
+		event := c.closeEvent
+		c.mu.Unlock()
+		if event != nil {
+			c.Send(event.event, "", event.data)
+		}
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = ErrServerClosing
+		}
+		c.mu.Unlock()
+	case <-c.r.Context().Done():
+	case <-c.stop:
+		return
+	}
+	close(c.done)
+}
+
+// CloseEvent sets an event sent when the router closes the stream for shutdown, e.g. to
+// tell clients to reconnect elsewhere or later; without it the stream just ends
+func (c *SSEConn) CloseEvent(event string, data any) {
+	c.mu.Lock()
+	c.closeEvent = &sseEvent{event: event, data: data}
+	c.mu.Unlock()
+}
+
+// Send writes one event and flushes it; event and id are omitted when empty
+// Strings are sent as is and any other data is JSON encoded; multi-line data is split into
+// one data field per line as the wire format requires
+// Returns an error matching ErrClientGone once the client disconnected
+func (c *SSEConn) Send(event, id string, data any) error {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n") {
+		return errors.New("tobingo: SSE event and id must not contain line breaks")
+	}
+
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	case []byte:
+		payload = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	// Normalize CRLF and CR so every line becomes its own data field
+	payload = strings.ReplaceAll(payload, "\r\n", "\n")
+	payload = strings.ReplaceAll(payload, "\r", "\n")
+	for line := range strings.SplitSeq(payload, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return c.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore; useful as a keep-alive
+func (c *SSEConn) Comment(text string) error {
+	var b strings.Builder
+	for line := range strings.SplitSeq(text, "\n") {
+		b.WriteString(": " + line + "\n")
+	}
+	b.WriteString("\n")
+	return c.write(b.String())
+}
+
+// Heartbeat sends a comment every interval until Close is called or the client disconnects,
+// keeping idle proxies from closing the connection
+func (c *SSEConn) Heartbeat(interval time.Duration) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if c.Comment("heartbeat") != nil {
+					return
+				}
+			case <-c.stop:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// Done returns a channel closed when the client disconnects or the router closes the stream
+// for shutdown, after which the handler should return
+func (c *SSEConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close stops the heartbeat and waits for it to finish, after which writes fail
+// The stream itself ends when the handler returns
+func (c *SSEConn) Close() {
+	c.once.Do(func() { close(c.stop) })
+	c.wg.Wait()
+
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = errSSEClosed
+	}
+	c.mu.Unlock()
+}
+
+// write sends s and flushes it, failing fast after the first error or a disconnect
+func (c *SSEConn) write(s string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.r.Context().Err(); err != nil {
+		c.err = fmt.Errorf("%w: %w", ErrClientGone, err)
+		return c.err
+	}
+	if _, err := c.w.Write([]byte(s)); err != nil {
+		c.err = err
+		return err
+	}
+	if err := c.rc.Flush(); err != nil {
+		c.err = err
+	}
+	return c.err
+}
@@ -0,0 +1,144 @@
+package tobingo
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseFrame is one blank-line terminated block of the event stream
+type sseFrame struct {
+	event, id string
+	data      []string
+	comments  []string
+}
+
+// readFrame parses the next frame of the stream
+func readFrame(t *testing.T, br *bufio.Reader) sseFrame {
+	t.Helper()
+	var f sseFrame
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return f
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "":
+			f.comments = append(f.comments, value)
+		case "event":
+			f.event = value
+		case "id":
+			f.id = value
+		case "data":
+			f.data = append(f.data, value)
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestSSE(t *testing.T) {
+	disconnected := make(chan error, 1)
+	rt := NewRastaRouterInitializer()
+	rt.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := SSE(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		conn.Send("progress", "1", map[string]int{"pct": 50})
+		conn.Send("", "2", "line1\nline2\r\nline3")
+		conn.Heartbeat(10 * time.Millisecond)
+
+		<-conn.Done()
+		disconnected <- conn.Send("late", "", "x")
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"Content-Type": "text/event-stream", "Cache-Control": "no-cache", "X-Accel-Buffering": "no"} {
+		if got := res.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	br := bufio.NewReader(res.Body)
+	if f := readFrame(t, br); f.event != "progress" || f.id != "1" || len(f.data) != 1 || f.data[0] != `{"pct":50}` {
+		t.Errorf("first event = %+v", f)
+	}
+	if f := readFrame(t, br); f.event != "" || f.id != "2" || strings.Join(f.data, "|") != "line1|line2|line3" {
+		t.Errorf("multi-line event = %+v", f)
+	}
+	if f := readFrame(t, br); len(f.comments) != 1 || f.comments[0] != "heartbeat" || f.data != nil {
+		t.Errorf("heartbeat = %+v", f)
+	}
+	res.Body.Close()
+
+	select {
+	case err := <-disconnected:
+		if !errors.Is(err, ErrClientGone) {
+			t.Errorf("Send after disconnect = %v, want ErrClientGone", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnect not noticed")
+	}
+}
+
+func TestSSEComment(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := SSE(w, r)
+		conn.Comment("two\nlines")
+		conn.Close()
+		if err := conn.Send("", "", "x"); err == nil {
+			t.Error("Send after Close succeeded")
+		}
+	})
+	if got := rt.Test("GET", "/events", nil).BodyString(); got != ": two\n: lines\n\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestSSERejectsLineBreaks(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := SSE(w, r)
+		defer conn.Close()
+		if conn.Send("a\nevent: forged", "", "x") == nil || conn.Send("", "1\r", "x") == nil {
+			t.Error("line break in event or id accepted")
+		}
+	})
+	if got := rt.Test("GET", "/events", nil).BodyString(); got != "" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestSSEUnsupported(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(hiddenFlusher{w}, r)
+		})
+	})
+	rt.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := SSE(w, r); !errors.Is(err, ErrStreamingUnsupported) {
+			t.Errorf("err = %v, want ErrStreamingUnsupported", err)
+		}
+	})
+	rt.Test("GET", "/events", nil)
+}
@@ -0,0 +1,538 @@
+package tobingo
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticOptions configures a static file mount registered with Static
+type StaticOptions struct {
+	FollowSymlinks bool      // Serve symlinks that point outside the root, refused by default; Static only
+	Dir            string    // Subdirectory of the filesystem to serve, e.g. "dist" for files embedded with //go:embed dist
+	LastModified   time.Time // Last-Modified for files without a modification time, such as embed.FS entries
+	ETag           string    // Fixed ETag sent for every file, e.g. a build ID, instead of one derived per file
+
+	Index           string             // File served for a directory, "index.html" when empty
+	DirList         bool               // List directories without an index file instead of answering 404
+	DirListTemplate *template.Template // Renders a DirListing, a plain table when nil
+
+	NotFound http.HandlerFunc // Answers missing files of this mount instead of the router's NotFound handler
+
+	Precompressed bool // Serve name.br or name.gz instead of name when present and accepted
+
+	Immutable    func(name string) bool // Reports content-hashed file names cached for a year, HashedFilename when nil; a regexp's MatchString works
+	CacheControl string                 // Cache-Control for other files, "no-cache" when empty so they revalidate with their ETag
+}
+
+// precompressedVariants lists the encodings Precompressed looks for in order of preference
+var precompressedVariants = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// DirListing is the data a DirListTemplate renders
+type DirListing struct {
+	Path    string         // Request path of the directory as the client sees it, ending in a slash
+	Entries []DirListEntry // Directory contents sorted by name
+}
+
+// DirListEntry is one file or subdirectory of a DirListing
+type DirListEntry struct {
+	Name    string    // File name, to be escaped by the template
+	URL     string    // Relative link to the entry, path-escaped, with a trailing slash for directories
+	IsDir   bool      // Whether the entry is a directory
+	Size    int64     // Size in bytes, 0 for directories
+	ModTime time.Time // Modification time, zero when the filesystem doesn't record one
+}
+
+// dirListTemplate is the listing used when DirListTemplate is nil
+var dirListTemplate = template.Must(template.New("dirlist").Parse(`<!doctype html>
+<meta charset="utf-8">
+<title>Index of {{.Path}}</title>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{range .Entries}}<tr><td><a href="{{.URL}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{if not .ModTime.IsZero}}{{.ModTime.UTC.Format "2006-01-02 15:04"}}{{end}}</td></tr>
+{{end}}</table>
+`))
+
+// staticMount serves the files of one static mount
+type staticMount struct {
+	rt       *Rastauter    // Router the mount is registered with
+	fsys     fs.FS         // Files to serve, paths are relative to the mount prefix
+	opts     StaticOptions // Options given at registration
+	fallback string        // Index served for client-side routes by SPA mounts, "" for plain mounts
+	etags    sync.Map      // Content hashes of files without a modification time, by name and encoding
+}
+
+// Static serves the files under the directory root at prefix, registering GET and HEAD
+// routes for prefix + "/*filepath"
+// Paths that climb out of root with ".." answer 400 and symlinks leading outside it 404,
+// unless FollowSymlinks is set; a directory serves its index.html, a listing with DirList,
+// or answers 404, and requests for it without a trailing slash are redirected to add one
+// Files go through http.ServeContent, so Content-Type, Last-Modified, conditional requests,
+// and Range requests work; more specific routes under prefix always take precedence
+// Example: rt.Static("/assets", "./public") serves ./public/css/site.css at /assets/css/site.css
+func (rt *Rastauter) Static(prefix, root string, opts ...StaticOptions) {
+	o := staticOptions(opts)
+
+	var fsys fs.FS = rootFS(root)
+	if o.FollowSymlinks {
+		fsys = os.DirFS(root)
+	}
+	rt.mountStatic(prefix, fsys, o)
+}
+
+// StaticFS serves the files of fsys at prefix like Static, e.g. assets compiled in with embed.FS
+// embed.FS keeps the embedded directory in every path, so set Dir (or use fs.Sub) to serve
+// its contents rather than the directory itself
+// Embedded files have no modification time, so they get an ETag hashed from their content;
+// set LastModified to the build time or ETag to a build ID to use those instead
+// Example: rt.StaticFS("/app", distFS, tobingo.StaticOptions{Dir: "dist", ETag: buildID})
+func (rt *Rastauter) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) {
+	rt.mountStatic(prefix, fsys, staticOptions(opts))
+}
+
+// SPA serves a single-page app from fsys at prefix like StaticFS, answering client-side
+// routes such as /settings/profile with the index file so the app can route them itself
+// The index is served with 200 for GET and HEAD page navigations, meaning requests that
+// accept text/html explicitly and whose path has no file extension, which aren't files
+// Other misses, like a missing .js asset or an unknown API path requested as JSON, go to the
+// NotFound handler; registered routes always take precedence, other methods are untouched
+// Example: rt.SPA("/", distFS, "index.html", tobingo.StaticOptions{Dir: "dist"})
+func (rt *Rastauter) SPA(prefix string, fsys fs.FS, index string, opts ...StaticOptions) {
+	if !fs.ValidPath(index) {
+		panic(fmt.Sprintf("tobingo: SPA %q: invalid fs.FS path %q", prefix, index))
+	}
+	rt.mountStatic(prefix, fsys, staticOptions(opts)).fallback = index
+}
+
+// staticOptions returns the options given to a static mount, the zero value when none
+func staticOptions(opts []StaticOptions) StaticOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return StaticOptions{}
+}
+
+// StaticFile serves the file at the path file for exactly the route path, registering GET
+// and HEAD, e.g. for /favicon.ico or /.well-known/security.txt
+// file is trusted configuration and opened on every request, so a file that goes missing
+// answers 404; Content-Type and Last-Modified come from http.ServeContent
+// Example: rt.StaticFile("/favicon.ico", "./assets/favicon.ico")
+func (rt *Rastauter) StaticFile(path, file string) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		f, err := os.Open(file)
+		if err != nil {
+			fileError(w, r, err)
+			return
+		}
+		defer f.Close()
+
+		serveFile(w, r, f, file)
+	}
+	rt.addRoute(http.MethodGet, path, h)
+	rt.addRoute(http.MethodHead, path, h)
+}
+
+// StaticFileFS serves the named file of fsys for exactly the route path like StaticFile
+// name must be a valid fs.FS path; anything else panics at registration
+// Example: rt.StaticFileFS("/robots.txt", assets, "static/robots.txt")
+func (rt *Rastauter) StaticFileFS(path string, fsys fs.FS, name string) {
+	if !fs.ValidPath(name) {
+		panic(fmt.Sprintf("tobingo: static file %q: invalid fs.FS path %q", path, name))
+	}
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		FileFS(w, r, fsys, name)
+	}
+	rt.addRoute(http.MethodGet, path, h)
+	rt.addRoute(http.MethodHead, path, h)
+}
+
+// mountStatic registers the GET and HEAD catch-all routes of a static mount serving fsys
+func (rt *Rastauter) mountStatic(prefix string, fsys fs.FS, o StaticOptions) *staticMount {
+	m, err := rt.tryMountStatic(prefix, fsys, o)
+	if err != nil {
+		panic(err.Error())
+	}
+	return m
+}
+
+// tryMountStatic implements mountStatic, returning an error where mountStatic panics
+func (rt *Rastauter) tryMountStatic(prefix string, fsys fs.FS, o StaticOptions) (*staticMount, error) {
+	if o.Dir != "" && o.Dir != "." {
+		sub, err := fs.Sub(fsys, o.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("tobingo: static mount %q: %w", prefix, err)
+		}
+		fsys = sub
+	}
+
+	m := &staticMount{rt: rt, fsys: fsys, opts: o}
+	pattern := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		if _, err := rt.tryAddRoute(method, pattern, m.serve); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// serve answers a request for the file named by the catch-all capture
+func (m *staticMount) serve(w http.ResponseWriter, r *http.Request) {
+	name, err := GetWildcardClean(r)
+	if err != nil {
+		fileError(w, r, fs.ErrInvalid)
+		return
+	}
+
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		m.fail(w, r, name, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		m.fail(w, r, name, err)
+		return
+	}
+
+	// Directories are served through their index file, or listed when enabled
+	if info.IsDir() {
+		dir := name
+		name = path.Join(dir, cmp.Or(m.opts.Index, "index.html"))
+		index, err := m.fsys.Open(name)
+		listing := errors.Is(err, fs.ErrNotExist) && m.opts.DirList
+		if err != nil && !listing {
+			m.fail(w, r, dir, err)
+			return
+		}
+		if err == nil {
+			defer index.Close()
+		}
+
+		// Relative links in the index or listing need the trailing slash; the name is escaped
+		// like the listing's links, and "./" keeps one with a colon from reading as a scheme
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			location := "./" + url.PathEscape(path.Base(r.URL.Path)) + "/"
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+
+		if listing {
+			m.serveListing(w, r, dir)
+			return
+		}
+		if info, err = index.Stat(); err != nil {
+			m.fail(w, r, dir, err)
+			return
+		}
+		f = index
+	}
+
+	m.serveContent(w, r, f, info, name)
+}
+
+// serveContent serves an opened file of the mount with the configured validators and caching
+func (m *staticMount) serveContent(w http.ResponseWriter, r *http.Request, f fs.File, info fs.FileInfo, name string) {
+	h := w.Header()
+
+	// Variants keep the Content-Type of name, only the encoding and validators change
+	encoding := ""
+	if m.opts.Precompressed {
+		h.Add("Vary", "Accept-Encoding")
+		if variant, variantInfo, enc := m.openVariant(r, name); variant != nil {
+			defer variant.Close()
+			f, info, encoding = variant, variantInfo, enc
+			h.Set("Content-Encoding", encoding)
+		}
+	}
+
+	etag := strings.Trim(m.opts.ETag, `"`)
+	if etag == "" {
+		etag = m.fileETag(f, info, name+"."+encoding)
+	}
+	if etag != "" {
+		if encoding != "" {
+			etag += "-" + encoding
+		}
+		h.Set("ETag", quoteETag(etag))
+	}
+
+	immutable := m.opts.Immutable
+	if immutable == nil {
+		immutable = HashedFilename
+	}
+	switch {
+	case immutable(path.Base(name)):
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	case m.opts.CacheControl != "":
+		h.Set("Cache-Control", m.opts.CacheControl)
+	default:
+		h.Set("Cache-Control", "no-cache")
+	}
+
+	modtime := modTime(info)
+	if modtime.IsZero() {
+		modtime = m.opts.LastModified
+	}
+	serveContent(w, r, f, name, modtime)
+}
+
+// fileETag derives a validator from the modification time and size, or from the content for
+// files without a modification time such as embedded ones, hashing each of those only once
+// Returns "" when neither is available
+func (m *staticMount) fileETag(f fs.File, info fs.FileInfo, key string) string {
+	if t := modTime(info); !t.IsZero() {
+		return fmt.Sprintf("%x-%x", t.UnixNano(), info.Size())
+	}
+	if tag, ok := m.etags.Load(key); ok {
+		return tag.(string)
+	}
+
+	seeker, ok := f.(io.ReadSeeker)
+	if !ok {
+		return ""
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, seeker); err != nil {
+		return ""
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+
+	tag := base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:16])
+	m.etags.Store(key, tag)
+	return tag
+}
+
+// HashedFilename reports whether a file name carries a content hash, as in "app.3f9ab2.js" or
+// "index-B1x2k9Qz.js", which is the default Immutable classification of static mounts
+// A hash is a dot or dash separated part before the extension that is at least 6 hex digits,
+// or at least 8 letters and digits mixing both
+func HashedFilename(name string) bool {
+	stem, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+	if ext == "" {
+		return false
+	}
+
+	parts := strings.FieldsFunc(stem, func(c rune) bool { return c == '.' || c == '-' })
+	for _, part := range parts[min(1, len(parts)):] {
+		if isContentHash(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isContentHash reports whether s looks like a hex or base64url digest rather than a word
+func isContentHash(s string) bool {
+	var hex, digits, letters int
+	for _, c := range s {
+		switch {
+		case '0' <= c && c <= '9':
+			digits++
+			hex++
+		case 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+			letters++
+			hex++
+		case 'g' <= c && c <= 'z', 'G' <= c && c <= 'Z', c == '_':
+			letters++
+		default:
+			return false
+		}
+	}
+	if hex == len(s) && len(s) >= 6 && digits > 0 {
+		return true
+	}
+	return len(s) >= 8 && digits > 0 && letters > 0
+}
+
+// openVariant opens the preferred precompressed variant of name the client accepts
+// Returns a nil file when there is none, so the uncompressed file is served
+// Clients that send no Accept-Encoding get the uncompressed file, as they may not decode any
+func (m *staticMount) openVariant(r *http.Request, name string) (fs.File, fs.FileInfo, string) {
+	if !accepted(r).hasEncoding {
+		return nil, nil, ""
+	}
+	for _, variant := range precompressedVariants {
+		if !AcceptsEncoding(r, variant.encoding) {
+			continue
+		}
+		f, err := m.fsys.Open(name + variant.ext)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			f.Close()
+			continue
+		}
+		return f, info, variant.encoding
+	}
+	return nil, nil, ""
+}
+
+// serveListing renders the contents of the directory dir as HTML
+func (m *staticMount) serveListing(w http.ResponseWriter, r *http.Request, dir string) {
+	entries, err := fs.ReadDir(m.fsys, dir)
+	if err != nil {
+		m.fail(w, r, dir, err)
+		return
+	}
+
+	listing := DirListing{Path: ExternalPath(r, r.URL.Path), Entries: make([]DirListEntry, 0, len(entries))}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+
+		// "./" keeps names like "a:b" from being read as a URL scheme
+		e := DirListEntry{Name: entry.Name(), URL: "./" + url.PathEscape(entry.Name()), IsDir: entry.IsDir(), ModTime: modTime(info)}
+		if e.IsDir {
+			e.URL += "/"
+		} else {
+			e.Size = info.Size()
+		}
+		listing.Entries = append(listing.Entries, e)
+	}
+
+	tmpl := m.opts.DirListTemplate
+	if tmpl == nil {
+		tmpl = dirListTemplate
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := tmpl.Execute(buf, listing); err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// fail answers a request for a file the mount couldn't serve
+// Missing files go to the mount's NotFound handler, or the router's; SPA mounts answer page
+// navigations with the app's index instead; other filesystem errors such as permission or
+// I/O errors are server faults and go to the error handler as a 500
+func (m *staticMount) fail(w http.ResponseWriter, r *http.Request, name string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrInvalid):
+		fileError(w, r, err)
+	case !errors.Is(err, fs.ErrNotExist):
+		handleError(w, r, http.StatusInternalServerError, err)
+	case m.fallback != "" && path.Ext(name) == "" && acceptsHTML(r):
+		m.serveFallback(w, r)
+	case m.opts.NotFound != nil:
+		m.opts.NotFound(w, r)
+	default:
+		m.rt.handleNotFound(w, r)
+	}
+}
+
+// serveFallback serves the index of an SPA mount
+func (m *staticMount) serveFallback(w http.ResponseWriter, r *http.Request) {
+	f, err := m.fsys.Open(m.fallback)
+	if err != nil {
+		m.failFallback(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		m.failFallback(w, r, err)
+		return
+	}
+	m.serveContent(w, r, f, info, m.fallback)
+}
+
+// failFallback answers a request whose SPA index couldn't be served
+// A missing index is a deployment mistake, so it is reported like any other server fault
+func (m *staticMount) failFallback(w http.ResponseWriter, r *http.Request, err error) {
+	handleError(w, r, http.StatusInternalServerError, fmt.Errorf("tobingo: SPA index %q: %w", m.fallback, err))
+}
+
+// acceptsHTML reports whether the Accept header names text/html explicitly, as browsers do
+// for page navigations while fetch and API clients usually send */* or a JSON type
+func acceptsHTML(r *http.Request) bool {
+	for _, ar := range accepted(r).media {
+		if ar.typ == "text" && ar.sub == "html" && ar.q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag adds the quotes an entity tag needs unless it already has them
+func quoteETag(tag string) string {
+	if strings.HasSuffix(tag, `"`) {
+		return tag
+	}
+	return `"` + tag + `"`
+}
+
+// rootFS is a directory served with os.OpenInRoot, which refuses to follow symlinks out of it
+type rootFS string
+
+// Open opens the named file inside the directory
+// A refused symlink is reported as not existing so it answers 404 like a missing file
+func (dir rootFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, err := os.OpenInRoot(string(dir), name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission) && dir.hasSymlink(name) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// hasSymlink reports whether any element of the named path is a symlink
+// os.Root doesn't export its escape error, so this tells a refused symlink from an I/O error
+func (dir rootFS) hasSymlink(name string) bool {
+	p := string(dir)
+	for elem := range strings.SplitSeq(name, "/") {
+		p = filepath.Join(p, elem)
+		info, err := os.Lstat(p)
+		if err != nil {
+			return false
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
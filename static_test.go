@@ -0,0 +1,616 @@
+package tobingo
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// staticDir writes the files, keyed by slash-separated path, under a new directory
+func staticDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := staticDir(t, map[string]string{
+		"site.css":          "body{}",
+		"js/app.js":         "app()",
+		"docs/index.html":   "<p>docs",
+		"docs/deep/a/b.txt": "deep",
+		"empty/.keep":       "",
+	})
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", dir)
+
+	for target, want := range map[string]string{
+		"/assets/site.css":          "body{}",
+		"/assets/js/app.js":         "app()",
+		"/assets/docs/deep/a/b.txt": "deep",
+		"/assets/docs/":             "<p>docs",
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != want {
+			t.Errorf("%s = %d %q, want %q", target, res.StatusCode(), res.BodyString(), want)
+		}
+	}
+
+	res := rt.Test("GET", "/assets/site.css", nil)
+	if ct := res.Header("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if res.Header("ETag") == "" || res.Header("Last-Modified") == "" || res.Header("Cache-Control") != "no-cache" {
+		t.Errorf("validators = %v", res.Recorder.Header())
+	}
+
+	res = rt.Test("HEAD", "/assets/js/app.js", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "" || res.Header("Content-Length") != "5" {
+		t.Errorf("HEAD = %d %q, Content-Length %q", res.StatusCode(), res.BodyString(), res.Header("Content-Length"))
+	}
+
+	for _, target := range []string{"/assets/missing.css", "/assets/js/missing.js", "/assets/empty/"} {
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusNotFound {
+			t.Errorf("%s = %d, want 404", target, res.StatusCode())
+		}
+	}
+}
+
+func TestStaticDirectoryRedirect(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", staticDir(t, map[string]string{
+		"docs/index.html": "<p>docs",
+		"a?b/index.html":  "<p>query",
+		"a#b/index.html":  "<p>fragment",
+		"100%/index.html": "<p>percent",
+		"a b/index.html":  "<p>space",
+		"a:b/index.html":  "<p>colon",
+	}))
+
+	for target, want := range map[string]string{
+		"/assets/docs?v=2": "./docs/?v=2",
+		"/assets/a%3Fb":    "./a%3Fb/",
+		"/assets/a%23b":    "./a%23b/",
+		"/assets/100%25":   "./100%25/",
+		"/assets/a%20b":    "./a%20b/",
+		"/assets/a:b":      "./a:b/",
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusMovedPermanently || res.Header("Location") != want {
+			t.Errorf("%s: got %d Location %q, want %q", target, res.StatusCode(), res.Header("Location"), want)
+			continue
+		}
+
+		// Following the redirect reaches the directory's index
+		next, err := url.Parse("http://example.com" + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loc, _ := url.Parse(want)
+		if res := rt.Test("GET", next.ResolveReference(loc).RequestURI(), nil); res.StatusCode() != http.StatusOK || !strings.HasPrefix(res.BodyString(), "<p>") {
+			t.Errorf("%s: following %q got %d %q", target, want, res.StatusCode(), res.BodyString())
+		}
+	}
+}
+
+func TestStaticTraversal(t *testing.T) {
+	parent := staticDir(t, map[string]string{"secret.txt": "top secret", "public/ok.txt": "ok"})
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", filepath.Join(parent, "public"))
+
+	for _, target := range []string{
+		"/assets/..%2fsecret.txt",
+		"/assets/%2e%2e/secret.txt",
+		"/assets/%2e%2e%2fsecret.txt",
+		"/assets/ok.txt%2f..%2f..%2fsecret.txt",
+		"/assets/..%5csecret.txt",
+		"/assets/../secret.txt",
+	} {
+		res := rt.Test("GET", target, nil)
+		if strings.Contains(res.BodyString(), "top secret") || res.StatusCode() != http.StatusBadRequest {
+			t.Errorf("%s = %d %q", target, res.StatusCode(), res.BodyString())
+		}
+	}
+}
+
+func TestStaticSymlinks(t *testing.T) {
+	parent := staticDir(t, map[string]string{"secret.txt": "top secret", "public/ok.txt": "ok"})
+	public := filepath.Join(parent, "public")
+	if err := os.Symlink(filepath.Join(parent, "secret.txt"), filepath.Join(public, "escape.txt")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	if err := os.Symlink("ok.txt", filepath.Join(public, "inside.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", public)
+	if res := rt.Test("GET", "/assets/escape.txt", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("escaping symlink = %d %q, want 404", res.StatusCode(), res.BodyString())
+	}
+	if res := rt.Test("GET", "/assets/inside.txt", nil); res.StatusCode() != http.StatusOK || res.BodyString() != "ok" {
+		t.Errorf("symlink inside the root = %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	rt = NewRastaRouterInitializer()
+	rt.Static("/assets", public, StaticOptions{FollowSymlinks: true})
+	if res := rt.Test("GET", "/assets/escape.txt", nil); res.BodyString() != "top secret" {
+		t.Errorf("FollowSymlinks = %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestStaticPrecedence(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", staticDir(t, map[string]string{"config.json": "file", "app.js": "app()"}))
+	rt.GET("/assets/config.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("route"))
+	})
+	rt.GET("/assets/:name/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("info " + GetParam(r, "name")))
+	})
+
+	for target, want := range map[string]string{
+		"/assets/config.json": "route",
+		"/assets/app.js":      "app()",
+		"/assets/app.js/info": "info app.js",
+	} {
+		if res := rt.Test("GET", target, nil); res.BodyString() != want {
+			t.Errorf("%s = %d %q, want %q", target, res.StatusCode(), res.BodyString(), want)
+		}
+	}
+}
+
+// errorFS fails to open the named files with their errors
+type errorFS struct {
+	fstest.MapFS
+	errs map[string]error
+}
+
+func (f errorFS) Open(name string) (fs.File, error) {
+	if err, ok := f.errs[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: "/srv/secret/" + name, Err: err}
+	}
+	return f.MapFS.Open(name)
+}
+
+func TestStaticNotFound(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	})
+	rt.Static("/assets", staticDir(t, nil), StaticOptions{NotFound: func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such asset", http.StatusNotFound)
+	}})
+	rt.Static("/other", staticDir(t, nil))
+
+	if res := rt.Test("GET", "/assets/x.css", nil); res.StatusCode() != http.StatusNotFound || res.BodyString() != "no such asset\n" {
+		t.Errorf("mount 404 = %d %q", res.StatusCode(), res.BodyString())
+	}
+	// Other mounts and unrouted paths keep the router's handler
+	for _, target := range []string{"/other/x.css", "/nowhere"} {
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusNotFound || res.BodyString() != `{"error":"not found"}` {
+			t.Errorf("%s = %d %q", target, res.StatusCode(), res.BodyString())
+		}
+	}
+}
+
+func TestStaticFilesystemErrors(t *testing.T) {
+	var handled []error
+	rt := NewRastaRouterInitializer()
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		handled = append(handled, err)
+		DefaultErrorHandler(w, r, status, err)
+	})
+	rt.StaticFS("/assets", errorFS{
+		MapFS: fstest.MapFS{"ok.txt": {Data: []byte("ok")}},
+		errs:  map[string]error{"locked.txt": fs.ErrPermission, "broken.txt": errors.New("input/output error")},
+	}, StaticOptions{NotFound: func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a filesystem error went to NotFound")
+	}})
+
+	for _, target := range []string{"/assets/locked.txt", "/assets/broken.txt"} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusInternalServerError {
+			t.Errorf("%s = %d, want 500", target, res.StatusCode())
+		}
+		if body := res.BodyString(); strings.Contains(body, "/srv/secret") || strings.Contains(body, "permission") || strings.Contains(body, "input/output") {
+			t.Errorf("%s leaked the error: %q", target, body)
+		}
+	}
+	if len(handled) != 2 || !errors.Is(handled[0], fs.ErrPermission) {
+		t.Errorf("error handler saw %v", handled)
+	}
+	if res := rt.Test("GET", "/assets/ok.txt", nil); res.BodyString() != "ok" {
+		t.Errorf("ok.txt = %q", res.BodyString())
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/index.html":       {Data: []byte("<p>home")},
+		"dist/js/app.js":        {Data: []byte("app()")},
+		"dist/img/icons/a.svg":  {Data: []byte("<svg/>")},
+		"dist/img/icons/b.json": {Data: []byte("{}")},
+	}
+	rt := NewRastaRouterInitializer()
+	rt.StaticFS("/app", fsys, StaticOptions{Dir: "dist"})
+
+	for target, want := range map[string]string{
+		"/app/":                "<p>home",
+		"/app/js/app.js":       "app()",
+		"/app/img/icons/a.svg": "<svg/>",
+	} {
+		res := rt.Test("GET", target, nil)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != want {
+			t.Errorf("%s = %d %q, want %q", target, res.StatusCode(), res.BodyString(), want)
+		}
+	}
+	for _, target := range []string{"/app/missing.js", "/app/img/icons/c.svg", "/app/dist/index.html", "/app/img/"} {
+		if res := rt.Test("GET", target, nil); res.StatusCode() != http.StatusNotFound {
+			t.Errorf("%s = %d, want 404", target, res.StatusCode())
+		}
+	}
+
+	// Without modification times the ETag is hashed from the content, and stays the same
+	res := rt.Test("GET", "/app/js/app.js", nil)
+	etag := res.Header("ETag")
+	if etag == "" || res.Header("Last-Modified") != "" {
+		t.Fatalf("ETag %q, Last-Modified %q", etag, res.Header("Last-Modified"))
+	}
+	if again := rt.Test("GET", "/app/js/app.js", nil).Header("ETag"); again != etag {
+		t.Errorf("ETag changed from %s to %s", etag, again)
+	}
+	if res := rt.Test("GET", "/app/js/app.js", nil, WithTestHeader("If-None-Match", etag)); res.StatusCode() != http.StatusNotModified {
+		t.Errorf("If-None-Match = %d, want 304", res.StatusCode())
+	}
+	if other := rt.Test("GET", "/app/img/icons/a.svg", nil).Header("ETag"); other == etag {
+		t.Error("different files share an ETag")
+	}
+}
+
+func TestStaticFSFixedValidators(t *testing.T) {
+	built := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{"app.js": {Data: []byte("app()")}, "site.css": {Data: []byte("body{}")}}
+	rt := NewRastaRouterInitializer()
+	rt.StaticFS("/a", fsys, StaticOptions{ETag: "build-42"})
+	rt.StaticFS("/b", fsys, StaticOptions{LastModified: built, CacheControl: "public, max-age=60"})
+
+	for _, target := range []string{"/a/app.js", "/a/site.css"} {
+		res := rt.Test("GET", target, nil)
+		if res.Header("ETag") != `"build-42"` {
+			t.Errorf("%s ETag = %q", target, res.Header("ETag"))
+		}
+		if res := rt.Test("GET", target, nil, WithTestHeader("If-None-Match", `"build-42"`)); res.StatusCode() != http.StatusNotModified || res.BodyString() != "" {
+			t.Errorf("%s If-None-Match = %d %q", target, res.StatusCode(), res.BodyString())
+		}
+	}
+	if res := rt.Test("GET", "/a/app.js", nil, WithTestHeader("If-None-Match", `"build-41"`)); res.StatusCode() != http.StatusOK {
+		t.Errorf("stale ETag = %d, want 200", res.StatusCode())
+	}
+
+	res := rt.Test("GET", "/b/app.js", nil)
+	if res.Header("Last-Modified") != built.Format(http.TimeFormat) || res.Header("Cache-Control") != "public, max-age=60" {
+		t.Errorf("headers = %v", res.Recorder.Header())
+	}
+	since := WithTestHeader("If-Modified-Since", built.Add(time.Hour).Format(http.TimeFormat))
+	if res := rt.Test("GET", "/b/app.js", nil, since); res.StatusCode() != http.StatusNotModified {
+		t.Errorf("If-Modified-Since = %d, want 304", res.StatusCode())
+	}
+	since = WithTestHeader("If-Modified-Since", built.Add(-time.Hour).Format(http.TimeFormat))
+	if res := rt.Test("GET", "/b/app.js", nil, since); res.StatusCode() != http.StatusOK {
+		t.Errorf("older If-Modified-Since = %d, want 200", res.StatusCode())
+	}
+}
+
+func TestStaticFSInvalidDir(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("an invalid Dir didn't panic")
+		}
+	}()
+	NewRastaRouterInitializer().StaticFS("/a", fstest.MapFS{}, StaticOptions{Dir: "../up"})
+}
+
+func TestStaticFile(t *testing.T) {
+	dir := staticDir(t, map[string]string{"favicon.ico": "icon", "robots.txt": "from the mount"})
+	modtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "favicon.ico"), modtime, modtime)
+
+	rt := NewRastaRouterInitializer()
+	rt.Static("/", dir)
+	rt.StaticFile("/favicon.ico", filepath.Join(dir, "favicon.ico"))
+	rt.StaticFile("/robots.txt", filepath.Join(dir, "missing.txt"))
+	rt.StaticFileFS("/.well-known/security.txt", fstest.MapFS{"sec.txt": {Data: []byte("Contact: x")}}, "sec.txt")
+
+	res := rt.Test("GET", "/favicon.ico", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "icon" || res.Header("Content-Type") != "image/vnd.microsoft.icon" {
+		t.Errorf("got %d %q, Content-Type %q", res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+	}
+	if res.Header("Last-Modified") != modtime.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", res.Header("Last-Modified"))
+	}
+
+	res = rt.Test("HEAD", "/favicon.ico", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "" || res.Header("Content-Length") != "4" {
+		t.Errorf("HEAD = %d %q, Content-Length %q", res.StatusCode(), res.BodyString(), res.Header("Content-Length"))
+	}
+
+	since := WithTestHeader("If-Modified-Since", modtime.Format(http.TimeFormat))
+	if res := rt.Test("GET", "/favicon.ico", nil, since); res.StatusCode() != http.StatusNotModified {
+		t.Errorf("If-Modified-Since = %d, want 304", res.StatusCode())
+	}
+
+	// A missing file answers 404 rather than falling through to the catch-all mount
+	if res := rt.Test("GET", "/robots.txt", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("missing file = %d %q, want 404", res.StatusCode(), res.BodyString())
+	}
+
+	res = rt.Test("GET", "/.well-known/security.txt", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "Contact: x" || res.Header("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("StaticFileFS = %d %q, Content-Type %q", res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+	}
+}
+
+func TestStaticFileFSInvalidName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("an invalid name didn't panic")
+		}
+	}()
+	NewRastaRouterInitializer().StaticFileFS("/x", fstest.MapFS{}, "/abs.txt")
+}
+
+func TestSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/index.html":  {Data: []byte("<div id=app>")},
+		"dist/assets/a.js": {Data: []byte("app()")},
+	}
+	rt := NewRastaRouterInitializer()
+	rt.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "custom 404", http.StatusNotFound)
+	})
+	rt.GET("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+	rt.SPA("/", fsys, "index.html", StaticOptions{Dir: "dist"})
+	page := WithTestHeader("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+
+	for _, target := range []string{"/", "/settings", "/settings/profile/security"} {
+		res := rt.Test("GET", target, nil, page)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != "<div id=app>" || res.Header("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("%s = %d %q, Content-Type %q", target, res.StatusCode(), res.BodyString(), res.Header("Content-Type"))
+		}
+	}
+	if res := rt.Test("HEAD", "/settings", nil, page); res.StatusCode() != http.StatusOK || res.BodyString() != "" {
+		t.Errorf("HEAD = %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	// Registered routes and real files are served as they are
+	if res := rt.Test("GET", "/api/users", nil, page); res.BodyString() != "users" {
+		t.Errorf("API route = %q", res.BodyString())
+	}
+	if res := rt.Test("GET", "/assets/a.js", nil, page); res.BodyString() != "app()" {
+		t.Errorf("asset = %q", res.BodyString())
+	}
+
+	// Missing assets, API misses, and non-navigation requests reach the NotFound handler
+	for name, res := range map[string]*TestResponse{
+		"missing asset":  rt.Test("GET", "/assets/missing.js", nil, page),
+		"API miss":       rt.Test("GET", "/api/nothing", nil, WithTestHeader("Accept", "application/json")),
+		"fetch with */*": rt.Test("GET", "/settings", nil, WithTestHeader("Accept", "*/*")),
+		"no Accept":      rt.Test("GET", "/settings", nil),
+	} {
+		if res.StatusCode() != http.StatusNotFound || res.BodyString() != "custom 404\n" {
+			t.Errorf("%s = %d %q", name, res.StatusCode(), res.BodyString())
+		}
+	}
+
+	// Other methods never get the index
+	res := rt.Test("POST", "/settings", strings.NewReader("x"), page)
+	if res.StatusCode() != http.StatusNotFound || res.BodyString() != "custom 404\n" {
+		t.Errorf("POST = %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestSPAMissingIndex(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(slog.DiscardHandler))
+	rt.SPA("/", fstest.MapFS{"a.js": {Data: []byte("app()")}}, "index.html")
+	if res := rt.Test("GET", "/settings", nil, WithTestHeader("Accept", "text/html")); res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("missing index = %d, want 500", res.StatusCode())
+	}
+}
+
+func TestStaticDirListing(t *testing.T) {
+	dir := staticDir(t, map[string]string{
+		"docs/index.html":           "<p>docs",
+		"docs/custom.htm":           "custom index",
+		"files/b.txt":               "bb",
+		"files/<script>alert(1).js": "x",
+		"files/sub/c.txt":           "c",
+	})
+	rt := NewRastaRouterInitializer()
+	rt.Static("/plain", dir)
+	rt.Static("/list", dir, StaticOptions{DirList: true})
+	rt.Static("/custom", dir, StaticOptions{Index: "custom.htm"})
+
+	if res := rt.Test("GET", "/list/docs/", nil); res.BodyString() != "<p>docs" {
+		t.Errorf("index with DirList = %q", res.BodyString())
+	}
+	if res := rt.Test("GET", "/custom/docs/", nil); res.BodyString() != "custom index" {
+		t.Errorf("custom Index = %q", res.BodyString())
+	}
+	if res := rt.Test("GET", "/plain/files/", nil); res.StatusCode() != http.StatusNotFound {
+		t.Errorf("listing off = %d, want 404", res.StatusCode())
+	}
+
+	res := rt.Test("GET", "/list/files", nil)
+	if res.StatusCode() != http.StatusMovedPermanently || res.Header("Location") != "./files/" {
+		t.Errorf("listing without a slash = %d Location %q", res.StatusCode(), res.Header("Location"))
+	}
+
+	res = rt.Test("GET", "/list/files/", nil)
+	body := res.BodyString()
+	if res.StatusCode() != http.StatusOK || res.Header("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("listing = %d, Content-Type %q", res.StatusCode(), res.Header("Content-Type"))
+	}
+	for _, want := range []string{"Index of /list/files/", `href="./b.txt"`, `href="./sub/"`, "sub/</a>", "&lt;script&gt;alert(1).js", `href="./%3Cscript%3Ealert%281%29.js"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing lacks %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("listing has an unescaped name:\n%s", body)
+	}
+	if strings.Index(body, "b.txt") > strings.Index(body, "sub/") {
+		t.Error("entries aren't sorted by name")
+	}
+}
+
+func TestStaticDirListTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("list").Parse(`{{.Path}}:{{range .Entries}} {{.Name}}={{.Size}}{{end}}`))
+	rt := NewRastaRouterInitializer()
+	rt.Static("/list", staticDir(t, map[string]string{"a.txt": "aaa", "<b>.txt": "b"}), StaticOptions{DirList: true, DirListTemplate: tmpl})
+
+	if res := rt.Test("GET", "/list/", nil); res.BodyString() != "/list/: &lt;b&gt;.txt=1 a.txt=3" {
+		t.Errorf("custom listing = %q", res.BodyString())
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	dir := staticDir(t, map[string]string{
+		"app.js":    "app()",
+		"app.js.gz": "gzip bytes",
+		"app.js.br": "brotli bytes",
+		"old.js":    "old()",
+		"old.js.gz": "old gzip",
+		"plain.css": "body{}",
+	})
+	rt := NewRastaRouterInitializer()
+	rt.Static("/assets", dir, StaticOptions{Precompressed: true})
+	rt.Static("/off", dir)
+
+	for name, tc := range map[string]struct {
+		target, accept, body, encoding string
+	}{
+		"brotli preferred":    {"/assets/app.js", "gzip, deflate, br", "brotli bytes", "br"},
+		"gzip only accepted":  {"/assets/app.js", "gzip", "gzip bytes", "gzip"},
+		"brotli refused":      {"/assets/app.js", "br;q=0, gzip", "gzip bytes", "gzip"},
+		"no variant accepted": {"/assets/app.js", "zstd", "app()", ""},
+		"no Accept-Encoding":  {"/assets/app.js", "", "app()", ""},
+		"only gzip on disk":   {"/assets/old.js", "br, gzip", "old gzip", "gzip"},
+		"uncompressed only":   {"/assets/plain.css", "br, gzip", "body{}", ""},
+		"option off":          {"/off/app.js", "br", "app()", ""},
+	} {
+		var opts []TestOption
+		if tc.accept != "" {
+			opts = append(opts, WithTestHeader("Accept-Encoding", tc.accept))
+		}
+		res := rt.Test("GET", tc.target, nil, opts...)
+		if res.StatusCode() != http.StatusOK || res.BodyString() != tc.body || res.Header("Content-Encoding") != tc.encoding {
+			t.Errorf("%s = %d %q, Content-Encoding %q", name, res.StatusCode(), res.BodyString(), res.Header("Content-Encoding"))
+		}
+		if ct := res.Header("Content-Type"); !strings.HasPrefix(ct, "text/javascript") && !strings.HasPrefix(ct, "text/css") {
+			t.Errorf("%s Content-Type = %q", name, ct)
+		}
+		if vary := res.Header("Vary"); (vary == "Accept-Encoding") != strings.HasPrefix(tc.target, "/assets/") {
+			t.Errorf("%s Vary = %q", name, vary)
+		}
+	}
+
+	// Each variant has its own validator, so a cached gzip body isn't revalidated as brotli
+	br := rt.Test("GET", "/assets/app.js", nil, WithTestHeader("Accept-Encoding", "br")).Header("ETag")
+	gz := rt.Test("GET", "/assets/app.js", nil, WithTestHeader("Accept-Encoding", "gzip")).Header("ETag")
+	plain := rt.Test("GET", "/assets/app.js", nil).Header("ETag")
+	if br == gz || gz == plain || br == plain || !strings.HasSuffix(br, `-br"`) {
+		t.Errorf("ETags br %s, gzip %s, plain %s", br, gz, plain)
+	}
+	res := rt.Test("GET", "/assets/app.js", nil, WithTestHeader("Accept-Encoding", "br"), WithTestHeader("If-None-Match", br))
+	if res.StatusCode() != http.StatusNotModified {
+		t.Errorf("If-None-Match on the variant = %d, want 304", res.StatusCode())
+	}
+	res = rt.Test("GET", "/assets/app.js", nil, WithTestHeader("Accept-Encoding", "gzip"), WithTestHeader("If-None-Match", br))
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "gzip bytes" {
+		t.Errorf("brotli ETag for a gzip response = %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestStaticImmutable(t *testing.T) {
+	dir := staticDir(t, map[string]string{
+		"index.html":          "<p>home",
+		"app.3f9ab2.js":       "app()",
+		"index-B1x2k9Qz.js":   "chunk()",
+		"logo.png":            "png",
+		"vendor/lib.v2-0.css": "lib",
+	})
+	rt := NewRastaRouterInitializer()
+	rt.Static("/default", dir)
+	rt.Static("/custom", dir, StaticOptions{
+		Immutable:    regexp.MustCompile(`\.png$`).MatchString,
+		CacheControl: "public, max-age=300",
+	})
+	const immutable = "public, max-age=31536000, immutable"
+
+	for target, want := range map[string]string{
+		"/default/app.3f9ab2.js":       immutable,
+		"/default/index-B1x2k9Qz.js":   immutable,
+		"/default/":                    "no-cache",
+		"/default/logo.png":            "no-cache",
+		"/default/vendor/lib.v2-0.css": "no-cache",
+		"/custom/logo.png":             immutable,
+		"/custom/app.3f9ab2.js":        "public, max-age=300",
+		"/custom/":                     "public, max-age=300",
+	} {
+		res := rt.Test("GET", target, nil)
+		if cc := res.Header("Cache-Control"); cc != want {
+			t.Errorf("%s Cache-Control = %q, want %q", target, cc, want)
+		}
+		if res.Header("ETag") == "" {
+			t.Errorf("%s has no ETag", target)
+		}
+	}
+}
+
+func TestHashedFilename(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app.3f9ab2.js":        true,
+		"app.3F9AB2C1.js":      true,
+		"index-B1x2k9Qz.js":    true,
+		"chunk.d41d8cd9.css":   true,
+		"index.html":           false,
+		"app.js":               false,
+		"jquery-3.7.1.min.js":  false,
+		"favicon.ico":          false,
+		"my-component.css":     false,
+		"decade.js":            false, // Hex letters but no digits
+		"a1b2c3":               false, // No extension
+		"report-2024.pdf":      false,
+		"3f9ab2.js":            false, // The name itself isn't a hash part
+		"vendor.abcdefgh12.js": true,
+	} {
+		if got := HashedFilename(name); got != want {
+			t.Errorf("HashedFilename(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
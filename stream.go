@@ -0,0 +1,104 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Errors returned by the streaming helpers
+var (
+	ErrStreamingUnsupported = errors.New("tobingo: response writer does not support flushing")
+	ErrClientGone           = errors.New("tobingo: client disconnected")
+)
+
+// streamWriter fails writes once the client has gone away so producers stop early
+type streamWriter struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	closing <-chan struct{} // Closed when the router closes long-lived responses
+	err     error           // First write or flush error, sticky
+}
+
+// Write forwards to the response unless the client disconnected or an earlier write failed
+func (s *streamWriter) Write(b []byte) (int, error) {
+	if s.err == nil {
+		s.checkClient()
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.w.Write(b)
+	if err != nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// flush sends buffered data to the client, recording failures for the next Write
+func (s *streamWriter) flush() {
+	if s.err == nil {
+		s.checkClient()
+	}
+	if s.err != nil {
+		return
+	}
+	if err := http.NewResponseController(s.w).Flush(); err != nil {
+		s.err = err
+	}
+}
+
+// checkClient records ErrClientGone once the request context is done, and ErrServerClosing
+// once the router closes long-lived responses
+func (s *streamWriter) checkClient() {
+	if err := s.r.Context().Err(); err != nil {
+		s.err = fmt.Errorf("%w: %w", ErrClientGone, err)
+		return
+	}
+	select {
+	case <-s.closing:
+		s.err = ErrServerClosing
+	default:
+	}
+}
+
+// Stream sends a 200 response with the given Content-Type whose body is produced by fn as it
+// goes, for exports and other long-running responses
+// fn writes to w and calls flush whenever the client should see what was written so far
+// The headers are flushed before fn runs, so a writer that can't flush, e.g. because a
+// middleware wrapper hides http.Flusher, fails with ErrStreamingUnsupported up front
+// Once the client disconnects writes fail and Stream returns an error matching ErrClientGone,
+// and once the router closes long-lived responses for shutdown one matching ErrServerClosing;
+// otherwise it returns the error of fn
+// Example: err := tobingo.Stream(w, r, "text/csv", func(w io.Writer, flush func()) error { ...; flush(); return nil })
+func Stream(w http.ResponseWriter, r *http.Request, contentType string, fn func(w io.Writer, flush func()) error) error {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	// Ask reverse proxies such as nginx not to buffer the stream
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	writeStatus(w, http.StatusOK)
+
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return ErrStreamingUnsupported
+		}
+		return err
+	}
+
+	s := &streamWriter{w: w, r: r, closing: RegisterLongLived(r)}
+	err := fn(s, s.flush)
+
+	// Report a disconnect even if fn swallowed the write error
+	if s.err == nil {
+		s.checkClient()
+	}
+	if errors.Is(s.err, ErrClientGone) || errors.Is(s.err, ErrServerClosing) {
+		return s.err
+	}
+	if err != nil {
+		return err
+	}
+	return s.err
+}
@@ -0,0 +1,118 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// hiddenFlusher stands in for a middleware wrapper that hides http.Flusher
+type hiddenFlusher struct {
+	http.ResponseWriter
+}
+
+func TestStream(t *testing.T) {
+	for name, mw := range map[string]Middleware{
+		"plain": nil,
+		"cache": Cache(time.Minute),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var streamErr error
+			rt := NewRastaRouterInitializer()
+			if mw != nil {
+				rt.Use(mw)
+			}
+			rt.GET("/export", func(w http.ResponseWriter, r *http.Request) {
+				streamErr = Stream(w, r, "text/csv", func(w io.Writer, flush func()) error {
+					if !rec.Flushed {
+						t.Error("headers not flushed before fn")
+					}
+					for i := range 3 {
+						fmt.Fprintf(w, "row %d\n", i)
+						flush()
+						if rec.Body.Len() == 0 {
+							t.Error("row not sent before the stream ended")
+						}
+					}
+					return nil
+				})
+			})
+			rt.ServeHTTP(rec, httptest.NewRequest("GET", "/export", nil))
+
+			if streamErr != nil {
+				t.Fatal(streamErr)
+			}
+			if rec.Code != http.StatusOK || rec.Body.String() != "row 0\nrow 1\nrow 2\n" {
+				t.Errorf("got %d %q", rec.Code, rec.Body.String())
+			}
+			if rec.Header().Get("Content-Type") != "text/csv" || rec.Header().Get("X-Accel-Buffering") != "no" {
+				t.Errorf("headers = %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestStreamFnError(t *testing.T) {
+	boom := errors.New("query failed")
+	var streamErr error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/export", func(w http.ResponseWriter, r *http.Request) {
+		streamErr = Stream(w, r, "text/csv", func(w io.Writer, flush func()) error { return boom })
+	})
+	rt.Test("GET", "/export", nil)
+	if !errors.Is(streamErr, boom) {
+		t.Errorf("err = %v, want the error of fn", streamErr)
+	}
+}
+
+func TestStreamUnsupported(t *testing.T) {
+	var streamErr error
+	called := false
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(hiddenFlusher{w}, r)
+		})
+	})
+	rt.GET("/export", func(w http.ResponseWriter, r *http.Request) {
+		streamErr = Stream(w, r, "text/csv", func(w io.Writer, flush func()) error {
+			called = true
+			return nil
+		})
+	})
+	rt.Test("GET", "/export", nil)
+	if !errors.Is(streamErr, ErrStreamingUnsupported) || called {
+		t.Errorf("err = %v, fn called %v", streamErr, called)
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var streamErr, writeErr error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/export", func(w http.ResponseWriter, r *http.Request) {
+		streamErr = Stream(w, r, "text/csv", func(w io.Writer, flush func()) error {
+			io.WriteString(w, "row 0\n")
+			flush()
+			cancel()
+			_, writeErr = io.WriteString(w, "row 1\n")
+			// Like many producers, ignore the write error
+			return nil
+		})
+	})
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("GET", "/export", nil).WithContext(ctx))
+
+	if !errors.Is(writeErr, ErrClientGone) || !errors.Is(streamErr, ErrClientGone) || !errors.Is(streamErr, context.Canceled) {
+		t.Errorf("write err = %v, stream err = %v", writeErr, streamErr)
+	}
+	if rec.Body.String() != "row 0\n" {
+		t.Errorf("body = %q, want only the row before the disconnect", rec.Body.String())
+	}
+}
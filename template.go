@@ -0,0 +1,116 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+)
+
+// ErrTemplateNotFound is returned by Render when no template with the given name was loaded
+var ErrTemplateNotFound = errors.New("tobingo: template not found")
+
+// templateSet holds the templates loaded with LoadTemplates and how to parse them again
+type templateSet struct {
+	fsys   fs.FS              // Filesystem the templates are read from
+	glob   string             // Pattern selecting the template files
+	funcs  template.FuncMap   // Functions available to every template
+	tmpl   *template.Template // Templates parsed at load time
+	reload bool               // Re-parse on every render, for development
+}
+
+// parse reads every template matching the glob into a single set so files can use each
+// other's define and block templates
+func (ts *templateSet) parse() (*template.Template, error) {
+	return template.New("").Funcs(ts.funcs).ParseFS(ts.fsys, ts.glob)
+}
+
+// LoadTemplates parses the files of fsys matching glob as html/template templates, named
+// after their base file name, and makes them available to Render
+// All files share one set, so layouts and partials composed with define, block, and template
+// can live in separate files
+// Returns the parse error, which typically should stop the program at startup
+// Example: err := rt.LoadTemplates(os.DirFS("views"), "*.html", template.FuncMap{"upper": strings.ToUpper})
+func (rt *Rastauter) LoadTemplates(fsys fs.FS, glob string, funcs template.FuncMap) error {
+	ts := &templateSet{fsys: fsys, glob: glob, funcs: funcs}
+	tmpl, err := ts.parse()
+	if err != nil {
+		return err
+	}
+	ts.tmpl = tmpl
+
+	// Serialized with SetTemplateReload so neither loses the other's change
+	rt.mu.Lock()
+	if old := rt.templates.Load(); old != nil {
+		ts.reload = old.reload
+	}
+	rt.templates.Store(ts)
+	rt.mu.Unlock()
+	return nil
+}
+
+// SetTemplateReload makes Render re-parse the templates on every call so edits show up
+// without a restart; use it in development only since parsing is slow
+// It may be changed while serving
+func (rt *Rastauter) SetTemplateReload(on bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	// Copy the set so requests rendering with the old one don't see it change
+	var ts templateSet
+	if old := rt.templates.Load(); old != nil {
+		ts = *old
+	}
+	ts.reload = on
+	rt.templates.Store(&ts)
+}
+
+// Render executes the named template loaded by the router serving r and writes the result
+// with the given status and Content-Type text/html
+// The template is executed into a buffer first, so a missing template or an execution error
+// produces a clean 500 response instead of a half-written page; the error is returned for logging
+// Example: return tobingo.Render(w, r, http.StatusOK, "user_show.html", user)
+func Render(w http.ResponseWriter, r *http.Request, status int, name string, data any) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := executeTemplate(buf, routerFrom(r), name, data); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	writeStatus(w, status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// executeTemplate looks up the named template of rt and executes it into buf
+func executeTemplate(buf *bytes.Buffer, rt *Rastauter, name string, data any) error {
+	var ts *templateSet
+	if rt != nil {
+		ts = rt.templates.Load()
+	}
+	if ts == nil || ts.fsys == nil {
+		return fmt.Errorf("%w: %q: no templates loaded", ErrTemplateNotFound, name)
+	}
+
+	tmpl := ts.tmpl
+	if ts.reload {
+		var err error
+		if tmpl, err = ts.parse(); err != nil {
+			return err
+		}
+	}
+
+	t := tmpl.Lookup(name)
+	if t == nil {
+		return fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return t.Execute(buf, data)
+}
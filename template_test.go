@@ -0,0 +1,113 @@
+package tobingo
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// templateRouter loads fsys and renders the template named by the :name param
+func templateRouter(t *testing.T, fsys fstest.MapFS, renderErr *error) *Rastauter {
+	t.Helper()
+	rt := NewRastaRouterInitializer()
+	if err := rt.LoadTemplates(fsys, "*.html", template.FuncMap{"upper": strings.ToUpper}); err != nil {
+		t.Fatal(err)
+	}
+	rt.GET("/:name", func(w http.ResponseWriter, r *http.Request) {
+		*renderErr = Render(w, r, http.StatusOK, GetParam(r, "name"), map[string]any{"Name": "<ann>"})
+	})
+	return rt
+}
+
+func TestRender(t *testing.T) {
+	var err error
+	rt := templateRouter(t, fstest.MapFS{
+		"layout.html": {Data: []byte(`{{define "layout"}}<main>{{block "body" .}}{{end}}</main>{{end}}`)},
+		"user.html":   {Data: []byte(`{{template "layout" .}}{{define "body"}}hi {{upper .Name}}{{end}}`)},
+	}, &err)
+
+	res := rt.Test("GET", "/user.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<main>hi &lt;ANN&gt;</main>"; res.StatusCode() != http.StatusOK || res.BodyString() != want {
+		t.Errorf("got %d %q, want 200 %q", res.StatusCode(), res.BodyString(), want)
+	}
+	if ct := res.Header("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestRenderMissingTemplate(t *testing.T) {
+	var err error
+	rt := templateRouter(t, fstest.MapFS{"user.html": {Data: []byte(`hi`)}}, &err)
+
+	res := rt.Test("GET", "/nope.html", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("err = %v, want ErrTemplateNotFound", err)
+	}
+	if res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("status = %d", res.StatusCode())
+	}
+}
+
+func TestRenderWithoutTemplates(t *testing.T) {
+	var err error
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		err = Render(w, r, http.StatusOK, "user.html", nil)
+	})
+	rt.Test("GET", "/", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("err = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestRenderRuntimeError(t *testing.T) {
+	var err error
+	rt := templateRouter(t, fstest.MapFS{
+		"broken.html": {Data: []byte(`<p>partial {{index .Name 99}}</p>`)},
+	}, &err)
+
+	res := rt.Test("GET", "/broken.html", nil)
+	if err == nil {
+		t.Fatal("execution error not returned")
+	}
+	// The page is buffered, so none of it reaches the client
+	if res.StatusCode() != http.StatusInternalServerError || strings.Contains(res.BodyString(), "partial") {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestRenderReload(t *testing.T) {
+	var err error
+	fsys := fstest.MapFS{"page.html": {Data: []byte(`v1`)}}
+	rt := templateRouter(t, fsys, &err)
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte(`v2`)}
+	if got := rt.Test("GET", "/page.html", nil).BodyString(); got != "v1" {
+		t.Errorf("without reload = %q, want the parsed v1", got)
+	}
+
+	rt.SetTemplateReload(true)
+	if got := rt.Test("GET", "/page.html", nil).BodyString(); got != "v2" {
+		t.Errorf("with reload = %q, want v2", got)
+	}
+
+	// Loading again keeps reload on
+	rt.LoadTemplates(fsys, "*.html", nil)
+	fsys["page.html"] = &fstest.MapFile{Data: []byte(`v3`)}
+	if got := rt.Test("GET", "/page.html", nil).BodyString(); got != "v3" {
+		t.Errorf("after LoadTemplates = %q, want v3", got)
+	}
+}
+
+func TestLoadTemplatesParseError(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	if err := rt.LoadTemplates(fstest.MapFS{"bad.html": {Data: []byte(`{{if}}`)}}, "*.html", nil); err == nil {
+		t.Error("parse error not returned")
+	}
+}
@@ -0,0 +1,149 @@
+package tobingo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// testRequest collects the settings applied by TestOption
+type testRequest struct {
+	header http.Header // Headers added to the request
+	host   string      // Host overriding the one in the target, "" to keep it
+	values []any       // Alternating context keys and values
+
+	overrides map[any]any // Router values replaced for the request, see WithTestProvided
+}
+
+// TestOption customizes the request built by Test
+type TestOption func(*testRequest)
+
+// WithTestHeader adds a request header; repeating a name adds further values
+func WithTestHeader(name, value string) TestOption {
+	return func(tr *testRequest) {
+		tr.header.Add(name, value)
+	}
+}
+
+// WithTestHost sets the request's Host, "example.com" unless the target is an absolute URL
+func WithTestHost(host string) TestOption {
+	return func(tr *testRequest) {
+		tr.host = host
+	}
+}
+
+// WithTestValue stores val under key in the request context, as a caller of the router
+// such as an outer middleware would
+func WithTestValue(key, val any) TestOption {
+	return func(tr *testRequest) {
+		tr.values = append(tr.values, key, val)
+	}
+}
+
+// TestResponse is the response recorded by Test
+type TestResponse struct {
+	Recorder *httptest.ResponseRecorder // Underlying recorder, for anything the helpers don't cover
+}
+
+// Test dispatches a request through the router in-process, running the full ServeHTTP path
+// with middleware, and returns the recorded response
+// target is a path such as "/users/42?page=2" or an absolute URL; a nil body sends none
+// Example: res := rt.Test("POST", "/users", strings.NewReader(`{"name":"Ada"}`), tobingo.WithTestHeader("Content-Type", "application/json"))
+func (rt *Rastauter) Test(method, target string, body io.Reader, opts ...TestOption) *TestResponse {
+	tr := testRequest{header: make(http.Header)}
+	for _, opt := range opts {
+		opt(&tr)
+	}
+
+	req := httptest.NewRequest(method, target, body)
+	for name, values := range tr.header {
+		req.Header[name] = append(req.Header[name], values...)
+	}
+	if tr.host != "" {
+		req.Host = tr.host
+	}
+	if len(tr.values) > 0 {
+		ctx := req.Context()
+		for i := 0; i < len(tr.values); i += 2 {
+			ctx = context.WithValue(ctx, tr.values[i], tr.values[i+1])
+		}
+		req = req.WithContext(ctx)
+	}
+
+	rec := httptest.NewRecorder()
+	if tr.overrides == nil {
+		rt.ServeHTTP(rec, req)
+		return &TestResponse{Recorder: rec}
+	}
+
+	// Replacing router values needs the chain ServeHTTP would pick, served with them
+	var h http.Handler = http.HandlerFunc(rt.dispatch)
+	if chain := rt.handler.Load(); chain != nil {
+		h = *chain
+	}
+	rt.serve(rec, req, h, tr.overrides)
+	return &TestResponse{Recorder: rec}
+}
+
+// StatusCode returns the status of the response
+func (res *TestResponse) StatusCode() int {
+	return res.Recorder.Code
+}
+
+// Header returns the first value of the named response header, "" when absent
+func (res *TestResponse) Header(name string) string {
+	return res.Recorder.Header().Get(name)
+}
+
+// BodyString returns the response body as a string
+func (res *TestResponse) BodyString() string {
+	return res.Recorder.Body.String()
+}
+
+// JSON decodes the response body into v, failing on malformed JSON or trailing data
+// The body is left intact for BodyString
+// Example: var user User; if err := res.JSON(&user); err != nil { t.Fatal(err) }
+func (res *TestResponse) JSON(v any) error {
+	dec := json.NewDecoder(bytes.NewReader(res.Recorder.Body.Bytes()))
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("tobingo: decoding %d response: %w", res.Recorder.Code, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("tobingo: decoding %d response: unexpected data after the JSON value", res.Recorder.Code)
+	}
+	return nil
+}
+
+// InvokeMiddleware runs mw around inner the way the router would for a request matching
+// pattern with the given path parameters, for unit tests of middleware
+// Like during ServeHTTP the middleware sees the router's context and response recorder, the
+// parameters and MatchedRoute become visible to inner and to mw once its next handler returns,
+// and panics are recovered; params need not match req's path, a nil map means none
+// Example: res := tobingo.InvokeMiddleware(auth, httptest.NewRequest("GET", "/users/7", nil), h, "/users/:id", map[string]string{"id": "7"})
+func InvokeMiddleware(mw Middleware, req *http.Request, inner http.HandlerFunc, pattern string, params map[string]string) *TestResponse {
+	rt := NewRastaRouterInitializer()
+	route := &Route{Method: req.Method, Path: pattern, Handler: inner, rt: rt}
+
+	// The catch-all capture is also stored on its own, as dispatch does
+	var wildcardName string
+	if i := strings.LastIndex(pattern, "/*"); i >= 0 {
+		wildcardName = pattern[i+2:]
+	}
+	params = maps.Clone(params)
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	dispatch := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt.serveRoute(w, r, route, params, wildcardName)
+	})
+	rec := httptest.NewRecorder()
+	rt.serve(rec, req, mw(dispatch), nil)
+	return &TestResponse{Recorder: rec}
+}
@@ -0,0 +1,154 @@
+package tobingo
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTestParamRouteAndMiddleware(t *testing.T) {
+	var order []string
+	rt := NewRastaRouterInitializer()
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "middleware")
+			w.Header().Set("X-Middleware", "ran")
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.addRoute(http.MethodPost, "/users/:id/posts", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+		body, _ := io.ReadAll(r.Body)
+		JSON(w, http.StatusCreated, map[string]string{"id": GetParam(r, "id"), "page": Query(r, "page"), "body": string(body)})
+	})
+
+	res := rt.Test("POST", "/users/42/posts?page=2", strings.NewReader("hello"))
+	if res.StatusCode() != http.StatusCreated || res.Header("X-Middleware") != "ran" || strings.Join(order, ",") != "middleware,handler" {
+		t.Errorf("got %d, X-Middleware %q, order %v", res.StatusCode(), res.Header("X-Middleware"), order)
+	}
+	var got map[string]string
+	if err := res.JSON(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != "42" || got["page"] != "2" || got["body"] != "hello" {
+		t.Errorf("handler saw %v", got)
+	}
+	if !strings.HasPrefix(res.BodyString(), `{"body":"hello"`) {
+		t.Errorf("JSON consumed the body, BodyString = %q", res.BodyString())
+	}
+}
+
+func TestTestResponseJSONErrors(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/:body", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, map[string]string{"bad": `{"a":`, "trailing": `{"a":1} {"b":2}`, "text": "Internal Server Error"}[GetParam(r, "body")])
+	})
+
+	var v map[string]int
+	for _, target := range []string{"/bad", "/trailing", "/text"} {
+		err := rt.Test("GET", target, nil).JSON(&v)
+		if err == nil || !strings.Contains(err.Error(), "decoding 200 response") {
+			t.Errorf("%s: err = %v", target, err)
+		}
+	}
+}
+
+func TestTestOptions(t *testing.T) {
+	type ctxKey string
+	var header http.Header
+	var host string
+	var value any
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		header, host, value = r.Header, r.Host, r.Context().Value(ctxKey("tenant"))
+	})
+
+	rt.Test("GET", "/", nil,
+		WithTestHeader("Accept", "text/html"),
+		WithTestHeader("X-Tag", "a"), WithTestHeader("X-Tag", "b"),
+		WithTestHost("api.example.com"),
+		WithTestValue(ctxKey("tenant"), "acme"))
+	if header.Get("Accept") != "text/html" || strings.Join(header.Values("X-Tag"), ",") != "a,b" {
+		t.Errorf("header = %v", header)
+	}
+	if host != "api.example.com" || value != "acme" {
+		t.Errorf("host %q, value %v", host, value)
+	}
+
+	rt.Test("GET", "https://shop.example.org/", nil)
+	if host != "shop.example.org" || value != nil {
+		t.Errorf("absolute target: host %q, value %v", host, value)
+	}
+	rt.Test("GET", "/", nil)
+	if host != "example.com" {
+		t.Errorf("default host = %q", host)
+	}
+}
+
+func TestInvokeMiddleware(t *testing.T) {
+	var before, after string
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			before = MatchedPattern(r) + " " + GetParam(r, "id")
+			w.Header().Set("X-Auth", "ok")
+			next.ServeHTTP(w, r)
+			info, _ := MatchedRoute(r)
+			after = fmt.Sprintf("%s %s %d", info.Pattern, GetParam(r, "id"), Recorder(r).Status())
+		})
+	}
+	var inner string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		inner = MatchedPattern(r) + " " + GetParam(r, "id")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "user "+GetParam(r, "id"))
+	}
+
+	req := httptest.NewRequest("GET", "/users/7", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	res := InvokeMiddleware(auth, req, handler, "/users/:id", map[string]string{"id": "7"})
+	if res.StatusCode() != http.StatusAccepted || res.BodyString() != "user 7" || res.Header("X-Auth") != "ok" {
+		t.Errorf("got %d %q, X-Auth %q", res.StatusCode(), res.BodyString(), res.Header("X-Auth"))
+	}
+	if inner != "/users/:id 7" || after != "/users/:id 7 202" {
+		t.Errorf("inner saw %q, middleware after next %q", inner, after)
+	}
+	if before != " " {
+		t.Errorf("middleware before next saw %q, want nothing as when wrapping the router", before)
+	}
+
+	// A rejected request never reaches the inner handler
+	inner = ""
+	res = InvokeMiddleware(auth, httptest.NewRequest("GET", "/users/7", nil), handler, "/users/:id", nil)
+	if res.StatusCode() != http.StatusUnauthorized || inner != "" {
+		t.Errorf("unauthorized = %d, inner ran: %v", res.StatusCode(), inner != "")
+	}
+}
+
+func TestInvokeMiddlewareWildcardAndPanics(t *testing.T) {
+	identity := func(next http.Handler) http.Handler { return next }
+	var rest string
+	res := InvokeMiddleware(identity, httptest.NewRequest("GET", "/files/a/b.txt", nil), func(w http.ResponseWriter, r *http.Request) {
+		rest, _ = GetWildcard(r)
+	}, "/files/*path", map[string]string{"path": "a/b.txt"})
+	if res.StatusCode() != http.StatusOK || rest != "a/b.txt" {
+		t.Errorf("got %d, wildcard %q", res.StatusCode(), rest)
+	}
+
+	// The harness has its own router, which logs to the default logger
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	res = InvokeMiddleware(identity, httptest.NewRequest("GET", "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}, "/", nil)
+	if res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("panic = %d, want 500", res.StatusCode())
+	}
+}
@@ -0,0 +1,271 @@
+package tobingo
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestTimeout sets the deadline for the handlers of every route, which a route can override
+// with Timeout or NoTimeout; zero, the default, means none
+// The deadline is attached to the request context before the handler runs, and if the handler
+// hasn't sent the status when it passes, 504 is written through the error handler right away
+// while the handler's later writes fail with http.ErrHandlerTimeout, like http.TimeoutHandler;
+// handlers should still give up when the context is done, as they keep running until they return
+// It may be changed while serving
+func (rt *Rastauter) RequestTimeout(d time.Duration) {
+	rt.requestTimeout.Store(int64(max(d, 0)))
+}
+
+// Timeout overrides the router's RequestTimeout for this route, e.g. for a slow report
+// An earlier deadline already on the request context, such as one set by a caller, still
+// wins; a d of zero or less is the same as NoTimeout
+// Example: rt.GET("/reports/:id", generateReport).Timeout(2 * time.Minute)
+func (route *Route) Timeout(d time.Duration) *Route {
+	if d <= 0 {
+		return route.NoTimeout()
+	}
+	route.timeout = d
+	return route
+}
+
+// NoTimeout exempts the route from the router's RequestTimeout, e.g. for streams or websockets
+func (route *Route) NoTimeout() *Route {
+	route.timeout = -1
+	return route
+}
+
+// EffectiveTimeout returns the deadline applied to the route's handler, 0 when there is none
+func (route *Route) EffectiveTimeout() time.Duration {
+	switch {
+	case route.timeout < 0:
+		return 0
+	case route.timeout > 0:
+		return route.timeout
+	case route.rt != nil:
+		return time.Duration(route.rt.requestTimeout.Load())
+	}
+	return 0
+}
+
+// serveWithTimeout runs h with a deadline d from now, answering 504 if it passes before the
+// handler has sent the status without waiting for a handler that ignores the context
+// Panics of h are raised again here so the router recovers them as usual
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, d time.Duration, h http.HandlerFunc) {
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{w: w, ctx: ctx, header: w.Header().Clone()}
+
+	// The handler tracks its response in a recorder of its own, which Recorder returns too,
+	// since the 504 may go through the router's while the handler still runs
+	rec := &responseWriter{ResponseWriter: exposeFeatures(tw, writerFeatures(w)), req: r}
+	if rw := trackedWriter(w); rw != nil {
+		rec.status, rec.implicitStatus = rw.status, rw.implicitStatus
+	}
+	hr := r.WithContext(context.WithValue(ctx, recorderKey, rec))
+
+	returned := make(chan any, 1)
+	go func() {
+		// nil when the handler returned, the recovered value when it panicked
+		var recovered any
+		defer func() {
+			if tw.handlerReturned() {
+				returned <- recovered
+			} else if recovered != nil {
+				loggerFor(hr).Error("tobingo: handler panicked after its deadline", "method", hr.Method, "path", hr.URL.Path, "panic", recovered)
+			}
+		}()
+		defer func() { recovered = recover() }()
+		h(exposeFeatures(rec, writerFeatures(w)), hr)
+	}()
+
+	var recovered any
+	select {
+	case recovered = <-returned:
+	case <-ctx.Done():
+		if tw.abandon(func() { handleError(w, r, http.StatusGatewayTimeout, http.ErrHandlerTimeout) }) {
+			return
+		}
+		// The handler answered in time, or the client went away: the response is still its own
+		recovered = <-returned
+	}
+	if recovered != nil {
+		panic(recovered)
+	}
+	if tw.finish() {
+		handleError(w, r, http.StatusGatewayTimeout, http.ErrHandlerTimeout)
+	}
+}
+
+// timeoutWriter fails the handler's writes once the deadline of ctx has passed, the same
+// moment the handler sees ctx done; until the status is sent, headers go to a private map so
+// the 504 written afterwards doesn't carry what the handler set
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	ctx context.Context // Request context carrying the deadline
+
+	mu          sync.Mutex
+	header      http.Header // Headers set before the status was sent
+	wroteHeader bool        // Whether the handler sent a final status, after which the deadline no longer applies
+	timedOut    bool        // Whether the deadline passed first, after which writes fail
+	hijacked    bool        // Whether the handler took over the connection
+	returned    bool        // Whether the handler returned or panicked, leaving the 504 to finish
+	abandoned   bool        // Whether the 504 was sent while the handler still ran
+}
+
+// expiredLocked reports whether the deadline passed before the handler answered, marking
+// the response timed out when it just did; the caller must hold mu
+func (tw *timeoutWriter) expiredLocked() bool {
+	if !tw.timedOut && !tw.wroteHeader && !tw.hijacked && tw.ctx.Err() == context.DeadlineExceeded {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// abandon sends the 504 with send when the handler, still running, hadn't answered before
+// the deadline, holding mu so none of its writes slip in; reports whether it did
+func (tw *timeoutWriter) abandon(send func()) bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.returned || !tw.expiredLocked() {
+		return false
+	}
+	tw.abandoned = true
+	send()
+	return true
+}
+
+// handlerReturned records that the handler is done, reporting whether the serving goroutine
+// still waits for it rather than having answered 504 already
+func (tw *timeoutWriter) handlerReturned() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.returned = true
+	return !tw.abandoned
+}
+
+// finish reports, once the handler returned, whether 504 is owed
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.expiredLocked()
+}
+
+// Header returns the private header map until the status is sent, the real one afterwards so
+// trailers still reach the client
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return tw.w.Header()
+	}
+	return tw.header
+}
+
+// WriteHeader copies the headers over and sends the status unless the deadline passed first
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.expiredLocked() {
+		tw.writeHeaderLocked(code)
+	}
+}
+
+// writeHeaderLocked sends the status; the caller must hold mu
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if !tw.wroteHeader {
+		tw.commitHeader()
+		tw.wroteHeader = code >= 200 || code == http.StatusSwitchingProtocols
+	}
+	tw.w.WriteHeader(code)
+}
+
+// commitHeader replaces the real headers with the private ones, deletions included
+func (tw *timeoutWriter) commitHeader() {
+	h := tw.w.Header()
+	clear(h)
+	maps.Copy(h, tw.header)
+}
+
+// Write sends b, committing an implicit 200 first, or fails once the deadline passed
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// Flush commits the response and flushes it, keeping http.Flusher available
+func (tw *timeoutWriter) Flush() {
+	tw.FlushError()
+}
+
+// FlushError flushes like Flush and reports why it couldn't
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return http.NewResponseController(tw.w).Flush()
+}
+
+// Push initiates an HTTP/2 server push when the underlying writer supports it
+func (tw *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	return pushTo(tw.w, target, opts)
+}
+
+// ReadFrom commits the response and copies src into it, through the underlying writer's
+// io.ReaderFrom when it has one; the deadline no longer applies once the status is sent
+func (tw *timeoutWriter) ReadFrom(src io.Reader) (int64, error) {
+	tw.mu.Lock()
+	if tw.expiredLocked() {
+		tw.mu.Unlock()
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	tw.mu.Unlock()
+
+	if rf, ok := tw.w.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(writerOnly{tw.w}, src)
+}
+
+// Unwrap returns the underlying ResponseWriter, so trackedWriter and http.ResponseController
+// find what's beneath
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// Hijack hands over the connection, after which the deadline no longer applies
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, brw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil {
+		tw.hijacked = true
+		tw.commitHeader()
+	}
+	return conn, brw, err
+}
@@ -0,0 +1,255 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutAnswers504(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(20 * time.Millisecond)
+
+	writeErr := make(chan error, 1)
+	rt.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Handler", "late")
+		_, err := w.Write([]byte("too late"))
+		writeErr <- err
+	})
+
+	res := rt.Test("GET", "/slow", nil)
+	if res.StatusCode() != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", res.StatusCode())
+	}
+	if res.Header("X-Handler") != "" {
+		t.Error("headers set by the timed out handler reached the client")
+	}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late Write error = %v, want http.ErrHandlerTimeout", err)
+	}
+}
+
+func TestRequestTimeoutFastHandler(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(time.Second)
+	rt.GET("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "yes")
+		w.Write([]byte("done"))
+	})
+
+	res := rt.Test("GET", "/fast", nil)
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "done" || res.Header("X-Handler") != "yes" {
+		t.Fatalf("got %d %q, want the handler's response", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestRequestTimeoutCommittedResponseStands(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(20 * time.Millisecond)
+	rt.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		<-r.Context().Done()
+	})
+
+	if res := rt.Test("GET", "/stream", nil); res.StatusCode() != http.StatusAccepted {
+		t.Fatalf("status = %d, want the 202 sent before the deadline", res.StatusCode())
+	}
+}
+
+func TestRouteTimeoutOverrides(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(10 * time.Millisecond)
+	sleep := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}
+	rt.GET("/report", sleep).Timeout(time.Second)
+	rt.GET("/ws", sleep).NoTimeout()
+
+	for _, path := range []string{"/report", "/ws"} {
+		if res := rt.Test("GET", path, nil); res.StatusCode() != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", path, res.StatusCode())
+		}
+	}
+	if d := rt.routes[1].EffectiveTimeout(); d != 0 {
+		t.Errorf("NoTimeout route has EffectiveTimeout %v", d)
+	}
+}
+
+func TestRouteTimeoutShorterThanGlobal(t *testing.T) {
+	// Guarded since the 504 doesn't wait for the handler
+	var mu sync.Mutex
+	deadlines := map[string]time.Duration{}
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(time.Minute)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if deadline, ok := r.Context().Deadline(); ok {
+			mu.Lock()
+			deadlines[r.URL.Path] = time.Until(deadline)
+			mu.Unlock()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+			w.Write([]byte("late"))
+		}
+	}
+	rt.GET("/quick", handler).Timeout(10 * time.Millisecond)
+	rt.GET("/default", func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		deadlines[r.URL.Path] = time.Until(deadline)
+	})
+	rt.GET("/stream", handler).NoTimeout()
+
+	if res := rt.Test("GET", "/quick", nil); res.StatusCode() != http.StatusGatewayTimeout || res.BodyString() == "late" {
+		t.Errorf("/quick = %d %q, want 504", res.StatusCode(), res.BodyString())
+	}
+	mu.Lock()
+	if d := deadlines["/quick"]; d <= 0 || d > 10*time.Millisecond {
+		t.Errorf("/quick deadline %v ahead, want the route's 10ms", d)
+	}
+	mu.Unlock()
+	rt.Test("GET", "/default", nil)
+	if d := deadlines["/default"]; d < 59*time.Second || d > time.Minute {
+		t.Errorf("/default deadline %v ahead, want the router's minute", d)
+	}
+	if res := rt.Test("GET", "/stream", nil); res.StatusCode() != http.StatusOK || res.BodyString() != "late" {
+		t.Errorf("/stream = %d %q", res.StatusCode(), res.BodyString())
+	}
+	if _, ok := deadlines["/stream"]; ok {
+		t.Error("NoTimeout route got a deadline")
+	}
+
+	for i, want := range []time.Duration{10 * time.Millisecond, time.Minute, 0} {
+		if d := rt.routes[i].EffectiveTimeout(); d != want {
+			t.Errorf("%s: EffectiveTimeout = %v, want %v", rt.routes[i].Path, d, want)
+		}
+	}
+	if s := rt.String(); !strings.Contains(s, "GET /quick timeout=10ms") || !strings.Contains(s, "GET /default timeout=1m0s") || strings.Contains(s, "/stream timeout") {
+		t.Errorf("String =\n%s", s)
+	}
+}
+
+// A deadline already on the request context wins over a longer route timeout
+func TestRouteTimeoutEarlierCallerDeadline(t *testing.T) {
+	var left time.Duration
+	rt := NewRastaRouterInitializer()
+	rt.GET("/report", func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		left = time.Until(deadline)
+	}).Timeout(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/report", nil).WithContext(ctx))
+	if left <= 0 || left > time.Second {
+		t.Errorf("deadline %v ahead, want the caller's second", left)
+	}
+}
+
+// The handler polls the recorder while the deadline passes; run with -race
+func TestRequestTimeoutDoesNotRaceHandler(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(5 * time.Millisecond)
+	rt.GET("/poll", func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(30 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			if Recorder(r).Written() {
+				return
+			}
+			writeStatus(w, http.StatusOK)
+		}
+	})
+
+	for range 20 {
+		rt.Test("GET", "/poll", nil)
+	}
+}
+
+// A handler stuck in a call that ignores the context, such as a slow query, still gets its
+// client answered at the deadline
+func TestRequestTimeoutDoesNotWaitForHandler(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.RequestTimeout(50 * time.Millisecond)
+
+	release := make(chan struct{})
+	writeErr := make(chan error, 1)
+	rt.GET("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("X-Handler", "late")
+		_, err := w.Write([]byte("too late"))
+		writeErr <- err
+		RegisterLongLived(r)
+	})
+	defer close(release)
+
+	start := time.Now()
+	res := rt.Test("GET", "/stuck", nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("answered after %v, want near the 50ms deadline", elapsed)
+	}
+	if res.StatusCode() != http.StatusGatewayTimeout || res.Header("X-Handler") != "" {
+		t.Fatalf("got %d %q, want a bare 504", res.StatusCode(), res.Header("X-Handler"))
+	}
+
+	release <- struct{}{}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late Write error = %v, want http.ErrHandlerTimeout", err)
+	}
+	if err := rt.CloseLongLived(context.Background()); err != nil {
+		t.Errorf("CloseLongLived = %v, want nothing left registered", err)
+	}
+}
+
+// Panics are recovered by the router as usual, before the deadline, and logged after it
+func TestRequestTimeoutPanics(t *testing.T) {
+	logs := newRecordHandler()
+	rt := NewRastaRouterInitializer()
+	rt.SetLogger(slog.New(logs))
+	rt.RequestTimeout(50 * time.Millisecond)
+
+	rt.GET("/early", func(w http.ResponseWriter, r *http.Request) { panic("early") })
+	if res := rt.Test("GET", "/early", nil); res.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("early panic: status %d, want 500", res.StatusCode())
+	}
+	if msg := panicMessage(func() {
+		rt.GET("/abort", func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })
+		rt.Test("GET", "/abort", nil)
+	}); !strings.Contains(msg, http.ErrAbortHandler.Error()) {
+		t.Errorf("ErrAbortHandler panic = %q, want it raised to the server", msg)
+	}
+
+	release, done := make(chan struct{}), make(chan struct{})
+	rt.GET("/late", func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		<-release
+		panic("late")
+	})
+	if res := rt.Test("GET", "/late", nil); res.StatusCode() != http.StatusGatewayTimeout {
+		t.Errorf("late panic: status %d, want 504", res.StatusCode())
+	}
+	close(release)
+	<-done
+	// Logged as the handler's goroutine unwinds, just after done is closed
+	deadline := time.Now().Add(time.Second)
+	for {
+		records := logs.all()
+		if n := len(records); n > 0 && records[n-1].message == "tobingo: handler panicked after its deadline" {
+			if records[n-1].attrs["panic"] != "late" || records[n-1].attrs["path"] != "/late" {
+				t.Errorf("record = %+v", records[n-1])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("records = %+v, want the late panic", records)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,121 @@
+package tobingo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// traceKey is the context key under which the trace context of a request is stored
+const traceKey contextKey = "trace"
+
+// traceContext is the W3C trace context of a request
+type traceContext struct {
+	traceID  string // 32 lowercase hex digits shared by every span of the trace
+	spanID   string // 16 lowercase hex digits identifying this server's span
+	parentID string // Span ID of the caller, "" when the trace started here
+	flags    byte   // Trace flags, bit 0 is "sampled"
+	state    string // Vendor-specific tracestate, passed on unchanged
+}
+
+// TraceContext returns middleware implementing W3C Trace Context propagation
+// A valid traceparent header continues the caller's trace with a new span ID; a missing or
+// malformed one starts a new trace, dropping tracestate as the spec requires
+// The IDs are available through TraceID, SpanID, and TraceParent, are added to access logs
+// and LogWith loggers, and are returned to the client in a traceresponse header
+// Example: rt.Use(tobingo.TraceContext(), tobingo.Logger())
+func TraceContext() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc, ok := parseTraceparent(r.Header.Get("traceparent"))
+			if ok {
+				tc.state = strings.Join(r.Header.Values("tracestate"), ",")
+			} else {
+				tc = traceContext{traceID: randomHex(16)}
+			}
+			tc.spanID = randomHex(8)
+
+			w.Header().Set("traceresponse", tc.header())
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), traceKey, &tc)))
+		})
+	}
+}
+
+// parseTraceparent parses a traceparent header, "version-traceid-parentid-flags"
+// Versions after 00 may append fields, which are ignored; version ff and all-zero IDs are invalid
+func parseTraceparent(header string) (traceContext, bool) {
+	header = strings.TrimSpace(header)
+	if len(header) < 55 || (len(header) > 55 && (header[:2] == "00" || header[55] != '-')) {
+		return traceContext{}, false
+	}
+
+	version, traceID, parentID, flags := header[:2], header[3:35], header[36:52], header[53:55]
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return traceContext{}, false
+	}
+	if !isLowerHex(version) || version == "ff" || !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) {
+		return traceContext{}, false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return traceContext{}, false
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return traceContext{traceID: traceID, parentID: parentID, flags: b[0]}, true
+}
+
+// isLowerHex reports whether s consists of lowercase hex digits only, as trace context requires
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes as lowercase hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// header formats the trace context as a version 00 traceparent value for this server's span
+func (tc *traceContext) header() string {
+	return "00-" + tc.traceID + "-" + tc.spanID + "-" + hex.EncodeToString([]byte{tc.flags & 1})
+}
+
+// traceFrom returns the trace context of r, or nil without the TraceContext middleware
+func traceFrom(r *http.Request) *traceContext {
+	tc, _ := r.Context().Value(traceKey).(*traceContext)
+	return tc
+}
+
+// TraceID returns the W3C trace ID of the request, "" without the TraceContext middleware
+func TraceID(r *http.Request) string {
+	if tc := traceFrom(r); tc != nil {
+		return tc.traceID
+	}
+	return ""
+}
+
+// SpanID returns the span ID this server uses for the request, "" without the TraceContext middleware
+func SpanID(r *http.Request) string {
+	if tc := traceFrom(r); tc != nil {
+		return tc.spanID
+	}
+	return ""
+}
+
+// TraceParent returns the traceparent and tracestate values to send on outgoing requests
+// so downstream services join the trace as children of this request's span
+// Example: p, s := tobingo.TraceParent(r); out.Header.Set("traceparent", p); if s != "" { out.Header.Set("tracestate", s) }
+func TraceParent(r *http.Request) (traceparent, tracestate string) {
+	if tc := traceFrom(r); tc != nil {
+		return tc.header(), tc.state
+	}
+	return "", ""
+}
@@ -0,0 +1,109 @@
+package tobingo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// traceSeen is what a handler behind TraceContext observed
+type traceSeen struct {
+	traceID, spanID, parent, state string
+}
+
+// traceRouter serves / behind TraceContext and a JSON access log, recording what the handler saw
+func traceRouter(seen *traceSeen, log *bytes.Buffer) *Rastauter {
+	rt := NewRastaRouterInitializer()
+	rt.Use(TraceContext(), Logger(LoggerOptions{Output: log, Format: JSONLogFormat}))
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		seen.traceID, seen.spanID = TraceID(r), SpanID(r)
+		seen.parent, seen.state = TraceParent(r)
+	})
+	return rt
+}
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceContextValid(t *testing.T) {
+	var seen traceSeen
+	var log bytes.Buffer
+	rt := traceRouter(&seen, &log)
+
+	res := rt.Test("GET", "/", nil, WithTestHeader("traceparent", validTraceparent), WithTestHeader("tracestate", "vendor=abc"))
+	if seen.traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q, want the caller's", seen.traceID)
+	}
+	if len(seen.spanID) != 16 || seen.spanID == "00f067aa0ba902b7" || !isLowerHex(seen.spanID) {
+		t.Errorf("SpanID = %q, want a new span", seen.spanID)
+	}
+	if want := "00-" + seen.traceID + "-" + seen.spanID + "-01"; seen.parent != want || res.Header("traceresponse") != want {
+		t.Errorf("TraceParent = %q, traceresponse %q, want %q", seen.parent, res.Header("traceresponse"), want)
+	}
+	if seen.state != "vendor=abc" {
+		t.Errorf("tracestate = %q", seen.state)
+	}
+
+	var line jsonLogLine
+	json.Unmarshal(log.Bytes(), &line)
+	if line.TraceID != seen.traceID || line.SpanID != seen.spanID {
+		t.Errorf("access log trace %q span %q", line.TraceID, line.SpanID)
+	}
+}
+
+func TestTraceContextMalformed(t *testing.T) {
+	for _, header := range []string{
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", // Uppercase
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // Zero trace ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", // Zero parent ID
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", // Forbidden version
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+		"garbage",
+	} {
+		var seen traceSeen
+		var log bytes.Buffer
+		rt := traceRouter(&seen, &log)
+		res := rt.Test("GET", "/", nil, WithTestHeader("traceparent", header), WithTestHeader("tracestate", "vendor=abc"))
+
+		if seen.traceID == "4bf92f3577b34da6a3ce929d0e0e4736" || len(seen.traceID) != 32 || !isLowerHex(seen.traceID) {
+			t.Errorf("%q: TraceID = %q, want a new trace", header, seen.traceID)
+		}
+		if seen.state != "" || !strings.HasSuffix(res.Header("traceresponse"), "-00") {
+			t.Errorf("%q: tracestate %q, traceresponse %q", header, seen.state, res.Header("traceresponse"))
+		}
+	}
+}
+
+func TestTraceContextFutureVersion(t *testing.T) {
+	var seen traceSeen
+	rt := traceRouter(&seen, new(bytes.Buffer))
+	rt.Test("GET", "/", nil, WithTestHeader("traceparent", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-future"))
+	if seen.traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || !strings.HasSuffix(seen.parent, "-01") {
+		t.Errorf("future version: TraceID %q, TraceParent %q", seen.traceID, seen.parent)
+	}
+}
+
+func TestTraceContextAbsent(t *testing.T) {
+	var first, second traceSeen
+	rt := traceRouter(&first, new(bytes.Buffer))
+	res := rt.Test("GET", "/", nil)
+	if len(first.traceID) != 32 || len(first.spanID) != 16 || res.Header("traceresponse") != first.parent {
+		t.Errorf("seen %+v, traceresponse %q", first, res.Header("traceresponse"))
+	}
+	traceRouter(&second, new(bytes.Buffer)).Test("GET", "/", nil)
+	if first.traceID == second.traceID {
+		t.Error("two requests started the same trace")
+	}
+
+	// Without the middleware the accessors report nothing
+	rt = NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		if p, s := TraceParent(r); TraceID(r) != "" || SpanID(r) != "" || p != "" || s != "" {
+			t.Error("trace context without the middleware")
+		}
+	})
+	rt.Test("GET", "/", nil)
+}
@@ -0,0 +1,281 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultUpgradeReadyTimeout is how long StartServerUpgradable waits for the new process
+// to become ready when UpgradableOptions.ReadyTimeout is not set
+const DefaultUpgradeReadyTimeout = time.Minute
+
+// Environment variables telling a process started by an upgrade which descriptors it inherited
+const (
+	upgradeListenerEnv = "TOBINGO_UPGRADE_LISTENER_FD" // The listener, absent with ReusePort
+	upgradeReadyEnv    = "TOBINGO_UPGRADE_READY_FD"    // The pipe readiness is written to
+)
+
+// upgradeReadyByte is what the new process writes to the readiness pipe once it serves
+const upgradeReadyByte = 'R'
+
+// ErrUpgradeUnsupported is returned by StartServerUpgradable on platforms where listeners
+// can't be handed to a new process
+var ErrUpgradeUnsupported = errors.New("tobingo: upgrades are not supported on this platform")
+
+// UpgradableOptions configures StartServerUpgradable
+// Zero values fall back to the defaults noted on each field
+type UpgradableOptions struct {
+	Signal       os.Signal     // Signal starting an upgrade, SIGUSR2 by default
+	ReusePort    bool          // Have both processes bind with SO_REUSEPORT instead of passing the listener
+	ReadyTimeout time.Duration // How long the new process has to become ready, DefaultUpgradeReadyTimeout by default
+	Path         string        // Executable started, the running one's path by default so a replaced binary is used
+	Args         []string      // Arguments of the new process, those of this one by default
+}
+
+// StartServerUpgradable serves this router on addr like StartServer and, on the upgrade
+// signal, starts a new copy of the executable that adopts the listening socket, so a deploy
+// replaces the binary and signals the process without refusing a single connection
+// The new process, calling StartServerUpgradable in turn, serves the inherited socket and
+// reports back once it does; this process then drains like StartServerWithGracefulShutdown
+// and returns nil, so main can exit
+// When the new process fails to start, exits, or isn't ready within ReadyTimeout, it is
+// stopped and this process keeps serving, waiting for the next signal
+// With ReusePort nothing is passed and the new process binds addr itself; connections still
+// queued on the old socket when it closes are reset, so prefer the default where it works
+// Returns ErrUpgradeUnsupported on platforms without descriptor passing, and
+// http.ErrServerClosed after a direct Shutdown
+// Example: log.Fatal(rt.StartServerUpgradable(":8080"))
+func (rt *Rastauter) StartServerUpgradable(addr string, opts ...UpgradableOptions) error {
+	if !upgradeSupported {
+		return ErrUpgradeUnsupported
+	}
+	var o UpgradableOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Signal == nil {
+		o.Signal = upgradeSignal
+	}
+	if o.ReadyTimeout <= 0 {
+		o.ReadyTimeout = DefaultUpgradeReadyTimeout
+	}
+	if o.Path == "" {
+		// Resolve the path now, as the file may be replaced by the time of the upgrade
+		path, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		o.Path = path
+	}
+	if o.Args == nil {
+		o.Args = os.Args[1:]
+	}
+
+	l, err := rt.InheritListener()
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		if o.ReusePort {
+			l, err = listenReusePort(addr)
+		} else {
+			l, err = listenTCP(addr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for the signal before serving so none are missed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, o.Signal)
+	defer signal.Stop(signals)
+
+	rt.newServer(addr)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rt.Serve(l)
+	}()
+
+	// Connections already queue on the listener, so the old process can stop accepting now
+	if err := rt.UpgradeReady(); err != nil {
+		rt.logger().Warn("tobingo: upgrade readiness not reported", "error", err)
+	}
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-signals:
+		}
+
+		rt.logger().Info("tobingo: upgrading", "path", o.Path)
+		if err := startUpgrade(l, o); err != nil {
+			rt.logger().Error("tobingo: upgrade failed, still serving", "error", err)
+			continue
+		}
+		break
+	}
+
+	// The new process serves the socket, so hand over by draining this one
+	signal.Stop(signals)
+	rt.logger().Info("tobingo: upgrade ready, draining old process")
+	if err := rt.shutdownWithTimeout(); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// InheritListener returns the listener handed over by the process that started this one
+// for an upgrade, or nil without error when there is none
+// StartServerUpgradable calls it itself; call it directly to serve the listener some other
+// way, calling UpgradeReady once serving so the old process stops
+// The environment variable naming the descriptor is cleared so it is adopted only once
+func (rt *Rastauter) InheritListener() (net.Listener, error) {
+	value, ok := os.LookupEnv(upgradeListenerEnv)
+	if !ok {
+		return nil, nil
+	}
+	os.Unsetenv(upgradeListenerEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 0 {
+		return nil, fmt.Errorf("tobingo: invalid %s %q", upgradeListenerEnv, value)
+	}
+
+	// FileListener duplicates the descriptor, so the original can be closed right away
+	f := os.NewFile(uintptr(fd), "upgrade-listener")
+	l, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("tobingo: inherited listener fd %d: %w", fd, err)
+	}
+	return l, nil
+}
+
+// UpgradeReady tells the process that started this one for an upgrade that it now serves,
+// so the old process drains and exits; it does nothing when there is no such process
+// The environment variable naming the pipe is cleared so readiness is reported only once
+func (rt *Rastauter) UpgradeReady() error {
+	value, ok := os.LookupEnv(upgradeReadyEnv)
+	if !ok {
+		return nil
+	}
+	os.Unsetenv(upgradeReadyEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 0 {
+		return fmt.Errorf("tobingo: invalid %s %q", upgradeReadyEnv, value)
+	}
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer f.Close()
+
+	_, err = f.Write([]byte{upgradeReadyByte})
+	return err
+}
+
+// startUpgrade starts the new process of an upgrade, handing it l unless ReusePort is set,
+// and waits until it reports that it serves; a process that doesn't is killed
+func startUpgrade(l net.Listener, o UpgradableOptions) error {
+	path, err := exec.LookPath(o.Path)
+	if err != nil {
+		return err
+	}
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	// Files become descriptors 0 and up in the new process
+	env := append(upgradeEnviron(), upgradeReadyEnv+"=3")
+	files := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd(), readyWriter.Fd()}
+	var proc *os.Process
+	if o.ReusePort {
+		proc, err = startProcess(path, o.Args, env, files)
+	} else {
+		err = withListenerFD(l, func(fd uintptr) error {
+			var startErr error
+			proc, startErr = startProcess(path, o.Args, append(env, upgradeListenerEnv+"=4"), append(files, fd))
+			return startErr
+		})
+	}
+
+	// Only the new process may hold the writing end, so its exit ends the read below
+	readyWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	timer := time.AfterFunc(o.ReadyTimeout, func() { proc.Kill() })
+	err = waitReady(ready)
+	if !timer.Stop() {
+		err = fmt.Errorf("tobingo: new process not ready within %s", o.ReadyTimeout)
+	}
+	if err != nil {
+		proc.Kill()
+		proc.Wait()
+		return err
+	}
+	return nil
+}
+
+// withListenerFD calls fn with the descriptor of l, valid until fn returns
+// The descriptor is passed on raw: an *os.File for it, as os/exec takes, would put the
+// socket shared with the new process into blocking mode, and this process's Accept with it,
+// so that closing the listener hangs
+func withListenerFD(l net.Listener, fn func(fd uintptr) error) error {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("tobingo: listener %T can't be passed to another process", l)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := rc.Control(func(fd uintptr) { fnErr = fn(fd) }); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// waitReady reads the readiness report of the new process from r, failing when the pipe
+// closes first because the process exited
+func waitReady(r io.Reader) error {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return errors.New("tobingo: new process exited before becoming ready")
+	}
+	if b[0] != upgradeReadyByte {
+		return fmt.Errorf("tobingo: unexpected readiness report %q", b[0])
+	}
+	return nil
+}
+
+// upgradeEnviron returns this process's environment without the variables of an earlier
+// upgrade, which the new process gets fresh values of
+func upgradeEnviron() []string {
+	env := os.Environ()
+	kept := env[:0:0]
+	for _, kv := range env {
+		if strings.HasPrefix(kv, upgradeListenerEnv+"=") || strings.HasPrefix(kv, upgradeReadyEnv+"=") {
+			continue
+		}
+		kept = append(kept, kv)
+	}
+	return kept
+}
@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package tobingo
+
+import (
+	"net"
+	"os"
+)
+
+// upgradeSupported reports whether StartServerUpgradable works on this platform
+const upgradeSupported = false
+
+// upgradeSignal is the signal StartServerUpgradable upgrades on by default
+var upgradeSignal os.Signal
+
+// listenReusePort is unavailable here, as StartServerUpgradable is
+func listenReusePort(addr string) (net.Listener, error) {
+	return nil, ErrUpgradeUnsupported
+}
+
+// startProcess is unavailable here, as StartServerUpgradable is
+func startProcess(path string, args, env []string, files []uintptr) (*os.Process, error) {
+	return nil, ErrUpgradeUnsupported
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tobingo
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+)
+
+// upgradeSupported reports whether StartServerUpgradable works on this platform
+const upgradeSupported = true
+
+// upgradeSignal is the signal StartServerUpgradable upgrades on by default
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// listenReusePort listens on addr with SO_REUSEPORT set, so the new process of an upgrade
+// can bind the same address while this one still serves it
+func listenReusePort(addr string) (net.Listener, error) {
+	if addr == "" {
+		addr = ":http"
+	}
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var optErr error
+			err := c.Control(func(fd uintptr) {
+				optErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return optErr
+		},
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// startProcess starts the executable at path with files as its descriptors 0 and up,
+// passed as they are without changing their mode
+func startProcess(path string, args, env []string, files []uintptr) (*os.Process, error) {
+	pid, _, err := syscall.StartProcess(path, append([]string{path}, args...), &syscall.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return nil, &os.PathError{Op: "fork/exec", Path: path, Err: err}
+	}
+	return os.FindProcess(pid)
+}
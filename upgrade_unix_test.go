@@ -0,0 +1,253 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package tobingo
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// upgradeChildEnv selects what TestUpgradeChildProcess does when it runs as the new process
+// of an upgrade: "serve", "exit", or "hang"
+const upgradeChildEnv = "TOBINGO_TEST_UPGRADE_CHILD"
+
+// TestUpgradeChildProcess is the new process started by the startUpgrade tests, doing
+// nothing when run as a normal test
+func TestUpgradeChildProcess(t *testing.T) {
+	switch os.Getenv(upgradeChildEnv) {
+	case "serve":
+		served := make(chan struct{})
+		rt := NewRastaRouterInitializer()
+		rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "child "+strconv.Itoa(os.Getpid()))
+			close(served)
+		})
+		l, err := rt.InheritListener()
+		if err != nil || l == nil {
+			os.Exit(2)
+		}
+		go http.Serve(l, rt)
+		if rt.UpgradeReady() != nil {
+			os.Exit(3)
+		}
+		select {
+		case <-served:
+			time.Sleep(100 * time.Millisecond) // Let the response go out
+		case <-time.After(10 * time.Second):
+		}
+		os.Exit(0)
+	case "exit":
+		os.Exit(0)
+	case "hang":
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+}
+
+// startChild runs startUpgrade with this test binary as the new process, in the given mode
+func startChild(t *testing.T, l net.Listener, mode string, timeout time.Duration) error {
+	t.Helper()
+	t.Setenv(upgradeChildEnv, mode)
+	return startUpgrade(l, UpgradableOptions{
+		Path:         os.Args[0],
+		Args:         []string{"-test.run=^TestUpgradeChildProcess$"},
+		ReadyTimeout: timeout,
+	})
+}
+
+func TestInheritListenerRoundTrip(t *testing.T) {
+	listeners, fds := inheritable(t, 1)
+	t.Setenv(upgradeListenerEnv, strconv.Itoa(fds[0]))
+
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "adopted") })
+	l, err := rt.InheritListener()
+	if err != nil || l == nil {
+		t.Fatalf("InheritListener = %v, %v", l, err)
+	}
+	defer l.Close()
+	if l.Addr().String() != listeners[0].Addr().String() {
+		t.Errorf("adopted %s, want %s", l.Addr(), listeners[0].Addr())
+	}
+	if _, ok := os.LookupEnv(upgradeListenerEnv); ok {
+		t.Error("listener variable was not cleared")
+	}
+
+	// The adopted socket serves on its own once the original is closed
+	listeners[0].Close()
+	go http.Serve(l, rt)
+	res, err := http.Get("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "adopted" {
+		t.Errorf("body = %q", body)
+	}
+
+	// Adopted once, and nothing to adopt without the variable
+	if l, err := rt.InheritListener(); l != nil || err != nil {
+		t.Errorf("second InheritListener = %v, %v", l, err)
+	}
+}
+
+func TestInheritListenerErrors(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	for _, value := range []string{"", "x", "-1"} {
+		t.Setenv(upgradeListenerEnv, value)
+		if _, err := rt.InheritListener(); err == nil || !strings.Contains(err.Error(), upgradeListenerEnv) {
+			t.Errorf("%q: %v", value, err)
+		}
+	}
+
+	// A descriptor that isn't a socket
+	f, err := os.CreateTemp(t.TempDir(), "not-a-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(upgradeListenerEnv, strconv.Itoa(fd))
+	if _, err := rt.InheritListener(); err == nil {
+		t.Error("file adopted as a listener")
+	}
+}
+
+func TestUpgradeReadySignaling(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	if err := rt.UpgradeReady(); err != nil {
+		t.Errorf("UpgradeReady without an old process: %v", err)
+	}
+
+	// The new process's report reaches the old process through the pipe
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	fd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(upgradeReadyEnv, strconv.Itoa(fd))
+	if err := rt.UpgradeReady(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := os.LookupEnv(upgradeReadyEnv); ok {
+		t.Error("readiness variable was not cleared")
+	}
+	if err := waitReady(r); err != nil {
+		t.Errorf("waitReady: %v", err)
+	}
+	// UpgradeReady closed its end, so nothing more arrives
+	if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("after the report: %d, %v", n, err)
+	}
+
+	if err := waitReady(strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("closed pipe: %v", err)
+	}
+	if err := waitReady(strings.NewReader("x")); err == nil || !strings.Contains(err.Error(), "unexpected") {
+		t.Errorf("wrong report: %v", err)
+	}
+}
+
+func TestStartUpgradeHandsOverListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := startChild(t, l, "serve", 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the new process serves the socket once this one lets go of it
+	addr := l.Addr().String()
+	l.Close()
+	res, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasPrefix(string(body), "child ") || string(body) == "child "+strconv.Itoa(os.Getpid()) {
+		t.Errorf("body = %q, want the new process's answer", body)
+	}
+}
+
+func TestStartUpgradeFailures(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := startChild(t, l, "exit", 30*time.Second); err == nil || !strings.Contains(err.Error(), "exited before becoming ready") {
+		t.Errorf("exiting process: %v", err)
+	}
+	start := time.Now()
+	if err := startChild(t, l, "hang", 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "not ready within") {
+		t.Errorf("hanging process: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("hanging process stopped after %s", elapsed)
+	}
+	if err := startUpgrade(l, UpgradableOptions{Path: "/nonexistent/binary", ReadyTimeout: time.Second}); err == nil {
+		t.Error("missing binary started")
+	}
+
+	// A listener without a descriptor can't be handed over
+	if err := startUpgrade(fakeListener{}, UpgradableOptions{Path: os.Args[0], ReadyTimeout: time.Second}); err == nil || !strings.Contains(err.Error(), "can't be passed") {
+		t.Errorf("fake listener: %v", err)
+	}
+
+	// The old process still serves after the failures, and its listener still closes: the
+	// socket shared with the new processes must not have been put into blocking mode
+	served := make(chan error, 1)
+	go func() { served <- http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})) }()
+	res, err := http.Get("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	closed := make(chan struct{})
+	go func() {
+		l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the listener hung after an upgrade attempt")
+	}
+	<-served
+}
+
+// fakeListener is a listener with no descriptor behind it
+type fakeListener struct{ net.Listener }
+
+func TestListenReusePort(t *testing.T) {
+	a, err := listenReusePort("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := listenReusePort(a.Addr().String())
+	if err != nil {
+		t.Fatalf("second bind of %s: %v", a.Addr(), err)
+	}
+	b.Close()
+}
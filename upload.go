@@ -0,0 +1,188 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrFileMissing is returned by FormFile and FormFiles when the form has no file under the field
+var ErrFileMissing = errors.New("tobingo: missing uploaded file")
+
+// multipartMemory is how much of a multipart body is kept in memory before spilling to disk
+const multipartMemory = 8 << 20
+
+// multipartOverhead is the room left for boundaries and other form fields beyond the file limit
+const multipartOverhead = 1 << 20
+
+// UploadedFile is a file received in a multipart form
+type UploadedFile struct {
+	Filename    string // Client file name without any directory parts, safe to use as a base name
+	Size        int64  // Size in bytes
+	ContentType string // Type sniffed from the content; the client's claim is not trusted
+
+	header *multipart.FileHeader
+}
+
+// Open returns a reader over the file's content; the caller must close it
+func (f *UploadedFile) Open() (multipart.File, error) {
+	return f.header.Open()
+}
+
+// SaveTo writes the file to path, replacing any existing file
+// A partially written file is removed if copying fails
+func (f *UploadedFile) SaveTo(path string) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.copyTo(dst); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// WriteFS is a filesystem files can be created in, satisfied by *os.Root
+type WriteFS interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error)
+}
+
+// SaveToFS writes the file as name inside fsys; with an *os.Root the name can't escape the root
+// Example: root, _ := os.OpenRoot("uploads"); err := file.SaveToFS(root, file.Filename)
+func (f *UploadedFile) SaveToFS(fsys WriteFS, name string) error {
+	dst, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.copyTo(dst)
+}
+
+// copyTo copies the content into dst and closes it, reporting the first error
+func (f *UploadedFile) copyTo(dst io.WriteCloser) error {
+	src, err := f.Open()
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// FormFile returns the file uploaded under field in a multipart form
+// maxSize limits the file; the request body is capped slightly above it so oversized uploads
+// stop early, and both cases fail with ErrBodyTooLarge
+// Returns ErrFileMissing when there is no such file and ErrUnsupportedMediaType when the
+// request isn't multipart
+// Example: file, err := tobingo.FormFile(r, "avatar", 5<<20)
+func FormFile(r *http.Request, field string, maxSize int64) (*UploadedFile, error) {
+	files, err := formFiles(r, field, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles returns every file uploaded under field, for inputs with the multiple attribute
+// maxSize limits the combined size of the files; errors are as for FormFile
+func FormFiles(r *http.Request, field string, maxSize int64) ([]*UploadedFile, error) {
+	return formFiles(r, field, maxSize)
+}
+
+// formFiles parses the multipart form and wraps the files of field
+func formFiles(r *http.Request, field string, maxSize int64) ([]*UploadedFile, error) {
+	if err := parseMultipart(r, maxSize+multipartOverhead); err != nil {
+		return nil, err
+	}
+
+	headers := r.MultipartForm.File[field]
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrFileMissing, field)
+	}
+
+	files := make([]*UploadedFile, 0, len(headers))
+	var total int64
+	for _, header := range headers {
+		total += header.Size
+		if total > maxSize {
+			return nil, fmt.Errorf("%w: %q exceeds %d bytes", ErrBodyTooLarge, field, maxSize)
+		}
+
+		contentType, err := sniffContentType(header)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &UploadedFile{
+			Filename:    sanitizeFilename(header.Filename),
+			Size:        header.Size,
+			ContentType: contentType,
+			header:      header,
+		})
+	}
+	return files, nil
+}
+
+// parseMultipart parses a multipart body limited to limit bytes, at most once per request
+func parseMultipart(r *http.Request, limit int64) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, limit)
+	}
+
+	err := r.ParseMultipartForm(multipartMemory)
+	var maxErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, http.ErrNotMultipart):
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, r.Header.Get("Content-Type"))
+	case errors.As(err, &maxErr):
+		return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidBody, err)
+}
+
+// sniffContentType detects the type from the first 512 bytes as http.DetectContentType does
+func sniffContentType(header *multipart.FileHeader) (string, error) {
+	f, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// sanitizeFilename strips directory parts (with either slash), control characters, and
+// dot-only names from a client supplied file name, falling back to "upload"
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(c rune) rune {
+		if c < 0x20 || c == 0x7f {
+			return -1
+		}
+		return c
+	}, name)
+	if strings.Trim(name, ".") == "" || name == "/" {
+		return "upload"
+	}
+	return name
+}
@@ -0,0 +1,164 @@
+package tobingo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// uploadPart is one file of a multipart body
+type uploadPart struct {
+	field, filename, content string
+}
+
+// uploadRequest builds a multipart POST carrying the files and a plain "title" field
+func uploadRequest(t *testing.T, parts ...uploadPart) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "holiday")
+	for _, p := range parts {
+		// The client's claimed type is deliberately wrong
+		h := textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="` + p.field + `"; filename="` + p.filename + `"`},
+			"Content-Type":        {"application/x-evil"},
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.content)
+	}
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// pngHeader is enough of a PNG for content sniffing
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestFormFile(t *testing.T) {
+	r := uploadRequest(t, uploadPart{"avatar", "me.png", pngHeader})
+	f, err := FormFile(r, "avatar", 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Filename != "me.png" || f.Size != int64(len(pngHeader)) || f.ContentType != "image/png" {
+		t.Errorf("file = %+v", f)
+	}
+	if r.FormValue("title") != "holiday" {
+		t.Errorf("title = %q", r.FormValue("title"))
+	}
+
+	rd, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+	if b, _ := io.ReadAll(rd); string(b) != pngHeader {
+		t.Errorf("content = %q", b)
+	}
+}
+
+func TestFormFileSave(t *testing.T) {
+	dir := t.TempDir()
+	f, err := FormFile(uploadRequest(t, uploadPart{"doc", "notes.txt", "hello"}), "doc", 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.SaveTo(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if err := f.SaveToFS(root, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != "hello" {
+			t.Errorf("%s = %q", name, b)
+		}
+	}
+
+	// A root keeps names from escaping it
+	if err := f.SaveToFS(root, "../escape.txt"); err == nil {
+		t.Error("SaveToFS wrote outside the root")
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	for name, want := range map[string]string{
+		"report.pdf":           "report.pdf",
+		"../../etc/passwd":     "passwd",
+		`..\..\windows\system`: "system",
+		"/abs/path.txt":        "path.txt",
+		"bad\x00name\n.txt":    "badname.txt",
+		"..":                   "upload",
+		"":                     "upload",
+		"/":                    "upload",
+	} {
+		if got := sanitizeFilename(name); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	f, err := FormFile(uploadRequest(t, uploadPart{"doc", "../../secret.txt", "x"}), "doc", 1<<10)
+	if err != nil || f.Filename != "secret.txt" {
+		t.Errorf("uploaded name = %v, %v", f, err)
+	}
+}
+
+func TestFormFileTooLarge(t *testing.T) {
+	r := uploadRequest(t, uploadPart{"doc", "big.bin", strings.Repeat("x", 2<<10)})
+	if _, err := FormFile(r, "doc", 1<<10); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("err = %v, want ErrBodyTooLarge", err)
+	}
+
+	// Far beyond the limit the body itself is cut off while parsing
+	r = uploadRequest(t, uploadPart{"doc", "huge.bin", strings.Repeat("x", 2*multipartOverhead)})
+	if _, err := FormFile(r, "doc", 1<<10); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("huge err = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestFormFileErrors(t *testing.T) {
+	if _, err := FormFile(uploadRequest(t), "avatar", 1<<10); !errors.Is(err, ErrFileMissing) {
+		t.Errorf("missing field err = %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/upload", strings.NewReader("a=b"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := FormFile(r, "avatar", 1<<10); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("urlencoded err = %v", err)
+	}
+}
+
+func TestFormFiles(t *testing.T) {
+	parts := []uploadPart{{"photos", "a.txt", "aaaa"}, {"photos", "b.txt", "bbbb"}}
+	files, err := FormFiles(uploadRequest(t, parts...), "photos", 1<<10)
+	if err != nil || len(files) != 2 || files[0].Filename != "a.txt" || files[1].Filename != "b.txt" {
+		t.Fatalf("files = %v, %v", files, err)
+	}
+	if files[0].ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("ContentType = %q", files[0].ContentType)
+	}
+
+	// The limit covers the files together
+	if _, err := FormFiles(uploadRequest(t, parts...), "photos", 6); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("combined limit err = %v", err)
+	}
+}
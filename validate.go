@@ -0,0 +1,59 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrValidation is matched by every *ValidationError
+var ErrValidation = errors.New("tobingo: validation failed")
+
+// ValidationError wraps the error returned by a Validate method or the router's validator
+// after binding succeeded; handlers typically answer 422 Unprocessable Entity with its message
+type ValidationError struct {
+	Err error // Error reported by the validator
+}
+
+// Error returns the validator's message
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes both ErrValidation and the validator's error
+func (e *ValidationError) Unwrap() []error {
+	return []error{ErrValidation, e.Err}
+}
+
+// Validator checks a bound value, e.g. by delegating to a struct tag validation library
+type Validator func(v any) error
+
+// SetValidator installs a validator that Bind, BindJSON, BindForm, BindQuery, and BindParams
+// run on every destination after a successful bind, after the destination's own
+// Validate() error method if it has one; either failing yields a *ValidationError
+// Example: rt.SetValidator(func(v any) error { return validate.Struct(v) })
+func (rt *Rastauter) SetValidator(fn Validator) {
+	rt.validator.Store(&fn)
+}
+
+// validateBound runs the validation step when binding succeeded, wrapping failures in
+// *ValidationError; binding errors are returned unchanged
+func validateBound(r *http.Request, dst any, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if v, ok := dst.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+
+	if rt := routerFrom(r); rt != nil {
+		if validator := rt.validator.Load(); validator != nil && *validator != nil {
+			if err := (*validator)(dst); err != nil {
+				return &ValidationError{Err: err}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package tobingo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// signup validates itself, rejecting short passwords
+type signup struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (s signup) Validate() error {
+	if len(s.Password) < 8 {
+		return errors.New("password must have at least 8 characters")
+	}
+	return nil
+}
+
+// bindSignup binds the JSON body of every GET into a signup
+func bindSignup(rt *Rastauter, bindErr *error) {
+	rt.GET("/signup", func(w http.ResponseWriter, r *http.Request) {
+		var in signup
+		*bindErr = BindJSON(r, &in)
+	})
+}
+
+func TestValidateMethod(t *testing.T) {
+	var bindErr error
+	rt := NewRastaRouterInitializer()
+	bindSignup(rt, &bindErr)
+
+	rt.Test("GET", "/signup", strings.NewReader(`{"email":"a@b.c","password":"short"}`), WithTestHeader("Content-Type", "application/json"))
+	var ve *ValidationError
+	if !errors.As(bindErr, &ve) || !errors.Is(bindErr, ErrValidation) || ve.Error() != "password must have at least 8 characters" {
+		t.Errorf("err = %v, want a *ValidationError", bindErr)
+	}
+
+	rt.Test("GET", "/signup", strings.NewReader(`{"email":"a@b.c","password":"long enough"}`), WithTestHeader("Content-Type", "application/json"))
+	if bindErr != nil {
+		t.Errorf("passing validation err = %v", bindErr)
+	}
+}
+
+func TestSetValidator(t *testing.T) {
+	var bindErr error
+	var seen []any
+	rt := NewRastaRouterInitializer()
+	rt.SetValidator(func(v any) error {
+		seen = append(seen, v)
+		if in := v.(*signup); !strings.Contains(in.Email, "@") {
+			return errors.New("email is invalid")
+		}
+		return nil
+	})
+	bindSignup(rt, &bindErr)
+
+	rt.Test("GET", "/signup", strings.NewReader(`{"email":"nope","password":"long enough"}`), WithTestHeader("Content-Type", "application/json"))
+	if !errors.Is(bindErr, ErrValidation) || bindErr.Error() != "email is invalid" {
+		t.Errorf("err = %v", bindErr)
+	}
+
+	// The Validate method runs first, and a failure there skips the router's validator
+	seen = nil
+	rt.Test("GET", "/signup", strings.NewReader(`{"email":"nope","password":"short"}`), WithTestHeader("Content-Type", "application/json"))
+	if bindErr == nil || !strings.Contains(bindErr.Error(), "password") || len(seen) != 0 {
+		t.Errorf("err = %v, validator saw %d values", bindErr, len(seen))
+	}
+
+	// Binding failures are returned as they are, without validating
+	rt.Test("GET", "/signup", strings.NewReader(`{`), WithTestHeader("Content-Type", "application/json"))
+	if errors.Is(bindErr, ErrValidation) || !errors.Is(bindErr, ErrInvalidBody) || len(seen) != 0 {
+		t.Errorf("err = %v, validator saw %d values", bindErr, len(seen))
+	}
+}
+
+func TestValidationRenderedAs422(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.GET("/signup", JSONHandler(func(ctx context.Context, in signup, _ map[string]string) (string, error) {
+		return "welcome", nil
+	}))
+	rt.GET("/h", H(func(r *http.Request) (int, any, error) {
+		var in signup
+		return http.StatusCreated, nil, BindJSON(r, &in)
+	}))
+
+	res := rt.Test("GET", "/signup", strings.NewReader(`{"password":"short"}`), WithTestHeader("Content-Type", "application/json"))
+	if res.StatusCode() != http.StatusUnprocessableEntity || res.BodyString() != "password must have at least 8 characters\n" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+
+	res = rt.Test("GET", "/h", strings.NewReader(`{"password":"short"}`), WithTestHeader("Content-Type", "application/json"))
+	if res.StatusCode() != http.StatusUnprocessableEntity {
+		t.Errorf("H status = %d, want 422", res.StatusCode())
+	}
+
+	res = rt.Test("GET", "/signup", strings.NewReader(`{"password":"long enough"}`), WithTestHeader("Content-Type", "application/json"))
+	if res.StatusCode() != http.StatusOK || res.BodyString() != `"welcome"`+"\n" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
@@ -0,0 +1,106 @@
+package tobingo
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// routerKey is the context key under which the router serving a request can be found
+const routerKey contextKey = "router"
+
+// requestStateKey is the context key under which the per-request state can be found
+const requestStateKey contextKey = "requestState"
+
+// recorderKey is the context key under which the request's ResponseRecorder can be found
+const recorderKey contextKey = "recorder"
+
+// routerContext layers the serving router and its injected values over a request context
+// A single wrapper serves every value, so injecting many values costs one allocation per request
+type routerContext struct {
+	context.Context
+	rt     *Rastauter     // Router handling the request
+	values map[any]any    // Values set with WithValue, never mutated after publication
+	state  requestState   // Lazily computed per-request data shared by the helpers
+	rw     responseWriter // Tracks the response, allocated with the context to save an allocation
+	head   BodylessWriter // Discards the body of HEAD responses beneath rw
+
+	recorder *responseWriter // Writer tracking the response, &rw or the one of an enclosing router
+}
+
+// requestState caches data derived from the request so helpers compute it at most once
+type requestState struct {
+	queryOnce sync.Once  // Guards the parsing of query and queryErr
+	query     url.Values // Parsed query string
+	queryErr  error      // First error hit while parsing the query string
+
+	body         []byte // Request body read by BufferBody
+	bodyBuffered bool   // Whether body holds the buffered request body
+
+	route     *Route            // Route dispatch matched, nil until then and for unmatched requests
+	params    map[string]string // Path parameters of route, read-only
+	active    *activeRequest    // Registry entry while TrackInFlight is on, nil otherwise
+	longLived *longLivedConn    // Registration made with RegisterLongLived, nil for none, guarded by mu
+	locale    string            // Locale prefix stripped by Locales, "" when the path had none
+	prefix    string            // External prefix in front of the router's paths, "" at the root
+	outside   bool              // Whether the path lies outside the base path, answered 404
+	recovered any               // Value of a recovered handler panic, nil without one
+
+	// A handler still running past its deadline may touch these after the router moved on
+	mu    sync.Mutex // Guards err, longLived, and ended
+	err   error      // First error a response was rendered for, for OnErrorResponse
+	ended bool       // Whether the router is done with the request, after which nothing registers
+
+	acceptOnce sync.Once      // Guards the parsing of accept
+	accept     *acceptHeaders // Parsed Accept, Accept-Encoding, and Accept-Language headers
+}
+
+// Value returns the router, request state, or an injected value for key, falling back to the parent context
+func (c *routerContext) Value(key any) any {
+	if key == routerKey {
+		return c.rt
+	}
+	if key == requestStateKey {
+		return &c.state
+	}
+	if key == recorderKey {
+		return c.recorder
+	}
+	if v, ok := c.values[key]; ok {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// stateFrom returns the per-request state of r, or nil when r didn't come through a router
+func stateFrom(r *http.Request) *requestState {
+	state, _ := r.Context().Value(requestStateKey).(*requestState)
+	return state
+}
+
+// routerFrom returns the router serving r, or nil when r didn't come through a router
+// Package-level helpers use it to find router-wide settings
+func routerFrom(r *http.Request) *Rastauter {
+	rt, _ := r.Context().Value(routerKey).(*Rastauter)
+	return rt
+}
+
+// WithValue makes val available under key in the context of every request handled by
+// this router, before route matching and handler execution
+// Path parameters are added after injected values, so a value stored under ParamsKey can
+// never hide them; keys should be unexported types just like with context.WithValue
+// Safe to call while the server is running
+func (rt *Rastauter) WithValue(key, val any) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	// Copy on write so ServeHTTP can read the current map without locking
+	values := make(map[any]any)
+	if current := rt.values.Load(); current != nil {
+		maps.Copy(values, *current)
+	}
+	values[key] = val
+	rt.values.Store(&values)
+}
@@ -0,0 +1,64 @@
+package tobingo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// valueKey is a context key used by the tests
+type valueKey string
+
+func TestWithValue(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.WithValue(valueKey("flags"), "dark-mode")
+	rt.WithValue(ParamsKey, "clobbered")
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Values are injected before middleware runs
+			w.Header().Set("X-Flags", r.Context().Value(valueKey("flags")).(string))
+			next.ServeHTTP(w, r)
+		})
+	})
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value(valueKey("flags")).(string) + " " + GetParam(r, "id")))
+	})
+
+	res := rt.Test("GET", "/users/42", nil)
+	if res.BodyString() != "dark-mode 42" || res.Header("X-Flags") != "dark-mode" {
+		t.Errorf("got %q, X-Flags %q", res.BodyString(), res.Header("X-Flags"))
+	}
+}
+
+func TestBaseAndConnContext(t *testing.T) {
+	rt := NewRastaRouterInitializer()
+	rt.ConfigureServer(
+		WithBaseContext(func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), valueKey("base"), "tracer")
+		}),
+		WithConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, valueKey("conn"), "conn")
+		}),
+	)
+	rt.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		base, _ := r.Context().Value(valueKey("base")).(string)
+		conn, _ := r.Context().Value(valueKey("conn")).(string)
+		w.Write([]byte(base + " " + conn + " " + GetParam(r, "id")))
+	})
+	addr, stop, err := rt.StartServerAsync("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop(context.Background())
+
+	res, err := http.Get("http://" + addr + "/users/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if body, _ := io.ReadAll(res.Body); string(body) != "tracer conn 42" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,394 @@
+package tobingo
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Errors returned by Upgrade, which has already answered the request when it returns one
+var (
+	ErrNotWebSocket = errors.New("tobingo: not a websocket handshake")
+	ErrBadOrigin    = errors.New("tobingo: websocket origin not allowed")
+)
+
+// websocketGUID is appended to the client key before hashing, as RFC 6455 section 1.3 defines
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket message types, the frame opcodes of RFC 6455
+const (
+	TextMessage   = 1 // UTF-8 text
+	BinaryMessage = 2 // Arbitrary bytes
+)
+
+// Control frame opcodes
+const (
+	opClose = 8
+	opPing  = 9
+	opPong  = 10
+)
+
+// Close status codes sent by WSConn, see RFC 6455 section 7.4.1
+const (
+	CloseNormal        = 1000 // The purpose of the connection was fulfilled
+	CloseGoingAway     = 1001 // The server is shutting down or the client navigated away
+	CloseProtocolError = 1002 // The peer violated the protocol
+	CloseInvalidData   = 1007 // A text message wasn't valid UTF-8
+	CloseTooLarge      = 1009 // A message exceeded the read limit
+)
+
+// UpgradeOptions configures Upgrade
+type UpgradeOptions struct {
+	Subprotocols []string                   // Supported subprotocols in order of preference, none by default
+	CheckOrigin  func(r *http.Request) bool // Accepts the Origin header, by default it must be absent or match the Host
+	ReadLimit    int64                      // Maximum message size in bytes, 1 MB by default
+}
+
+// WSConn is a server-side WebSocket connection with frame-level reads and writes
+// Pings are answered with pongs while reading; ReadMessage may only be called from one
+// goroutine at a time, writes are safe from any number of goroutines
+type WSConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+	readLimit   int64
+
+	writeMu sync.Mutex // Serializes frames so concurrent writers don't interleave
+	closed  bool       // Whether a close frame was sent, guarded by writeMu
+}
+
+// CloseError is returned by ReadMessage once the peer closed the connection
+type CloseError struct {
+	Code   int    // Status code sent by the peer, 1005 when it sent none
+	Reason string // Optional reason sent by the peer
+}
+
+// Error implements the error interface
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return "tobingo: websocket closed with code " + strconv.Itoa(e.Code)
+	}
+	return fmt.Sprintf("tobingo: websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Upgrade performs the RFC 6455 opening handshake and takes over the connection
+// Path parameters and the rest of the request remain readable before and after the upgrade;
+// on failure the client has been answered with 400, 403, or 426 and an error is returned
+// The connection is hijacked through the router's response writer, so the server's read and
+// write timeouts are cleared for it
+// Example: conn, err := tobingo.Upgrade(w, r, tobingo.UpgradeOptions{Subprotocols: []string{"chat"}}); if err != nil { return }; defer conn.Close()
+func Upgrade(w http.ResponseWriter, r *http.Request, opts ...UpgradeOptions) (*WSConn, error) {
+	var o UpgradeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.ReadLimit <= 0 {
+		o.ReadLimit = 1 << 20
+	}
+	if o.CheckOrigin == nil {
+		o.CheckOrigin = sameOrigin
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet || !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket"):
+		err := fmt.Errorf("%w: missing upgrade headers", ErrNotWebSocket)
+		handleError(w, r, http.StatusBadRequest, err)
+		return nil, err
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		err := fmt.Errorf("%w: unsupported version %q", ErrNotWebSocket, r.Header.Get("Sec-WebSocket-Version"))
+		handleError(w, r, http.StatusUpgradeRequired, err)
+		return nil, err
+	case !validWebSocketKey(key):
+		err := fmt.Errorf("%w: invalid Sec-WebSocket-Key %q", ErrNotWebSocket, key)
+		handleError(w, r, http.StatusBadRequest, err)
+		return nil, err
+	case !o.CheckOrigin(r):
+		err := fmt.Errorf("%w: %q", ErrBadOrigin, r.Header.Get("Origin"))
+		handleError(w, r, http.StatusForbidden, err)
+		return nil, err
+	}
+
+	subprotocol := negotiateSubprotocol(r, o.Subprotocols)
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, err)
+		return nil, err
+	}
+	if rw := trackedWriter(w); rw != nil {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if subprotocol != "" {
+		resp += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	if _, err := io.WriteString(conn, resp+"\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &WSConn{conn: conn, br: brw.Reader, subprotocol: subprotocol, readLimit: o.ReadLimit}, nil
+}
+
+// sameOrigin accepts requests without an Origin header and those whose Origin host is the request Host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerHasToken reports whether a comma-separated header contains token, ignoring case
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validWebSocketKey reports whether key is the base64 encoding of 16 bytes
+func validWebSocketKey(key string) bool {
+	b, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(b) == 16
+}
+
+// negotiateSubprotocol picks the first supported subprotocol the client offered
+func negotiateSubprotocol(r *http.Request, supported []string) string {
+	var offered []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for part := range strings.SplitSeq(value, ",") {
+			offered = append(offered, strings.TrimSpace(part))
+		}
+	}
+	for _, proto := range supported {
+		if slices.Contains(offered, proto) {
+			return proto
+		}
+	}
+	return ""
+}
+
+// Subprotocol returns the negotiated subprotocol, "" if none
+func (c *WSConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection, e.g. to set deadlines
+func (c *WSConn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadMessage returns the next text or binary message, reassembling fragments
+// Pings are answered and pongs skipped on the way; once the peer closes, the close is
+// acknowledged and a *CloseError returned; protocol violations, invalid UTF-8 in text
+// messages, and messages over the read limit close the connection with the matching code
+func (c *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, c.peerClosed(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message inside a fragmented one")
+			}
+			messageType = opcode
+		case 0:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "continuation without a message")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(opcode))
+		}
+
+		if int64(len(data))+int64(len(payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseTooLarge, "message exceeds "+strconv.FormatInt(c.readLimit, 10)+" bytes")
+		}
+		data = append(data, payload...)
+		if !fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(data) {
+			return 0, nil, c.fail(CloseInvalidData, "text message is not valid UTF-8")
+		}
+		return messageType, data, nil
+	}
+}
+
+// readFrame reads one frame from the client, unmasking its payload
+func (c *WSConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = head[0]&0x80 != 0, int(head[0]&0x0f)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set without an extension")
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= opClose && (size > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if size > uint64(c.readLimit) {
+		return false, 0, nil, c.fail(CloseTooLarge, "frame exceeds "+strconv.FormatInt(c.readLimit, 10)+" bytes")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// peerClosed acknowledges a close frame from the client and describes it as a *CloseError
+func (c *WSConn) peerClosed(payload []byte) error {
+	cerr := &CloseError{Code: 1005}
+	if len(payload) >= 2 {
+		cerr.Code = int(binary.BigEndian.Uint16(payload))
+		cerr.Reason = string(payload[2:])
+	}
+
+	// Echo the code as RFC 6455 section 5.5.1 asks, then drop the connection
+	var reply []byte
+	if len(payload) >= 2 {
+		reply = payload[:2]
+	}
+	c.writeMu.Lock()
+	if !c.closed {
+		c.closed = true
+		c.writeFrameLocked(opClose, reply)
+	}
+	c.writeMu.Unlock()
+	c.conn.Close()
+	return cerr
+}
+
+// fail closes the connection with code after a violation by the peer and returns the error
+func (c *WSConn) fail(code int, reason string) error {
+	c.CloseWithStatus(code, reason)
+	return fmt.Errorf("tobingo: websocket: %s", reason)
+}
+
+// WriteMessage sends data as a single text or binary frame
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("tobingo: websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteText sends s as a text message
+func (c *WSConn) WriteText(s string) error {
+	return c.writeFrame(TextMessage, []byte(s))
+}
+
+// Ping sends a ping with up to 125 bytes of data; the pong is skipped by ReadMessage
+func (c *WSConn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("tobingo: websocket: ping data exceeds 125 bytes")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a normal close frame and closes the connection
+func (c *WSConn) Close() error {
+	return c.CloseWithStatus(CloseNormal, "")
+}
+
+// CloseWithStatus sends a close frame with code and reason, then closes the connection
+func (c *WSConn) CloseWithStatus(code int, reason string) error {
+	c.writeMu.Lock()
+	if !c.closed {
+		c.closed = true
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		c.writeFrameLocked(opClose, append(payload, reason...))
+	}
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+// writeFrame sends one unmasked, final frame
+func (c *WSConn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked sends one frame; the caller must hold writeMu
+func (c *WSConn) writeFrameLocked(opcode int, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(n))
+	}
+	_, err := c.conn.Write(append(frame, payload...))
+	return err
+}
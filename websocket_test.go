@@ -0,0 +1,264 @@
+package tobingo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is the client end of a handshake made against a test server
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+	res  *http.Response
+}
+
+// dialWebSocket sends an opening handshake for path with the extra headers and reads the answer
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, header http.Header) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{conn: conn, br: br, res: res}
+}
+
+// send writes one masked client frame
+func (c *wsClient) send(t *testing.T, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{head, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receive reads one unmasked server frame
+func (c *wsClient) receive(t *testing.T) (opcode byte, payload []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0]&0x80 == 0 || head[1]&0x80 != 0 {
+		t.Fatalf("server frame header %08b %08b: want final and unmasked", head[0], head[1])
+	}
+	size := int(head[1] & 0x7f)
+	if size == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0f, payload
+}
+
+// logLines hands each line written to it to the test, which the handler of a hijacked
+// connection writes after the server stopped tracking it
+type logLines chan string
+
+func (l logLines) Write(b []byte) (int, error) {
+	l <- string(b)
+	return len(b), nil
+}
+
+func TestUpgradeHandshake(t *testing.T) {
+	logs := make(logLines, 1)
+	closed := make(chan error, 1)
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: logs}))
+	rt.GET("/ws/:room", func(w http.ResponseWriter, r *http.Request) {
+		room := Params(r)["room"]
+		conn, err := Upgrade(w, r, UpgradeOptions{Subprotocols: []string{"chat.v2", "chat.v1"}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		if Params(r)["room"] != room {
+			t.Errorf("room after the upgrade = %q, want %q", Params(r)["room"], room)
+		}
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			conn.WriteMessage(typ, []byte(room+": "+string(data)))
+		}
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	c := dialWebSocket(t, srv, "/ws/lobby", http.Header{"Sec-Websocket-Protocol": {"chat.v1, chat.v2"}})
+	if c.res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d", c.res.StatusCode)
+	}
+	// The accept value of the sample key in RFC 6455 section 1.3
+	for name, want := range map[string]string{
+		"Upgrade":                "websocket",
+		"Connection":             "Upgrade",
+		"Sec-Websocket-Accept":   "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=",
+		"Sec-Websocket-Protocol": "chat.v2",
+	} {
+		if got := c.res.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	c.send(t, true, TextMessage, []byte("hello"))
+	if op, data := c.receive(t); op != TextMessage || string(data) != "lobby: hello" {
+		t.Errorf("echo = %d %q", op, data)
+	}
+
+	// A fragmented message with a ping in between comes back whole, after the pong
+	c.send(t, false, TextMessage, []byte("frag"))
+	c.send(t, true, opPing, []byte("are you there"))
+	c.send(t, true, 0, []byte("mented"))
+	if op, data := c.receive(t); op != opPong || string(data) != "are you there" {
+		t.Errorf("pong = %d %q", op, data)
+	}
+	if op, data := c.receive(t); op != TextMessage || string(data) != "lobby: fragmented" {
+		t.Errorf("reassembled = %d %q", op, data)
+	}
+
+	// The close is echoed with the client's code
+	c.send(t, true, opClose, append(binary.BigEndian.AppendUint16(nil, CloseGoingAway), "bye"...))
+	if op, data := c.receive(t); op != opClose || len(data) != 2 || binary.BigEndian.Uint16(data) != CloseGoingAway {
+		t.Errorf("close reply = %d %v", op, data)
+	}
+	var cerr *CloseError
+	if err := <-closed; !errors.As(err, &cerr) || cerr.Code != CloseGoingAway || cerr.Reason != "bye" {
+		t.Errorf("ReadMessage after close = %v", err)
+	}
+
+	if line := <-logs; !strings.Contains(line, `"GET /ws/lobby HTTP/1.1" 101`) {
+		t.Errorf("access log = %q, want the upgrade logged as 101", line)
+	}
+}
+
+func TestUpgradeProtocolViolations(t *testing.T) {
+	readErr := make(chan error, 1)
+	rt := NewRastaRouterInitializer()
+	rt.GET("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, UpgradeOptions{ReadLimit: 8})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		readErr <- err
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	for name, tc := range map[string]struct {
+		opcode  byte
+		payload []byte
+		code    uint16
+	}{
+		"too large":     {TextMessage, []byte("more than eight bytes"), CloseTooLarge},
+		"invalid UTF-8": {TextMessage, []byte{0xff, 0xfe}, CloseInvalidData},
+		"continuation":  {0, []byte("x"), CloseProtocolError},
+	} {
+		c := dialWebSocket(t, srv, "/ws", nil)
+		c.send(t, true, tc.opcode, tc.payload)
+		if op, data := c.receive(t); op != opClose || len(data) < 2 || binary.BigEndian.Uint16(data) != tc.code {
+			t.Errorf("%s: close frame %d %v, want code %d", name, op, data, tc.code)
+		}
+		if err := <-readErr; err == nil {
+			t.Errorf("%s: ReadMessage returned no error", name)
+		}
+	}
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	errs := make(chan error, 1)
+	rt := NewRastaRouterInitializer()
+	rt.ErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		errs <- err
+		DefaultErrorHandler(w, r, status, err)
+	})
+	handler := func(opts UpgradeOptions) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if conn, err := Upgrade(w, r, opts); err == nil {
+				conn.Close()
+			}
+		}
+	}
+	rt.GET("/ws", handler(UpgradeOptions{}))
+	rt.GET("/ws/trusted", handler(UpgradeOptions{CheckOrigin: func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://app.example.com"
+	}}))
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	for name, tc := range map[string]struct {
+		path   string
+		header http.Header
+		status int
+		err    error
+	}{
+		"plain GET":      {"/ws", http.Header{"Upgrade": {"h2c"}}, http.StatusBadRequest, ErrNotWebSocket},
+		"old version":    {"/ws", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired, ErrNotWebSocket},
+		"short key":      {"/ws", http.Header{"Sec-Websocket-Key": {"c2hvcnQ="}}, http.StatusBadRequest, ErrNotWebSocket},
+		"cross origin":   {"/ws", http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden, ErrBadOrigin},
+		"untrusted":      {"/ws/trusted", http.Header{"Origin": {"https://other.example.com"}}, http.StatusForbidden, ErrBadOrigin},
+		"trusted":        {"/ws/trusted", http.Header{"Origin": {"https://app.example.com"}}, http.StatusSwitchingProtocols, nil},
+		"same origin":    {"/ws", http.Header{"Origin": {srv.URL}}, http.StatusSwitchingProtocols, nil},
+		"no subprotocol": {"/ws", http.Header{"Sec-Websocket-Protocol": {"chat"}}, http.StatusSwitchingProtocols, nil},
+	} {
+		c := dialWebSocket(t, srv, tc.path, tc.header)
+		if c.res.StatusCode != tc.status {
+			t.Errorf("%s: status %d, want %d", name, c.res.StatusCode, tc.status)
+		}
+		if tc.err != nil {
+			if err := <-errs; !errors.Is(err, tc.err) {
+				t.Errorf("%s: error %v, want %v", name, err, tc.err)
+			}
+		}
+		if tc.status == http.StatusSwitchingProtocols && c.res.Header.Get("Sec-Websocket-Protocol") != "" {
+			t.Errorf("%s: subprotocol %q chosen without support", name, c.res.Header.Get("Sec-Websocket-Protocol"))
+		}
+		if tc.status == http.StatusUpgradeRequired && c.res.Header.Get("Sec-Websocket-Version") != "13" {
+			t.Errorf("%s: Sec-WebSocket-Version = %q", name, c.res.Header.Get("Sec-Websocket-Version"))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tobingo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// wildcardKey is the context key under which the catch-all capture of the matched route is stored
+const wildcardKey contextKey = "wildcard"
+
+// ErrPathTraversal is returned by GetWildcardClean when the capture would escape its root
+var ErrPathTraversal = errors.New("tobingo: path escapes its root")
+
+// GetWildcard returns the text captured by a trailing catch-all segment such as "*filepath"
+// The capture is the decoded request path after the fixed part of the pattern, slashes
+// included, e.g. "css/site.css" for route "/assets/*filepath" and request "/assets/css/site.css"
+// The boolean is false when the matched route has no catch-all segment
+func GetWildcard(r *http.Request) (string, bool) {
+	capture, ok := r.Context().Value(wildcardKey).(string)
+	return capture, ok
+}
+
+// GetWildcardClean returns the catch-all capture as a cleaned relative path that is safe to
+// join with a filesystem root: redundant slashes and "." elements are removed and ".."
+// elements are resolved, returning ErrPathTraversal if they would climb above the root
+// An empty capture yields "."; backslashes and NUL bytes are rejected as invalid
+// Returns ErrParamMissing when the matched route has no catch-all segment
+func GetWildcardClean(r *http.Request) (string, error) {
+	capture, ok := GetWildcard(r)
+	if !ok {
+		return "", fmt.Errorf("%w: catch-all", ErrParamMissing)
+	}
+	return cleanRelativePath(capture)
+}
+
+// cleanRelativePath cleans p as a slash separated path relative to a root, rejecting
+// anything that would resolve outside it
+func cleanRelativePath(p string) (string, error) {
+	// Backslashes act as separators on Windows and NUL truncates paths in some syscalls
+	if strings.ContainsAny(p, "\\\x00") {
+		return "", fmt.Errorf("%w: %q", ErrParamInvalid, p)
+	}
+
+	// Track depth so "a/../b" is fine while "a/../../b" is not
+	depth := 0
+	for _, elem := range strings.Split(p, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return "", fmt.Errorf("%w: %q", ErrPathTraversal, p)
+			}
+		default:
+			depth++
+		}
+	}
+
+	cleaned := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if cleaned == "" {
+		return ".", nil
+	}
+	return cleaned, nil
+}
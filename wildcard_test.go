@@ -0,0 +1,47 @@
+package tobingo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetWildcard(t *testing.T) {
+	for _, tc := range []struct {
+		path, raw, clean string
+		err              error
+	}{
+		{"/files/css/site.css", "css/site.css", "css/site.css", nil},
+		{"/files/a/b/c/d.txt", "a/b/c/d.txt", "a/b/c/d.txt", nil},
+		{"/files/a%2Fb/c", "a/b/c", "a/b/c", nil},
+		{"/files/a//b/./c", "a//b/./c", "a/b/c", nil},
+		{"/files/a/../b", "a/../b", "b", nil},
+		{"/files/a/../../etc/passwd", "a/../../etc/passwd", "", ErrPathTraversal},
+		{"/files/%2E%2E/secret", "../secret", "", ErrPathTraversal},
+		{"/files/a%5Cb", `a\b`, "", ErrParamInvalid},
+		{"/files/", "", ".", nil},
+	} {
+		withParams(t, "/files/*filepath", tc.path, func(r *http.Request) {
+			raw, ok := GetWildcard(r)
+			if raw != tc.raw || !ok {
+				t.Errorf("%s: GetWildcard = %q, %v, want %q", tc.path, raw, ok, tc.raw)
+			}
+			if GetParam(r, "filepath") != tc.raw {
+				t.Errorf("%s: GetParam = %q", tc.path, GetParam(r, "filepath"))
+			}
+			clean, err := GetWildcardClean(r)
+			if clean != tc.clean || !errors.Is(err, tc.err) || tc.err == nil && err != nil {
+				t.Errorf("%s: GetWildcardClean = %q, %v, want %q, %v", tc.path, clean, err, tc.clean, tc.err)
+			}
+		})
+	}
+
+	withParams(t, "/users/:id", "/users/1", func(r *http.Request) {
+		if _, ok := GetWildcard(r); ok {
+			t.Error("GetWildcard reported a capture for a route without a catch-all")
+		}
+		if _, err := GetWildcardClean(r); !errors.Is(err, ErrParamMissing) {
+			t.Errorf("GetWildcardClean = %v, want ErrParamMissing", err)
+		}
+	})
+}
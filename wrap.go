@@ -0,0 +1,210 @@
+package tobingo
+
+import (
+	"io"
+	"net/http"
+)
+
+// Optional interfaces of a ResponseWriter, as the bits of a writer feature set
+const (
+	featureFlush = 1 << iota
+	featureHijack
+	featurePush
+	featureReadFrom
+)
+
+// fullWriter is a wrapper implementing every optional interface by forwarding, failing with
+// http.ErrNotSupported when the writer beneath it lacks one; exposeFeatures hides the ones
+// that would fail so type assertions such as w.(http.Hijacker) tell the truth
+type fullWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+	FlushError() error
+}
+
+// errorFlusher is the flushing part of fullWriter, FlushError included for http.ResponseController
+type errorFlusher interface {
+	http.Flusher
+	FlushError() error
+}
+
+// writerFeatures returns the optional interfaces implemented by the writer at the bottom of
+// w's Unwrap chain, usually net/http's own, which the wrappers above it forward to
+func writerFeatures(w http.ResponseWriter) int {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+
+	features := 0
+	if _, ok := w.(http.Flusher); ok {
+		features |= featureFlush
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		features |= featureHijack
+	}
+	if _, ok := w.(http.Pusher); ok {
+		features |= featurePush
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		features |= featureReadFrom
+	}
+	return features
+}
+
+// exposedWriter is the base of the writers built by exposeFeatures, carrying only the
+// methods every ResponseWriter has
+type exposedWriter struct {
+	w fullWriter
+}
+
+// Header returns the header map of the wrapped writer
+func (e exposedWriter) Header() http.Header {
+	return e.w.Header()
+}
+
+// Write writes to the wrapped writer
+func (e exposedWriter) Write(b []byte) (int, error) {
+	return e.w.Write(b)
+}
+
+// WriteHeader sends the status through the wrapped writer
+func (e exposedWriter) WriteHeader(code int) {
+	e.w.WriteHeader(code)
+}
+
+// Unwrap returns the wrapped writer, so trackedWriter and http.ResponseController find it
+func (e exposedWriter) Unwrap() http.ResponseWriter {
+	return e.w
+}
+
+// exposed returns the wrapped writer for the checks that must look through exposeFeatures
+func (e exposedWriter) exposed() http.ResponseWriter {
+	return e.w
+}
+
+// exposedTarget returns the writer behind an exposeFeatures result, w itself otherwise
+func exposedTarget(w http.ResponseWriter) http.ResponseWriter {
+	if e, ok := w.(interface{ exposed() http.ResponseWriter }); ok {
+		return e.exposed()
+	}
+	return w
+}
+
+// exposeFeatures returns w with exactly the optional interfaces in features, so handlers
+// and middleware see what the connection really supports through any number of wrappers
+func exposeFeatures(w fullWriter, features int) http.ResponseWriter {
+	if features == featureFlush|featureHijack|featurePush|featureReadFrom {
+		return w
+	}
+
+	e := exposedWriter{w}
+	switch features {
+	case featureFlush:
+		return struct {
+			exposedWriter
+			errorFlusher
+		}{e, w}
+	case featureHijack:
+		return struct {
+			exposedWriter
+			http.Hijacker
+		}{e, w}
+	case featureFlush | featureHijack:
+		return struct {
+			exposedWriter
+			errorFlusher
+			http.Hijacker
+		}{e, w, w}
+	case featurePush:
+		return struct {
+			exposedWriter
+			http.Pusher
+		}{e, w}
+	case featureFlush | featurePush:
+		return struct {
+			exposedWriter
+			errorFlusher
+			http.Pusher
+		}{e, w, w}
+	case featureHijack | featurePush:
+		return struct {
+			exposedWriter
+			http.Hijacker
+			http.Pusher
+		}{e, w, w}
+	case featureFlush | featureHijack | featurePush:
+		return struct {
+			exposedWriter
+			errorFlusher
+			http.Hijacker
+			http.Pusher
+		}{e, w, w, w}
+	case featureReadFrom:
+		return struct {
+			exposedWriter
+			io.ReaderFrom
+		}{e, w}
+	case featureFlush | featureReadFrom:
+		return struct {
+			exposedWriter
+			errorFlusher
+			io.ReaderFrom
+		}{e, w, w}
+	case featureHijack | featureReadFrom:
+		return struct {
+			exposedWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{e, w, w}
+	case featureFlush | featureHijack | featureReadFrom:
+		return struct {
+			exposedWriter
+			errorFlusher
+			http.Hijacker
+			io.ReaderFrom
+		}{e, w, w, w}
+	case featurePush | featureReadFrom:
+		return struct {
+			exposedWriter
+			http.Pusher
+			io.ReaderFrom
+		}{e, w, w}
+	case featureFlush | featurePush | featureReadFrom:
+		return struct {
+			exposedWriter
+			errorFlusher
+			http.Pusher
+			io.ReaderFrom
+		}{e, w, w, w}
+	case featureHijack | featurePush | featureReadFrom:
+		return struct {
+			exposedWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{e, w, w, w}
+	}
+	return e
+}
+
+// pushTo pushes through the first writer in w's Unwrap chain that implements http.Pusher
+func pushTo(w http.ResponseWriter, target string, opts *http.PushOptions) error {
+	for w != nil {
+		if p, ok := w.(http.Pusher); ok {
+			return p.Push(target, opts)
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,341 @@
+package tobingo
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// plainWriter is a connection writer without any optional interface
+type plainWriter struct {
+	rec *httptest.ResponseRecorder
+}
+
+func (w *plainWriter) Header() http.Header         { return w.rec.Header() }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.rec.Write(b) }
+func (w *plainWriter) WriteHeader(code int)        { w.rec.WriteHeader(code) }
+
+// http1Writer has the optional interfaces of net/http's HTTP/1.1 writer, hijacking onto conn
+type http1Writer struct {
+	plainWriter
+	conn     net.Conn
+	readFrom int // Calls of ReadFrom
+}
+
+func (w *http1Writer) Flush() { w.rec.Flush() }
+
+func (w *http1Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+func (w *http1Writer) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.rec, src)
+}
+
+// http2Writer has the optional interfaces of net/http's HTTP/2 writer
+type http2Writer struct {
+	plainWriter
+	pushed []string
+}
+
+func (w *http2Writer) Flush() { w.rec.Flush() }
+
+func (w *http2Writer) Push(target string, opts *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+// writerInterfaces names the optional interfaces w implements
+func writerInterfaces(w http.ResponseWriter) string {
+	var names []string
+	if _, ok := w.(http.Flusher); ok {
+		names = append(names, "Flusher")
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		names = append(names, "Hijacker")
+	}
+	if _, ok := w.(http.Pusher); ok {
+		names = append(names, "Pusher")
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		names = append(names, "ReaderFrom")
+	}
+	return strings.Join(names, ",")
+}
+
+func TestResponseRecorderForwarding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		w    http.ResponseWriter
+		want string
+	}{
+		"plain":  {&plainWriter{httptest.NewRecorder()}, ""},
+		"HTTP/1": {&http1Writer{plainWriter: plainWriter{httptest.NewRecorder()}}, "Flusher,Hijacker,ReaderFrom"},
+		"HTTP/2": {&http2Writer{plainWriter: plainWriter{httptest.NewRecorder()}}, "Flusher,Pusher"},
+	} {
+		var got string
+		rt := NewRastaRouterInitializer()
+		rt.GET("/", func(w http.ResponseWriter, r *http.Request) { got = writerInterfaces(w) })
+		rt.ServeHTTP(tc.w, httptest.NewRequest("GET", "/", nil))
+		if got != tc.want {
+			t.Errorf("%s: handler saw %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestResponseRecorderCalls(t *testing.T) {
+	w := &http1Writer{plainWriter: plainWriter{httptest.NewRecorder()}}
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		rec := Recorder(r)
+		w.(io.ReaderFrom).ReadFrom(strings.NewReader("sendfile"))
+		if rec.Status() != http.StatusOK || rec.BytesWritten() != 8 {
+			t.Errorf("after ReadFrom: status %d, %d bytes", rec.Status(), rec.BytesWritten())
+		}
+		w.(http.Flusher).Flush()
+	})
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.readFrom != 1 || w.rec.Body.String() != "sendfile" || !w.rec.Flushed {
+		t.Errorf("ReadFrom calls %d, body %q, flushed %v", w.readFrom, w.rec.Body.String(), w.rec.Flushed)
+	}
+
+	h2 := &http2Writer{plainWriter: plainWriter{httptest.NewRecorder()}}
+	rt = NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.css", nil); err != nil {
+			t.Error(err)
+		}
+	})
+	rt.ServeHTTP(h2, httptest.NewRequest("GET", "/", nil))
+	if len(h2.pushed) != 1 || h2.pushed[0] != "/app.css" {
+		t.Errorf("pushed %v", h2.pushed)
+	}
+}
+
+func TestResponseRecorderImplicitStatus(t *testing.T) {
+	var before, after [3]any
+	rt := NewRastaRouterInitializer()
+	rt.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		rec := Recorder(r)
+		before = [3]any{rec.Status(), rec.BytesWritten(), rec.Written()}
+		w.Write([]byte("hello"))
+		w.Write([]byte(" world"))
+		after = [3]any{rec.Status(), rec.BytesWritten(), rec.Written()}
+	})
+	res := rt.Test("GET", "/", nil)
+
+	if before != [3]any{0, int64(0), false} {
+		t.Errorf("before writing: %v", before)
+	}
+	if after != [3]any{http.StatusOK, int64(11), true} {
+		t.Errorf("after writing: %v", after)
+	}
+	if res.StatusCode() != http.StatusOK || res.BodyString() != "hello world" {
+		t.Errorf("got %d %q", res.StatusCode(), res.BodyString())
+	}
+}
+
+func TestResponseRecorderNotStacked(t *testing.T) {
+	var outerRec, innerRec ResponseRecorder
+	var innerWriter string
+	inner := NewRastaRouterInitializer()
+	inner.GET("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		innerRec = Recorder(r)
+		innerWriter = writerInterfaces(w)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	outer := NewRastaRouterInitializer()
+	outer.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			outerRec = Recorder(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+	outer.GET("/api/*rest", inner.ServeHTTP)
+
+	w := &http1Writer{plainWriter: plainWriter{httptest.NewRecorder()}}
+	outer.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if outerRec == nil || innerRec != outerRec {
+		t.Errorf("inner router installed its own recorder: outer %p, inner %p", outerRec, innerRec)
+	}
+	if outerRec.Status() != http.StatusAccepted || w.rec.Code != http.StatusAccepted {
+		t.Errorf("status %d, sent %d", outerRec.Status(), w.rec.Code)
+	}
+	if innerWriter != "Flusher,Hijacker,ReaderFrom" {
+		t.Errorf("inner handler saw %q", innerWriter)
+	}
+}
+
+func TestRecorderOutsideRouter(t *testing.T) {
+	if rec := Recorder(httptest.NewRequest("GET", "/", nil)); rec != nil {
+		t.Errorf("Recorder = %v, want nil", rec)
+	}
+}
+
+// wrapperStack registers handler at "/" behind some of the shipped middleware that wrap the
+// writer, returning the method and headers a request needs to pass through all of them
+type wrapperStack func(rt *Rastauter, handler http.HandlerFunc) (method string, header http.Header)
+
+// wrapperStacks are 0, 1, and 2 layers of the shipped wrapping middleware
+var wrapperStacks = map[string]wrapperStack{
+	"none": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.GET("/", h)
+		return "GET", nil
+	},
+	"Logger": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.Use(Logger(LoggerOptions{Output: io.Discard}))
+		rt.GET("/", h)
+		return "GET", nil
+	},
+	"Cache": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.GET("/", h).Use(Cache(time.Minute))
+		return "GET", nil
+	},
+	"Idempotency": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.addRoute("POST", "/", h).Use(Idempotency(NewMemoryIdempotencyStore()))
+		return "POST", http.Header{"Idempotency-Key": {"k"}}
+	},
+	"Timeout": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.GET("/", h).Timeout(time.Minute)
+		return "GET", nil
+	},
+	"Logger+Cache": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.Use(Logger(LoggerOptions{Output: io.Discard}))
+		rt.GET("/", h).Use(Cache(time.Minute))
+		return "GET", nil
+	},
+	"Cache+Cache": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.GET("/", h).Use(Cache(time.Minute), Cache(time.Minute, CacheOptions{Query: []string{}}))
+		return "GET", nil
+	},
+	"Timeout+Cache": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.GET("/", h).Use(Cache(time.Minute)).Timeout(time.Minute)
+		return "GET", nil
+	},
+	"Timeout+Idempotency": func(rt *Rastauter, h http.HandlerFunc) (string, http.Header) {
+		rt.addRoute("POST", "/", h).Use(Idempotency(NewMemoryIdempotencyStore())).Timeout(time.Minute)
+		return "POST", http.Header{"Idempotency-Key": {"k"}}
+	},
+}
+
+func TestWriterInterfacesThroughMiddleware(t *testing.T) {
+	writers := map[string]struct {
+		new  func() http.ResponseWriter
+		want string
+	}{
+		"plain":  {func() http.ResponseWriter { return &plainWriter{httptest.NewRecorder()} }, ""},
+		"HTTP/1": {func() http.ResponseWriter { return &http1Writer{plainWriter: plainWriter{httptest.NewRecorder()}} }, "Flusher,Hijacker,ReaderFrom"},
+		"HTTP/2": {func() http.ResponseWriter { return &http2Writer{plainWriter: plainWriter{httptest.NewRecorder()}} }, "Flusher,Pusher"},
+	}
+	for stackName, stack := range wrapperStacks {
+		for writerName, writer := range writers {
+			got := "not called"
+			rt := NewRastaRouterInitializer()
+			method, header := stack(rt, func(w http.ResponseWriter, r *http.Request) {
+				got = writerInterfaces(w)
+				w.Write([]byte("ok"))
+			})
+			req := httptest.NewRequest(method, "/", nil)
+			for name, values := range header {
+				req.Header[name] = values
+			}
+			rt.ServeHTTP(writer.new(), req)
+			if got != writer.want {
+				t.Errorf("%s over %s: handler saw %q, want %q", stackName, writerName, got, writer.want)
+			}
+		}
+	}
+}
+
+func TestWriterInterfacesWorkThroughMiddleware(t *testing.T) {
+	for stackName, stack := range wrapperStacks {
+		// Flush and ReadFrom reach an HTTP/1 connection
+		w1 := &http1Writer{plainWriter: plainWriter{httptest.NewRecorder()}}
+		rt := NewRastaRouterInitializer()
+		method, header := stack(rt, func(w http.ResponseWriter, r *http.Request) {
+			if _, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("sendfile")); err != nil {
+				t.Errorf("%s: ReadFrom: %v", stackName, err)
+			}
+			w.(http.Flusher).Flush()
+		})
+		req := httptest.NewRequest(method, "/", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rt.ServeHTTP(w1, req)
+		if w1.rec.Body.String() != "sendfile" || !w1.rec.Flushed {
+			t.Errorf("%s: body %q, flushed %v", stackName, w1.rec.Body.String(), w1.rec.Flushed)
+		}
+
+		// Push reaches an HTTP/2 connection
+		w2 := &http2Writer{plainWriter: plainWriter{httptest.NewRecorder()}}
+		rt = NewRastaRouterInitializer()
+		method, header = stack(rt, func(w http.ResponseWriter, r *http.Request) {
+			if err := w.(http.Pusher).Push("/app.css", nil); err != nil {
+				t.Errorf("%s: Push: %v", stackName, err)
+			}
+		})
+		req = httptest.NewRequest(method, "/", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rt.ServeHTTP(w2, req)
+		if len(w2.pushed) != 1 || w2.pushed[0] != "/app.css" {
+			t.Errorf("%s: pushed %v", stackName, w2.pushed)
+		}
+	}
+}
+
+func TestHijackUnderLogger(t *testing.T) {
+	logs := make(logLines, 1)
+	rt := NewRastaRouterInitializer()
+	rt.Use(Logger(LoggerOptions{Output: logs}))
+	rt.GET("/raw", func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\nraw ok")
+		brw.Flush()
+	}).Use(Cache(time.Minute))
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "raw ok" {
+		t.Errorf("hijacked response: %d %q", res.StatusCode, body)
+	}
+
+	// The hijacked request is still logged, and the cache kept nothing of it
+	select {
+	case line := <-logs:
+		if !strings.Contains(line, "GET /raw") {
+			t.Errorf("log line %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("hijacked request was not logged")
+	}
+	res, err = http.Get(srv.URL + "/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.Header.Get("X-Cache") == "HIT" {
+		t.Error("hijacked response was cached")
+	}
+	<-logs
+}